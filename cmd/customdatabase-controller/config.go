@@ -0,0 +1,480 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/yaml"
+
+	"k8s.io/custom-database/internal/customdatabase"
+	"k8s.io/custom-database/internal/customdatabase/usecases"
+)
+
+const (
+	// configAPIVersion and configKind of -config file, file of another version is rejected
+	configAPIVersion = "config.igor.yatsevich.ru/v1alpha1"
+	configKind       = "ControllerConfiguration"
+
+	// redacted replaces secrets in dump of configuration
+	redacted = "REDACTED"
+)
+
+const (
+	// featureServerMigration CustomDatabase is moved to server of the new spec.serverRef
+	featureServerMigration = "ServerMigration"
+	// featureDatabaseUsers, featureDatabaseGrants and featureDatabaseBackups run controllers of their resources, they
+	// may be disabled, when CRDs of resources aren't installed. DatabaseRestores are run together with DatabaseBackups.
+	featureDatabaseUsers   = "DatabaseUsers"
+	featureDatabaseGrants  = "DatabaseGrants"
+	featureDatabaseBackups = "DatabaseBackups"
+)
+
+// defaultFeatureGates features known by controller and whether they are enabled by default
+var defaultFeatureGates = map[string]bool{
+	featureServerMigration: true,
+	featureDatabaseUsers:   true,
+	featureDatabaseGrants:  true,
+	featureDatabaseBackups: true,
+}
+
+// ControllerConfiguration settings of controller from -config file. Flags, which are set explicitly, override file.
+type ControllerConfiguration struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+
+	Kubernetes  KubernetesConfiguration  `json:"kubernetes"`
+	Workers     WorkersConfiguration     `json:"workers"`
+	RateLimiter RateLimiterConfiguration `json:"rateLimiter"`
+	// NamingStrategy how databases and owner roles are named after CustomDatabases: name or namespace-name
+	NamingStrategy string `json:"namingStrategy"`
+	// FeatureGates enables or disables features by name, features, which aren't listed, have default state
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+
+	MetricsAddr string `json:"metricsAddr"`
+	DryRun      bool   `json:"dryRun"`
+	ReadOnly    bool   `json:"readOnly"`
+
+	Periods           PeriodsConfiguration `json:"periods"`
+	DriftPolicy       string               `json:"driftPolicy"`
+	MigrationsDir     string               `json:"migrationsDir"`
+	AdoptionAllowList []string             `json:"adoptionAllowList,omitempty"`
+	Orphans           OrphansConfiguration `json:"orphans"`
+
+	Backup    BackupConfiguration    `json:"backup"`
+	Postgres  PostgresConfiguration  `json:"postgres"`
+	TenantTLS TenantTLSConfiguration `json:"tenantTLS"`
+
+	// Servers additional Postgresql servers, which CustomDatabase may choose by spec.serverRef
+	Servers []serverConfig `json:"servers,omitempty"`
+	// ServersFile JSON file with more additional servers, e.g. mounted Secret with their passwords
+	ServersFile                string          `json:"serversFile"`
+	ServerMigrationGracePeriod metav1.Duration `json:"serverMigrationGracePeriod"`
+}
+
+// KubernetesConfiguration connection to Kubernetes API and informers
+type KubernetesConfiguration struct {
+	Kubeconfig  string `json:"kubeconfig"`
+	Master      string `json:"master"`
+	ClusterName string `json:"clusterName"`
+	// ResyncPeriod how often informers of Secrets and custom resources send all objects to controllers again
+	ResyncPeriod metav1.Duration `json:"resyncPeriod"`
+}
+
+// WorkersConfiguration amount of workers of every controller
+type WorkersConfiguration struct {
+	CustomDatabases  int `json:"customDatabases"`
+	DatabaseUsers    int `json:"databaseUsers"`
+	DatabaseGrants   int `json:"databaseGrants"`
+	DatabaseBackups  int `json:"databaseBackups"`
+	DatabaseRestores int `json:"databaseRestores"`
+}
+
+// RateLimiterConfiguration how failed objects are retried by workqueues of all controllers
+type RateLimiterConfiguration struct {
+	BaseDelay metav1.Duration `json:"baseDelay"`
+	MaxDelay  metav1.Duration `json:"maxDelay"`
+	QPS       float64         `json:"qps"`
+	Burst     int             `json:"burst"`
+}
+
+// PeriodsConfiguration how often every database is sampled, audited and checked
+type PeriodsConfiguration struct {
+	StorageSampling  metav1.Duration `json:"storageSampling"`
+	SecurityAudit    metav1.Duration `json:"securityAudit"`
+	DriftCheck       metav1.Duration `json:"driftCheck"`
+	ActivitySampling metav1.Duration `json:"activitySampling"`
+	ExpiryWarning    metav1.Duration `json:"expiryWarning"`
+}
+
+// OrphansConfiguration databases and roles, whose CustomDatabase was deleted while controller was down
+type OrphansConfiguration struct {
+	SweepPeriod metav1.Duration `json:"sweepPeriod"`
+	GC          bool            `json:"gc"`
+	GracePeriod metav1.Duration `json:"gracePeriod"`
+}
+
+// BackupConfiguration utilities and storage of backups
+type BackupConfiguration struct {
+	PgDumpPath    string          `json:"pgDumpPath"`
+	PgRestorePath string          `json:"pgRestorePath"`
+	Storage       string          `json:"storage"`
+	Dir           string          `json:"dir"`
+	S3            S3Configuration `json:"s3"`
+}
+
+// S3Configuration S3-compatible storage of backups
+type S3Configuration struct {
+	Endpoint        string `json:"endpoint"`
+	Region          string `json:"region"`
+	Bucket          string `json:"bucket"`
+	AccessKeyID     string `json:"accessKeyID"`
+	SecretAccessKey string `json:"secretAccessKey"`
+	UsePathStyle    bool   `json:"usePathStyle"`
+}
+
+// PostgresConfiguration default Postgresql server and its admin connections
+type PostgresConfiguration struct {
+	// ServerName name of server, it's used by CustomDatabases without spec.serverRef
+	ServerName        string          `json:"serverName"`
+	Host              string          `json:"host"`
+	Port              int             `json:"port"`
+	AdminUser         string          `json:"adminUser"`
+	AdminPassword     string          `json:"adminPassword"`
+	AdminPasswordFile string          `json:"adminPasswordFile"`
+	AdminSecret       string          `json:"adminSecret"`
+	AdminReloadPeriod metav1.Duration `json:"adminReloadPeriod"`
+	SSLMode           string          `json:"sslMode"`
+	SSLRootCert       string          `json:"sslRootCert"`
+	SSLCert           string          `json:"sslCert"`
+	SSLKey            string          `json:"sslKey"`
+	TLSSecret         string          `json:"tlsSecret"`
+}
+
+// TenantTLSConfiguration TLS settings, which are published to Secrets of tenants
+type TenantTLSConfiguration struct {
+	SSLMode          string          `json:"sslMode"`
+	CAFile           string          `json:"caFile"`
+	CertIssuerSecret string          `json:"certIssuerSecret"`
+	CertValidity     metav1.Duration `json:"certValidity"`
+}
+
+// defaultConfiguration returns configuration, which is used without -config file and flags
+func defaultConfiguration() ControllerConfiguration {
+	rateLimiter := usecases.DefaultRateLimiterConfig()
+
+	return ControllerConfiguration{
+		APIVersion: configAPIVersion,
+		Kind:       configKind,
+		Kubernetes: KubernetesConfiguration{ResyncPeriod: metav1.Duration{Duration: 30 * time.Second}},
+		Workers: WorkersConfiguration{
+			CustomDatabases:  2,
+			DatabaseUsers:    2,
+			DatabaseGrants:   2,
+			DatabaseBackups:  2,
+			DatabaseRestores: 2,
+		},
+		RateLimiter: RateLimiterConfiguration{
+			BaseDelay: metav1.Duration{Duration: rateLimiter.BaseDelay},
+			MaxDelay:  metav1.Duration{Duration: rateLimiter.MaxDelay},
+			QPS:       rateLimiter.QPS,
+			Burst:     rateLimiter.Burst,
+		},
+		NamingStrategy: string(customdatabase.NamingStrategyName),
+		MetricsAddr:    ":8080",
+		Periods: PeriodsConfiguration{
+			StorageSampling:  metav1.Duration{Duration: time.Minute},
+			SecurityAudit:    metav1.Duration{Duration: 10 * time.Minute},
+			DriftCheck:       metav1.Duration{Duration: 10 * time.Minute},
+			ActivitySampling: metav1.Duration{Duration: 10 * time.Minute},
+			ExpiryWarning:    metav1.Duration{Duration: time.Hour},
+		},
+		DriftPolicy: string(customdatabase.DriftPolicyRepair),
+		Orphans: OrphansConfiguration{
+			SweepPeriod: metav1.Duration{Duration: 10 * time.Minute},
+			GracePeriod: metav1.Duration{Duration: 24 * time.Hour},
+		},
+		Backup: BackupConfiguration{
+			PgDumpPath:    "pg_dump",
+			PgRestorePath: "pg_restore",
+			Storage:       "filesystem",
+			Dir:           "/var/lib/customdatabase/backups",
+			S3: S3Configuration{
+				Endpoint:        "https://s3.amazonaws.com",
+				Region:          "us-east-1",
+				AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+				SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+				UsePathStyle:    true,
+			},
+		},
+		Postgres: PostgresConfiguration{
+			ServerName:        "default",
+			Host:              "localhost",
+			Port:              5432,
+			AdminReloadPeriod: metav1.Duration{Duration: 30 * time.Second},
+			SSLMode:           "disable",
+		},
+		TenantTLS:                  TenantTLSConfiguration{CertValidity: metav1.Duration{Duration: 365 * 24 * time.Hour}},
+		ServerMigrationGracePeriod: metav1.Duration{Duration: 24 * time.Hour},
+	}
+}
+
+// loadConfiguration reads -config file into configuration, which flags of fs are bound to. Flags, which are set
+// explicitly, are applied again over file. Configuration is only validated, when path is empty.
+func loadConfiguration(fs *flag.FlagSet, path string, config *ControllerConfiguration) error {
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		fileConfig := defaultConfiguration()
+		if err = yaml.UnmarshalStrict(data, &fileConfig); err != nil {
+			return fmt.Errorf("config file %s: %w", path, err)
+		}
+		if fileConfig.APIVersion != configAPIVersion || fileConfig.Kind != configKind {
+			return fmt.Errorf("config file %s: expected %s %s, got %s %s",
+				path, configAPIVersion, configKind, fileConfig.APIVersion, fileConfig.Kind,
+			)
+		}
+
+		// flags are bound to fields of configuration, so values of flags are lost, when it's replaced by file
+		explicitFlags := make(map[string]string)
+		fs.Visit(func(f *flag.Flag) {
+			explicitFlags[f.Name] = f.Value.String()
+		})
+		*config = fileConfig
+		for name, value := range explicitFlags {
+			if err = fs.Set(name, value); err != nil {
+				return fmt.Errorf("flag -%s: %w", name, err)
+			}
+		}
+	}
+
+	return config.Validate()
+}
+
+// Validate returns all errors of configuration
+func (c *ControllerConfiguration) Validate() error {
+	var errs []error
+
+	for name, workers := range map[string]int{
+		"customDatabases":  c.Workers.CustomDatabases,
+		"databaseUsers":    c.Workers.DatabaseUsers,
+		"databaseGrants":   c.Workers.DatabaseGrants,
+		"databaseBackups":  c.Workers.DatabaseBackups,
+		"databaseRestores": c.Workers.DatabaseRestores,
+	} {
+		if workers < 1 {
+			errs = append(errs, fmt.Errorf("workers.%s should be positive", name))
+		}
+	}
+	if c.RateLimiter.BaseDelay.Duration <= 0 || c.RateLimiter.MaxDelay.Duration < c.RateLimiter.BaseDelay.Duration {
+		errs = append(errs, fmt.Errorf("rateLimiter.baseDelay should be positive and not longer than maxDelay"))
+	}
+	if c.RateLimiter.QPS <= 0 || c.RateLimiter.Burst < 1 {
+		errs = append(errs, fmt.Errorf("rateLimiter.qps and rateLimiter.burst should be positive"))
+	}
+	if c.Kubernetes.ResyncPeriod.Duration < 0 {
+		errs = append(errs, fmt.Errorf("kubernetes.resyncPeriod should not be negative"))
+	}
+
+	if _, err := customdatabase.ParseNamingStrategy(c.NamingStrategy); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := customdatabase.ParseDriftPolicy(c.DriftPolicy); err != nil {
+		errs = append(errs, err)
+	}
+	for name := range c.FeatureGates {
+		if _, ok := defaultFeatureGates[name]; !ok {
+			errs = append(errs, fmt.Errorf("unknown feature gate %q", name))
+		}
+	}
+
+	for name, period := range map[string]metav1.Duration{
+		"periods.storageSampling":    c.Periods.StorageSampling,
+		"periods.securityAudit":      c.Periods.SecurityAudit,
+		"periods.driftCheck":         c.Periods.DriftCheck,
+		"periods.activitySampling":   c.Periods.ActivitySampling,
+		"orphans.sweepPeriod":        c.Orphans.SweepPeriod,
+		"postgres.adminReloadPeriod": c.Postgres.AdminReloadPeriod,
+	} {
+		if period.Duration <= 0 {
+			errs = append(errs, fmt.Errorf("%s should be positive", name))
+		}
+	}
+
+	if c.Backup.Storage != "filesystem" && c.Backup.Storage != "s3" {
+		errs = append(errs, fmt.Errorf("unknown backup storage %q", c.Backup.Storage))
+	}
+	if c.Postgres.Host == "" || c.Postgres.Port < 1 || c.Postgres.Port > 65535 {
+		errs = append(errs, fmt.Errorf("postgres.host and postgres.port should be valid"))
+	}
+	if c.Postgres.ServerName == "" {
+		errs = append(errs, fmt.Errorf("postgres.serverName should be not empty"))
+	}
+	// certificate with shorter validity would be issued again on every sync
+	if c.TenantTLS.CertValidity.Duration <= usecases.ClientCertificateRenewBefore {
+		errs = append(errs, fmt.Errorf("tenantTLS.certValidity should be longer than %s, certificates are issued "+
+			"again before expiration", usecases.ClientCertificateRenewBefore,
+		))
+	}
+	if err := validateServers(c.Postgres.ServerName, c.Servers); err != nil {
+		errs = append(errs, fmt.Errorf("servers: %w", err))
+	}
+
+	return utilerrors.NewAggregate(sortedErrors(errs))
+}
+
+// sortedErrors returns errors in order of their messages, because errors of maps are found in random order
+func sortedErrors(errs []error) []error {
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Error() < errs[j].Error()
+	})
+
+	return errs
+}
+
+// isFeatureEnabled reports whether feature is enabled by gates of configuration or by default
+func (c *ControllerConfiguration) isFeatureEnabled(name string) bool {
+	if enabled, ok := c.FeatureGates[name]; ok {
+		return enabled
+	}
+
+	return defaultFeatureGates[name]
+}
+
+// rateLimiter returns settings of rate limiter of workqueues
+func (c *ControllerConfiguration) rateLimiter() usecases.RateLimiterConfig {
+	return usecases.RateLimiterConfig{
+		BaseDelay: c.RateLimiter.BaseDelay.Duration,
+		MaxDelay:  c.RateLimiter.MaxDelay.Duration,
+		QPS:       c.RateLimiter.QPS,
+		Burst:     c.RateLimiter.Burst,
+	}
+}
+
+// redacted returns copy of configuration, where passwords and secret keys are replaced
+func (c *ControllerConfiguration) redacted() ControllerConfiguration {
+	config := *c
+	config.Postgres.AdminPassword = redactedValue(config.Postgres.AdminPassword)
+	config.Backup.S3.SecretAccessKey = redactedValue(config.Backup.S3.SecretAccessKey)
+	config.Servers = make([]serverConfig, len(c.Servers))
+	for i, server := range c.Servers {
+		server.AdminPassword = redactedValue(server.AdminPassword)
+		config.Servers[i] = server
+	}
+
+	return config
+}
+
+// dump returns YAML of configuration without secrets, which is logged at startup
+func (c *ControllerConfiguration) dump() (string, error) {
+	data, err := yaml.Marshal(c.redacted())
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// redactedValue hides secret, empty value is kept to show that secret isn't set
+func redactedValue(secret string) string {
+	if secret == "" {
+		return ""
+	}
+
+	return redacted
+}
+
+// listValue flag of comma-separated list
+type listValue struct {
+	items *[]string
+}
+
+func (v listValue) String() string {
+	if v.items == nil {
+		return ""
+	}
+
+	return strings.Join(*v.items, ",")
+}
+
+func (v listValue) Set(value string) error {
+	*v.items = splitList(value)
+	return nil
+}
+
+// workersValue flag, which sets the same amount of workers for all controllers
+type workersValue struct {
+	workers *WorkersConfiguration
+}
+
+func (v workersValue) String() string {
+	if v.workers == nil {
+		return ""
+	}
+
+	return strconv.Itoa(v.workers.CustomDatabases)
+}
+
+func (v workersValue) Set(value string) error {
+	workers, err := strconv.Atoi(value)
+	if err != nil {
+		return err
+	}
+	*v.workers = WorkersConfiguration{
+		CustomDatabases:  workers,
+		DatabaseUsers:    workers,
+		DatabaseGrants:   workers,
+		DatabaseBackups:  workers,
+		DatabaseRestores: workers,
+	}
+
+	return nil
+}
+
+// featureGatesValue flag of comma-separated gates Name=true|false, they are added to gates of configuration
+type featureGatesValue struct {
+	gates *map[string]bool
+}
+
+func (v featureGatesValue) String() string {
+	if v.gates == nil {
+		return ""
+	}
+
+	items := make([]string, 0, len(*v.gates))
+	for name, enabled := range *v.gates {
+		items = append(items, fmt.Sprintf("%s=%t", name, enabled))
+	}
+	sort.Strings(items)
+
+	return strings.Join(items, ",")
+}
+
+func (v featureGatesValue) Set(value string) error {
+	gates := make(map[string]bool, len(*v.gates))
+	for name, enabled := range *v.gates {
+		gates[name] = enabled
+	}
+	for _, item := range splitList(value) {
+		name, state, ok := strings.Cut(item, "=")
+		if !ok {
+			return fmt.Errorf("feature gate %q should be in format Name=true|false", item)
+		}
+		enabled, err := strconv.ParseBool(state)
+		if err != nil {
+			return fmt.Errorf("feature gate %q: %w", name, err)
+		}
+		gates[strings.TrimSpace(name)] = enabled
+	}
+	*v.gates = gates
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testConfig = `
+apiVersion: config.igor.yatsevich.ru/v1alpha1
+kind: ControllerConfiguration
+kubernetes:
+  resyncPeriod: 5m
+workers:
+  customDatabases: 4
+  databaseBackups: 1
+rateLimiter:
+  baseDelay: 100ms
+  maxDelay: 5m
+  qps: 5
+  burst: 20
+namingStrategy: namespace-name
+featureGates:
+  DatabaseBackups: false
+postgres:
+  host: db.internal
+  adminUser: admin
+  adminPassword: file-password
+backup:
+  s3:
+    secretAccessKey: s3-secret
+servers:
+- name: reserve
+  host: reserve.internal
+  port: 5432
+  adminUser: admin
+  adminPassword: reserve-password
+`
+
+func TestLoadConfiguration(t *testing.T) {
+	path := writeConfig(t, testConfig)
+	config := defaultConfiguration()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	bindFlags(fs, &config)
+	if err := fs.Parse([]string{"-pg_host=db.flag", "-feature-gates=ServerMigration=false"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := loadConfiguration(fs, path, &config); err != nil {
+		t.Fatal(err)
+	}
+	if config.Postgres.Host != "db.flag" {
+		t.Errorf("flag should override file, got host %s", config.Postgres.Host)
+	}
+	if config.Postgres.AdminUser != "admin" || config.Kubernetes.ResyncPeriod.Duration != 5*time.Minute {
+		t.Errorf("settings of file should be applied, got %+v", config)
+	}
+	if config.Workers.CustomDatabases != 4 || config.Workers.DatabaseBackups != 1 || config.Workers.DatabaseUsers != 2 {
+		t.Errorf("workers missing in file should have defaults, got %+v", config.Workers)
+	}
+	if config.Postgres.Port != 5432 || config.Backup.Storage != "filesystem" {
+		t.Errorf("settings missing in file should have defaults, got %+v", config)
+	}
+	if config.isFeatureEnabled(featureServerMigration) || config.isFeatureEnabled(featureDatabaseBackups) ||
+		!config.isFeatureEnabled(featureDatabaseUsers) {
+		t.Errorf("feature gates of flag should be added to gates of file, got %v", config.FeatureGates)
+	}
+
+	// flags keep working after configuration is replaced by file
+	if err := fs.Set("workers", "3"); err != nil {
+		t.Fatal(err)
+	}
+	if config.Workers.DatabaseRestores != 3 {
+		t.Errorf("-workers should set workers of all controllers, got %+v", config.Workers)
+	}
+}
+
+func TestRejectInvalidConfiguration(t *testing.T) {
+	for name, content := range map[string]string{
+		"unknown field": testConfig + "unknownSetting: true\n",
+		"another kind":  strings.Replace(testConfig, "kind: ControllerConfiguration", "kind: Config", 1),
+		"zero workers":  strings.Replace(testConfig, "customDatabases: 4", "customDatabases: 0", 1),
+		"unknown gate":  strings.Replace(testConfig, "DatabaseBackups: false", "Unknown: true", 1),
+		"naming":        strings.Replace(testConfig, "namespace-name", "uid", 1),
+		"rate limiter":  strings.Replace(testConfig, "maxDelay: 5m", "maxDelay: 10ms", 1),
+		"server":        strings.Replace(testConfig, "name: reserve", "name: default", 1),
+		"cert validity": testConfig + "tenantTLS:\n  certValidity: 720h\n",
+	} {
+		config := defaultConfiguration()
+		if err := loadConfiguration(flag.NewFlagSet("test", flag.ContinueOnError), writeConfig(t, content), &config); err == nil {
+			t.Errorf("%s: configuration should be rejected", name)
+		}
+	}
+}
+
+func TestDumpConfigurationWithoutSecrets(t *testing.T) {
+	config := defaultConfiguration()
+	if err := loadConfiguration(flag.NewFlagSet("test", flag.ContinueOnError), writeConfig(t, testConfig), &config); err != nil {
+		t.Fatal(err)
+	}
+
+	dump, err := config.dump()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"file-password", "s3-secret", "reserve-password"} {
+		if strings.Contains(dump, secret) {
+			t.Errorf("dump shouldn't contain secret %s:\n%s", secret, dump)
+		}
+	}
+	if !strings.Contains(dump, "host: db.internal") {
+		t.Errorf("dump should contain settings:\n%s", dump)
+	}
+	if config.Servers[0].AdminPassword != "reserve-password" {
+		t.Errorf("dump shouldn't change configuration")
+	}
+}
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// secretKeyUsername and secretKeyPassword keys of Secret of type kubernetes.io/basic-auth
+	secretKeyUsername = "username"
+	secretKeyPassword = "password"
+)
+
+// adminCredentials user and password of admin connections
+type adminCredentials struct {
+	user     string
+	password string
+}
+
+// adminCredentialsSource where credentials of admin connections to server are read from: password of flags or
+// configuration is replaced by file or Secret, when they are set
+type adminCredentialsSource struct {
+	user         string
+	password     string
+	passwordFile string
+	secret       string
+}
+
+// defaultAdminCredentialsSource returns source of credentials of the default server of -pg_host
+func defaultAdminCredentialsSource() adminCredentialsSource {
+	settings := configuration.Postgres
+
+	return adminCredentialsSource{
+		user:         settings.AdminUser,
+		password:     settings.AdminPassword,
+		passwordFile: settings.AdminPasswordFile,
+		secret:       settings.AdminSecret,
+	}
+}
+
+// isReloadable reports whether credentials are read from Secret or file, which may change
+func (s adminCredentialsSource) isReloadable() bool {
+	return s.secret != "" || s.passwordFile != ""
+}
+
+// load reads credentials from Secret or from file, user of source is used, when Secret has no user name. Credentials
+// of source are used without them.
+func (s adminCredentialsSource) load(ctx context.Context, kubeClient kubernetes.Interface) (adminCredentials, error) {
+	credentials := adminCredentials{user: s.user, password: s.password}
+
+	switch {
+	case s.secret != "":
+		secret, err := getSecret(ctx, kubeClient, s.secret)
+		if err != nil {
+			return credentials, err
+		}
+		password, ok := secret.Data[secretKeyPassword]
+		if !ok {
+			return credentials, fmt.Errorf("secret %s has no %s", s.secret, secretKeyPassword)
+		}
+		credentials.password = string(password)
+		if user := secret.Data[secretKeyUsername]; len(user) > 0 {
+			credentials.user = string(user)
+		}
+	case s.passwordFile != "":
+		password, err := os.ReadFile(s.passwordFile)
+		if err != nil {
+			return credentials, err
+		}
+		credentials.password = strings.TrimRight(string(password), "\r\n")
+	}
+
+	return credentials, nil
+}
+
+// watchAdminCredentials polls source of credentials and reconnects to server, when they change. Reconnect is retried
+// by the next poll, when server rejects new credentials.
+func watchAdminCredentials(
+	ctx context.Context, logger klog.Logger, kubeClient kubernetes.Interface, server *serverConnection,
+	source adminCredentialsSource, current adminCredentials,
+) {
+	period := configuration.Postgres.AdminReloadPeriod.Duration
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		credentials, err := source.load(ctx, kubeClient)
+		if err != nil {
+			logger.Error(err, "Error reading admin credentials")
+			return
+		}
+		if credentials == current {
+			return
+		}
+
+		reconnectCtx, cancel := context.WithTimeout(ctx, period)
+		defer cancel()
+		if err = server.reconnect(reconnectCtx, credentials.user, credentials.password); err != nil {
+			logger.Error(err, "Error reconnecting with new admin credentials, the old ones are used")
+			return
+		}
+		current = credentials
+		logger.Info("Admin connections are reopened with new credentials", "user", credentials.user)
+	}, period)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestLoadAdminCredentials(t *testing.T) {
+	ctx := context.Background()
+	defer func(saved ControllerConfiguration) { configuration = saved }(configuration)
+	configuration.Postgres.AdminUser, configuration.Postgres.AdminPassword = "admin", "flag-password"
+
+	configuration.Postgres.AdminPasswordFile = filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(configuration.Postgres.AdminPasswordFile, []byte("file-password\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	credentials, err := defaultAdminCredentialsSource().load(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if credentials != (adminCredentials{user: "admin", password: "file-password"}) {
+		t.Errorf("password should be read from file, got %+v", credentials)
+	}
+
+	configuration.Postgres.AdminSecret = "db/admin"
+	kubeClient := k8sfake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "db", Name: "admin"},
+		Data:       map[string][]byte{secretKeyUsername: []byte("rotated"), secretKeyPassword: []byte("secret")},
+	})
+	if credentials, err = defaultAdminCredentialsSource().load(ctx, kubeClient); err != nil {
+		t.Fatal(err)
+	}
+	if credentials != (adminCredentials{user: "rotated", password: "secret"}) {
+		t.Errorf("credentials should be read from Secret, got %+v", credentials)
+	}
+
+	configuration.Postgres.AdminSecret = "admin"
+	if _, err = defaultAdminCredentialsSource().load(ctx, kubeClient); err == nil {
+		t.Errorf("reference without namespace should be rejected")
+	}
+
+	server := serverConfig{Name: "pg2", AdminUser: "pg2-admin", AdminPassword: "pg2-password"}
+	if source := server.adminCredentialsSource(); source.isReloadable() {
+		t.Errorf("credentials of server without file and Secret shouldn't be reloaded")
+	}
+	server.AdminSecret = "db/admin"
+	if credentials, err = server.adminCredentialsSource().load(ctx, kubeClient); err != nil {
+		t.Fatal(err)
+	}
+	if credentials != (adminCredentials{user: "rotated", password: "secret"}) {
+		t.Errorf("credentials of server should be read from its Secret, got %+v", credentials)
+	}
+}
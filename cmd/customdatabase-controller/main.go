@@ -1,10 +1,17 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"net/http"
+	"os"
+	"strings"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
@@ -13,21 +20,19 @@ import (
 	"k8s.io/klog/v2"
 
 	"k8s.io/custom-database/internal/customdatabase"
+	"k8s.io/custom-database/internal/customdatabase/adapters/backupstorage"
+	"k8s.io/custom-database/internal/customdatabase/adapters/dryrun"
 	"k8s.io/custom-database/internal/customdatabase/adapters/postgres"
+	"k8s.io/custom-database/internal/customdatabase/adapters/router"
 	"k8s.io/custom-database/internal/customdatabase/usecases"
 	clientset "k8s.io/custom-database/pkg/generated/clientset/versioned"
 	informers "k8s.io/custom-database/pkg/generated/informers/externalversions"
 )
 
 var (
-	masterURL  string
-	kubeconfig string
-	workers    int
-
-	pgHost          string
-	pgPort          int
-	pgAdminUser     string
-	pgAdminPassword string
+	// configuration settings of controller, flags are bound to its fields
+	configuration = defaultConfiguration()
+	configFile    string
 )
 
 func main() {
@@ -38,12 +43,30 @@ func main() {
 	ctx := signals.SetupSignalHandler()
 	logger := klog.FromContext(ctx)
 
-	cfg, err := clientcmd.BuildConfigFromFlags(masterURL, kubeconfig)
+	if err := loadConfiguration(flag.CommandLine, configFile, &configuration); err != nil {
+		logger.Error(err, "Error loading configuration")
+		klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+	}
+	configDump, err := configuration.dump()
+	if err != nil {
+		logger.Error(err, "Error dumping configuration")
+		klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+	}
+	logger.Info("Controller configuration", "config", configDump)
+
+	cfg, err := clientcmd.BuildConfigFromFlags(configuration.Kubernetes.Master, configuration.Kubernetes.Kubeconfig)
 	if err != nil {
 		logger.Error(err, "Error building kubeconfig")
 		klog.FlushAndExit(klog.ExitFlushTimeout, 1)
 	}
 
+	// planned actions of dry-run mode, writes to Kubernetes are sent with dryRun=All
+	var plan *dryrun.Plan
+	if configuration.DryRun {
+		plan = dryrun.NewPlan()
+		cfg.Wrap(dryrun.WrapTransport(plan))
+	}
+
 	kubeClient, err := kubernetes.NewForConfig(cfg)
 	if err != nil {
 		logger.Error(err, "Error building kubernetes clientset")
@@ -56,62 +79,381 @@ func main() {
 		klog.FlushAndExit(klog.ExitFlushTimeout, 1)
 	}
 
-	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubeClient, time.Second*30)
-	exampleInformerFactory := informers.NewSharedInformerFactory(exampleClient, time.Second*30)
+	resyncPeriod := configuration.Kubernetes.ResyncPeriod.Duration
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubeClient, resyncPeriod)
+	exampleInformerFactory := informers.NewSharedInformerFactory(exampleClient, resyncPeriod)
 
-	dbPool, err := commonDatabase.NewDBWithPoolSettings(
-		ctx,
-		makePostgresqlConnectionDSN(pgHost, pgPort, pgAdminUser, pgAdminPassword),
-		commonDatabase.DefaultPoolSettings,
-		commonDatabase.DatabaseWithLogger(logger),
-		commonDatabase.DatabaseWithBinaryParams(),
-	)
+	// files of -pg_tls_secret exist only while controller runs
+	tlsDir, err := os.MkdirTemp("", "customdatabase-tls")
+	if err != nil {
+		logger.Error(err, "Error creating directory of TLS files")
+		klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+	}
+	defer os.RemoveAll(tlsDir)
+	adminTLS, err := adminTLSConfig(ctx, kubeClient, tlsDir)
+	if err != nil {
+		logger.Error(err, "Error configuring TLS of admin connections")
+		klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+	}
+	tenantTLSSettings, err := tenantTLS(ctx, kubeClient, adminTLS)
+	if err != nil {
+		logger.Error(err, "Error configuring TLS settings of tenants")
+		klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+	}
+	// dumps are made by database owner, so client certificate of admin isn't used
+	ownerTLS := adminTLS.WithoutClientCertificate()
+
+	pgCredentialsSource := defaultAdminCredentialsSource()
+	pgCredentials, err := pgCredentialsSource.load(ctx, kubeClient)
+	if err != nil {
+		logger.Error(err, "Error reading admin credentials")
+		klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+	}
+
+	pgAdminConnection := commonDatabase.ConnectionConfig{
+		Host:     configuration.Postgres.Host,
+		Port:     configuration.Postgres.Port,
+		User:     pgCredentials.user,
+		Password: pgCredentials.password,
+		Database: "postgres",
+		TLS:      adminTLS,
+	}
+	fileServers, err := loadServers(configuration.ServersFile)
+	if err != nil {
+		logger.Error(err, "Error loading servers")
+		klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+	}
+	// servers of -servers-file are added to servers of configuration
+	pgServers := append(append([]serverConfig{}, configuration.Servers...), fileServers...)
+	if err = validateServers(configuration.Postgres.ServerName, pgServers); err != nil {
+		logger.Error(err, "Error loading servers")
+		klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+	}
+
+	// every server has its own pool, calls are routed to server of CustomDatabase
+	dbManagers := make(map[string]usecases.DatabaseManager, len(pgServers)+1)
+	defaultServer, err := connectServer(ctx, logger, pgAdminConnection)
 	if err != nil {
 		logger.Error(err, "Error running commonDatabase connection pool")
 		klog.FlushAndExit(klog.ExitFlushTimeout, 1)
 	}
-	defer dbPool.Close() // todo сделать консистентно с текущим кодом
+	defer defaultServer.close() // todo сделать консистентно с текущим кодом
+	dbManagers[configuration.Postgres.ServerName] = defaultServer.dbManager(plan)
+	// pool is replaced, when credentials are rotated, reconciles use the old one until they finish
+	if pgCredentialsSource.isReloadable() {
+		go watchAdminCredentials(ctx, logger, kubeClient, defaultServer, pgCredentialsSource, pgCredentials)
+	}
+	for _, server := range pgServers {
+		serverLogger := logger.WithValues("server", server.Name)
+		credentialsSource := server.adminCredentialsSource()
+		credentials, err := credentialsSource.load(ctx, kubeClient)
+		if err != nil {
+			serverLogger.Error(err, "Error reading admin credentials")
+			klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+		}
+		connection, err := connectServer(ctx, serverLogger, commonDatabase.ConnectionConfig{
+			Host:     server.Host,
+			Port:     server.Port,
+			User:     credentials.user,
+			Password: credentials.password,
+			Database: "postgres",
+			TLS:      server.tlsConfig(adminTLS),
+		})
+		if err != nil {
+			serverLogger.Error(err, "Error running commonDatabase connection pool")
+			klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+		}
+		defer connection.close()
+		dbManagers[server.Name] = connection.dbManager(plan)
+		if credentialsSource.isReloadable() {
+			go watchAdminCredentials(ctx, serverLogger, kubeClient, connection, credentialsSource, credentials)
+		}
+	}
+	pgDbManager := router.NewDbManager(configuration.Postgres.ServerName, dbManagers)
 
-	pgDbManager := postgres.NewDbManager(dbPool.DB())
-	customDatabaseDomainService, err := customdatabase.NewDomainService(pgHost, pgPort)
+	parsedDriftPolicy, err := customdatabase.ParseDriftPolicy(configuration.DriftPolicy)
+	if err != nil {
+		logger.Error(err, "Error parsing drift policy")
+		klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+	}
+
+	namingStrategy, err := customdatabase.ParseNamingStrategy(configuration.NamingStrategy)
+	if err != nil {
+		logger.Error(err, "Error parsing naming strategy")
+		klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+	}
+
+	customDatabaseDomainService, err := customdatabase.NewDomainService(
+		configuration.Postgres.Host, configuration.Postgres.Port, configuration.Kubernetes.ClusterName,
+		customdatabase.WithNamingStrategy(namingStrategy),
+	)
 	if err != nil {
 		logger.Error(err, "Error running commonDatabase connection pool")
 		klog.FlushAndExit(klog.ExitFlushTimeout, 1)
 	}
 
+	// commits of other controllers in databases aren't taken for activity of tenants
+	ownSessions := usecases.NewOwnSessions()
 	c := usecases.NewController(
 		ctx, kubeClient, exampleClient,
 		kubeInformerFactory.Core().V1().Secrets(),
+		kubeInformerFactory.Core().V1().ConfigMaps(),
 		exampleInformerFactory.Igor().V1().CustomDatabases(),
 		pgDbManager,
 		customDatabaseDomainService,
+		usecases.WithStorageSamplingPeriod(configuration.Periods.StorageSampling.Duration),
+		usecases.WithSecurityAuditPeriod(configuration.Periods.SecurityAudit.Duration),
+		usecases.WithDriftCheckPeriod(configuration.Periods.DriftCheck.Duration),
+		usecases.WithDriftPolicy(parsedDriftPolicy),
+		usecases.WithActivitySamplingPeriod(configuration.Periods.ActivitySampling.Duration),
+		usecases.WithExpiryWarningPeriod(configuration.Periods.ExpiryWarning.Duration),
+		controllerMigrationsOption(),
+		usecases.WithAdoptionAllowList(configuration.AdoptionAllowList),
+		usecases.WithOrphanSweepPeriod(configuration.Orphans.SweepPeriod.Duration),
+		usecases.WithOrphanGC(configuration.Orphans.GC, configuration.Orphans.GracePeriod.Duration),
+		usecases.WithReadOnly(configuration.ReadOnly),
+		usecases.WithServers(configuration.Postgres.ServerName, serverHosts(pgServers)),
+		serverMigrationOption(ownerTLS),
+		usecases.WithTenantTLS(tenantTLSSettings),
+		usecases.WithRateLimiter(configuration.rateLimiter()),
+		usecases.WithOwnSessions(ownSessions),
 	)
+	metricsRegistry := prometheus.NewRegistry()
+	usecases.RegisterMetrics(metricsRegistry)
+
+	httpMux := http.NewServeMux()
+	httpMux.Handle("/metrics", promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{}))
+	if configuration.DryRun {
+		httpMux.Handle("/debug/plan", plan)
+	}
+	go serveHTTP(ctx, logger, configuration.MetricsAddr, httpMux)
+
+	// in read-only mode users, grants, backups and restores are only observed, their finalizers are removed without
+	// changes of databases, so they don't block deletion of namespaces
+	if configuration.ReadOnly {
+		logger.Info("Controller runs in read-only mode, databases are only observed")
+	}
+	if configuration.isFeatureEnabled(featureDatabaseUsers) {
+		databaseUserController := usecases.NewDatabaseUserController(
+			ctx, kubeClient, exampleClient,
+			kubeInformerFactory.Core().V1().Secrets(),
+			exampleInformerFactory.Igor().V1().CustomDatabases(),
+			exampleInformerFactory.Igor().V1().DatabaseUsers(),
+			pgDbManager,
+			customDatabaseDomainService,
+			usecases.WithRoleTenantTLS(tenantTLSSettings),
+			usecases.WithRoleRateLimiter(configuration.rateLimiter()),
+			usecases.WithRoleOwnSessions(ownSessions),
+			usecases.WithRoleReadOnly(configuration.ReadOnly),
+			usecases.WithRoleAdoptionAllowList(configuration.AdoptionAllowList),
+		)
+		go func() {
+			if err := databaseUserController.Run(ctx, configuration.Workers.DatabaseUsers); err != nil {
+				logger.Error(err, "Error running DatabaseUser controller")
+				klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+			}
+		}()
+	}
+	if configuration.isFeatureEnabled(featureDatabaseGrants) {
+		databaseGrantController := usecases.NewDatabaseGrantController(
+			ctx, kubeClient, exampleClient,
+			kubeInformerFactory.Core().V1().Secrets(),
+			exampleInformerFactory.Igor().V1().CustomDatabases(),
+			exampleInformerFactory.Igor().V1().DatabaseGrants(),
+			pgDbManager,
+			customDatabaseDomainService,
+			usecases.WithRoleTenantTLS(tenantTLSSettings),
+			usecases.WithRoleRateLimiter(configuration.rateLimiter()),
+			usecases.WithRoleOwnSessions(ownSessions),
+			usecases.WithRoleReadOnly(configuration.ReadOnly),
+			usecases.WithRoleAdoptionAllowList(configuration.AdoptionAllowList),
+		)
+		go func() {
+			if err := databaseGrantController.Run(ctx, configuration.Workers.DatabaseGrants); err != nil {
+				logger.Error(err, "Error running DatabaseGrant controller")
+				klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+			}
+		}()
+	}
+	// dumps are written to backup storage and restored by pg_restore, they can't be planned
+	if configuration.DryRun {
+		logger.Info("Controller runs in dry-run mode, planned actions are logged and served at /debug/plan")
+	} else if configuration.isFeatureEnabled(featureDatabaseBackups) {
+		backupStorage, err := newBackupStorage()
+		if err != nil {
+			logger.Error(err, "Error building backup storage")
+			klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+		}
+		databaseBackupController := usecases.NewDatabaseBackupController(
+			ctx, kubeClient, exampleClient,
+			kubeInformerFactory.Core().V1().Secrets(),
+			exampleInformerFactory.Igor().V1().CustomDatabases(),
+			exampleInformerFactory.Igor().V1().DatabaseBackups(),
+			exampleInformerFactory.Igor().V1().DatabaseRestores(),
+			postgres.NewDumper(configuration.Backup.PgDumpPath, ownerTLS),
+			backupStorage,
+			usecases.WithBackupRateLimiter(configuration.rateLimiter()),
+			usecases.WithBackupOwnSessions(ownSessions),
+			usecases.WithBackupReadOnly(configuration.ReadOnly),
+		)
+		databaseRestoreController := usecases.NewDatabaseRestoreController(
+			ctx, kubeClient, exampleClient,
+			kubeInformerFactory.Core().V1().Secrets(),
+			exampleInformerFactory.Igor().V1().CustomDatabases(),
+			exampleInformerFactory.Igor().V1().DatabaseBackups(),
+			exampleInformerFactory.Igor().V1().DatabaseRestores(),
+			pgDbManager,
+			postgres.NewRestorer(configuration.Backup.PgRestorePath, ownerTLS),
+			backupStorage,
+			usecases.WithBackupRateLimiter(configuration.rateLimiter()),
+			usecases.WithBackupOwnSessions(ownSessions),
+			usecases.WithBackupReadOnly(configuration.ReadOnly),
+		)
+		go func() {
+			if err := databaseBackupController.Run(ctx, configuration.Workers.DatabaseBackups); err != nil {
+				logger.Error(err, "Error running DatabaseBackup controller")
+				klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+			}
+		}()
+		go func() {
+			if err := databaseRestoreController.Run(ctx, configuration.Workers.DatabaseRestores); err != nil {
+				logger.Error(err, "Error running DatabaseRestore controller")
+				klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+			}
+		}()
+	}
 
 	// notice that there is no need to run Start methods in a separate goroutine. (i.e. go kubeInformerFactory.Start(ctx.done())
 	// Start method is non-blocking and runs all registered informers in a dedicated goroutine.
 	kubeInformerFactory.Start(ctx.Done())
 	exampleInformerFactory.Start(ctx.Done())
 
-	if err = c.Run(ctx, workers); err != nil {
+	if err = c.Run(ctx, configuration.Workers.CustomDatabases); err != nil {
 		logger.Error(err, "Error running controller")
 		klog.FlushAndExit(klog.ExitFlushTimeout, 1)
 	}
 }
 
 func init() {
-	flag.StringVar(&kubeconfig, "kubeconfig", "", "Path to a kubeconfig. Only required if out-of-cluster.")
-	flag.StringVar(&masterURL, "master", "", "The address of the Kubernetes API server. Overrides any value in kubeconfig. Only required if out-of-cluster.")
-	flag.IntVar(&workers, "workers", 2, "Controller run with amount of workers")
-
-	flag.StringVar(&pgHost, "pg_host", "localhost", "Postgresql server host name")
-	flag.IntVar(&pgPort, "pg_port", 5432, "Postgresql server port")
-	flag.StringVar(&pgAdminUser, "pg_admin_user", "", "Postgresql user with privileges to create databases and roles")
-	flag.StringVar(&pgAdminPassword, "pg_admin_password", "", "Postgresql admin user's password")
+	flag.StringVar(&configFile, "config", "", "YAML file with ControllerConfiguration, flags, which are set explicitly, override it")
+	bindFlags(flag.CommandLine, &configuration)
 }
 
-func makePostgresqlConnectionDSN(host string, port int, username, password string) string {
-	return fmt.Sprintf(
-		"postgres://%s:%s@%s:%d/postgres?sslmode=disable",
-		username, password, host, port,
-	)
+// bindFlags defines flags, which are bound to fields of configuration, current values of fields are defaults of flags
+func bindFlags(fs *flag.FlagSet, c *ControllerConfiguration) {
+	fs.StringVar(&c.Kubernetes.Kubeconfig, "kubeconfig", c.Kubernetes.Kubeconfig, "Path to a kubeconfig. Only required if out-of-cluster.")
+	fs.StringVar(&c.Kubernetes.Master, "master", c.Kubernetes.Master, "The address of the Kubernetes API server. Overrides any value in kubeconfig. Only required if out-of-cluster.")
+	fs.Var(workersValue{&c.Workers}, "workers", "Amount of workers of every controller, it overrides workers of all controllers in -config")
+	fs.DurationVar(&c.Kubernetes.ResyncPeriod.Duration, "resync-period", c.Kubernetes.ResyncPeriod.Duration, "How often informers send all Secrets and custom resources to controllers again")
+	fs.StringVar(&c.NamingStrategy, "naming-strategy", c.NamingStrategy, "How databases and owner roles are named after CustomDatabases: name or namespace-name. Only new databases are named by it, names longer than 63 bytes are cut and suffixed by hash")
+	fs.Var(featureGatesValue{&c.FeatureGates}, "feature-gates", "Comma-separated features Name=true|false, which are enabled or disabled: ServerMigration, DatabaseUsers, DatabaseGrants, DatabaseBackups")
+
+	fs.StringVar(&c.MetricsAddr, "metrics-addr", c.MetricsAddr, "The address to expose metrics on. Empty value disables HTTP server")
+	fs.DurationVar(&c.Periods.StorageSampling.Duration, "storage-sampling-period", c.Periods.StorageSampling.Duration, "How often size of every database is sampled")
+	fs.DurationVar(&c.Periods.SecurityAudit.Duration, "security-audit-period", c.Periods.SecurityAudit.Duration, "How often privileges of every database are audited")
+	fs.DurationVar(&c.Periods.DriftCheck.Duration, "drift-check-period", c.Periods.DriftCheck.Duration, "How often database and its role on server are compared with desired state, e.g. to find manual changes of grants, password or limits")
+	fs.StringVar(&c.DriftPolicy, "drift-policy", c.DriftPolicy, "What to do with manual changes of database and its role: repair applies desired state again, report only sets Drifted condition and stops provisioning of database")
+	fs.DurationVar(&c.Periods.ActivitySampling.Duration, "activity-sampling-period", c.Periods.ActivitySampling.Duration, "How often usage counters of every database are sampled to detect idle databases")
+	fs.DurationVar(&c.Periods.ExpiryWarning.Duration, "expiry-warning-period", c.Periods.ExpiryWarning.Duration, "How long before expiry of CustomDatabase with TTL warning event is emitted")
+
+	fs.StringVar(&c.MigrationsDir, "migrations-dir", c.MigrationsDir, "Directory of migrations, which are referenced by spec.migrations.path, e.g. mounted volume. Empty value allows only migrations from ConfigMaps")
+	fs.Var(listValue{&c.AdoptionAllowList}, "adoption-allow-list", "Comma-separated patterns <namespace>/<database> of pre-existing databases, which CustomDatabase may adopt by spec.adopt, e.g. legacy/*. Pre-existing roles of DatabaseUsers and DatabaseGrants of such CustomDatabase are matched by <namespace>/<role>")
+	fs.StringVar(&c.Kubernetes.ClusterName, "cluster-name", c.Kubernetes.ClusterName, "Name of Kubernetes cluster in ownership markers of databases and roles. It's required, when several clusters share one Postgresql server")
+	fs.DurationVar(&c.Orphans.SweepPeriod.Duration, "orphan-sweep-period", c.Orphans.SweepPeriod.Duration, "How often server is checked for databases and roles, whose CustomDatabase was deleted while controller was down")
+	fs.BoolVar(&c.Orphans.GC, "gc-orphans", c.Orphans.GC, "Drop orphaned databases and roles after grace period. Without it orphans are only reported by metrics and events")
+	fs.DurationVar(&c.Orphans.GracePeriod.Duration, "orphan-grace-period", c.Orphans.GracePeriod.Duration, "How long database or role stays orphaned before it's dropped by -gc-orphans")
+	fs.BoolVar(&c.DryRun, "dry-run", c.DryRun, "Record changes of databases, roles and Kubernetes objects instead of executing them. Planned actions are logged and served at /debug/plan of -metrics-addr. Backups and restores aren't run")
+	fs.BoolVar(&c.ReadOnly, "read-only", c.ReadOnly, "Only observe databases and report their state in status of CustomDatabases, e.g. during maintenance of server. Nothing is created, changed or dropped")
+
+	fs.StringVar(&c.Backup.PgDumpPath, "pg-dump-path", c.Backup.PgDumpPath, "Path to pg_dump utility, that makes backups")
+	fs.StringVar(&c.Backup.PgRestorePath, "pg-restore-path", c.Backup.PgRestorePath, "Path to pg_restore utility, that restores backups")
+	fs.StringVar(&c.Backup.Storage, "backup-storage", c.Backup.Storage, "Storage of backups: filesystem or s3")
+	fs.StringVar(&c.Backup.Dir, "backup-dir", c.Backup.Dir, "Directory of backups for filesystem storage, e.g. mounted PVC")
+	fs.StringVar(&c.Backup.S3.Endpoint, "backup-s3-endpoint", c.Backup.S3.Endpoint, "Endpoint of S3-compatible storage of backups")
+	fs.StringVar(&c.Backup.S3.Region, "backup-s3-region", c.Backup.S3.Region, "Region of S3 bucket of backups")
+	fs.StringVar(&c.Backup.S3.Bucket, "backup-s3-bucket", c.Backup.S3.Bucket, "S3 bucket of backups")
+	fs.StringVar(&c.Backup.S3.AccessKeyID, "backup-s3-access-key-id", c.Backup.S3.AccessKeyID, "Access key of S3 storage, AWS_ACCESS_KEY_ID by default")
+	fs.StringVar(&c.Backup.S3.SecretAccessKey, "backup-s3-secret-access-key", c.Backup.S3.SecretAccessKey, "Secret key of S3 storage, AWS_SECRET_ACCESS_KEY by default")
+	fs.BoolVar(&c.Backup.S3.UsePathStyle, "backup-s3-path-style", c.Backup.S3.UsePathStyle, "Address S3 bucket by path instead of subdomain, it's required by most of self-hosted storages")
+
+	fs.StringVar(&c.Postgres.Host, "pg_host", c.Postgres.Host, "Postgresql server host name")
+	fs.IntVar(&c.Postgres.Port, "pg_port", c.Postgres.Port, "Postgresql server port")
+	fs.StringVar(&c.Postgres.AdminUser, "pg_admin_user", c.Postgres.AdminUser, "Postgresql user with privileges to create databases and roles")
+	fs.StringVar(&c.Postgres.AdminPassword, "pg_admin_password", c.Postgres.AdminPassword, "Postgresql admin user's password. It's visible in list of processes, prefer -pg_admin_password_file or -pg_admin_secret")
+	fs.StringVar(&c.Postgres.AdminPasswordFile, "pg_admin_password_file", c.Postgres.AdminPasswordFile, "File with Postgresql admin user's password, e.g. mounted Secret. Changes of file are applied without restart")
+	fs.StringVar(&c.Postgres.AdminSecret, "pg_admin_secret", c.Postgres.AdminSecret, "Secret namespace/name with password and optional username of Postgresql admin user. Changes of Secret are applied without restart")
+	fs.DurationVar(&c.Postgres.AdminReloadPeriod.Duration, "pg_admin_reload_period", c.Postgres.AdminReloadPeriod.Duration, "How often -pg_admin_password_file or -pg_admin_secret is checked for new credentials")
+
+	fs.StringVar(&c.Postgres.SSLMode, "pg_sslmode", c.Postgres.SSLMode, "sslmode of admin connections: disable, require, verify-ca or verify-full")
+	fs.StringVar(&c.Postgres.SSLRootCert, "pg_sslrootcert", c.Postgres.SSLRootCert, "CA bundle file, which certificate of Postgresql server is verified by")
+	fs.StringVar(&c.Postgres.SSLCert, "pg_sslcert", c.Postgres.SSLCert, "Client certificate file of admin user, when it authenticates by certificate")
+	fs.StringVar(&c.Postgres.SSLKey, "pg_sslkey", c.Postgres.SSLKey, "Private key file of client certificate of admin user")
+	fs.StringVar(&c.Postgres.TLSSecret, "pg_tls_secret", c.Postgres.TLSSecret, "Secret namespace/name with ca.crt, tls.crt and tls.key of admin connections, they replace files of -pg_sslrootcert, -pg_sslcert and -pg_sslkey")
+
+	fs.StringVar(&c.TenantTLS.SSLMode, "tenant-sslmode", c.TenantTLS.SSLMode, "sslmode, which is published with CA bundle into DB_SSLMODE and ca.crt of tenant Secrets. TLS settings aren't published, when it's empty")
+	fs.StringVar(&c.TenantTLS.CAFile, "tenant-ca-file", c.TenantTLS.CAFile, "CA bundle file, which is published into tenant Secrets, CA bundle of admin connections by default")
+	fs.StringVar(&c.TenantTLS.CertIssuerSecret, "tenant-cert-issuer-secret", c.TenantTLS.CertIssuerSecret, "Secret namespace/name with tls.crt and tls.key of CA, which issues client certificates into tls.crt and tls.key of tenant Secrets for cert authentication. Requires -tenant-sslmode")
+	fs.DurationVar(&c.TenantTLS.CertValidity.Duration, "tenant-cert-validity", c.TenantTLS.CertValidity.Duration, "Validity of client certificates of tenants, they are issued again 30 days before expiration")
+
+	fs.StringVar(&c.Postgres.ServerName, "server-name", c.Postgres.ServerName, "Name of Postgresql server of -pg_host, it's used by CustomDatabases without spec.serverRef")
+	fs.StringVar(&c.ServersFile, "servers-file", c.ServersFile, "JSON file with list of additional Postgresql servers {name, host, port, adminUser, adminPassword, adminPasswordFile, adminSecret}, which CustomDatabase may choose by spec.serverRef")
+	fs.DurationVar(&c.ServerMigrationGracePeriod.Duration, "server-migration-grace-period", c.ServerMigrationGracePeriod.Duration, "How long closed database is kept on the old server after CustomDatabase is moved to server of the new spec.serverRef")
+}
+
+// controllerMigrationsOption returns option with directory of migrations chosen by flags
+func controllerMigrationsOption() usecases.ControllerOption {
+	if configuration.MigrationsDir == "" {
+		return usecases.WithMigrationsFS(nil)
+	}
+
+	return usecases.WithMigrationsFS(os.DirFS(configuration.MigrationsDir))
+}
+
+// splitList returns not empty items of comma-separated list
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// newBackupStorage returns storage of backups chosen by flags
+func newBackupStorage() (usecases.BackupStorage, error) {
+	switch configuration.Backup.Storage {
+	case "filesystem":
+		return backupstorage.NewFilesystemStorage(configuration.Backup.Dir)
+	case "s3":
+		return backupstorage.NewS3Storage(backupstorage.S3Config{
+			Endpoint:        configuration.Backup.S3.Endpoint,
+			Region:          configuration.Backup.S3.Region,
+			Bucket:          configuration.Backup.S3.Bucket,
+			AccessKeyID:     configuration.Backup.S3.AccessKeyID,
+			SecretAccessKey: configuration.Backup.S3.SecretAccessKey,
+			UsePathStyle:    configuration.Backup.S3.UsePathStyle,
+		}, http.DefaultClient)
+	default:
+		return nil, fmt.Errorf("unknown backup storage %q", configuration.Backup.Storage)
+	}
+}
+
+// serveHTTP runs HTTP server until context is done
+func serveHTTP(ctx context.Context, logger klog.Logger, addr string, handler http.Handler) {
+	if addr == "" {
+		return
+	}
+
+	server := &http.Server{Addr: addr, Handler: handler, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		<-ctx.Done()
+		_ = server.Shutdown(context.Background())
+	}()
+
+	logger.Info("Starting HTTP server", "addr", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error(err, "Error running HTTP server")
+	}
 }
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"k8s.io/klog/v2"
+
+	"k8s.io/custom-database/internal/customdatabase"
+	"k8s.io/custom-database/internal/customdatabase/adapters/dryrun"
+	"k8s.io/custom-database/internal/customdatabase/adapters/postgres"
+	"k8s.io/custom-database/internal/customdatabase/usecases"
+	commonDatabase "k8s.io/custom-database/pkg/postgres"
+)
+
+// serverConfig additional Postgresql server from -servers-file, which CustomDatabase may choose by spec.serverRef
+type serverConfig struct {
+	Name          string `json:"name"`
+	Host          string `json:"host"`
+	Port          int    `json:"port"`
+	AdminUser     string `json:"adminUser"`
+	AdminPassword string `json:"adminPassword"`
+	// AdminPasswordFile and AdminSecret replace AdminPassword like -pg_admin_password_file and -pg_admin_secret
+	AdminPasswordFile string `json:"adminPasswordFile"`
+	AdminSecret       string `json:"adminSecret"`
+	// SSLMode and files of admin connections, server without sslMode uses TLS of -pg_host
+	SSLMode     string `json:"sslMode"`
+	SSLRootCert string `json:"sslRootCert"`
+	SSLCert     string `json:"sslCert"`
+	SSLKey      string `json:"sslKey"`
+}
+
+// tlsConfig returns TLS of admin connections to server
+func (s serverConfig) tlsConfig(defaultTLS commonDatabase.TLSConfig) commonDatabase.TLSConfig {
+	if s.SSLMode == "" {
+		return defaultTLS
+	}
+
+	return commonDatabase.TLSConfig{
+		Mode:         s.SSLMode,
+		RootCertFile: s.SSLRootCert,
+		CertFile:     s.SSLCert,
+		KeyFile:      s.SSLKey,
+	}
+}
+
+// adminCredentialsSource returns source of credentials of admin connections to server
+func (s serverConfig) adminCredentialsSource() adminCredentialsSource {
+	return adminCredentialsSource{
+		user:         s.AdminUser,
+		password:     s.AdminPassword,
+		passwordFile: s.AdminPasswordFile,
+		secret:       s.AdminSecret,
+	}
+}
+
+// loadServers reads JSON list of additional servers of -servers-file, empty path means that file isn't used
+func loadServers(path string) ([]serverConfig, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var servers []serverConfig
+	if err = json.Unmarshal(data, &servers); err != nil {
+		return nil, fmt.Errorf("servers file %s: %w", path, err)
+	}
+
+	return servers, nil
+}
+
+// validateServers checks that additional servers have names and hosts, and that names are unique
+func validateServers(defaultServerName string, servers []serverConfig) error {
+	names := map[string]bool{defaultServerName: true}
+	for _, server := range servers {
+		switch {
+		case server.Name == "" || server.Host == "":
+			return fmt.Errorf("name and host of server must be specified")
+		case names[server.Name]:
+			return fmt.Errorf("server %q is duplicated", server.Name)
+		}
+		if server.SSLMode != "" {
+			if err := server.tlsConfig(commonDatabase.TLSConfig{}).Validate(); err != nil {
+				return fmt.Errorf("server %q: %w", server.Name, err)
+			}
+		}
+		names[server.Name] = true
+	}
+
+	return nil
+}
+
+// serverConnection pool and connector of admin user on Postgresql server
+type serverConnection struct {
+	db        *commonDatabase.ReloadableDB
+	connector *commonDatabase.Connector
+	logger    klog.Logger
+}
+
+// connectServer opens pool of admin connections to Postgresql server
+func connectServer(
+	ctx context.Context, logger klog.Logger, connection commonDatabase.ConnectionConfig,
+) (*serverConnection, error) {
+	dbPool, err := openPool(ctx, logger, connection)
+	if err != nil {
+		return nil, err
+	}
+
+	return &serverConnection{
+		db:        commonDatabase.NewReloadableDB(dbPool),
+		connector: commonDatabase.NewConnector(connection, logger),
+		logger:    logger,
+	}, nil
+}
+
+// dbManager returns database manager of server, it's wrapped by dry-run plan, when it's given
+func (s *serverConnection) dbManager(plan *dryrun.Plan) usecases.DatabaseManager {
+	var dbManager usecases.DatabaseManager = postgres.NewDbManager(s.db, s.connector)
+	if plan != nil {
+		dbManager = dryrun.NewDbManager(dbManager, plan)
+	}
+
+	return dbManager
+}
+
+// reconnect opens pool with new credentials of admin user and replaces the current one. Queries of the current pool
+// are finished before it's closed, the current pool is kept, when server rejects new credentials.
+func (s *serverConnection) reconnect(ctx context.Context, user, password string) error {
+	connection := s.connector.Config()
+	connection.User = user
+	connection.Password = password
+
+	dbPool, err := openPool(ctx, s.logger, connection)
+	if err != nil {
+		return err
+	}
+	s.db.Swap(dbPool)
+	s.connector.SetCredentials(user, password)
+
+	return nil
+}
+
+func (s *serverConnection) close() {
+	_ = s.db.Close()
+}
+
+func openPool(
+	ctx context.Context, logger klog.Logger, connection commonDatabase.ConnectionConfig,
+) (*commonDatabase.Database, error) {
+	return commonDatabase.NewDBWithPoolSettings(
+		ctx,
+		connection.DSN(),
+		commonDatabase.DefaultPoolSettings,
+		commonDatabase.DatabaseWithLogger(logger),
+		commonDatabase.DatabaseWithBinaryParams(),
+	)
+}
+
+// serverHosts returns hosts of additional servers by name
+func serverHosts(servers []serverConfig) map[string]customdatabase.Host {
+	hosts := make(map[string]customdatabase.Host, len(servers))
+	for _, server := range servers {
+		hosts[server.Name] = customdatabase.Host{Name: server.Host, Port: server.Port}
+	}
+
+	return hosts
+}
+
+// serverMigrationOption copies databases between servers by pg_dump and pg_restore, dumps can't be planned, so
+// databases aren't moved in dry-run mode. They aren't moved also, when ServerMigration feature is disabled.
+func serverMigrationOption(ownerTLS commonDatabase.TLSConfig) usecases.ControllerOption {
+	if configuration.DryRun || !configuration.isFeatureEnabled(featureServerMigration) {
+		return usecases.WithServerMigration(nil, nil, configuration.ServerMigrationGracePeriod.Duration)
+	}
+
+	return usecases.WithServerMigration(
+		postgres.NewDumper(configuration.Backup.PgDumpPath, ownerTLS),
+		postgres.NewRestorer(configuration.Backup.PgRestorePath, ownerTLS),
+		configuration.ServerMigrationGracePeriod.Duration,
+	)
+}
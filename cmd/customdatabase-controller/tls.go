@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"k8s.io/custom-database/internal/customdatabase/adapters/tlscert"
+	"k8s.io/custom-database/internal/customdatabase/usecases"
+	commonDatabase "k8s.io/custom-database/pkg/postgres"
+)
+
+// adminTLSConfig returns TLS of admin connections from flags. Files of -pg_tls_secret are written to dir and
+// replace files of flags.
+func adminTLSConfig(ctx context.Context, kubeClient kubernetes.Interface, dir string) (commonDatabase.TLSConfig, error) {
+	config := commonDatabase.TLSConfig{
+		Mode:         configuration.Postgres.SSLMode,
+		RootCertFile: configuration.Postgres.SSLRootCert,
+		CertFile:     configuration.Postgres.SSLCert,
+		KeyFile:      configuration.Postgres.SSLKey,
+	}
+
+	if configuration.Postgres.TLSSecret != "" {
+		secret, err := getSecret(ctx, kubeClient, configuration.Postgres.TLSSecret)
+		if err != nil {
+			return config, err
+		}
+		config, err = config.WithPEM(dir, secret.Data[usecases.SecretKeyCACert],
+			secret.Data[usecases.SecretKeyClientCert], secret.Data[usecases.SecretKeyClientKey],
+		)
+		if err != nil {
+			return config, err
+		}
+	}
+
+	return config, config.Validate()
+}
+
+// tenantTLS returns TLS settings, which are published to Secrets of tenants, nil means that they aren't published
+func tenantTLS(
+	ctx context.Context, kubeClient kubernetes.Interface, adminTLS commonDatabase.TLSConfig,
+) (*usecases.TenantTLS, error) {
+	settings := configuration.TenantTLS
+	if settings.SSLMode == "" {
+		return nil, nil
+	}
+	// tenants may use libpq, which supports more modes than driver of controller
+	switch settings.SSLMode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		return nil, fmt.Errorf("unsupported tenant sslmode %q", settings.SSLMode)
+	}
+
+	tls := &usecases.TenantTLS{SSLMode: settings.SSLMode}
+	caFile := settings.CAFile
+	if caFile == "" {
+		caFile = adminTLS.RootCertFile
+	}
+	if caFile != "" {
+		caCert, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		tls.CACert = caCert
+	}
+
+	if settings.CertIssuerSecret != "" {
+		secret, err := getSecret(ctx, kubeClient, settings.CertIssuerSecret)
+		if err != nil {
+			return nil, err
+		}
+		tls.Issuer, err = tlscert.NewIssuer(
+			secret.Data[usecases.SecretKeyClientCert], secret.Data[usecases.SecretKeyClientKey], settings.CertValidity.Duration,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("secret %s: %w", settings.CertIssuerSecret, err)
+		}
+	}
+
+	return tls, nil
+}
+
+// getSecret returns Secret by reference in format namespace/name
+func getSecret(ctx context.Context, kubeClient kubernetes.Interface, reference string) (*corev1.Secret, error) {
+	namespace, name, ok := strings.Cut(reference, "/")
+	if !ok || namespace == "" || name == "" {
+		return nil, fmt.Errorf("secret reference %q should be in format namespace/name", reference)
+	}
+
+	return kubeClient.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/custom-database/internal/customdatabase"
+)
+
+// States of database on server
+const (
+	// stateManaged database belongs to existing resource of this cluster
+	stateManaged = "managed"
+	// stateOrphaned database belongs to resource of this cluster, which doesn't exist
+	stateOrphaned = "orphaned"
+	// stateForeign database belongs to another cluster, which shares server
+	stateForeign = "foreign"
+	// stateUnmanaged database has no ownership marker, e.g. it was created manually
+	stateUnmanaged = "unmanaged"
+)
+
+type databaseRow struct {
+	Database string                          `json:"database"`
+	State    string                          `json:"state"`
+	Owner    *customdatabase.OwnershipMarker `json:"owner,omitempty"`
+	// Size is unknown, when admin can't connect to database, e.g. to hibernated one
+	Size *int64 `json:"size,omitempty"`
+}
+
+type orphanRow struct {
+	Object string                         `json:"object"`
+	Name   string                         `json:"name"`
+	Owner  customdatabase.OwnershipMarker `json:"owner"`
+}
+
+func runDatabases(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("databases", flag.ContinueOnError)
+	state := fs.String("state", "", "Show only databases in state: managed, orphaned, foreign or unmanaged")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	rows, err := listDatabases(ctx, a)
+	if err != nil {
+		return err
+	}
+	if *state != "" {
+		filtered := rows[:0]
+		for _, row := range rows {
+			if row.State == *state {
+				filtered = append(filtered, row)
+			}
+		}
+		rows = filtered
+	}
+
+	return printRows(a, []string{"DATABASE", "STATE", "OWNER", "SIZE"}, rows, func(row databaseRow) []string {
+		owner := "<none>"
+		if row.Owner != nil {
+			owner = row.Owner.Owner()
+		}
+		size := "<unknown>"
+		if row.Size != nil {
+			size = strconv.FormatInt(*row.Size, 10)
+		}
+		return []string{row.Database, row.State, owner, size}
+	})
+}
+
+func runOrphans(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("orphans", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	rows, err := findOrphans(ctx, a)
+	if err != nil {
+		return err
+	}
+
+	return printRows(a, []string{"OBJECT", "NAME", "OWNER"}, rows, func(row orphanRow) []string {
+		return []string{row.Object, row.Name, row.Owner.Owner()}
+	})
+}
+
+// listDatabases returns all databases on server with their owners
+func listDatabases(ctx context.Context, a *app) ([]databaseRow, error) {
+	databases, err := a.server.ListDatabases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	markers, err := a.server.ListDatabaseOwnership(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resources, err := listResources(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]databaseRow, 0, len(databases))
+	for _, database := range databases {
+		row := databaseRow{Database: database, State: stateUnmanaged}
+		if marker, ok := markers[database]; ok {
+			row.Owner = &marker
+			row.State = a.markerState(marker, resources)
+		}
+		if size, err := a.server.GetDatabaseSize(ctx, database); err == nil {
+			row.Size = &size
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// findOrphans returns databases and then roles, which are marked by resource of this cluster, that doesn't exist.
+// Unlike sweeper of controller, roles of DatabaseUsers and DatabaseGrants are checked too.
+func findOrphans(ctx context.Context, a *app) ([]orphanRow, error) {
+	databaseMarkers, err := a.server.ListDatabaseOwnership(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userMarkers, err := a.server.ListUserOwnership(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resources, err := listResources(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []orphanRow
+	for _, objects := range []struct {
+		object  string
+		markers map[string]customdatabase.OwnershipMarker
+	}{{"database", databaseMarkers}, {"role", userMarkers}} {
+		names := make([]string, 0, len(objects.markers))
+		for name := range objects.markers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			marker := objects.markers[name]
+			if a.markerState(marker, resources) == stateOrphaned {
+				rows = append(rows, orphanRow{Object: objects.object, Name: name, Owner: marker})
+			}
+		}
+	}
+
+	return rows, nil
+}
+
+func (a *app) markerState(marker customdatabase.OwnershipMarker, resources map[string]bool) string {
+	switch {
+	case !a.domainService.IsOwnCluster(marker):
+		return stateForeign
+	case resources[resourceKey(marker.Kind, marker.Namespace, marker.Name)]:
+		return stateManaged
+	default:
+		return stateOrphaned
+	}
+}
+
+// listResources returns keys of all resources, which own databases and roles, in all namespaces
+func listResources(ctx context.Context, a *app) (map[string]bool, error) {
+	resources := make(map[string]bool)
+
+	customDatabases, err := a.client.IgorV1().CustomDatabases(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range customDatabases.Items {
+		resources[resourceKey(customdatabase.OwnerKindCustomDatabase, item.Namespace, item.Name)] = true
+	}
+
+	databaseUsers, err := a.client.IgorV1().DatabaseUsers(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range databaseUsers.Items {
+		resources[resourceKey(customdatabase.OwnerKindDatabaseUser, item.Namespace, item.Name)] = true
+	}
+
+	databaseGrants, err := a.client.IgorV1().DatabaseGrants(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range databaseGrants.Items {
+		resources[resourceKey(customdatabase.OwnerKindDatabaseGrant, item.Namespace, item.Name)] = true
+	}
+
+	return resources, nil
+}
+
+func resourceKey(kind, namespace, name string) string {
+	return fmt.Sprintf("%s %s/%s", kind, namespace, name)
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+
+	"k8s.io/custom-database/internal/customdatabase"
+	fakeadapter "k8s.io/custom-database/internal/customdatabase/adapters/fake"
+	"k8s.io/custom-database/internal/customdatabase/usecases"
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+	"k8s.io/custom-database/pkg/generated/clientset/versioned/fake"
+	commonDatabase "k8s.io/custom-database/pkg/postgres"
+)
+
+func TestDatabasesAndOrphans(t *testing.T) {
+	ctx := context.Background()
+	server := fakeadapter.NewDbManager()
+	a := newTestApp(t, server, nil, newCustomDatabase("managed"))
+	createDatabase(t, server, newMarker("managed"))
+	createDatabase(t, server, newMarker("deleted"))
+	foreign := newMarker("foreign")
+	foreign.Cluster = "another"
+	createDatabase(t, server, foreign)
+	_ = server.CreateDatabase(ctx, "manual", customdatabase.DatabaseOptions{}, customdatabase.OwnershipMarker{})
+	delete(server.DatabaseOwnership, "manual")
+
+	if err := runDatabases(ctx, a, nil); err != nil {
+		t.Fatal(err)
+	}
+	var databases []databaseRow
+	decodeOutput(t, a, &databases)
+	expected := map[string]string{
+		"managed": stateManaged, "deleted": stateOrphaned, "foreign": stateForeign, "manual": stateUnmanaged,
+	}
+	if len(databases) != len(expected) {
+		t.Fatalf("expected %d databases, got %+v", len(expected), databases)
+	}
+	for _, row := range databases {
+		if row.State != expected[row.Database] {
+			t.Errorf("database %s: expected state %s, got %s", row.Database, expected[row.Database], row.State)
+		}
+	}
+
+	if err := runOrphans(ctx, a, nil); err != nil {
+		t.Fatal(err)
+	}
+	var orphans []orphanRow
+	decodeOutput(t, a, &orphans)
+	if len(orphans) != 2 || orphans[0].Object != "database" || orphans[0].Name != "deleted" ||
+		orphans[1].Object != "role" || orphans[1].Name != "deleted" {
+		t.Errorf("database and role of deleted CustomDatabase should be orphaned, got %+v", orphans)
+	}
+}
+
+func TestVerifyOwnership(t *testing.T) {
+	ctx := context.Background()
+	server := fakeadapter.NewDbManager()
+	recreated := newCustomDatabase("recreated")
+	recreated.UID = "new-uid"
+	a := newTestApp(t, server, nil, newCustomDatabase("test"), newCustomDatabase("pending"), recreated)
+	createDatabase(t, server, newMarker("test"))
+	createDatabase(t, server, newMarker("recreated"))
+	// role belongs to another CustomDatabase
+	server.UserOwnership["test"] = newMarker("other")
+
+	if err := runVerify(ctx, a, nil); err == nil {
+		t.Errorf("problems should be reported by error")
+	}
+	var rows []verifyRow
+	decodeOutput(t, a, &rows)
+
+	results := make(map[string]string)
+	for _, row := range rows {
+		results[row.Object+" "+row.Name] = row.Result
+	}
+	expected := map[string]string{
+		"database test":      verifyOK,
+		"role test":          verifyForeign,
+		"database pending":   verifyMissing,
+		"role pending":       verifyMissing,
+		"database recreated": verifyUIDMismatch,
+		"role recreated":     verifyUIDMismatch,
+	}
+	for object, result := range expected {
+		if results[object] != result {
+			t.Errorf("%s: expected %s, got %s", object, result, results[object])
+		}
+	}
+}
+
+func newTestApp(
+	t *testing.T, server *fakeadapter.DbManager, kubeObjects []runtime.Object, objects ...runtime.Object,
+) *app {
+	t.Helper()
+
+	domainService, err := customdatabase.NewDomainService("source", 5432, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &app{
+		kubeClient:    k8sfake.NewSimpleClientset(kubeObjects...),
+		client:        fake.NewSimpleClientset(objects...),
+		domainService: domainService,
+		server:        server,
+		host:          customdatabase.Host{Name: "source", Port: 5432},
+		connect: func(context.Context, commonDatabase.ConnectionConfig) (serverManager, func(), error) {
+			return nil, nil, nil
+		},
+		dumper:   fakeadapter.NewDumper(),
+		restorer: fakeadapter.NewRestorer(),
+		output:   "json",
+		out:      &bytes.Buffer{},
+	}
+}
+
+func newCustomDatabase(name string) *v1.CustomDatabase {
+	return &v1.CustomDatabase{
+		TypeMeta: metav1.TypeMeta{APIVersion: v1.SchemeGroupVersion.String()},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: metav1.NamespaceDefault,
+			UID:       types.UID(name + "-uid"),
+		},
+		Spec: v1.CustomDatabaseSpec{SecretName: name + "-secret"},
+	}
+}
+
+func newSecret(name, host string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name + "-secret", Namespace: metav1.NamespaceDefault},
+		Data: map[string][]byte{
+			usecases.SecretVarDbHost:     []byte(host),
+			usecases.SecretVarDbPort:     []byte("5432"),
+			usecases.SecretVarDbName:     []byte(name),
+			usecases.SecretVarDbUserName: []byte(name),
+			usecases.SecretVarDbPassword: []byte(name + "-password"),
+		},
+	}
+}
+
+func newMarker(name string) customdatabase.OwnershipMarker {
+	return customdatabase.OwnershipMarker{
+		ManagedBy: customdatabase.OwnershipMarkerManagedBy,
+		Kind:      customdatabase.OwnerKindCustomDatabase,
+		Namespace: metav1.NamespaceDefault,
+		Name:      name,
+		UID:       name + "-uid",
+	}
+}
+
+// createDatabase creates database and role with the name of marker
+func createDatabase(t *testing.T, server *fakeadapter.DbManager, marker customdatabase.OwnershipMarker) {
+	t.Helper()
+
+	ctx := context.Background()
+	if err := server.CreateUser(ctx, marker.Name, marker.Name+"-password", marker); err != nil {
+		t.Fatal(err)
+	}
+	err := server.CreateDatabase(ctx, marker.Name, customdatabase.DatabaseOptions{Owner: marker.Name}, marker)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// decodeOutput decodes JSON output of the last command
+func decodeOutput(t *testing.T, a *app, rows any) {
+	t.Helper()
+
+	out := a.out.(*bytes.Buffer)
+	if err := json.NewDecoder(out).Decode(rows); err != nil {
+		t.Fatalf("output isn't JSON: %v", err)
+	}
+	out.Reset()
+}
@@ -0,0 +1,218 @@
+// customdb-admin is a tool of operators of Postgresql server, which is shared by CustomDatabases. Unlike controller,
+// it's run on demand: it makes inventory of databases and roles on server, verifies their ownership markers and
+// moves CustomDatabases to another server.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+
+	"k8s.io/custom-database/internal/customdatabase"
+	"k8s.io/custom-database/internal/customdatabase/adapters/postgres"
+	"k8s.io/custom-database/internal/customdatabase/usecases"
+	clientset "k8s.io/custom-database/pkg/generated/clientset/versioned"
+	commonDatabase "k8s.io/custom-database/pkg/postgres"
+)
+
+const usage = `Usage: customdb-admin [flags] <command> [flags] [args]
+
+Commands:
+  databases                     list databases on server with their owners
+  orphans                       list databases and roles, whose owner resource doesn't exist
+  verify                        verify ownership markers of databases and roles of all resources
+  migrate <namespace/name>...   move CustomDatabases to another server by dump and restore
+
+Run "customdb-admin <command> -h" for flags of command.
+
+Flags:
+`
+
+// serverManager database manager of Postgresql server, which also lists databases for inventory
+type serverManager interface {
+	usecases.DatabaseManager
+	ListDatabases(ctx context.Context) ([]string, error)
+}
+
+// app clients and options, which are shared by commands
+type app struct {
+	kubeClient    kubernetes.Interface
+	client        clientset.Interface
+	domainService *customdatabase.DomainService
+
+	// server source server, which is configured by global flags
+	server serverManager
+	host   customdatabase.Host
+	admin  commonDatabase.ConnectionConfig
+	// connect opens connection to another server, e.g. target of migration
+	connect func(ctx context.Context, config commonDatabase.ConnectionConfig) (serverManager, func(), error)
+
+	dumper   usecases.DatabaseDumper
+	restorer usecases.DatabaseRestorer
+
+	// output format: table or json
+	output string
+	out    io.Writer
+}
+
+type command func(ctx context.Context, a *app, args []string) error
+
+var commands = map[string]command{
+	"databases": runDatabases,
+	"orphans":   runOrphans,
+	"verify":    runVerify,
+	"migrate":   runMigrate,
+}
+
+func main() {
+	klog.InitFlags(nil)
+	if err := run(context.Background(), os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string) error {
+	var (
+		kubeconfig     string
+		kubeContext    string
+		clusterName    string
+		namingStrategy string
+		output         string
+		pgDumpPath     string
+		pgRestorePath  string
+		pgAdmin        = commonDatabase.ConnectionConfig{Database: "postgres"}
+	)
+	fs := flag.NewFlagSet("customdb-admin", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&kubeconfig, "kubeconfig", "", "Path to a kubeconfig, KUBECONFIG by default")
+	fs.StringVar(&kubeContext, "context", "", "Name of kubeconfig context")
+	fs.StringVar(&clusterName, "cluster-name", "", "Name of Kubernetes cluster in ownership markers, the same as "+
+		"-cluster-name of controller")
+	fs.StringVar(&namingStrategy, "naming-strategy", string(customdatabase.NamingStrategyName), "How databases are "+
+		"named after CustomDatabases, the same as namingStrategy of controller: name or namespace-name")
+	fs.StringVar(&output, "o", "table", "Output format: table or json")
+	fs.StringVar(&pgDumpPath, "pg-dump-path", "pg_dump", "Path to pg_dump utility")
+	fs.StringVar(&pgRestorePath, "pg-restore-path", "pg_restore", "Path to pg_restore utility")
+	fs.StringVar(&pgAdmin.Host, "pg_host", "localhost", "Postgresql server host name")
+	fs.IntVar(&pgAdmin.Port, "pg_port", 5432, "Postgresql server port")
+	fs.StringVar(&pgAdmin.User, "pg_admin_user", "", "Postgresql user with privileges to create databases and roles")
+	fs.StringVar(&pgAdmin.Password, "pg_admin_password", os.Getenv("PGPASSWORD"), "Postgresql admin user's "+
+		"password, PGPASSWORD by default")
+	fs.StringVar(&pgAdmin.TLS.Mode, "pg_sslmode", "disable", "sslmode of admin connections: disable, require, "+
+		"verify-ca or verify-full")
+	fs.StringVar(&pgAdmin.TLS.RootCertFile, "pg_sslrootcert", "", "CA bundle file, which certificate of "+
+		"Postgresql server is verified by")
+	fs.StringVar(&pgAdmin.TLS.CertFile, "pg_sslcert", "", "Client certificate file of admin user")
+	fs.StringVar(&pgAdmin.TLS.KeyFile, "pg_sslkey", "", "Private key file of client certificate of admin user")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := pgAdmin.TLS.Validate(); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("command is required")
+	}
+	if output != "table" && output != "json" {
+		return fmt.Errorf("unknown output format %q", output)
+	}
+
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		fs.Usage()
+		return fmt.Errorf("unknown command %q", fs.Arg(0))
+	}
+
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = kubeconfig
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		loadingRules, &clientcmd.ConfigOverrides{CurrentContext: kubeContext},
+	).ClientConfig()
+	if err != nil {
+		return err
+	}
+
+	a := &app{
+		host:     customdatabase.Host{Name: pgAdmin.Host, Port: pgAdmin.Port},
+		admin:    pgAdmin,
+		connect:  connectServer,
+		dumper:   postgres.NewDumper(pgDumpPath, pgAdmin.TLS.WithoutClientCertificate()),
+		restorer: postgres.NewRestorer(pgRestorePath, pgAdmin.TLS.WithoutClientCertificate()),
+		output:   output,
+		out:      os.Stdout,
+	}
+	if a.kubeClient, err = kubernetes.NewForConfig(restConfig); err != nil {
+		return err
+	}
+	if a.client, err = clientset.NewForConfig(restConfig); err != nil {
+		return err
+	}
+	strategy, err := customdatabase.ParseNamingStrategy(namingStrategy)
+	if err != nil {
+		return err
+	}
+	a.domainService, err = customdatabase.NewDomainService(pgAdmin.Host, pgAdmin.Port, clusterName,
+		customdatabase.WithNamingStrategy(strategy),
+	)
+	if err != nil {
+		return err
+	}
+
+	server, closeServer, err := connectServer(ctx, pgAdmin)
+	if err != nil {
+		return err
+	}
+	defer closeServer()
+	a.server = server
+
+	return cmd(ctx, a, fs.Args()[1:])
+}
+
+// connectServer opens admin connection to Postgresql server. Unlike controller, it doesn't retry failed attempts.
+func connectServer(
+	ctx context.Context, config commonDatabase.ConnectionConfig,
+) (serverManager, func(), error) {
+	logger := klog.FromContext(ctx)
+	connector := commonDatabase.NewConnector(config, logger)
+
+	db, err := connector.Connect(ctx, config.Database)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return postgres.NewDbManager(db.DB(), connector), func() { _ = db.Close() }, nil
+}
+
+// printRows prints rows as table or as JSON array, header is used only by table
+func printRows[T any](a *app, header []string, rows []T, cells func(row T) []string) error {
+	if a.output == "json" {
+		if rows == nil {
+			rows = []T{}
+		}
+		encoder := json.NewEncoder(a.out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(rows)
+	}
+
+	w := tabwriter.NewWriter(a.out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(cells(row), "\t"))
+	}
+
+	return w.Flush()
+}
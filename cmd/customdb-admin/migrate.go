@@ -0,0 +1,384 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"k8s.io/custom-database/internal/customdatabase"
+	"k8s.io/custom-database/internal/customdatabase/usecases"
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+	commonDatabase "k8s.io/custom-database/pkg/postgres"
+)
+
+// Results of migration of CustomDatabase
+const (
+	migrateMigrated = "migrated"
+	migrateSkipped  = "skipped"
+	migrateFailed   = "failed"
+)
+
+type migrateRow struct {
+	Resource string `json:"resource"`
+	Database string `json:"database"`
+	Result   string `json:"result"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+type migrateOptions struct {
+	target customdatabase.Host
+	// secretHost address of target server for tenants, it's written to Secrets
+	secretHost string
+	dropSource bool
+	resume     bool
+	force      bool
+}
+
+func runMigrate(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	target := commonDatabase.ConnectionConfig{Database: "postgres"}
+	fs.StringVar(&target.Host, "target-host", "", "Target Postgresql server host name")
+	fs.IntVar(&target.Port, "target-port", 5432, "Target Postgresql server port")
+	fs.StringVar(&target.User, "target-admin-user", "", "Admin user of target server, -pg_admin_user by default")
+	fs.StringVar(&target.Password, "target-admin-password", "", "Password of admin user of target server, "+
+		"-pg_admin_password by default")
+	secretHost := fs.String("target-secret-host", "", "Host name of target server, which is written to Secrets "+
+		"of tenants, -target-host by default. It's needed, when tenants reach server by another address")
+	namespace := fs.String("namespace", "", "Namespace of CustomDatabases, which are given by name or -selector. "+
+		"All namespaces for -selector by default")
+	selector := fs.String("selector", "", "Label selector of CustomDatabases to migrate")
+	opts := migrateOptions{}
+	fs.BoolVar(&opts.dropSource, "drop-source", false, "Drop database and role on source server after "+
+		"migration. By default they are kept read-only as fallback")
+	fs.BoolVar(&opts.resume, "resume", false, "Resume reconciliation of migrated CustomDatabases. Enable it only "+
+		"when they are reconciled by controller of target server, otherwise databases are created on source again")
+	fs.BoolVar(&opts.force, "force", false, "Migrate database, which has sessions. Writes of existing sessions "+
+		"during dump are lost")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if target.Host == "" {
+		return fmt.Errorf("migrate: -target-host is required")
+	}
+	if fs.NArg() == 0 && *selector == "" {
+		return fmt.Errorf("migrate: names of CustomDatabases or -selector are required")
+	}
+	if target.User == "" {
+		target.User, target.Password = a.admin.User, a.admin.Password
+	}
+	// target is usually reached with the same CA and client certificate
+	target.TLS = a.admin.TLS
+	opts.target = customdatabase.Host{Name: target.Host, Port: target.Port}
+	opts.secretHost = *secretHost
+	if opts.secretHost == "" {
+		opts.secretHost = target.Host
+	}
+
+	customDatabases, err := selectCustomDatabases(ctx, a, *namespace, *selector, fs.Args())
+	if err != nil {
+		return err
+	}
+
+	targetServer, closeTarget, err := a.connect(ctx, target)
+	if err != nil {
+		return err
+	}
+	defer closeTarget()
+
+	// CustomDatabases are migrated one by one, failure of one of them doesn't stop the rest
+	rows := make([]migrateRow, 0, len(customDatabases))
+	failed := 0
+	for _, customDatabase := range customDatabases {
+		row := migrateRow{
+			Resource: resourceKey(customdatabase.OwnerKindCustomDatabase, customDatabase.Namespace, customDatabase.Name),
+			Database: customDatabase.Name,
+		}
+		row.Result, row.Detail, err = migrateCustomDatabase(ctx, a, targetServer, customDatabase, opts)
+		if err != nil {
+			row.Result, row.Detail = migrateFailed, err.Error()
+			failed++
+		}
+		rows = append(rows, row)
+	}
+
+	err = printRows(a, []string{"RESOURCE", "DATABASE", "RESULT", "DETAIL"}, rows, func(row migrateRow) []string {
+		return []string{row.Resource, row.Database, row.Result, row.Detail}
+	})
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d CustomDatabases aren't migrated", failed, len(rows))
+	}
+
+	return nil
+}
+
+// selectCustomDatabases returns CustomDatabases by names "<namespace>/<name>" or "<name>" and by label selector
+func selectCustomDatabases(
+	ctx context.Context, a *app, namespace, selector string, names []string,
+) ([]*v1.CustomDatabase, error) {
+	var customDatabases []*v1.CustomDatabase
+	for _, name := range names {
+		customDatabaseNamespace := namespace
+		if i := strings.Index(name, "/"); i >= 0 {
+			customDatabaseNamespace, name = name[:i], name[i+1:]
+		}
+		if customDatabaseNamespace == "" {
+			customDatabaseNamespace = metav1.NamespaceDefault
+		}
+
+		customDatabase, err := a.client.IgorV1().CustomDatabases(customDatabaseNamespace).Get(
+			ctx, name, metav1.GetOptions{},
+		)
+		if err != nil {
+			return nil, err
+		}
+		customDatabases = append(customDatabases, customDatabase)
+	}
+
+	if selector != "" {
+		list, err := a.client.IgorV1().CustomDatabases(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			customDatabases = append(customDatabases, &list.Items[i])
+		}
+	}
+
+	return customDatabases, nil
+}
+
+// migrateCustomDatabase moves database and role of CustomDatabase to target server. CustomDatabase is paused, so
+// controller of source server doesn't recreate them, and source database is read-only, while it's dumped. Failed
+// migration can be run again: database and role, which were created on target server, are reused and restore
+// replaces their objects. Roles of DatabaseUsers and DatabaseGrants aren't moved, they are created again by
+// controller of target server.
+func migrateCustomDatabase(
+	ctx context.Context, a *app, targetServer serverManager, customDatabase *v1.CustomDatabase, opts migrateOptions,
+) (result, detail string, err error) {
+	if customDatabase.Status.Hibernation != nil {
+		return "", "", fmt.Errorf("database is hibernated, wake it up before migration")
+	}
+
+	secret, err := a.kubeClient.CoreV1().Secrets(customDatabase.Namespace).Get(
+		ctx, customDatabase.Spec.SecretName, metav1.GetOptions{},
+	)
+	if err != nil {
+		return "", "", fmt.Errorf("credentials of database are unknown: %w", err)
+	}
+	secretServer := net.JoinHostPort(
+		string(secret.Data[usecases.SecretVarDbHost]), string(secret.Data[usecases.SecretVarDbPort]),
+	)
+	targetServerAddr := net.JoinHostPort(opts.secretHost, strconv.Itoa(opts.target.Port))
+	if secretServer == targetServerAddr {
+		return migrateSkipped, "already on " + targetServerAddr, nil
+	}
+
+	database := customdatabase.Database{
+		Name:     string(secret.Data[usecases.SecretVarDbName]),
+		User:     string(secret.Data[usecases.SecretVarDbUserName]),
+		Password: string(secret.Data[usecases.SecretVarDbPassword]),
+	}
+	owner := a.domainService.NewOwnershipMarker(customdatabase.OwnerKindCustomDatabase,
+		customDatabase.Namespace, customDatabase.Name, string(customDatabase.UID),
+	)
+
+	// markers are copied to target server as is, so adopted objects stay adopted
+	databaseOwnership, err := a.server.GetDatabaseOwnership(ctx, database.Name)
+	if err != nil {
+		return "", "", err
+	}
+	userOwnership, err := a.server.GetUserOwnership(ctx, database.User)
+	if err != nil {
+		return "", "", err
+	}
+	if !databaseOwnership.Exists || !userOwnership.Exists {
+		return "", "", fmt.Errorf("database %s or role %s doesn't exist on source server", database.Name, database.User)
+	}
+	for _, marker := range []*customdatabase.OwnershipMarker{databaseOwnership.Marker, userOwnership.Marker} {
+		if marker == nil || !marker.IsOwnedBy(owner) {
+			return "", "", fmt.Errorf("database %s or role %s isn't marked by CustomDatabase, run verify",
+				database.Name, database.User,
+			)
+		}
+	}
+
+	activity, err := a.server.GetDatabaseActivity(ctx, database.Name)
+	if err != nil {
+		return "", "", err
+	}
+	if activity.NumBackends > 0 && !opts.force {
+		return "", "", fmt.Errorf("database has %d sessions, stop applications or use -force", activity.NumBackends)
+	}
+
+	isPausedByMigration := customDatabase.Annotations[v1.AnnotationPaused] != "true"
+	if isPausedByMigration {
+		if err = setPaused(ctx, a, customDatabase, true); err != nil {
+			return "", "", err
+		}
+	}
+	if err = a.server.SetDatabaseReadOnly(ctx, database.Name, true); err != nil {
+		return "", "", withRollback(ctx, a, customDatabase, database.Name, isPausedByMigration, err)
+	}
+
+	limits, err := a.server.GetDatabaseLimits(ctx, database.Name)
+	if err == nil {
+		err = copyDatabase(ctx, a, targetServer, opts.target, database, limits,
+			*databaseOwnership.Marker, *userOwnership.Marker,
+		)
+	}
+	if err != nil {
+		return "", "", withRollback(ctx, a, customDatabase, database.Name, isPausedByMigration, err)
+	}
+
+	secret = secret.DeepCopy()
+	secret.Data[usecases.SecretVarDbHost] = []byte(opts.secretHost)
+	secret.Data[usecases.SecretVarDbPort] = []byte(strconv.Itoa(opts.target.Port))
+	if _, err = a.kubeClient.CoreV1().Secrets(secret.Namespace).Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+		return "", "", withRollback(ctx, a, customDatabase, database.Name, isPausedByMigration, err)
+	}
+
+	detail = "moved to " + targetServerAddr
+	if opts.dropSource {
+		// sessions are terminated, otherwise database can't be dropped
+		if err = a.server.RevokeDatabaseConnect(ctx, database.Name); err != nil {
+			return "", "", fmt.Errorf("database is migrated, but source can't be dropped: %w", err)
+		}
+		if err = a.server.DropDatabase(ctx, database.Name, *databaseOwnership.Marker); err != nil {
+			return "", "", fmt.Errorf("database is migrated, but source can't be dropped: %w", err)
+		}
+		if err = a.server.DropUser(ctx, database.User, *userOwnership.Marker); err != nil {
+			return "", "", fmt.Errorf("database is migrated, but source role can't be dropped: %w", err)
+		}
+	} else {
+		detail += ", source database is kept read-only"
+	}
+
+	if opts.resume {
+		if err = setPaused(ctx, a, customDatabase, false); err != nil {
+			return "", "", fmt.Errorf("database is migrated, but reconciliation can't be resumed: %w", err)
+		}
+	} else {
+		detail += ", CustomDatabase is paused"
+	}
+
+	return migrateMigrated, detail, nil
+}
+
+// copyDatabase creates database and role on target server and restores dump of source database into it. Dump is
+// streamed from pg_dump to pg_restore without temporary file.
+func copyDatabase(
+	ctx context.Context, a *app, targetServer serverManager, target customdatabase.Host,
+	database customdatabase.Database, limits customdatabase.Limits,
+	databaseMarker, userMarker customdatabase.OwnershipMarker,
+) error {
+	err := targetServer.CreateUser(ctx, database.User, database.Password, userMarker)
+	if err == customdatabase.ErrUserAlreadyExists {
+		err = checkTargetOwnership(ctx, targetServer.GetUserOwnership, database.User, userMarker)
+		if err == nil {
+			err = targetServer.ChangeUserPassword(ctx, database.User, database.Password)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("role %s on target server: %w", database.User, err)
+	}
+
+	// dump of another server may be restored only into empty database, which is copied from template0
+	isRestoredAgain := false
+	err = targetServer.CreateDatabase(ctx, database.Name,
+		customdatabase.DatabaseOptions{Owner: database.User, Template: "template0"}, databaseMarker,
+	)
+	if err == customdatabase.ErrDatabaseAlreadyExists {
+		err = checkTargetOwnership(ctx, targetServer.GetDatabaseOwnership, database.Name, databaseMarker)
+		isRestoredAgain = true
+	}
+	if err == nil {
+		err = targetServer.SetDatabaseLimits(ctx, database.Name, limits)
+	}
+	if err != nil {
+		return fmt.Errorf("database %s on target server: %w", database.Name, err)
+	}
+
+	dump, dumpWriter := io.Pipe()
+	dumpErr := make(chan error, 1)
+	go func() {
+		err := a.dumper.DumpDatabase(ctx, a.host, database, dumpWriter)
+		_ = dumpWriter.CloseWithError(err)
+		dumpErr <- err
+	}()
+
+	// objects of previous failed attempt are replaced
+	restoreErr := a.restorer.RestoreDatabase(ctx, target, database, dump, isRestoredAgain)
+	// dump is interrupted, when restore fails
+	_ = dump.Close()
+	err = <-dumpErr
+	switch {
+	case restoreErr != nil && err != nil:
+		return fmt.Errorf("%w, dump: %v", restoreErr, err)
+	case restoreErr != nil:
+		return restoreErr
+	default:
+		return err
+	}
+}
+
+// checkTargetOwnership checks, that existing object on target server was created by previous attempt of migration
+func checkTargetOwnership(
+	ctx context.Context, getOwnership func(ctx context.Context, name string) (customdatabase.Ownership, error),
+	name string, marker customdatabase.OwnershipMarker,
+) error {
+	ownership, err := getOwnership(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	return ownership.CheckDrop(name, marker)
+}
+
+// withRollback makes source database writable and resumes CustomDatabase, which was paused by migration. It returns
+// cause of failed migration together with errors of rollback.
+func withRollback(
+	ctx context.Context, a *app, customDatabase *v1.CustomDatabase, database string, isPausedByMigration bool,
+	cause error,
+) error {
+	if err := a.server.SetDatabaseReadOnly(ctx, database, false); err != nil {
+		return fmt.Errorf("%w, database stays read-only: %v", cause, err)
+	}
+	if isPausedByMigration {
+		if err := setPaused(ctx, a, customDatabase, false); err != nil {
+			return fmt.Errorf("%w, CustomDatabase stays paused: %v", cause, err)
+		}
+	}
+
+	return cause
+}
+
+// setPaused sets or removes annotation, which pauses reconciliation of CustomDatabase
+func setPaused(ctx context.Context, a *app, customDatabase *v1.CustomDatabase, isPaused bool) error {
+	var value any
+	if isPaused {
+		value = "true"
+	}
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{"annotations": map[string]any{v1.AnnotationPaused: value}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = a.client.IgorV1().CustomDatabases(customDatabase.Namespace).Patch(
+		ctx, customDatabase.Name, types.MergePatchType, patch, metav1.PatchOptions{},
+	)
+
+	return err
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"k8s.io/custom-database/internal/customdatabase"
+	fakeadapter "k8s.io/custom-database/internal/customdatabase/adapters/fake"
+	"k8s.io/custom-database/internal/customdatabase/usecases"
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+	commonDatabase "k8s.io/custom-database/pkg/postgres"
+)
+
+func TestMigrateCustomDatabase(t *testing.T) {
+	ctx := context.Background()
+	source, target := fakeadapter.NewDbManager(), fakeadapter.NewDbManager()
+	a := newTestApp(t, source, []runtime.Object{newSecret("test", "source")}, newCustomDatabase("test"))
+	a.connect = func(_ context.Context, config commonDatabase.ConnectionConfig) (serverManager, func(), error) {
+		if config.Host != "target" || config.Port != 5433 {
+			return nil, nil, fmt.Errorf("unexpected server %s:%d", config.Host, config.Port)
+		}
+		return target, func() {}, nil
+	}
+	createDatabase(t, source, newMarker("test"))
+	source.Limits["test"] = customdatabase.Limits{ConnectionLimit: 10}
+
+	err := runMigrate(ctx, a, []string{"-target-host", "target", "-target-port", "5433", "default/test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows []migrateRow
+	decodeOutput(t, a, &rows)
+	if len(rows) != 1 || rows[0].Result != migrateMigrated {
+		t.Fatalf("CustomDatabase should be migrated, got %+v", rows)
+	}
+
+	if target.Users["test"] != "test-password" || target.DatabaseOwnership["test"] != newMarker("test") ||
+		target.UserOwnership["test"] != newMarker("test") {
+		t.Errorf("role and database should be created on target with the same password and markers")
+	}
+	if target.Limits["test"].ConnectionLimit != 10 {
+		t.Errorf("limits should be copied, got %+v", target.Limits["test"])
+	}
+	if restored := string(a.restorer.(*fakeadapter.Restorer).Restored["test"]); restored != "dump of test" {
+		t.Errorf("dump of source should be restored, got %q", restored)
+	}
+	if !source.ReadOnly["test"] {
+		t.Errorf("source database should be kept read-only")
+	}
+
+	secret, _ := a.kubeClient.CoreV1().Secrets(metav1.NamespaceDefault).Get(ctx, "test-secret", metav1.GetOptions{})
+	if string(secret.Data[usecases.SecretVarDbHost]) != "target" ||
+		string(secret.Data[usecases.SecretVarDbPort]) != "5433" {
+		t.Errorf("Secret should point to target server, got %s", secret.Data)
+	}
+	customDatabase, _ := a.client.IgorV1().CustomDatabases(metav1.NamespaceDefault).Get(
+		ctx, "test", metav1.GetOptions{},
+	)
+	if customDatabase.Annotations[v1.AnnotationPaused] != "true" {
+		t.Errorf("CustomDatabase should stay paused")
+	}
+
+	// the second run finds CustomDatabase on target server
+	err = runMigrate(ctx, a, []string{"-target-host", "target", "-target-port", "5433", "-drop-source", "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decodeOutput(t, a, &rows)
+	if len(rows) != 1 || rows[0].Result != migrateSkipped {
+		t.Errorf("migrated CustomDatabase should be skipped, got %+v", rows)
+	}
+	if _, isExists := source.Databases["test"]; !isExists {
+		t.Errorf("source of skipped CustomDatabase shouldn't be dropped")
+	}
+}
+
+func TestFailedMigrationIsRolledBack(t *testing.T) {
+	ctx := context.Background()
+	source, target := fakeadapter.NewDbManager(), fakeadapter.NewDbManager()
+	a := newTestApp(t, source, []runtime.Object{newSecret("test", "source")}, newCustomDatabase("test"))
+	a.connect = func(context.Context, commonDatabase.ConnectionConfig) (serverManager, func(), error) {
+		return target, func() {}, nil
+	}
+	a.restorer.(*fakeadapter.Restorer).Err = fmt.Errorf("restore failed")
+	createDatabase(t, source, newMarker("test"))
+
+	if err := runMigrate(ctx, a, []string{"-target-host", "target", "test"}); err == nil {
+		t.Fatalf("failed migration should be reported by error")
+	}
+	var rows []migrateRow
+	decodeOutput(t, a, &rows)
+	if len(rows) != 1 || rows[0].Result != migrateFailed {
+		t.Errorf("CustomDatabase should fail, got %+v", rows)
+	}
+
+	if source.ReadOnly["test"] {
+		t.Errorf("source database should be writable again")
+	}
+	customDatabase, _ := a.client.IgorV1().CustomDatabases(metav1.NamespaceDefault).Get(
+		ctx, "test", metav1.GetOptions{},
+	)
+	if _, isPaused := customDatabase.Annotations[v1.AnnotationPaused]; isPaused {
+		t.Errorf("CustomDatabase should be resumed")
+	}
+	secret, _ := a.kubeClient.CoreV1().Secrets(metav1.NamespaceDefault).Get(ctx, "test-secret", metav1.GetOptions{})
+	if string(secret.Data[usecases.SecretVarDbHost]) != "source" {
+		t.Errorf("Secret shouldn't be changed")
+	}
+
+	// the next attempt reuses database and role, which were created by failed one, and replaces their objects
+	a.restorer.(*fakeadapter.Restorer).Err = nil
+	if err := runMigrate(ctx, a, []string{"-target-host", "target", "test"}); err != nil {
+		t.Fatalf("migration should be resumed: %v", err)
+	}
+}
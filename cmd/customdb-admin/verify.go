@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/custom-database/internal/customdatabase"
+)
+
+// Results of verification of ownership marker
+const (
+	verifyOK = "ok"
+	// verifyMissing object doesn't exist, e.g. it isn't created yet or database is dropped by hibernation
+	verifyMissing = "missing"
+	// verifyNoMarker object exists without marker, controller writes it on the next sync, when it created the object
+	verifyNoMarker = "no-marker"
+	// verifyForeign object is marked by another resource, controller never touches it
+	verifyForeign = "foreign"
+	// verifyUIDMismatch object is marked by resource with the same name, which was recreated
+	verifyUIDMismatch = "uid-mismatch"
+)
+
+type verifyRow struct {
+	Resource string `json:"resource"`
+	Object   string `json:"object"`
+	Name     string `json:"name"`
+	Result   string `json:"result"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// expectedObject database or role, which should be marked by resource
+type expectedObject struct {
+	object string
+	name   string
+	owner  customdatabase.OwnershipMarker
+}
+
+func runVerify(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	rows, err := verifyOwnership(ctx, a)
+	if err != nil {
+		return err
+	}
+
+	err = printRows(a, []string{"RESOURCE", "OBJECT", "NAME", "RESULT", "DETAIL"}, rows, func(row verifyRow) []string {
+		return []string{row.Resource, row.Object, row.Name, row.Result, row.Detail}
+	})
+	if err != nil {
+		return err
+	}
+
+	// non-zero exit code lets scripts notice problems without parsing output
+	problems := 0
+	for _, row := range rows {
+		if row.Result != verifyOK && row.Result != verifyMissing {
+			problems++
+		}
+	}
+	if problems > 0 {
+		return fmt.Errorf("%d objects have problems with ownership markers", problems)
+	}
+
+	return nil
+}
+
+// verifyOwnership compares markers of databases and roles of all resources with markers, which controller writes
+func verifyOwnership(ctx context.Context, a *app) ([]verifyRow, error) {
+	objects, err := expectedObjects(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]verifyRow, 0, len(objects))
+	for _, o := range objects {
+		var ownership customdatabase.Ownership
+		if o.object == "database" {
+			ownership, err = a.server.GetDatabaseOwnership(ctx, o.name)
+		} else {
+			ownership, err = a.server.GetUserOwnership(ctx, o.name)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		row := verifyRow{Resource: resourceKey(o.owner.Kind, o.owner.Namespace, o.owner.Name), Object: o.object,
+			Name: o.name, Result: verifyOK,
+		}
+		switch {
+		case !ownership.Exists:
+			row.Result = verifyMissing
+		case ownership.Marker == nil:
+			row.Result = verifyNoMarker
+		case !ownership.Marker.IsOwnedBy(o.owner):
+			row.Result, row.Detail = verifyForeign, "belongs to "+ownership.Marker.Owner()
+		case ownership.Marker.UID != o.owner.UID:
+			row.Result, row.Detail = verifyUIDMismatch, fmt.Sprintf(
+				"marker has UID %s, resource has UID %s", ownership.Marker.UID, o.owner.UID,
+			)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// expectedObjects returns databases and roles of CustomDatabases and roles of DatabaseUsers and DatabaseGrants
+func expectedObjects(ctx context.Context, a *app) ([]expectedObject, error) {
+	var objects []expectedObject
+
+	customDatabases, err := a.client.IgorV1().CustomDatabases(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range customDatabases.Items {
+		entity := a.domainService.CreateCustomDatabaseEntity(
+			item.Namespace, item.Name, item.Status.DatabaseName, customdatabase.DatabaseOptions{},
+		)
+		owner := a.domainService.NewOwnershipMarker(customdatabase.OwnerKindCustomDatabase, item.Namespace, item.Name,
+			string(item.UID),
+		)
+		objects = append(objects,
+			expectedObject{object: "database", name: entity.Database.Name, owner: owner},
+			expectedObject{object: "role", name: entity.Database.User, owner: owner},
+		)
+	}
+
+	// roles of users and grants are known only after they are created
+	databaseUsers, err := a.client.IgorV1().DatabaseUsers(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range databaseUsers.Items {
+		if item.Status.UserName != "" {
+			objects = append(objects, expectedObject{object: "role", name: item.Status.UserName,
+				owner: a.domainService.NewOwnershipMarker(customdatabase.OwnerKindDatabaseUser, item.Namespace,
+					item.Name, string(item.UID),
+				),
+			})
+		}
+	}
+
+	databaseGrants, err := a.client.IgorV1().DatabaseGrants(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range databaseGrants.Items {
+		if item.Status.UserName != "" {
+			objects = append(objects, expectedObject{object: "role", name: item.Status.UserName,
+				owner: a.domainService.NewOwnershipMarker(customdatabase.OwnerKindDatabaseGrant, item.Namespace,
+					item.Name, string(item.UID),
+				),
+			})
+		}
+	}
+
+	return objects, nil
+}
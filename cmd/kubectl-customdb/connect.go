@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// portForwardTimeout how long kubectl port-forward may start
+const portForwardTimeout = 15 * time.Second
+
+func runConnect(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("connect", flag.ContinueOnError)
+	isUser := fs.Bool("user", false, "Name is DatabaseUser instead of CustomDatabase")
+	psqlPath := fs.String("psql", "psql", "Path to psql")
+	kubectlPath := fs.String("kubectl", "kubectl", "Path to kubectl, which forwards port")
+	portForward := fs.String("port-forward", "", "Forward local port to Postgresql server by kubectl, e.g. svc/postgres. "+
+		"It's needed, when server isn't reachable outside of cluster")
+	portForwardNamespace := fs.String("port-forward-namespace", "", "Namespace of -port-forward target, "+
+		"namespace of CustomDatabase by default")
+
+	// arguments after "--" are passed to psql, e.g. "connect db -- -c 'SELECT 1'"
+	var psqlArgs []string
+	for i, arg := range args {
+		if arg == "--" {
+			args, psqlArgs = args[:i], args[i+1:]
+			break
+		}
+	}
+	name, err := exactlyOneName(fs, args)
+	if err != nil {
+		return err
+	}
+
+	creds, err := loadCredentials(ctx, a, name, *isUser)
+	if err != nil {
+		return err
+	}
+
+	if *portForward != "" {
+		namespace := *portForwardNamespace
+		if namespace == "" {
+			namespace = a.namespace
+		}
+		localPort, stop, err := startPortForward(ctx, *kubectlPath, namespace, *portForward, creds.Port)
+		if err != nil {
+			return err
+		}
+		defer stop()
+		creds.Host, creds.Port = "127.0.0.1", strconv.Itoa(localPort)
+	}
+
+	psql := exec.CommandContext(ctx, *psqlPath, psqlArgs...)
+	psql.Stdin, psql.Stdout, psql.Stderr = os.Stdin, os.Stdout, os.Stderr
+	// password is passed by environment, so it isn't visible in list of processes
+	psql.Env = os.Environ()
+	for name, value := range creds.env() {
+		psql.Env = append(psql.Env, name+"="+value)
+	}
+
+	return psql.Run()
+}
+
+// startPortForward runs kubectl port-forward to free local port and waits until it's ready
+func startPortForward(
+	ctx context.Context, kubectlPath, namespace, target, remotePort string,
+) (int, func(), error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, nil, err
+	}
+	localPort := listener.Addr().(*net.TCPAddr).Port
+	_ = listener.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	kubectl := exec.CommandContext(ctx, kubectlPath, "port-forward", "-n", namespace, target,
+		fmt.Sprintf("%d:%s", localPort, remotePort),
+	)
+	kubectl.Stderr = os.Stderr
+	stdout, err := kubectl.StdoutPipe()
+	if err != nil {
+		cancel()
+		return 0, nil, err
+	}
+	if err = kubectl.Start(); err != nil {
+		cancel()
+		return 0, nil, err
+	}
+	stop := func() {
+		cancel()
+		_ = kubectl.Wait()
+	}
+
+	// kubectl prints "Forwarding from 127.0.0.1:<port> -> <port>", when port is listened. The rest of output isn't
+	// interesting, but pipe is drained until kubectl exits.
+	ready := make(chan bool, 1)
+	go func() {
+		isReady := false
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			if !isReady && strings.HasPrefix(scanner.Text(), "Forwarding from") {
+				isReady = true
+				ready <- true
+			}
+		}
+		if !isReady {
+			ready <- false
+		}
+	}()
+
+	select {
+	case isReady := <-ready:
+		if !isReady {
+			stop()
+			return 0, nil, fmt.Errorf("kubectl port-forward to %s/%s exited", namespace, target)
+		}
+		return localPort, stop, nil
+	case <-time.After(portForwardTimeout):
+		stop()
+		return 0, nil, fmt.Errorf("port-forward to %s/%s isn't ready in %s", namespace, target, portForwardTimeout)
+	case <-ctx.Done():
+		stop()
+		return 0, nil, ctx.Err()
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/custom-database/internal/customdatabase/usecases"
+)
+
+// credentials of role from Secret of CustomDatabase or DatabaseUser
+type credentials struct {
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	Database string `json:"database"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+	// SSLMode is published, when controller requires TLS
+	SSLMode string `json:"sslMode,omitempty"`
+}
+
+func runCredentials(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("credentials", flag.ContinueOnError)
+	format := fs.String("o", "env", "Output format: env, export, json, uri or jdbc")
+	isUser := fs.Bool("user", false, "Name is DatabaseUser instead of CustomDatabase")
+	name, err := exactlyOneName(fs, args)
+	if err != nil {
+		return err
+	}
+
+	creds, err := loadCredentials(ctx, a, name, *isUser)
+	if err != nil {
+		return err
+	}
+
+	return printCredentials(a.out, creds, *format)
+}
+
+// loadCredentials reads Secret, which is referenced by CustomDatabase or DatabaseUser
+func loadCredentials(ctx context.Context, a *app, name string, isUser bool) (credentials, error) {
+	var secretName string
+	if isUser {
+		databaseUser, err := a.client.IgorV1().DatabaseUsers(a.namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return credentials{}, err
+		}
+		secretName = databaseUser.Spec.SecretName
+	} else {
+		customDatabase, err := a.client.IgorV1().CustomDatabases(a.namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return credentials{}, err
+		}
+		secretName = customDatabase.Spec.SecretName
+	}
+
+	secret, err := a.kubeClient.CoreV1().Secrets(a.namespace).Get(ctx, secretName, metav1.GetOptions{})
+	if err != nil {
+		return credentials{}, fmt.Errorf("credentials aren't ready: %w", err)
+	}
+
+	return credentials{
+		Host:     string(secret.Data[usecases.SecretVarDbHost]),
+		Port:     string(secret.Data[usecases.SecretVarDbPort]),
+		Database: string(secret.Data[usecases.SecretVarDbName]),
+		User:     string(secret.Data[usecases.SecretVarDbUserName]),
+		Password: string(secret.Data[usecases.SecretVarDbPassword]),
+		SSLMode:  string(secret.Data[usecases.SecretVarDbSSLMode]),
+	}, nil
+}
+
+func printCredentials(w io.Writer, creds credentials, format string) error {
+	switch format {
+	case "env", "export":
+		prefix := ""
+		if format == "export" {
+			prefix = "export "
+		}
+		vars := creds.env()
+		names := make([]string, 0, len(vars))
+		for name := range vars {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(w, "%s%s=%s\n", prefix, name, shellQuote(vars[name]))
+		}
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(creds)
+	case "uri":
+		fmt.Fprintln(w, creds.uri())
+	case "jdbc":
+		fmt.Fprintf(w, "jdbc:postgresql://%s/%s?user=%s&password=%s",
+			net.JoinHostPort(creds.Host, creds.Port), url.PathEscape(creds.Database),
+			url.QueryEscape(creds.User), url.QueryEscape(creds.Password),
+		)
+		if creds.SSLMode != "" {
+			fmt.Fprintf(w, "&sslmode=%s", url.QueryEscape(creds.SSLMode))
+		}
+		fmt.Fprintln(w)
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+
+	return nil
+}
+
+// env returns variables of libpq, which are understood by psql and most of Postgresql drivers
+func (c credentials) env() map[string]string {
+	vars := map[string]string{
+		"PGHOST":     c.Host,
+		"PGPORT":     c.Port,
+		"PGDATABASE": c.Database,
+		"PGUSER":     c.User,
+		"PGPASSWORD": c.Password,
+	}
+	if c.SSLMode != "" {
+		vars["PGSSLMODE"] = c.SSLMode
+	}
+
+	return vars
+}
+
+func (c credentials) uri() string {
+	u := url.URL{
+		Scheme: "postgresql",
+		User:   url.UserPassword(c.User, c.Password),
+		Host:   net.JoinHostPort(c.Host, c.Port),
+		Path:   "/" + c.Database,
+	}
+	if c.SSLMode != "" {
+		u.RawQuery = url.Values{"sslmode": {c.SSLMode}}.Encode()
+	}
+
+	return u.String()
+}
+
+// shellQuote quotes value for POSIX shell
+func shellQuote(value string) string {
+	quoted := "'"
+	for _, r := range value {
+		if r == '\'' {
+			quoted += `'\''`
+		} else {
+			quoted += string(r)
+		}
+	}
+
+	return quoted + "'"
+}
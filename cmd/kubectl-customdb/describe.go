@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"sort"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+
+	"k8s.io/custom-database/internal/customdatabase/usecases"
+)
+
+func runDescribe(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("describe", flag.ContinueOnError)
+	name, err := exactlyOneName(fs, args)
+	if err != nil {
+		return err
+	}
+
+	customDatabase, err := a.client.IgorV1().CustomDatabases(a.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	server := "<none>"
+	secret, err := a.kubeClient.CoreV1().Secrets(a.namespace).Get(
+		ctx, customDatabase.Spec.SecretName, metav1.GetOptions{},
+	)
+	hasSecret := err == nil
+	if hasSecret {
+		server = net.JoinHostPort(
+			string(secret.Data[usecases.SecretVarDbHost]), string(secret.Data[usecases.SecretVarDbPort]),
+		)
+	}
+
+	w := tabwriter.NewWriter(a.out, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "Name:\t%s\n", customDatabase.Name)
+	fmt.Fprintf(w, "Namespace:\t%s\n", customDatabase.Namespace)
+	fmt.Fprintf(w, "Age:\t%s\n", age(customDatabase.CreationTimestamp))
+	fmt.Fprintf(w, "Status:\t%s\n", customDatabaseStatus(customDatabase, hasSecret))
+	fmt.Fprintf(w, "Secret:\t%s\n", customDatabase.Spec.SecretName)
+	fmt.Fprintf(w, "Server:\t%s\n", server)
+
+	status := customDatabase.Status
+	if status.Size != nil {
+		fmt.Fprintf(w, "Size:\t%s\n", status.Size.String())
+	}
+	if quota := customDatabase.Spec.StorageQuota; quota != nil {
+		fmt.Fprintf(w, "Storage Quota:\t%s\n", quota.Limit.String())
+	}
+	if status.Limits.ConnectionLimit != nil {
+		fmt.Fprintf(w, "Connection Limit:\t%d\n", *status.Limits.ConnectionLimit)
+	}
+	if status.Limits.StatementTimeout != "" {
+		fmt.Fprintf(w, "Statement Timeout:\t%s\n", status.Limits.StatementTimeout)
+	}
+	if status.Limits.IdleInTransactionSessionTimeout != "" {
+		fmt.Fprintf(w, "Idle In Transaction Timeout:\t%s\n", status.Limits.IdleInTransactionSessionTimeout)
+	}
+	if status.Limits.WorkMem != "" {
+		fmt.Fprintf(w, "Work Mem:\t%s\n", status.Limits.WorkMem)
+	}
+	if status.Migrations != nil {
+		fmt.Fprintf(w, "Migrations:\t%d/%d\n", status.Migrations.CurrentVersion, status.Migrations.TargetVersion)
+	}
+	if status.ExpiresAt != nil {
+		fmt.Fprintf(w, "Expires At:\t%s\n", status.ExpiresAt.UTC().Format("2006-01-02T15:04:05Z"))
+	}
+	if status.LastActivityAt != nil {
+		fmt.Fprintf(w, "Last Activity:\t%s ago\n", age(*status.LastActivityAt))
+	}
+	if status.Hibernation != nil {
+		fmt.Fprintf(w, "Hibernation:\t%s since %s\n", status.Hibernation.Phase,
+			status.Hibernation.HibernatedAt.UTC().Format("2006-01-02T15:04:05Z"),
+		)
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(a.out, "\nConditions:")
+	if len(status.Conditions) == 0 {
+		fmt.Fprintln(a.out, "  <none>")
+	} else {
+		w = tabwriter.NewWriter(a.out, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "  TYPE\tSTATUS\tREASON\tAGE\tMESSAGE")
+		for _, condition := range status.Conditions {
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\n", condition.Type, condition.Status, condition.Reason,
+				age(condition.LastTransitionTime), condition.Message,
+			)
+		}
+		if err = w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	events, err := a.kubeClient.CoreV1().Events(a.namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.Set{
+			"involvedObject.kind": "CustomDatabase",
+			"involvedObject.name": customDatabase.Name,
+		}.String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(a.out, "\nEvents:")
+	if len(events.Items) == 0 {
+		fmt.Fprintln(a.out, "  <none>")
+		return nil
+	}
+	sort.Slice(events.Items, func(i, j int) bool {
+		return lastSeen(&events.Items[i]).Time.Before(lastSeen(&events.Items[j]).Time)
+	})
+	w = tabwriter.NewWriter(a.out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "  TYPE\tREASON\tAGE\tMESSAGE")
+	for i := range events.Items {
+		event := &events.Items[i]
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", event.Type, event.Reason, age(lastSeen(event)), event.Message)
+	}
+
+	return w.Flush()
+}
+
+// lastSeen time of the last occurrence of event, events of events.k8s.io API have only EventTime
+func lastSeen(event *corev1.Event) metav1.Time {
+	if !event.LastTimestamp.IsZero() {
+		return event.LastTimestamp
+	}
+	if !event.EventTime.IsZero() {
+		return metav1.Time{Time: event.EventTime.Time}
+	}
+
+	return event.CreationTimestamp
+}
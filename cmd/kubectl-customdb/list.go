@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+)
+
+// problemConditions conditions, which are shown as status of CustomDatabase, when they have the given status. The
+// first matched condition wins.
+var problemConditions = []struct {
+	conditionType string
+	status        metav1.ConditionStatus
+}{
+	{v1.ConditionPaused, metav1.ConditionTrue},
+	{v1.ConditionHibernated, metav1.ConditionTrue},
+	{v1.ConditionServerMigrating, metav1.ConditionTrue},
+	{v1.ConditionAdopted, metav1.ConditionFalse},
+	{v1.ConditionDrifted, metav1.ConditionTrue},
+	{v1.ConditionQuotaExceeded, metav1.ConditionTrue},
+	{v1.ConditionInitialized, metav1.ConditionFalse},
+	{v1.ConditionMigrated, metav1.ConditionFalse},
+	{v1.ConditionHardened, metav1.ConditionFalse},
+	{v1.ConditionExpiring, metav1.ConditionTrue},
+}
+
+func runList(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	allNamespaces := fs.Bool("A", false, "List CustomDatabases of all namespaces")
+	if _, err := parseInterspersed(fs, args); err != nil {
+		return err
+	}
+
+	namespace := a.namespace
+	if *allNamespaces {
+		namespace = metav1.NamespaceAll
+	}
+	customDatabases, err := a.client.IgorV1().CustomDatabases(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(a.out, 0, 8, 2, ' ', 0)
+	if *allNamespaces {
+		fmt.Fprint(w, "NAMESPACE\t")
+	}
+	fmt.Fprintln(w, "NAME\tSERVER\tSTATUS\tSIZE\tAGE")
+
+	// Secrets are listed once per namespace, not got for every CustomDatabase
+	secretNames := make(map[string]map[string]bool)
+	for i := range customDatabases.Items {
+		customDatabase := &customDatabases.Items[i]
+
+		names, ok := secretNames[customDatabase.Namespace]
+		if !ok {
+			if names, err = a.listSecretNames(ctx, customDatabase.Namespace); err != nil {
+				return err
+			}
+			secretNames[customDatabase.Namespace] = names
+		}
+		hasSecret := names[customDatabase.Spec.SecretName]
+
+		// server is known only after database is created
+		server := "<none>"
+		if customDatabase.Status.Server != "" {
+			server = customDatabase.Status.Server
+		}
+
+		size := "<unknown>"
+		if customDatabase.Status.Size != nil {
+			size = customDatabase.Status.Size.String()
+		}
+
+		if *allNamespaces {
+			fmt.Fprintf(w, "%s\t", customDatabase.Namespace)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", customDatabase.Name, server, customDatabaseStatus(customDatabase, hasSecret),
+			size, age(customDatabase.CreationTimestamp),
+		)
+	}
+
+	return w.Flush()
+}
+
+// listSecretNames returns names of Secrets in namespace
+func (a *app) listSecretNames(ctx context.Context, namespace string) (map[string]bool, error) {
+	secrets, err := a.kubeClient.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool, len(secrets.Items))
+	for _, secret := range secrets.Items {
+		names[secret.Name] = true
+	}
+
+	return names, nil
+}
+
+// customDatabaseStatus summarizes conditions of CustomDatabase in one word
+func customDatabaseStatus(customDatabase *v1.CustomDatabase, hasSecret bool) string {
+	for _, problem := range problemConditions {
+		condition := meta.FindStatusCondition(customDatabase.Status.Conditions, problem.conditionType)
+		if condition != nil && condition.Status == problem.status {
+			if problem.status == metav1.ConditionFalse {
+				return condition.Reason
+			}
+			return condition.Type
+		}
+	}
+
+	if !hasSecret {
+		return "Provisioning"
+	}
+
+	return "Ready"
+}
+
+func age(timestamp metav1.Time) string {
+	if timestamp.IsZero() {
+		return "<unknown>"
+	}
+
+	return duration.HumanDuration(time.Since(timestamp.Time))
+}
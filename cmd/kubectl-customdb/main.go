@@ -0,0 +1,126 @@
+// kubectl-customdb is kubectl plugin for everyday operations with CustomDatabases: credentials, psql sessions and
+// password rotation. Binary is found by kubectl in PATH and is run as "kubectl customdb <command>".
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	clientset "k8s.io/custom-database/pkg/generated/clientset/versioned"
+)
+
+const usage = `Usage: kubectl customdb [-kubeconfig path] [-context name] [-n namespace] <command> [flags] [args]
+
+Commands:
+  list                  list CustomDatabases with server, status, size and age
+  credentials <name>    print credentials from Secret of CustomDatabase or DatabaseUser
+  connect <name>        run psql with credentials of CustomDatabase or DatabaseUser
+  rotate <user>...      request new password of DatabaseUsers
+  describe <name>       show CustomDatabase with its conditions and events
+
+Run "kubectl customdb <command> -h" for flags of command.
+`
+
+// app clients and options, which are shared by commands
+type app struct {
+	namespace  string
+	restConfig *rest.Config
+	kubeClient kubernetes.Interface
+	client     clientset.Interface
+	out        io.Writer
+}
+
+type command func(ctx context.Context, a *app, args []string) error
+
+var commands = map[string]command{
+	"list":        runList,
+	"credentials": runCredentials,
+	"connect":     runConnect,
+	"rotate":      runRotate,
+	"describe":    runDescribe,
+}
+
+func main() {
+	if err := run(context.Background(), os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("kubectl customdb", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprint(fs.Output(), usage) }
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	overrides := &clientcmd.ConfigOverrides{}
+	fs.StringVar(&loadingRules.ExplicitPath, "kubeconfig", "", "Path to a kubeconfig, KUBECONFIG by default")
+	fs.StringVar(&overrides.CurrentContext, "context", "", "Name of kubeconfig context")
+	fs.StringVar(&overrides.Context.Namespace, "n", "", "Namespace, namespace of context by default")
+	fs.StringVar(&overrides.Context.Namespace, "namespace", "", "Namespace, namespace of context by default")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("command is required")
+	}
+
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		fs.Usage()
+		return fmt.Errorf("unknown command %q", fs.Arg(0))
+	}
+
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides)
+	a := &app{out: os.Stdout}
+	var err error
+	if a.namespace, _, err = clientConfig.Namespace(); err != nil {
+		return err
+	}
+	if a.restConfig, err = clientConfig.ClientConfig(); err != nil {
+		return err
+	}
+	if a.kubeClient, err = kubernetes.NewForConfig(a.restConfig); err != nil {
+		return err
+	}
+	if a.client, err = clientset.NewForConfig(a.restConfig); err != nil {
+		return err
+	}
+
+	return cmd(ctx, a, fs.Args()[1:])
+}
+
+// parseInterspersed parses flags, which may follow positional arguments like in kubectl, e.g. "credentials db -o uri"
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// exactlyOneName returns the only positional argument of command
+func exactlyOneName(fs *flag.FlagSet, args []string) (string, error) {
+	names, err := parseInterspersed(fs, args)
+	if err != nil {
+		return "", err
+	}
+	if len(names) != 1 {
+		return "", fmt.Errorf("%s: exactly one name is required, got %q", fs.Name(), strings.Join(names, " "))
+	}
+
+	return names[0], nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+)
+
+func runRotate(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("rotate", flag.ContinueOnError)
+	customDatabaseName := fs.String("database", "", "Rotate passwords of all DatabaseUsers of CustomDatabase")
+	names, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 && *customDatabaseName == "" {
+		return fmt.Errorf("rotate: names of DatabaseUsers or -database are required")
+	}
+
+	if *customDatabaseName != "" {
+		databaseUsers, err := a.client.IgorV1().DatabaseUsers(a.namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		for _, databaseUser := range databaseUsers.Items {
+			if databaseUser.Spec.CustomDatabaseName == *customDatabaseName {
+				names = append(names, databaseUser.Name)
+			}
+		}
+		if len(names) == 0 {
+			return fmt.Errorf("CustomDatabase %s has no DatabaseUsers, password of its owner isn't rotated",
+				*customDatabaseName,
+			)
+		}
+	}
+
+	// every new value of annotation leads to rotation, so timestamp is unique enough and shows, when it was requested
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]string{
+				v1.AnnotationRotatePassword: time.Now().UTC().Format(time.RFC3339),
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		_, err = a.client.IgorV1().DatabaseUsers(a.namespace).Patch(
+			ctx, name, types.MergePatchType, patch, metav1.PatchOptions{},
+		)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(a.out, "databaseuser/%s password rotation requested\n", name)
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package customdatabase
+
+import "time"
+
+// DatabaseActivity counters of database usage from pg_stat_database
+type DatabaseActivity struct {
+	// XactCommit amount of committed transactions since reset of statistics
+	XactCommit int64
+	// NumBackends amount of sessions, that are connected to database now
+	NumBackends int64
+	// StatsReset time of the last reset of statistics, zero if statistics were never reset
+	StatsReset time.Time
+}
+
+// IsActiveSince reports whether database was used since previous sample. Without previous sample only connected
+// sessions are taken into account. Reset of statistics is taken for activity, because commits since previous sample
+// are unknown.
+func (a DatabaseActivity) IsActiveSince(previous *DatabaseActivity) bool {
+	if a.NumBackends > 0 {
+		return true
+	}
+	if previous == nil {
+		return false
+	}
+
+	return a.XactCommit != previous.XactCommit || !a.StatsReset.Equal(previous.StatsReset)
+}
+
+// Hibernation policy of idle database
+type Hibernation struct {
+	// IdleDuration after which database is hibernated
+	IdleDuration time.Duration
+	// DumpAndDrop database is dumped to backup storage and dropped instead of revocation of CONNECT
+	DumpAndDrop bool
+}
+
+// IsDue reports whether database, that was used last time at lastActivityAt, should be hibernated
+func (h Hibernation) IsDue(lastActivityAt, now time.Time) bool {
+	return now.Sub(lastActivityAt) >= h.IdleDuration
+}
@@ -0,0 +1,99 @@
+package backupstorage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FilesystemStorage keeps dumps in local directory, e.g. mounted PVC
+type FilesystemStorage struct {
+	dir string
+}
+
+func NewFilesystemStorage(dir string) (*FilesystemStorage, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("directory of backups should be not empty")
+	}
+
+	return &FilesystemStorage{dir: dir}, nil
+}
+
+// Put writes dump to temporary file and renames it, so incomplete dump is never visible by its location
+func (s *FilesystemStorage) Put(ctx context.Context, location string, dump io.Reader) error {
+	path, err := s.path(location)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), ".tmp-"+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name()) // nolint: errcheck
+
+	_, err = io.Copy(file, readerWithContext{ctx: ctx, reader: dump})
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+func (s *FilesystemStorage) Get(_ context.Context, location string) (io.ReadCloser, error) {
+	path, err := s.path(location)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.Open(path)
+}
+
+// Delete removes dump, it's not an error if dump doesn't exist
+func (s *FilesystemStorage) Delete(_ context.Context, location string) error {
+	path, err := s.path(location)
+	if err != nil {
+		return err
+	}
+
+	if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// path returns path of dump inside storage directory, location can't point outside it
+func (s *FilesystemStorage) path(location string) (string, error) {
+	cleaned := filepath.Clean(filepath.FromSlash(location))
+	if cleaned == "." || filepath.IsAbs(cleaned) || cleaned == ".." ||
+		strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid backup location %q", location)
+	}
+
+	return filepath.Join(s.dir, cleaned), nil
+}
+
+// readerWithContext stops reading, when context is done
+type readerWithContext struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r readerWithContext) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return r.reader.Read(p)
+}
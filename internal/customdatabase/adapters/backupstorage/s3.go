@@ -0,0 +1,146 @@
+package backupstorage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Config settings of S3-compatible object storage, e.g. AWS S3 or MinIO
+type S3Config struct {
+	// Endpoint URL of storage, e.g. "https://s3.eu-west-1.amazonaws.com" or "http://minio:9000"
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// UsePathStyle addresses bucket by path instead of subdomain, it's required by most of self-hosted storages
+	UsePathStyle bool
+}
+
+// S3Storage keeps dumps in bucket of S3-compatible object storage. Requests are signed and retried by minio-go.
+type S3Storage struct {
+	bucket string
+	client *minio.Client
+}
+
+func NewS3Storage(config S3Config, client *http.Client) (*S3Storage, error) {
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("bucket of backups should be not empty")
+	}
+	if config.Region == "" {
+		return nil, fmt.Errorf("region of bucket should be not empty")
+	}
+
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("parse endpoint of S3 storage: %w", err)
+	}
+	if endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("endpoint of S3 storage should be absolute URL, given %q", config.Endpoint)
+	}
+	if strings.Trim(endpoint.Path, "/") != "" {
+		return nil, fmt.Errorf("endpoint of S3 storage shouldn't have path, given %q", config.Endpoint)
+	}
+
+	bucketLookup := minio.BucketLookupDNS
+	if config.UsePathStyle {
+		bucketLookup = minio.BucketLookupPath
+	}
+
+	minioClient, err := minio.New(endpoint.Host, &minio.Options{
+		Creds:        credentials.NewStaticV4(config.AccessKeyID, config.SecretAccessKey, ""),
+		Secure:       endpoint.Scheme == "https",
+		Transport:    client.Transport,
+		Region:       config.Region,
+		BucketLookup: bucketLookup,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create client of S3 storage: %w", err)
+	}
+
+	return &S3Storage{bucket: config.Bucket, client: minioClient}, nil
+}
+
+// Put uploads dump. Dump is spooled to temporary file first, so its length is known, and large dump is uploaded by
+// parts, which are read from the file and retried without keeping them in memory.
+func (s *S3Storage) Put(ctx context.Context, location string, dump io.Reader) error {
+	objectName, err := objectName(location)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp("", "customdatabase-backup-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name()) // nolint: errcheck
+	defer file.Close()
+
+	size, err := io.Copy(file, readerWithContext{ctx: ctx, reader: dump})
+	if err != nil {
+		return err
+	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	_, err = s.client.PutObject(ctx, s.bucket, objectName, file, size, minio.PutObjectOptions{
+		ContentType: "application/octet-stream",
+	})
+	if err != nil {
+		return fmt.Errorf("put %s: %w", objectName, err)
+	}
+
+	return nil
+}
+
+func (s *S3Storage) Get(ctx context.Context, location string) (io.ReadCloser, error) {
+	objectName, err := objectName(location)
+	if err != nil {
+		return nil, err
+	}
+
+	object, err := s.client.GetObject(ctx, s.bucket, objectName, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("get %s: %w", objectName, err)
+	}
+	// object is requested lazily, so missing dump is reported only by the first request
+	if _, err = object.Stat(); err != nil {
+		object.Close()
+		return nil, fmt.Errorf("get %s: %w", objectName, err)
+	}
+
+	return object, nil
+}
+
+// Delete removes dump, storage doesn't report error for missing object
+func (s *S3Storage) Delete(ctx context.Context, location string) error {
+	objectName, err := objectName(location)
+	if err != nil {
+		return err
+	}
+
+	if err = s.client.RemoveObject(ctx, s.bucket, objectName, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("delete %s: %w", objectName, err)
+	}
+
+	return nil
+}
+
+// objectName returns name of object in bucket by location of dump
+func objectName(location string) (string, error) {
+	name := strings.TrimPrefix(location, "/")
+	if name == "" {
+		return "", fmt.Errorf("invalid backup location %q", location)
+	}
+
+	return name, nil
+}
@@ -0,0 +1,124 @@
+package backupstorage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// s3StandIn in-memory S3-compatible storage with path-style addressing
+type s3StandIn struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (s *s3StandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=access-key/") {
+		http.Error(w, "AccessDenied", http.StatusForbidden)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("X-Amz-Content-Sha256") == "STREAMING-AWS4-HMAC-SHA256-PAYLOAD" {
+			body = decodeAWSChunked(body)
+			if r.Header.Get("X-Amz-Decoded-Content-Length") != strconv.Itoa(len(body)) {
+				http.Error(w, "IncompleteBody", http.StatusBadRequest)
+				return
+			}
+		} else if hash := sha256.Sum256(body); r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(hash[:]) {
+			http.Error(w, "XAmzContentSHA256Mismatch", http.StatusBadRequest)
+			return
+		}
+		s.objects[r.URL.Path] = body
+	case http.MethodGet, http.MethodHead:
+		body, isExists := s.objects[r.URL.Path]
+		if !isExists {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(body)
+		}
+	case http.MethodDelete:
+		delete(s.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "MethodNotAllowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// decodeAWSChunked returns payload of body, which is signed by chunks: "<hex size>;chunk-signature=<signature>\r\n<data>\r\n"
+func decodeAWSChunked(body []byte) []byte {
+	var payload []byte
+	for len(body) > 0 {
+		header, rest, _ := strings.Cut(string(body), "\r\n")
+		sizeHex, _, _ := strings.Cut(header, ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil || size == 0 || int64(len(rest)) < size {
+			break
+		}
+		payload = append(payload, rest[:size]...)
+		body = []byte(strings.TrimPrefix(rest[size:], "\r\n"))
+	}
+
+	return payload
+}
+
+func TestS3StoragePutGetDelete(t *testing.T) {
+	standIn := &s3StandIn{objects: map[string][]byte{}}
+	server := httptest.NewServer(standIn)
+	defer server.Close()
+
+	storage, err := NewS3Storage(S3Config{
+		Endpoint:        server.URL,
+		Region:          "us-east-1",
+		Bucket:          "backups",
+		AccessKeyID:     "access-key",
+		SecretAccessKey: "secret-key",
+		UsePathStyle:    true,
+	}, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err = storage.Put(ctx, "default/test/backup.dump", strings.NewReader("dump of test")); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if _, isExists := standIn.objects["/backups/default/test/backup.dump"]; !isExists {
+		t.Fatalf("object should be stored in bucket by path, given %v", standIn.objects)
+	}
+
+	body, err := storage.Get(ctx, "default/test/backup.dump")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	dump, _ := io.ReadAll(body)
+	body.Close()
+	if string(dump) != "dump of test" {
+		t.Errorf("wrong dump: %q", dump)
+	}
+
+	if err = storage.Delete(ctx, "default/test/backup.dump"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err = storage.Get(ctx, "default/test/backup.dump"); err == nil {
+		t.Errorf("deleted dump shouldn't be found")
+	}
+}
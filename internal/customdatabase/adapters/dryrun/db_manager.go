@@ -0,0 +1,373 @@
+package dryrun
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/lib/pq"
+
+	"k8s.io/custom-database/internal/customdatabase"
+	"k8s.io/custom-database/internal/customdatabase/usecases"
+)
+
+// redactedPassword replaces passwords in planned statements
+const redactedPassword = "'***'"
+
+// DbManager records changes of databases and roles in plan instead of executing them. Reads are executed by wrapped
+// manager, so actions are planned against the real state of server. Databases, which would be created, don't exist
+// on server, reads of them return state of just created database.
+type DbManager struct {
+	usecases.DatabaseManager
+
+	plan *Plan
+
+	mu sync.Mutex
+	// planned limits of databases, which would be created, by name of database
+	planned map[string]customdatabase.Limits
+}
+
+func NewDbManager(databaseManager usecases.DatabaseManager, plan *Plan) *DbManager {
+	return &DbManager{
+		DatabaseManager: databaseManager,
+		plan:            plan,
+		planned:         make(map[string]customdatabase.Limits),
+	}
+}
+
+func (m *DbManager) CreateDatabase(
+	ctx context.Context, database string, options customdatabase.DatabaseOptions,
+	marker customdatabase.OwnershipMarker,
+) error {
+	if exists, err := m.databaseExists(ctx, database); err != nil || exists {
+		if exists {
+			return customdatabase.ErrDatabaseAlreadyExists
+		}
+		return err
+	}
+
+	m.record(ctx, "CREATE DATABASE "+pq.QuoteIdentifier(database)+databaseOptions(options))
+	m.record(ctx, "COMMENT ON DATABASE "+pq.QuoteIdentifier(database)+" IS "+pq.QuoteLiteral(marker.String()))
+	m.planDatabase(database)
+
+	return nil
+}
+
+func (m *DbManager) CloneDatabase(
+	ctx context.Context, database string, source customdatabase.Database, options customdatabase.DatabaseOptions,
+	marker customdatabase.OwnershipMarker,
+) error {
+	if exists, err := m.databaseExists(ctx, database); err != nil || exists {
+		if exists {
+			return customdatabase.ErrDatabaseAlreadyExists
+		}
+		return err
+	}
+
+	options.Template = source.Name
+	m.record(ctx, "CREATE DATABASE "+pq.QuoteIdentifier(database)+databaseOptions(options))
+	m.record(ctx, "COMMENT ON DATABASE "+pq.QuoteIdentifier(database)+" IS "+pq.QuoteLiteral(marker.String()))
+	m.planDatabase(database)
+
+	return nil
+}
+
+// DropDatabase checks ownership like the real drop, so plan shows only drops, that would happen
+func (m *DbManager) DropDatabase(ctx context.Context, database string, owner customdatabase.OwnershipMarker) error {
+	ownership, err := m.GetDatabaseOwnership(ctx, database)
+	if err != nil {
+		return err
+	}
+	if err = ownership.CheckDrop("database "+database, owner); err != nil {
+		return err
+	}
+
+	m.record(ctx, "DROP DATABASE IF EXISTS "+pq.QuoteIdentifier(database))
+
+	return nil
+}
+
+func (m *DbManager) CreateUser(
+	ctx context.Context, userName, password string, marker customdatabase.OwnershipMarker,
+) error {
+	ownership, err := m.GetUserOwnership(ctx, userName)
+	if err != nil {
+		return err
+	}
+	if ownership.Exists {
+		return customdatabase.ErrUserAlreadyExists
+	}
+
+	m.record(ctx, "CREATE ROLE "+pq.QuoteIdentifier(userName)+" WITH LOGIN ENCRYPTED PASSWORD "+redactedPassword)
+	m.record(ctx, "COMMENT ON ROLE "+pq.QuoteIdentifier(userName)+" IS "+pq.QuoteLiteral(marker.String()))
+
+	return nil
+}
+
+func (m *DbManager) ChangeUserPassword(ctx context.Context, userName, password string) error {
+	m.record(ctx, "ALTER ROLE "+pq.QuoteIdentifier(userName)+" WITH ENCRYPTED PASSWORD "+redactedPassword)
+
+	return nil
+}
+
+// DropUser checks ownership like the real drop, so plan shows only drops, that would happen
+func (m *DbManager) DropUser(ctx context.Context, userName string, owner customdatabase.OwnershipMarker) error {
+	ownership, err := m.GetUserOwnership(ctx, userName)
+	if err != nil {
+		return err
+	}
+	if err = ownership.CheckDrop("role "+userName, owner); err != nil {
+		return err
+	}
+
+	m.record(ctx, "DROP ROLE IF EXISTS "+pq.QuoteIdentifier(userName))
+
+	return nil
+}
+
+func (m *DbManager) GrantUserToDatabase(ctx context.Context, userName, database string) error {
+	m.record(ctx, "GRANT CONNECT ON DATABASE "+pq.QuoteIdentifier(database)+" TO "+pq.QuoteIdentifier(userName))
+
+	return nil
+}
+
+// SetDatabaseLimits plans only changed limits, because limits are applied on every sync
+func (m *DbManager) SetDatabaseLimits(ctx context.Context, database string, limits customdatabase.Limits) error {
+	current, err := m.GetDatabaseLimits(ctx, database)
+	if err != nil {
+		return err
+	}
+	if current == limits {
+		return nil
+	}
+
+	name := pq.QuoteIdentifier(database)
+	if current.ConnectionLimit != limits.ConnectionLimit {
+		m.record(ctx, "ALTER DATABASE "+name+" CONNECTION LIMIT "+strconv.Itoa(limits.ConnectionLimit))
+	}
+	for _, setting := range []struct {
+		name            string
+		current, wanted string
+	}{
+		{"statement_timeout", current.StatementTimeout, limits.StatementTimeout},
+		{
+			"idle_in_transaction_session_timeout",
+			current.IdleInTransactionSessionTimeout, limits.IdleInTransactionSessionTimeout,
+		},
+		{"work_mem", current.WorkMem, limits.WorkMem},
+	} {
+		switch {
+		case setting.current == setting.wanted:
+		case setting.wanted == "":
+			m.record(ctx, "ALTER DATABASE "+name+" RESET "+setting.name)
+		default:
+			m.record(ctx, "ALTER DATABASE "+name+" SET "+setting.name+" = "+pq.QuoteLiteral(setting.wanted))
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.planned[database]; ok {
+		m.planned[database] = limits
+	}
+
+	return nil
+}
+
+func (m *DbManager) GetDatabaseLimits(ctx context.Context, database string) (customdatabase.Limits, error) {
+	if limits, ok := m.plannedDatabase(database); ok {
+		return limits, nil
+	}
+
+	return m.DatabaseManager.GetDatabaseLimits(ctx, database)
+}
+
+func (m *DbManager) GetDatabaseSize(ctx context.Context, database string) (int64, error) {
+	if _, ok := m.plannedDatabase(database); ok {
+		return 0, nil
+	}
+
+	return m.DatabaseManager.GetDatabaseSize(ctx, database)
+}
+
+func (m *DbManager) SetDatabaseReadOnly(ctx context.Context, database string, readOnly bool) error {
+	if readOnly {
+		m.record(ctx, "ALTER DATABASE "+pq.QuoteIdentifier(database)+" SET default_transaction_read_only = on")
+	} else {
+		m.record(ctx, "ALTER DATABASE "+pq.QuoteIdentifier(database)+" RESET default_transaction_read_only")
+	}
+
+	return nil
+}
+
+func (m *DbManager) HardenDatabase(ctx context.Context, database, owner string) error {
+	m.record(ctx, fmt.Sprintf("harden privileges of database %s for owner %s",
+		pq.QuoteIdentifier(database), pq.QuoteIdentifier(owner),
+	))
+
+	return nil
+}
+
+func (m *DbManager) AuditDatabase(ctx context.Context, database, owner string) ([]string, error) {
+	if _, ok := m.plannedDatabase(database); ok {
+		return nil, nil
+	}
+
+	return m.DatabaseManager.AuditDatabase(ctx, database, owner)
+}
+
+func (m *DbManager) DetectDrift(ctx context.Context, database customdatabase.Database) ([]string, error) {
+	if _, ok := m.plannedDatabase(database.Name); ok {
+		return nil, nil
+	}
+
+	return m.DatabaseManager.DetectDrift(ctx, database)
+}
+
+func (m *DbManager) RepairDrift(ctx context.Context, database customdatabase.Database) error {
+	m.record(ctx, fmt.Sprintf("repair drift of database %s and role %s",
+		pq.QuoteIdentifier(database.Name), pq.QuoteIdentifier(database.User),
+	))
+
+	return nil
+}
+
+func (m *DbManager) ApplyUserPrivileges(
+	ctx context.Context, database customdatabase.Database, user customdatabase.DatabaseUser,
+) error {
+	m.record(ctx, fmt.Sprintf("apply privileges of profile %s to role %s in database %s",
+		user.Privileges.Profile, pq.QuoteIdentifier(user.Name), pq.QuoteIdentifier(database.Name),
+	))
+
+	return nil
+}
+
+func (m *DbManager) RevokeUserPrivileges(
+	ctx context.Context, database customdatabase.Database, user customdatabase.DatabaseUser,
+) error {
+	m.record(ctx, fmt.Sprintf("revoke privileges of role %s in database %s",
+		pq.QuoteIdentifier(user.Name), pq.QuoteIdentifier(database.Name),
+	))
+
+	return nil
+}
+
+func (m *DbManager) GetDatabaseOwnership(ctx context.Context, database string) (customdatabase.Ownership, error) {
+	if _, ok := m.plannedDatabase(database); ok {
+		return customdatabase.Ownership{Exists: true}, nil
+	}
+
+	return m.DatabaseManager.GetDatabaseOwnership(ctx, database)
+}
+
+func (m *DbManager) SetDatabaseOwnership(
+	ctx context.Context, database string, marker customdatabase.OwnershipMarker,
+) error {
+	m.record(ctx, "COMMENT ON DATABASE "+pq.QuoteIdentifier(database)+" IS "+pq.QuoteLiteral(marker.String()))
+
+	return nil
+}
+
+func (m *DbManager) SetUserOwnership(ctx context.Context, userName string, marker customdatabase.OwnershipMarker) error {
+	m.record(ctx, "COMMENT ON ROLE "+pq.QuoteIdentifier(userName)+" IS "+pq.QuoteLiteral(marker.String()))
+
+	return nil
+}
+
+func (m *DbManager) GetDatabaseActivity(
+	ctx context.Context, database string,
+) (customdatabase.DatabaseActivity, error) {
+	if _, ok := m.plannedDatabase(database); ok {
+		return customdatabase.DatabaseActivity{}, nil
+	}
+
+	return m.DatabaseManager.GetDatabaseActivity(ctx, database)
+}
+
+func (m *DbManager) RevokeDatabaseConnect(ctx context.Context, database string) error {
+	m.record(ctx,
+		"REVOKE CONNECT ON DATABASE "+pq.QuoteIdentifier(database)+" FROM all roles and terminate sessions",
+	)
+
+	return nil
+}
+
+func (m *DbManager) TerminateDatabaseSessions(ctx context.Context, database string) error {
+	m.record(ctx, "terminate sessions of database "+pq.QuoteIdentifier(database))
+
+	return nil
+}
+
+func (m *DbManager) RunScript(
+	ctx context.Context, database customdatabase.Database, script customdatabase.InitScript,
+) (string, error) {
+	m.record(ctx, fmt.Sprintf("run script %s of %d bytes in database %s as %s",
+		script.Name, len(script.Content), pq.QuoteIdentifier(database.Name), pq.QuoteIdentifier(database.User),
+	))
+
+	return script.Checksum, nil
+}
+
+// Migrate plans migration to target version, version of database isn't changed, so it's planned on every sync
+func (m *DbManager) Migrate(
+	ctx context.Context, database customdatabase.Database, migrations []customdatabase.Migration, target int64,
+) (int64, error) {
+	m.record(ctx, fmt.Sprintf("migrate database %s to version %d", pq.QuoteIdentifier(database.Name), target))
+
+	return target, nil
+}
+
+func (m *DbManager) record(ctx context.Context, statement string) {
+	m.plan.Record(ctx, TargetPostgres, statement)
+}
+
+func (m *DbManager) databaseExists(ctx context.Context, database string) (bool, error) {
+	ownership, err := m.GetDatabaseOwnership(ctx, database)
+
+	return ownership.Exists, err
+}
+
+// planDatabase remembers database, which would be created, with limits of just created database
+func (m *DbManager) planDatabase(database string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.planned[database] = customdatabase.Limits{ConnectionLimit: customdatabase.NoConnectionLimit}
+}
+
+func (m *DbManager) plannedDatabase(database string) (customdatabase.Limits, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	limits, ok := m.planned[database]
+
+	return limits, ok
+}
+
+// databaseOptions returns options of CREATE DATABASE statement
+func databaseOptions(options customdatabase.DatabaseOptions) string {
+	var query string
+
+	for _, option := range []struct {
+		name, value string
+		isLiteral   bool
+	}{
+		{"OWNER", options.Owner, false},
+		{"TEMPLATE", options.Template, false},
+		{"ENCODING", options.Encoding, true},
+		{"LC_COLLATE", options.LcCollate, true},
+		{"LC_CTYPE", options.LcCtype, true},
+		{"TABLESPACE", options.Tablespace, false},
+	} {
+		switch {
+		case option.value == "":
+		case option.isLiteral:
+			query += " " + option.name + " " + pq.QuoteLiteral(option.value)
+		default:
+			query += " " + option.name + " " + pq.QuoteIdentifier(option.value)
+		}
+	}
+
+	return query
+}
@@ -0,0 +1,120 @@
+package dryrun
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"k8s.io/custom-database/internal/customdatabase"
+	"k8s.io/custom-database/internal/customdatabase/adapters/fake"
+)
+
+func TestPlanNewDatabase(t *testing.T) {
+	ctx := customdatabase.ContextWithResource(context.Background(), "CustomDatabase", "default/test")
+	databaseManager := fake.NewDbManager()
+	plan := NewPlan()
+	m := NewDbManager(databaseManager, plan)
+	marker := newOwnershipMarker("default", "test")
+
+	if err := m.CreateUser(ctx, "test", "secret", marker); err != nil {
+		t.Fatalf("user should be planned: %v", err)
+	}
+	if err := m.CreateDatabase(ctx, "test", customdatabase.DatabaseOptions{Owner: "test"}, marker); err != nil {
+		t.Fatalf("database should be planned: %v", err)
+	}
+	if err := m.SetDatabaseLimits(ctx, "test", customdatabase.Limits{ConnectionLimit: 10}); err != nil {
+		t.Fatalf("limits should be planned: %v", err)
+	}
+	if limits, err := m.GetDatabaseLimits(ctx, "test"); err != nil || limits.ConnectionLimit != 10 {
+		t.Errorf("planned database should have planned limits, got %+v, %v", limits, err)
+	}
+
+	if len(databaseManager.Databases) != 0 || len(databaseManager.Users) != 0 {
+		t.Errorf("nothing should be created in dry-run mode")
+	}
+
+	actions := plan.Actions()["CustomDatabase default/test"]
+	expected := []string{
+		`CREATE ROLE "test" WITH LOGIN ENCRYPTED PASSWORD '***'`,
+		`COMMENT ON ROLE "test" IS '` + marker.String() + `'`,
+		`CREATE DATABASE "test" OWNER "test"`,
+		`COMMENT ON DATABASE "test" IS '` + marker.String() + `'`,
+		`ALTER DATABASE "test" CONNECTION LIMIT 10`,
+	}
+	if len(actions) != len(expected) {
+		t.Fatalf("expected %d planned actions, got %+v", len(expected), actions)
+	}
+	for i, statement := range expected {
+		if actions[i].Target != TargetPostgres || actions[i].Statement != statement {
+			t.Errorf("action %d: expected %q, got %+v", i, statement, actions[i])
+		}
+	}
+}
+
+func TestPlanFollowsStateOfServer(t *testing.T) {
+	ctx := context.Background()
+	databaseManager := fake.NewDbManager()
+	plan := NewPlan()
+	m := NewDbManager(databaseManager, plan)
+	marker := newOwnershipMarker("default", "test")
+	_ = databaseManager.CreateDatabase(ctx, "test", customdatabase.DatabaseOptions{}, marker)
+
+	err := m.CreateDatabase(ctx, "test", customdatabase.DatabaseOptions{}, marker)
+	if err != customdatabase.ErrDatabaseAlreadyExists {
+		t.Errorf("existing database should be reported, got %v", err)
+	}
+
+	if err = m.DropDatabase(ctx, "test", newOwnershipMarker("other", "test")); !errors.Is(err, customdatabase.ErrNotOwned) {
+		t.Errorf("drop of database of another resource shouldn't be planned, got %v", err)
+	}
+
+	if err = m.DropDatabase(ctx, "test", marker); err != nil {
+		t.Fatalf("drop should be planned: %v", err)
+	}
+	if _, isExists := databaseManager.Databases["test"]; !isExists {
+		t.Errorf("database shouldn't be dropped in dry-run mode")
+	}
+
+	// the same action of the next sync is counted
+	_ = m.DropDatabase(ctx, "test", marker)
+	actions := plan.Actions()[unknownResource]
+	if len(actions) != 1 || actions[0].Count != 2 {
+		t.Errorf("expected one drop planned twice, got %+v", actions)
+	}
+}
+
+func TestDryRunTransport(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.Method+" "+r.URL.RawQuery)
+	}))
+	defer server.Close()
+
+	plan := NewPlan()
+	client := &http.Client{Transport: WrapTransport(plan)(http.DefaultTransport)}
+	ctx := customdatabase.ContextWithResource(context.Background(), "CustomDatabase", "default/test")
+
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		req, _ := http.NewRequestWithContext(ctx, method, server.URL+"/api/v1/namespaces/default/secrets", nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	if len(queries) != 2 || queries[0] != "GET " || queries[1] != "POST dryRun=All" {
+		t.Errorf("only writes should be sent with dryRun, got %v", queries)
+	}
+
+	actions := plan.Actions()["CustomDatabase default/test"]
+	if len(actions) != 1 || actions[0].Statement != "POST /api/v1/namespaces/default/secrets" {
+		t.Errorf("write should be planned, got %+v", actions)
+	}
+}
+
+func newOwnershipMarker(namespace, name string) customdatabase.OwnershipMarker {
+	return customdatabase.OwnershipMarker{Kind: customdatabase.OwnerKindCustomDatabase, Namespace: namespace, Name: name}
+}
@@ -0,0 +1,124 @@
+package dryrun
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+
+	"k8s.io/custom-database/internal/customdatabase"
+)
+
+const (
+	// TargetPostgres actions on databases and roles
+	TargetPostgres = "postgres"
+	// TargetKubernetes writes to Kubernetes API
+	TargetKubernetes = "kubernetes"
+
+	// unknownResource groups actions, which aren't made by sync of resource, e.g. events
+	unknownResource = "-"
+	// maxActionsPerResource the oldest actions of resource are forgotten, so plan doesn't grow forever
+	maxActionsPerResource = 100
+)
+
+// Action is planned, but not executed in dry-run mode
+type Action struct {
+	Target    string `json:"target"`
+	Statement string `json:"statement"`
+	// Count how many times action was planned, e.g. every sync plans the same actions again, because nothing is
+	// changed
+	Count          int       `json:"count"`
+	FirstPlannedAt time.Time `json:"firstPlannedAt"`
+	LastPlannedAt  time.Time `json:"lastPlannedAt"`
+}
+
+// Plan collects actions of dry-run mode by resource, which made them
+type Plan struct {
+	clock clock.PassiveClock
+
+	mu      sync.Mutex
+	actions map[string][]*Action
+}
+
+func NewPlan() *Plan {
+	return &Plan{clock: clock.RealClock{}, actions: make(map[string][]*Action)}
+}
+
+// Record adds action to plan of resource from context. Action is logged, when it's planned the first time.
+func (p *Plan) Record(ctx context.Context, target, statement string) {
+	resource := customdatabase.ResourceFromContext(ctx)
+	if resource == "" {
+		resource = unknownResource
+	}
+	now := p.clock.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	logger := klog.FromContext(ctx)
+	for _, action := range p.actions[resource] {
+		if action.Target == target && action.Statement == statement {
+			action.Count++
+			action.LastPlannedAt = now
+			logger.V(4).Info("Dry run, action is planned again", "resource", resource, "target", target,
+				"statement", statement,
+			)
+			return
+		}
+	}
+
+	logger.Info("Dry run, action is planned", "resource", resource, "target", target, "statement", statement)
+	actions := append(p.actions[resource], &Action{
+		Target: target, Statement: statement, Count: 1, FirstPlannedAt: now, LastPlannedAt: now,
+	})
+	if len(actions) > maxActionsPerResource {
+		actions = actions[len(actions)-maxActionsPerResource:]
+	}
+	p.actions[resource] = actions
+}
+
+// Actions returns copy of planned actions by resource
+func (p *Plan) Actions() map[string][]Action {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	result := make(map[string][]Action, len(p.actions))
+	for resource, actions := range p.actions {
+		copied := make([]Action, 0, len(actions))
+		for _, action := range actions {
+			copied = append(copied, *action)
+		}
+		result[resource] = copied
+	}
+
+	return result
+}
+
+// ServeHTTP writes planned actions as JSON. Query parameter resource filters them, e.g.
+// ?resource=CustomDatabase+default/test
+func (p *Plan) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	actions := p.Actions()
+
+	type resourcePlan struct {
+		Resource string   `json:"resource"`
+		Actions  []Action `json:"actions"`
+	}
+	plans := make([]resourcePlan, 0, len(actions))
+	for resource, resourceActions := range actions {
+		if filter := r.URL.Query().Get("resource"); filter != "" && filter != resource {
+			continue
+		}
+		plans = append(plans, resourcePlan{Resource: resource, Actions: resourceActions})
+	}
+	sort.Slice(plans, func(i, j int) bool { return plans[i].Resource < plans[j].Resource })
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(plans)
+}
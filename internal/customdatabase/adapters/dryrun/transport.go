@@ -0,0 +1,40 @@
+package dryrun
+
+import (
+	"net/http"
+
+	"k8s.io/client-go/transport"
+)
+
+// WrapTransport returns wrapper of transport of Kubernetes clients, which sends all writes with dryRun=All and records
+// them in plan. API server validates writes and runs admission, but doesn't persist anything.
+//
+// https://kubernetes.io/docs/reference/using-api/api-concepts/#dry-run
+func WrapTransport(plan *Plan) transport.WrapperFunc {
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return &dryRunRoundTripper{delegate: rt, plan: plan}
+	}
+}
+
+type dryRunRoundTripper struct {
+	delegate http.RoundTripper
+	plan     *Plan
+}
+
+func (rt *dryRunRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch req.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return rt.delegate.RoundTrip(req)
+	}
+
+	// request mustn't be changed by round tripper, so it's cloned together with URL
+	req = req.Clone(req.Context())
+	query := req.URL.Query()
+	query.Set("dryRun", "All")
+	req.URL.RawQuery = query.Encode()
+
+	rt.plan.Record(req.Context(), TargetKubernetes, req.Method+" "+req.URL.Path)
+
+	return rt.delegate.RoundTrip(req)
+}
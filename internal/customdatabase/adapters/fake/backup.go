@@ -0,0 +1,111 @@
+package fake
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"k8s.io/custom-database/internal/customdatabase"
+)
+
+// Dumper fake implementation for tests, dump contains only name of database
+type Dumper struct {
+	// Err fails every dump, when it's set
+	Err error
+}
+
+func NewDumper() *Dumper {
+	return &Dumper{}
+}
+
+func (d *Dumper) DumpDatabase(
+	_ context.Context, _ customdatabase.Host, database customdatabase.Database, dump io.Writer,
+) error {
+	if d.Err != nil {
+		return d.Err
+	}
+
+	_, err := fmt.Fprintf(dump, "dump of %s", database.Name)
+	return err
+}
+
+// Restorer fake implementation for tests, it keeps restored dumps by name of database
+type Restorer struct {
+	Restored map[string][]byte
+	// Err fails every restore, when it's set
+	Err error
+
+	mu sync.Mutex
+}
+
+func NewRestorer() *Restorer {
+	return &Restorer{Restored: make(map[string][]byte)}
+}
+
+func (r *Restorer) RestoreDatabase(
+	_ context.Context, _ customdatabase.Host, database customdatabase.Database, dump io.Reader, _ bool,
+) error {
+	if r.Err != nil {
+		return r.Err
+	}
+
+	content, err := io.ReadAll(dump)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Restored[database.Name] = content
+
+	return nil
+}
+
+// BackupStorage fake in-memory implementation for tests
+type BackupStorage struct {
+	Dumps map[string][]byte
+
+	mu sync.Mutex
+}
+
+func NewBackupStorage() *BackupStorage {
+	return &BackupStorage{Dumps: make(map[string][]byte)}
+}
+
+func (s *BackupStorage) Put(_ context.Context, location string, dump io.Reader) error {
+	content, err := io.ReadAll(dump)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Dumps[location] = content
+
+	return nil
+}
+
+func (s *BackupStorage) Get(_ context.Context, location string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	content, isExists := s.Dumps[location]
+	if !isExists {
+		return nil, fmt.Errorf("dump %s doesn't exist", location)
+	}
+
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (s *BackupStorage) Delete(_ context.Context, location string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.Dumps, location)
+
+	return nil
+}
@@ -3,6 +3,7 @@ package fake
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 
 	"k8s.io/custom-database/internal/customdatabase"
@@ -11,22 +12,71 @@ import (
 // DbManager fake implementation for tests
 type DbManager struct {
 	Users         map[string]string
-	Databases     map[string]struct{}
+	Databases     map[string]customdatabase.DatabaseOptions
 	User2Database map[string][]string
+	Limits        map[string]customdatabase.Limits
+	Sizes         map[string]int64
+	ReadOnly      map[string]bool
+	// SecurityDrift findings of audit for database, they are cleared by hardening
+	SecurityDrift map[string][]string
+	// UserPrivileges privileges of additional users by user name
+	UserPrivileges map[string]customdatabase.Privileges
+	// ClonedFrom name of source database by name of cloned database
+	ClonedFrom map[string]string
+	// ReassignedFrom owner of source database, whose objects were transferred, by name of cloned database
+	ReassignedFrom map[string]string
+	// Scripts applied scripts by name of database
+	Scripts map[string][]string
+	// AppliedScripts checksums of applied scripts by their names by name of database, like bookkeeping table
+	AppliedScripts map[string]map[string]string
+	// ScriptErr fails every script, when it's set
+	ScriptErr error
+	// MigrationVersions current version of migrations by name of database
+	MigrationVersions map[string]int64
+	// Activity counters of pg_stat_database by name of database
+	Activity map[string]customdatabase.DatabaseActivity
+	// ConnectRevoked databases, which CONNECT privilege was revoked, it's granted again by GrantUserToDatabase
+	ConnectRevoked map[string]bool
+	// SessionsTerminated databases, which sessions were terminated
+	SessionsTerminated map[string]bool
+	// DatabaseOwnership ownership markers by name of database
+	DatabaseOwnership map[string]customdatabase.OwnershipMarker
+	// UserOwnership ownership markers by name of user
+	UserOwnership map[string]customdatabase.OwnershipMarker
+	// Drift manual changes of database, they are cleared by RepairDrift. Password of owner is compared too.
+	Drift map[string][]string
 
 	mu sync.Mutex
 }
 
 func NewDbManager() *DbManager {
 	return &DbManager{
-		Users:         make(map[string]string),
-		Databases:     make(map[string]struct{}),
-		User2Database: make(map[string][]string),
-		mu:            sync.Mutex{},
+		Users:              make(map[string]string),
+		Databases:          make(map[string]customdatabase.DatabaseOptions),
+		User2Database:      make(map[string][]string),
+		Limits:             make(map[string]customdatabase.Limits),
+		Sizes:              make(map[string]int64),
+		ReadOnly:           make(map[string]bool),
+		SecurityDrift:      make(map[string][]string),
+		UserPrivileges:     make(map[string]customdatabase.Privileges),
+		ClonedFrom:         make(map[string]string),
+		ReassignedFrom:     make(map[string]string),
+		Scripts:            make(map[string][]string),
+		AppliedScripts:     make(map[string]map[string]string),
+		MigrationVersions:  make(map[string]int64),
+		Activity:           make(map[string]customdatabase.DatabaseActivity),
+		ConnectRevoked:     make(map[string]bool),
+		SessionsTerminated: make(map[string]bool),
+		DatabaseOwnership:  make(map[string]customdatabase.OwnershipMarker),
+		UserOwnership:      make(map[string]customdatabase.OwnershipMarker),
+		Drift:              make(map[string][]string),
+		mu:                 sync.Mutex{},
 	}
 }
 
-func (am *DbManager) CreateUser(_ context.Context, userName, password string) error {
+func (am *DbManager) CreateUser(
+	_ context.Context, userName, password string, marker customdatabase.OwnershipMarker,
+) error {
 	am.mu.Lock()
 	defer am.mu.Unlock()
 
@@ -35,6 +85,7 @@ func (am *DbManager) CreateUser(_ context.Context, userName, password string) er
 	}
 
 	am.Users[userName] = password
+	am.UserOwnership[userName] = marker
 
 	return nil
 }
@@ -52,20 +103,26 @@ func (am *DbManager) ChangeUserPassword(_ context.Context, userName, password st
 	return nil
 }
 
-func (am *DbManager) DropUser(_ context.Context, userName string) error {
+func (am *DbManager) DropUser(_ context.Context, userName string, owner customdatabase.OwnershipMarker) error {
 	am.mu.Lock()
 	defer am.mu.Unlock()
 
 	if _, isExists := am.Users[userName]; !isExists {
 		return fmt.Errorf("user doesn't exist")
 	}
+	if err := ownership(am.UserOwnership, userName).CheckDrop("role "+userName, owner); err != nil {
+		return err
+	}
 
 	delete(am.Users, userName)
+	delete(am.UserOwnership, userName)
 
 	return nil
 }
 
-func (am *DbManager) CreateDatabase(_ context.Context, database string) error {
+func (am *DbManager) CreateDatabase(
+	_ context.Context, database string, options customdatabase.DatabaseOptions, marker customdatabase.OwnershipMarker,
+) error {
 	am.mu.Lock()
 	defer am.mu.Unlock()
 
@@ -73,20 +130,58 @@ func (am *DbManager) CreateDatabase(_ context.Context, database string) error {
 		return customdatabase.ErrDatabaseAlreadyExists
 	}
 
-	am.Databases[database] = struct{}{}
+	am.Databases[database] = options
+	am.DatabaseOwnership[database] = marker
 
 	return nil
 }
 
-func (am *DbManager) DropDatabase(_ context.Context, database string) error {
+func (am *DbManager) CloneDatabase(
+	_ context.Context, database string, source customdatabase.Database, options customdatabase.DatabaseOptions,
+	marker customdatabase.OwnershipMarker,
+) error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	if _, isExists := am.Databases[database]; isExists {
+		am.ReassignedFrom[database] = source.User
+		return customdatabase.ErrDatabaseAlreadyExists
+	}
+	if _, isExists := am.Databases[source.Name]; !isExists {
+		return fmt.Errorf("source database doesn't exist")
+	}
+
+	options.Template = source.Name
+	am.Databases[database] = options
+	am.ClonedFrom[database] = source.Name
+	am.ReassignedFrom[database] = source.User
+	am.DatabaseOwnership[database] = marker
+
+	return nil
+}
+
+func (am *DbManager) DropDatabase(_ context.Context, database string, owner customdatabase.OwnershipMarker) error {
 	am.mu.Lock()
 	defer am.mu.Unlock()
 
 	if _, isExists := am.Databases[database]; !isExists {
 		return fmt.Errorf("database doesn't exist")
 	}
+	if err := ownership(am.DatabaseOwnership, database).CheckDrop("database "+database, owner); err != nil {
+		return err
+	}
 
 	delete(am.Databases, database)
+	delete(am.Limits, database)
+	delete(am.Sizes, database)
+	delete(am.ReadOnly, database)
+	delete(am.SecurityDrift, database)
+	delete(am.ClonedFrom, database)
+	delete(am.ReassignedFrom, database)
+	delete(am.Activity, database)
+	delete(am.ConnectRevoked, database)
+	delete(am.DatabaseOwnership, database)
+	delete(am.Drift, database)
 
 	return nil
 }
@@ -96,5 +191,351 @@ func (am *DbManager) GrantUserToDatabase(_ context.Context, userName, database s
 	defer am.mu.Unlock()
 
 	am.User2Database[userName] = append(am.User2Database[userName], database)
+	delete(am.ConnectRevoked, database)
+	return nil
+}
+
+func (am *DbManager) SetDatabaseLimits(_ context.Context, database string, limits customdatabase.Limits) error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	if _, isExists := am.Databases[database]; !isExists {
+		return fmt.Errorf("database doesn't exist")
+	}
+
+	am.Limits[database] = limits
+
+	return nil
+}
+
+func (am *DbManager) GetDatabaseLimits(_ context.Context, database string) (customdatabase.Limits, error) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	if _, isExists := am.Databases[database]; !isExists {
+		return customdatabase.Limits{}, fmt.Errorf("database doesn't exist")
+	}
+
+	limits, isExists := am.Limits[database]
+	if !isExists {
+		return customdatabase.Limits{ConnectionLimit: customdatabase.NoConnectionLimit}, nil
+	}
+
+	return limits, nil
+}
+
+func (am *DbManager) GetDatabaseSize(_ context.Context, database string) (int64, error) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	if _, isExists := am.Databases[database]; !isExists {
+		return 0, fmt.Errorf("database doesn't exist")
+	}
+
+	return am.Sizes[database], nil
+}
+
+func (am *DbManager) SetDatabaseReadOnly(_ context.Context, database string, readOnly bool) error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	if _, isExists := am.Databases[database]; !isExists {
+		return fmt.Errorf("database doesn't exist")
+	}
+
+	am.ReadOnly[database] = readOnly
+
+	return nil
+}
+
+func (am *DbManager) HardenDatabase(_ context.Context, database, _ string) error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	if _, isExists := am.Databases[database]; !isExists {
+		return fmt.Errorf("database doesn't exist")
+	}
+
+	delete(am.SecurityDrift, database)
+
+	return nil
+}
+
+func (am *DbManager) AuditDatabase(_ context.Context, database, _ string) ([]string, error) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	if _, isExists := am.Databases[database]; !isExists {
+		return nil, fmt.Errorf("database doesn't exist")
+	}
+
+	return am.SecurityDrift[database], nil
+}
+
+func (am *DbManager) DetectDrift(_ context.Context, database customdatabase.Database) ([]string, error) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	if _, isExists := am.Databases[database.Name]; !isExists {
+		return nil, nil
+	}
+
+	findings := append([]string(nil), am.Drift[database.Name]...)
+	limits, isExists := am.Limits[database.Name]
+	if !isExists {
+		limits.ConnectionLimit = customdatabase.NoConnectionLimit
+	}
+	if limits.ConnectionLimit != database.Limits.ConnectionLimit {
+		findings = append(findings, fmt.Sprintf("connection limit is %d instead of %d",
+			limits.ConnectionLimit, database.Limits.ConnectionLimit,
+		))
+	}
+	if password, isExists := am.Users[database.User]; !isExists {
+		findings = append(findings, fmt.Sprintf("role %s doesn't exist", database.User))
+	} else if password != database.Password {
+		findings = append(findings, fmt.Sprintf("password of role %s differs from Secret", database.User))
+	}
+	if am.ConnectRevoked[database.Name] {
+		findings = append(findings, fmt.Sprintf("role %s has no CONNECT privilege on database", database.User))
+	}
+
+	return findings, nil
+}
+
+func (am *DbManager) RepairDrift(_ context.Context, database customdatabase.Database) error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	if _, isExists := am.Users[database.User]; !isExists {
+		return fmt.Errorf("user doesn't exist")
+	}
+
+	am.Users[database.User] = database.Password
+	delete(am.Drift, database.Name)
+	delete(am.ConnectRevoked, database.Name)
+
 	return nil
 }
+
+func (am *DbManager) ApplyUserPrivileges(
+	_ context.Context, database customdatabase.Database, user customdatabase.DatabaseUser,
+) error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	if _, isExists := am.Databases[database.Name]; !isExists {
+		return fmt.Errorf("database doesn't exist")
+	}
+	if _, isExists := am.Users[user.Name]; !isExists {
+		return fmt.Errorf("user doesn't exist")
+	}
+
+	am.UserPrivileges[user.Name] = user.Privileges
+	for _, grantedDB := range am.User2Database[user.Name] {
+		if grantedDB == database.Name {
+			return nil
+		}
+	}
+	am.User2Database[user.Name] = append(am.User2Database[user.Name], database.Name)
+
+	return nil
+}
+
+func (am *DbManager) RevokeUserPrivileges(
+	_ context.Context, database customdatabase.Database, user customdatabase.DatabaseUser,
+) error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	if _, isExists := am.Databases[database.Name]; !isExists {
+		return fmt.Errorf("database doesn't exist")
+	}
+
+	delete(am.UserPrivileges, user.Name)
+	delete(am.User2Database, user.Name)
+
+	return nil
+}
+
+func (am *DbManager) RunScript(
+	_ context.Context, database customdatabase.Database, script customdatabase.InitScript,
+) (string, error) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	if _, isExists := am.Databases[database.Name]; !isExists {
+		return "", fmt.Errorf("database doesn't exist")
+	}
+	if checksum, isApplied := am.AppliedScripts[database.Name][script.Name]; isApplied {
+		return checksum, nil
+	}
+	if am.ScriptErr != nil {
+		return "", am.ScriptErr
+	}
+
+	am.Scripts[database.Name] = append(am.Scripts[database.Name], script.Content)
+	if am.AppliedScripts[database.Name] == nil {
+		am.AppliedScripts[database.Name] = make(map[string]string)
+	}
+	am.AppliedScripts[database.Name][script.Name] = script.Checksum
+
+	return script.Checksum, nil
+}
+
+func (am *DbManager) Migrate(
+	_ context.Context, database customdatabase.Database, migrations []customdatabase.Migration, target int64,
+) (int64, error) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	current := am.MigrationVersions[database.Name]
+	steps, err := customdatabase.PlanMigrations(migrations, current, target)
+	if err != nil {
+		return current, err
+	}
+
+	for _, step := range steps {
+		if am.ScriptErr != nil {
+			return current, &customdatabase.MigrationError{Version: step.Version, Down: step.Down, Err: am.ScriptErr}
+		}
+		am.Scripts[database.Name] = append(am.Scripts[database.Name], step.Script)
+		current = step.ResultVersion
+		am.MigrationVersions[database.Name] = current
+	}
+
+	return current, nil
+}
+
+func (am *DbManager) GetDatabaseActivity(_ context.Context, database string) (customdatabase.DatabaseActivity, error) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	if _, isExists := am.Databases[database]; !isExists {
+		return customdatabase.DatabaseActivity{}, fmt.Errorf("database doesn't exist")
+	}
+
+	return am.Activity[database], nil
+}
+
+func (am *DbManager) RevokeDatabaseConnect(_ context.Context, database string) error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	if _, isExists := am.Databases[database]; !isExists {
+		return fmt.Errorf("database doesn't exist")
+	}
+
+	am.ConnectRevoked[database] = true
+	am.SessionsTerminated[database] = true
+
+	return nil
+}
+
+func (am *DbManager) TerminateDatabaseSessions(_ context.Context, database string) error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	if _, isExists := am.Databases[database]; !isExists {
+		return fmt.Errorf("database doesn't exist")
+	}
+
+	am.SessionsTerminated[database] = true
+
+	return nil
+}
+
+func (am *DbManager) GetDatabaseOwnership(_ context.Context, database string) (customdatabase.Ownership, error) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	if _, isExists := am.Databases[database]; !isExists {
+		return customdatabase.Ownership{}, nil
+	}
+
+	return ownership(am.DatabaseOwnership, database), nil
+}
+
+func (am *DbManager) GetUserOwnership(_ context.Context, userName string) (customdatabase.Ownership, error) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	if _, isExists := am.Users[userName]; !isExists {
+		return customdatabase.Ownership{}, nil
+	}
+
+	return ownership(am.UserOwnership, userName), nil
+}
+
+func (am *DbManager) ListDatabaseOwnership(_ context.Context) (map[string]customdatabase.OwnershipMarker, error) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	return copyMarkers(am.DatabaseOwnership), nil
+}
+
+func (am *DbManager) ListUserOwnership(_ context.Context) (map[string]customdatabase.OwnershipMarker, error) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	return copyMarkers(am.UserOwnership), nil
+}
+
+func (am *DbManager) ListDatabases(_ context.Context) ([]string, error) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	databases := make([]string, 0, len(am.Databases))
+	for database := range am.Databases {
+		databases = append(databases, database)
+	}
+	sort.Strings(databases)
+
+	return databases, nil
+}
+
+func (am *DbManager) SetDatabaseOwnership(
+	_ context.Context, database string, marker customdatabase.OwnershipMarker,
+) error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	if _, isExists := am.Databases[database]; !isExists {
+		return fmt.Errorf("database doesn't exist")
+	}
+
+	am.DatabaseOwnership[database] = marker
+
+	return nil
+}
+
+func (am *DbManager) SetUserOwnership(
+	_ context.Context, userName string, marker customdatabase.OwnershipMarker,
+) error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	if _, isExists := am.Users[userName]; !isExists {
+		return fmt.Errorf("user doesn't exist")
+	}
+
+	am.UserOwnership[userName] = marker
+
+	return nil
+}
+
+func ownership(markers map[string]customdatabase.OwnershipMarker, name string) customdatabase.Ownership {
+	if marker, isExists := markers[name]; isExists {
+		return customdatabase.Ownership{Exists: true, Marker: &marker}
+	}
+
+	return customdatabase.Ownership{Exists: true}
+}
+
+func copyMarkers(markers map[string]customdatabase.OwnershipMarker) map[string]customdatabase.OwnershipMarker {
+	result := make(map[string]customdatabase.OwnershipMarker, len(markers))
+	for name, marker := range markers {
+		result[name] = marker
+	}
+
+	return result
+}
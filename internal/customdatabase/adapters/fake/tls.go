@@ -0,0 +1,56 @@
+package fake
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// CertificateIssuer fake implementation for tests, it issues self-signed certificates
+type CertificateIssuer struct {
+	// Validity of issued certificates from the current time
+	Validity time.Duration
+	// Issued number of issued certificates by name of role
+	Issued map[string]int
+
+	mu sync.Mutex
+}
+
+func NewCertificateIssuer(validity time.Duration) *CertificateIssuer {
+	return &CertificateIssuer{Validity: validity, Issued: make(map[string]int)}
+}
+
+func (i *CertificateIssuer) IssueClientCertificate(userName string) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: userName},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(i.Validity),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.Issued[userName]++
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), nil
+}
@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+
+	"k8s.io/custom-database/internal/customdatabase"
+)
+
+// GetDatabaseActivity reads usage counters of database. Statistics are collected by the server, so database isn't
+// connected and reading doesn't change counters.
+func (am *DbManager) GetDatabaseActivity(ctx context.Context, database string) (customdatabase.DatabaseActivity, error) {
+	var activity customdatabase.DatabaseActivity
+	var statsReset sql.NullTime
+
+	// https://www.postgresql.org/docs/current/monitoring-stats.html#MONITORING-PG-STAT-DATABASE-VIEW
+	err := am.db.QueryRowContext(ctx,
+		"SELECT xact_commit, numbackends, stats_reset FROM pg_stat_database WHERE datname = $1", database,
+	).Scan(&activity.XactCommit, &activity.NumBackends, &statsReset)
+	if err != nil {
+		return activity, err
+	}
+	if statsReset.Valid {
+		activity.StatsReset = statsReset.Time
+	}
+
+	return activity, nil
+}
+
+// RevokeDatabaseConnect revokes CONNECT privilege on database from all roles and terminates their sessions. Superusers
+// can still connect, e.g. to dump database.
+func (am *DbManager) RevokeDatabaseConnect(ctx context.Context, database string) error {
+	// https://www.postgresql.org/docs/current/functions-info.html#FUNCTIONS-ACLITEM-FN-TABLE
+	grantees, err := queryStrings(ctx, am.db,
+		"SELECT DISTINCT r.rolname FROM pg_database d, "+
+			"aclexplode(COALESCE(d.datacl, acldefault('d', d.datdba))) a JOIN pg_roles r ON r.oid = a.grantee "+
+			"WHERE d.datname = $1 AND a.privilege_type = 'CONNECT'",
+		database,
+	)
+	if err != nil {
+		return err
+	}
+
+	queries := []string{"REVOKE CONNECT ON DATABASE " + pq.QuoteIdentifier(database) + " FROM PUBLIC"}
+	for _, grantee := range grantees {
+		queries = append(queries,
+			"REVOKE CONNECT ON DATABASE "+pq.QuoteIdentifier(database)+" FROM "+pq.QuoteIdentifier(grantee),
+		)
+	}
+	for _, query := range queries {
+		if _, err = am.db.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+
+	return am.TerminateDatabaseSessions(ctx, database)
+}
+
+// TerminateDatabaseSessions terminates all sessions of database except own session of controller, e.g. so settings
+// of database are applied to all sessions
+func (am *DbManager) TerminateDatabaseSessions(ctx context.Context, database string) error {
+	// https://www.postgresql.org/docs/current/functions-admin.html#FUNCTIONS-ADMIN-SIGNAL
+	_, err := am.db.ExecContext(ctx,
+		"SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname = $1 AND pid <> pg_backend_pid()",
+		database,
+	)
+
+	return err
+}
@@ -0,0 +1,149 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/lib/pq"
+
+	"k8s.io/custom-database/internal/customdatabase"
+)
+
+// objectInUse error code of CREATE DATABASE, when somebody is connected to its template
+const objectInUse = "55006"
+
+// reassignOwnershipQuery generates statements, which transfer objects of one role inside current database to another
+// role. REASSIGN OWNED isn't used, because it also transfers databases of role - source database would be lost.
+// Sequences owned by columns and members of extensions are transferred together with their table or extension.
+//
+// https://www.postgresql.org/docs/current/catalogs.html
+const reassignOwnershipQuery = `
+WITH source AS (SELECT oid FROM pg_roles WHERE rolname = $1)
+SELECT format('ALTER SCHEMA %I OWNER TO %I', n.nspname, $2)
+FROM pg_namespace n, source WHERE n.nspowner = source.oid
+UNION ALL
+SELECT format('ALTER %s %I.%I OWNER TO %I',
+	CASE c.relkind WHEN 'v' THEN 'VIEW' WHEN 'm' THEN 'MATERIALIZED VIEW' WHEN 'S' THEN 'SEQUENCE'
+		WHEN 'f' THEN 'FOREIGN TABLE' ELSE 'TABLE' END,
+	n.nspname, c.relname, $2)
+FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace, source
+WHERE c.relowner = source.oid AND c.relkind IN ('r', 'p', 'v', 'm', 'S', 'f')
+	AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.classid = 'pg_class'::regclass AND d.objid = c.oid
+		AND d.deptype IN ('a', 'i', 'e'))
+UNION ALL
+SELECT format('ALTER ROUTINE %s OWNER TO %I', p.oid::regprocedure, $2)
+FROM pg_proc p, source
+WHERE p.proowner = source.oid
+	AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.classid = 'pg_proc'::regclass AND d.objid = p.oid
+		AND d.deptype = 'e')
+UNION ALL
+SELECT format('ALTER %s %s OWNER TO %I', CASE t.typtype WHEN 'd' THEN 'DOMAIN' ELSE 'TYPE' END, t.oid::regtype, $2)
+FROM pg_type t, source
+WHERE t.typowner = source.oid AND t.typtype IN ('c', 'd', 'e', 'r')
+	AND (t.typtype <> 'c' OR (SELECT relkind FROM pg_class WHERE oid = t.typrelid) = 'c')
+	AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.classid = 'pg_type'::regclass AND d.objid = t.oid
+		AND d.deptype = 'e')`
+
+// CloneDatabase creates database as a copy of source database and transfers all objects of the source owner to the
+// owner of the new database. Objects are transferred in already existing database too, then ErrDatabaseAlreadyExists
+// is returned after transfer. Postgresql can't copy database, while somebody is connected to it, so connections to
+// source are blocked and terminated for the time of copying.
+func (am *DbManager) CloneDatabase(
+	ctx context.Context, database string, source customdatabase.Database, options customdatabase.DatabaseOptions,
+	marker customdatabase.OwnershipMarker,
+) error {
+	options.Template = source.Name
+
+	// https://www.postgresql.org/docs/current/manage-ag-templatedbs.html
+	err := am.CreateDatabase(ctx, database, options, marker)
+	var pgError *pq.Error
+	if errors.As(err, &pgError) && pgError.Code == objectInUse {
+		err = am.createDatabaseFromBlockedTemplate(ctx, database, options, marker)
+	}
+	if err != nil && err != customdatabase.ErrDatabaseAlreadyExists {
+		return err
+	}
+
+	// database may be already copied by previous sync, which failed before ownership was transferred. Caller doesn't
+	// clone again after success, so objects of source owner in existing database are left from unfinished clone.
+	if reassignErr := am.reassignOwnership(ctx, database, source.User, options.Owner); reassignErr != nil {
+		return reassignErr
+	}
+
+	return err
+}
+
+// createDatabaseFromBlockedTemplate terminates sessions of template and creates database, while new connections to
+// template are forbidden. Connections are allowed again, even if copying failed.
+func (am *DbManager) createDatabaseFromBlockedTemplate(
+	ctx context.Context, database string, options customdatabase.DatabaseOptions, marker customdatabase.OwnershipMarker,
+) (err error) {
+	template := pq.QuoteIdentifier(options.Template)
+
+	// https://www.postgresql.org/docs/current/sql-alterdatabase.html
+	if _, err = am.db.ExecContext(ctx, "ALTER DATABASE "+template+" WITH ALLOW_CONNECTIONS false"); err != nil {
+		return err
+	}
+	defer func() {
+		// context may be already cancelled, but template must not stay blocked
+		_, allowErr := am.db.ExecContext(
+			context.Background(), "ALTER DATABASE "+template+" WITH ALLOW_CONNECTIONS true",
+		)
+		if err == nil {
+			err = allowErr
+		}
+	}()
+
+	// https://www.postgresql.org/docs/current/functions-admin.html#FUNCTIONS-ADMIN-SIGNAL
+	_, err = am.db.ExecContext(ctx,
+		"SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname = $1 AND pid <> pg_backend_pid()",
+		options.Template,
+	)
+	if err != nil {
+		return err
+	}
+
+	return am.CreateDatabase(ctx, database, options, marker)
+}
+
+// reassignOwnership transfers objects inside database from one role to another
+func (am *DbManager) reassignOwnership(ctx context.Context, database, fromUser, toUser string) error {
+	conn, err := am.connector.Connect(ctx, database)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	queries, err := queryStrings(ctx, conn.DB(), reassignOwnershipQuery, fromUser, toUser)
+	if err != nil {
+		return err
+	}
+
+	for _, query := range queries {
+		if _, err = conn.DB().ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// queryStrings returns the first column of all rows of query
+func queryStrings(ctx context.Context, db DB, query string, args ...any) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var value string
+		if err = rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	return values, rows.Err()
+}
@@ -3,34 +3,57 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
 	"github.com/lib/pq"
 
 	"k8s.io/custom-database/internal/customdatabase"
+	commonDatabase "k8s.io/custom-database/pkg/postgres"
 )
 
 // DbManager
 //
 // We can't use statements here. Generally anything that modifies schemas doesn't support them.
 type DbManager struct {
-	db DB
+	db        DB
+	connector Connector
 }
 
 type DB interface {
 	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
 }
 
-func NewDbManager(db DB) *DbManager {
-	return &DbManager{db}
+// tenantRoleAttributes are set explicitly, so tenant role doesn't depend on defaults of server or admin role
+const tenantRoleAttributes = "NOSUPERUSER NOCREATEDB NOCREATEROLE NOINHERIT NOREPLICATION NOBYPASSRLS"
+
+// Connector opens admin connection to the concrete database, it's needed for statements about objects inside database
+type Connector interface {
+	Connect(ctx context.Context, database string) (*commonDatabase.Database, error)
+	ConnectAs(ctx context.Context, database, user, password string) (*commonDatabase.Database, error)
 }
 
-func (am *DbManager) CreateUser(ctx context.Context, userName, password string) error {
+func NewDbManager(db DB, connector Connector) *DbManager {
+	return &DbManager{db: db, connector: connector}
+}
+
+// CreateUser creates role together with its ownership marker. Both statements are sent in one query, so they are
+// executed in one implicit transaction and role never exists without marker.
+func (am *DbManager) CreateUser(
+	ctx context.Context, userName, password string, marker customdatabase.OwnershipMarker,
+) error {
 	// https://www.postgresql.org/docs/current/sql-createrole.html
 	_, err := am.db.ExecContext(
-		ctx, "CREATE ROLE "+pq.QuoteIdentifier(userName)+" WITH LOGIN ENCRYPTED PASSWORD "+pq.QuoteLiteral(password),
+		ctx, "CREATE ROLE "+pq.QuoteIdentifier(userName)+" WITH LOGIN "+tenantRoleAttributes+
+			" ENCRYPTED PASSWORD "+pq.QuoteLiteral(password)+"; "+
+			"COMMENT ON ROLE "+pq.QuoteIdentifier(userName)+" IS "+pq.QuoteLiteral(marker.String()),
 	)
 	if err != nil {
-		pgError := err.(*pq.Error)
-		if pgError.Code == "42710" {
+		var pgError *pq.Error
+		if errors.As(err, &pgError) && pgError.Code == "42710" {
 			return customdatabase.ErrUserAlreadyExists
 		}
 		return err
@@ -51,9 +74,18 @@ func (am *DbManager) ChangeUserPassword(ctx context.Context, userName, password
 	return nil
 }
 
-func (am *DbManager) DropUser(ctx context.Context, userName string) error {
+// DropUser drops role, if it belongs to owner, otherwise customdatabase.ErrNotOwned is returned
+func (am *DbManager) DropUser(ctx context.Context, userName string, owner customdatabase.OwnershipMarker) error {
+	ownership, err := am.GetUserOwnership(ctx, userName)
+	if err != nil {
+		return err
+	}
+	if err = ownership.CheckDrop("role "+userName, owner); err != nil {
+		return err
+	}
+
 	// https://www.postgresql.org/docs/current/sql-createrole.html
-	_, err := am.db.ExecContext(
+	_, err = am.db.ExecContext(
 		ctx, "DROP ROLE IF EXISTS "+pq.QuoteIdentifier(userName),
 	)
 	if err != nil {
@@ -63,38 +95,188 @@ func (am *DbManager) DropUser(ctx context.Context, userName string) error {
 	return nil
 }
 
-func (am *DbManager) CreateDatabase(ctx context.Context, database string) error {
+// CreateDatabase creates database and writes its ownership marker. CREATE DATABASE can't be executed in transaction,
+// so just created database is dropped, if marker can't be written.
+func (am *DbManager) CreateDatabase(
+	ctx context.Context, database string, options customdatabase.DatabaseOptions, marker customdatabase.OwnershipMarker,
+) error {
 	// https://www.postgresql.org/docs/current/sql-createdatabase.html
-	_, err := am.db.ExecContext(ctx, "CREATE DATABASE "+pq.QuoteIdentifier(database))
+	_, err := am.db.ExecContext(ctx, "CREATE DATABASE "+pq.QuoteIdentifier(database)+createDatabaseOptions(options))
 	if err != nil {
-		pgError := err.(*pq.Error)
-		if pgError.Code == "42P04" {
+		var pgError *pq.Error
+		if errors.As(err, &pgError) && pgError.Code == "42P04" {
 			return customdatabase.ErrDatabaseAlreadyExists
 		}
 		return err
 	}
 
+	if err = am.SetDatabaseOwnership(ctx, database, marker); err != nil {
+		_, _ = am.db.ExecContext(context.Background(), "DROP DATABASE IF EXISTS "+pq.QuoteIdentifier(database))
+		return err
+	}
+
 	return nil
 }
 
-func (am *DbManager) DropDatabase(ctx context.Context, database string) error {
+func createDatabaseOptions(options customdatabase.DatabaseOptions) string {
+	var query string
+
+	if options.Owner != "" {
+		query += " OWNER " + pq.QuoteIdentifier(options.Owner)
+	}
+	if options.Template != "" {
+		query += " TEMPLATE " + pq.QuoteIdentifier(options.Template)
+	}
+	if options.Encoding != "" {
+		query += " ENCODING " + pq.QuoteLiteral(options.Encoding)
+	}
+	if options.LcCollate != "" {
+		query += " LC_COLLATE " + pq.QuoteLiteral(options.LcCollate)
+	}
+	if options.LcCtype != "" {
+		query += " LC_CTYPE " + pq.QuoteLiteral(options.LcCtype)
+	}
+	if options.Tablespace != "" {
+		query += " TABLESPACE " + pq.QuoteIdentifier(options.Tablespace)
+	}
+
+	return query
+}
+
+// DropDatabase drops database, if it belongs to owner, otherwise customdatabase.ErrNotOwned is returned
+func (am *DbManager) DropDatabase(ctx context.Context, database string, owner customdatabase.OwnershipMarker) error {
+	ownership, err := am.GetDatabaseOwnership(ctx, database)
+	if err != nil {
+		return err
+	}
+	if err = ownership.CheckDrop("database "+database, owner); err != nil {
+		return err
+	}
+
 	// https://www.postgresql.org/docs/current/sql-createdatabase.html
-	res, err := am.db.ExecContext(ctx, "DROP DATABASE IF EXISTS "+pq.QuoteIdentifier(database))
+	_, err = am.db.ExecContext(ctx, "DROP DATABASE IF EXISTS "+pq.QuoteIdentifier(database))
 	if err != nil {
 		return err
 	}
 
-	_ = res
 	return nil
 }
 
+// GrantUserToDatabase grants CONNECT on database to role. Owner has other privileges on database by ownership, roles
+// of DatabaseUsers and DatabaseGrants get only privileges of their spec.
 func (am *DbManager) GrantUserToDatabase(ctx context.Context, userName, database string) error {
 	// https://www.postgresql.org/docs/current/sql-grant.html
 	_, err := am.db.ExecContext(
-		ctx, "GRANT ALL PRIVILEGES ON DATABASE "+pq.QuoteIdentifier(database)+" TO "+pq.QuoteIdentifier(userName),
+		ctx, "GRANT CONNECT ON DATABASE "+pq.QuoteIdentifier(database)+" TO "+pq.QuoteIdentifier(userName),
 	)
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+func (am *DbManager) SetDatabaseLimits(ctx context.Context, database string, limits customdatabase.Limits) error {
+	// https://www.postgresql.org/docs/current/sql-alterdatabase.html
+	_, err := am.db.ExecContext(
+		ctx, "ALTER DATABASE "+pq.QuoteIdentifier(database)+" CONNECTION LIMIT "+strconv.Itoa(limits.ConnectionLimit),
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, setting := range limitSettings(&limits) {
+		query := "ALTER DATABASE " + pq.QuoteIdentifier(database) + " RESET " + setting.name
+		if *setting.value != "" {
+			query = "ALTER DATABASE " + pq.QuoteIdentifier(database) + " SET " + setting.name + " = " +
+				pq.QuoteLiteral(*setting.value)
+		}
+
+		_, err = am.db.ExecContext(ctx, query)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (am *DbManager) GetDatabaseLimits(ctx context.Context, database string) (customdatabase.Limits, error) {
+	var limits customdatabase.Limits
+
+	// https://www.postgresql.org/docs/current/catalog-pg-database.html
+	err := am.db.QueryRowContext(
+		ctx, "SELECT datconnlimit FROM pg_database WHERE datname = $1", database,
+	).Scan(&limits.ConnectionLimit)
+	if err != nil {
+		return limits, err
+	}
+
+	// https://www.postgresql.org/docs/current/catalog-pg-db-role-setting.html
+	rows, err := am.db.QueryContext(ctx,
+		"SELECT unnest(s.setconfig) FROM pg_db_role_setting s JOIN pg_database d ON d.oid = s.setdatabase "+
+			"WHERE d.datname = $1 AND s.setrole = 0",
+		database,
+	)
+	if err != nil {
+		return limits, err
+	}
+	defer rows.Close()
+
+	settings := limitSettings(&limits)
+	for rows.Next() {
+		var config string
+		if err = rows.Scan(&config); err != nil {
+			return limits, err
+		}
+
+		name, value, _ := strings.Cut(config, "=")
+		for _, setting := range settings {
+			if setting.name == name {
+				*setting.value = value
+			}
+		}
+	}
+
+	return limits, rows.Err()
+}
+
+func (am *DbManager) GetDatabaseSize(ctx context.Context, database string) (int64, error) {
+	var size int64
+
+	// https://www.postgresql.org/docs/current/functions-admin.html#FUNCTIONS-ADMIN-DBSIZE
+	err := am.db.QueryRowContext(ctx, "SELECT pg_database_size($1)", database).Scan(&size)
+	if err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
+
+func (am *DbManager) SetDatabaseReadOnly(ctx context.Context, database string, readOnly bool) error {
+	// New sessions can't write to database, but superuser or database owner still can change this setting
+	query := "ALTER DATABASE " + pq.QuoteIdentifier(database) + " RESET default_transaction_read_only"
+	if readOnly {
+		query = "ALTER DATABASE " + pq.QuoteIdentifier(database) + " SET default_transaction_read_only = on"
+	}
+
+	_, err := am.db.ExecContext(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+type limitSetting struct {
+	name  string
+	value *string
+}
+
+// limitSettings binds Postgresql run-time parameters with Limits fields
+func limitSettings(limits *customdatabase.Limits) []limitSetting {
+	return []limitSetting{
+		{name: "statement_timeout", value: &limits.StatementTimeout},
+		{name: "idle_in_transaction_session_timeout", value: &limits.IdleInTransactionSessionTimeout},
+		{name: "work_mem", value: &limits.WorkMem},
+	}
+}
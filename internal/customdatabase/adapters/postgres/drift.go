@@ -0,0 +1,207 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"k8s.io/custom-database/internal/customdatabase"
+)
+
+// invalidPassword error code of authentication with wrong password
+const invalidPassword = "28P01"
+
+// ownerDatabasePrivileges are needed by owner to work with database, they are granted again by RepairDrift
+var ownerDatabasePrivileges = []string{"CONNECT", "TEMPORARY"}
+
+// databaseGrant privilege on database, which is granted to role
+type databaseGrant struct {
+	role      string
+	privilege string
+	// isManaged is true for roles with ownership marker, e.g. roles of DatabaseUser
+	isManaged bool
+}
+
+// DetectDrift compares database and its owner role on server with desired state and returns human-readable list of
+// differences. Database, that doesn't exist, has no drift, it's created by provisioning. Privileges of PUBLIC and
+// attributes of role are audited by AuditDatabase, so they aren't compared here.
+func (am *DbManager) DetectDrift(ctx context.Context, database customdatabase.Database) ([]string, error) {
+	var findings []string
+
+	// https://www.postgresql.org/docs/current/catalog-pg-database.html
+	var owner string
+	var isConnectionAllowed bool
+	err := am.db.QueryRowContext(ctx,
+		"SELECT pg_get_userbyid(datdba), datallowconn FROM pg_database WHERE datname = $1", database.Name,
+	).Scan(&owner, &isConnectionAllowed)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if owner != database.User {
+		findings = append(findings, fmt.Sprintf("database is owned by %s instead of %s", owner, database.User))
+	}
+	if !isConnectionAllowed {
+		findings = append(findings, "connections to database aren't allowed")
+	}
+
+	limits, err := am.GetDatabaseLimits(ctx, database.Name)
+	if err != nil {
+		return nil, err
+	}
+	if limits.ConnectionLimit != database.Limits.ConnectionLimit {
+		findings = append(findings, fmt.Sprintf("connection limit is %d instead of %d",
+			limits.ConnectionLimit, database.Limits.ConnectionLimit,
+		))
+	}
+	expectedSettings := limitSettings(&database.Limits)
+	for i, setting := range limitSettings(&limits) {
+		if *setting.value != *expectedSettings[i].value {
+			findings = append(findings, fmt.Sprintf("%s is %q instead of %q",
+				setting.name, *setting.value, *expectedSettings[i].value,
+			))
+		}
+	}
+
+	// https://www.postgresql.org/docs/current/view-pg-roles.html
+	var canLogin bool
+	err = am.db.QueryRowContext(ctx,
+		"SELECT rolcanlogin FROM pg_roles WHERE rolname = $1", database.User,
+	).Scan(&canLogin)
+	if err == sql.ErrNoRows {
+		return append(findings, fmt.Sprintf("role %s doesn't exist", database.User)), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !canLogin {
+		findings = append(findings, fmt.Sprintf("role %s can't log in", database.User))
+	}
+
+	grants, err := am.databaseGrants(ctx, database.Name)
+	if err != nil {
+		return nil, err
+	}
+	ownerPrivileges := make(map[string]bool)
+	for _, grant := range grants {
+		switch {
+		case grant.role == database.User:
+			ownerPrivileges[grant.privilege] = true
+		case !grant.isManaged:
+			findings = append(findings, fmt.Sprintf("role %s has %s privilege on database", grant.role, grant.privilege))
+		}
+	}
+	for _, privilege := range ownerDatabasePrivileges {
+		if !ownerPrivileges[privilege] {
+			findings = append(findings, fmt.Sprintf("role %s has no %s privilege on database", database.User, privilege))
+		}
+	}
+
+	// password is checked only when role can connect, otherwise connection fails for another reason
+	if canLogin && isConnectionAllowed && ownerPrivileges["CONNECT"] {
+		isPasswordValid, err := am.isPasswordValid(ctx, database)
+		if err != nil {
+			return nil, err
+		}
+		if !isPasswordValid {
+			findings = append(findings, fmt.Sprintf("password of role %s differs from Secret", database.User))
+		}
+	}
+
+	return findings, nil
+}
+
+// RepairDrift applies desired state of database and its owner role again. Limits are applied by SetDatabaseLimits.
+func (am *DbManager) RepairDrift(ctx context.Context, database customdatabase.Database) error {
+	name, user := pq.QuoteIdentifier(database.Name), pq.QuoteIdentifier(database.User)
+
+	// https://www.postgresql.org/docs/current/sql-alterdatabase.html
+	queries := []string{
+		"ALTER ROLE " + user + " WITH LOGIN ENCRYPTED PASSWORD " + pq.QuoteLiteral(database.Password),
+		"ALTER DATABASE " + name + " OWNER TO " + user,
+		"ALTER DATABASE " + name + " WITH ALLOW_CONNECTIONS true",
+		"GRANT " + strings.Join(ownerDatabasePrivileges, ", ") + " ON DATABASE " + name + " TO " + user,
+	}
+
+	grants, err := am.databaseGrants(ctx, database.Name)
+	if err != nil {
+		return err
+	}
+	revoked := make(map[string]bool)
+	for _, grant := range grants {
+		if grant.role != database.User && !grant.isManaged && !revoked[grant.role] {
+			revoked[grant.role] = true
+			queries = append(queries, "REVOKE ALL ON DATABASE "+name+" FROM "+pq.QuoteIdentifier(grant.role))
+		}
+	}
+
+	for _, query := range queries {
+		if _, err = am.db.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// databaseGrants returns privileges on database, which are granted to roles except PUBLIC and admin role of
+// controller
+func (am *DbManager) databaseGrants(ctx context.Context, database string) ([]databaseGrant, error) {
+	// https://www.postgresql.org/docs/current/functions-info.html#FUNCTIONS-ACLITEM-FN-TABLE
+	rows, err := am.db.QueryContext(ctx,
+		"SELECT r.rolname, a.privilege_type, shobj_description(r.oid, 'pg_authid') FROM pg_database d "+
+			"CROSS JOIN LATERAL aclexplode(COALESCE(d.datacl, acldefault('d', d.datdba))) a "+
+			"JOIN pg_roles r ON r.oid = a.grantee "+
+			"WHERE d.datname = $1 AND r.rolname <> current_user",
+		database,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var grants []databaseGrant
+	for rows.Next() {
+		var grant databaseGrant
+		var comment sql.NullString
+		if err = rows.Scan(&grant.role, &grant.privilege, &comment); err != nil {
+			return nil, err
+		}
+		grant.isManaged = comment.Valid && customdatabase.ParseOwnershipMarker(comment.String) != nil
+		grants = append(grants, grant)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(grants, func(i, j int) bool {
+		if grants[i].role != grants[j].role {
+			return grants[i].role < grants[j].role
+		}
+		return grants[i].privilege < grants[j].privilege
+	})
+
+	return grants, nil
+}
+
+// isPasswordValid reports whether owner can connect to database with password from desired state
+func (am *DbManager) isPasswordValid(ctx context.Context, database customdatabase.Database) (bool, error) {
+	conn, err := am.connector.ConnectAs(ctx, database.Name, database.User, database.Password)
+	var pgError *pq.Error
+	if errors.As(err, &pgError) && pgError.Code == invalidPassword {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, conn.Close()
+}
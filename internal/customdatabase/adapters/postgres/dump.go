@@ -0,0 +1,100 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"k8s.io/custom-database/internal/customdatabase"
+	commonDatabase "k8s.io/custom-database/pkg/postgres"
+)
+
+// Dumper makes logical dumps of databases by pg_dump utility
+type Dumper struct {
+	pgDumpPath string
+	// tls of connections of database owner, client certificate of admin isn't used for them
+	tls commonDatabase.TLSConfig
+}
+
+func NewDumper(pgDumpPath string, tls commonDatabase.TLSConfig) *Dumper {
+	return &Dumper{pgDumpPath: pgDumpPath, tls: tls}
+}
+
+// DumpDatabase writes dump of database in pg_dump custom format. Dump is made by the database owner and doesn't
+// contain ownership and privileges, so it can be restored for another role.
+func (d *Dumper) DumpDatabase(
+	ctx context.Context, host customdatabase.Host, database customdatabase.Database, dump io.Writer,
+) error {
+	cmd := exec.CommandContext(ctx, d.pgDumpPath,
+		"--format=custom",
+		"--no-owner",
+		"--no-acl",
+		"--host", host.Name,
+		"--port", strconv.Itoa(host.Port),
+		"--username", database.User,
+		"--dbname", database.Name,
+	)
+	// password is passed by environment, so it's not visible in list of processes
+	cmd.Env = append(append(os.Environ(), "PGPASSWORD="+database.Password), d.tls.Env()...)
+	cmd.Stdout = dump
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("pg_dump of database %s: %w: %s", database.Name, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
+// Restorer restores dumps of databases by pg_restore utility
+type Restorer struct {
+	pgRestorePath string
+	// tls of connections of database owner, client certificate of admin isn't used for them
+	tls commonDatabase.TLSConfig
+}
+
+func NewRestorer(pgRestorePath string, tls commonDatabase.TLSConfig) *Restorer {
+	return &Restorer{pgRestorePath: pgRestorePath, tls: tls}
+}
+
+// RestoreDatabase restores dump in pg_dump custom format in single transaction. Restore is made by the owner of
+// database, so all restored objects belong to it regardless of their owner in source database. Objects of dump replace
+// existing objects with the same names, if clean is set.
+func (r *Restorer) RestoreDatabase(
+	ctx context.Context, host customdatabase.Host, database customdatabase.Database, dump io.Reader, clean bool,
+) error {
+	args := []string{
+		"--no-owner",
+		"--no-acl",
+		"--single-transaction",
+		"--exit-on-error",
+		"--host", host.Name,
+		"--port", strconv.Itoa(host.Port),
+		"--username", database.User,
+		"--dbname", database.Name,
+	}
+	if clean {
+		args = append(args, "--clean", "--if-exists")
+	}
+
+	cmd := exec.CommandContext(ctx, r.pgRestorePath, args...)
+	// password is passed by environment, so it's not visible in list of processes
+	cmd.Env = append(append(os.Environ(), "PGPASSWORD="+database.Password), r.tls.Env()...)
+	cmd.Stdin = dump
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("pg_restore of database %s: %w: %s", database.Name, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/crc32"
+	"strings"
+
+	"k8s.io/custom-database/internal/customdatabase"
+)
+
+// migrationsTable bookkeeping table of golang-migrate, so database can be migrated by controller and by migrate CLI
+//
+// https://github.com/golang-migrate/migrate/tree/master/database/postgres
+const migrationsTable = "schema_migrations"
+
+// migrationsLockSalt salt of advisory lock of golang-migrate
+const migrationsLockSalt uint32 = 1486364155
+
+// Migrate moves database to target version on behalf of its owner and returns the reached version. Every step is
+// executed in its own transaction together with change of version, so failed step leaves database at the previous
+// version. Version is marked dirty before step like golang-migrate does, so interrupted step isn't taken for applied.
+// Advisory lock of golang-migrate protects database from concurrent migrations by several workers, controller replicas
+// and migrate CLI.
+func (am *DbManager) Migrate(
+	ctx context.Context, database customdatabase.Database, migrations []customdatabase.Migration, target int64,
+) (int64, error) {
+	conn, err := am.connector.ConnectAs(ctx, database.Name, database.User, database.Password)
+	if err != nil {
+		return customdatabase.NoMigrationVersion, err
+	}
+	defer conn.Close()
+	db := conn.DB()
+
+	var schema string
+	if err = db.QueryRowContext(ctx, "SELECT current_schema()").Scan(&schema); err != nil {
+		return customdatabase.NoMigrationVersion, err
+	}
+
+	// connection has only one session, so session lock is held until unlock or close of connection
+	// https://www.postgresql.org/docs/current/explicit-locking.html#ADVISORY-LOCKS
+	lockID := migrationsLockID(database.Name, schema)
+	if _, err = db.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+		return customdatabase.NoMigrationVersion, err
+	}
+	defer db.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockID) // nolint: errcheck
+
+	_, err = db.ExecContext(ctx,
+		"CREATE TABLE IF NOT EXISTS "+migrationsTable+" (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)",
+	)
+	if err != nil {
+		return customdatabase.NoMigrationVersion, err
+	}
+
+	current, err := migrationVersion(ctx, db)
+	if err != nil {
+		return current, err
+	}
+
+	steps, err := customdatabase.PlanMigrations(migrations, current, target)
+	if err != nil {
+		return current, err
+	}
+
+	for _, step := range steps {
+		if err = setMigrationVersion(ctx, db, step.ResultVersion, true); err != nil {
+			return current, err
+		}
+		if err = applyMigrationStep(ctx, db, step); err != nil {
+			_, _ = db.ExecContext(ctx, "ROLLBACK")
+			// transaction of step is rolled back, so database is clean at the previous version. If version can't be
+			// restored, database stays dirty and is fixed manually.
+			if restoreErr := setMigrationVersion(ctx, db, current, false); restoreErr != nil {
+				return current, fmt.Errorf("%w, restore version: %v", customdatabase.ErrMigrationsDirty, restoreErr)
+			}
+			return current, &customdatabase.MigrationError{Version: step.Version, Down: step.Down, Err: err}
+		}
+		current = step.ResultVersion
+	}
+
+	return current, nil
+}
+
+// migrationsLockID returns key of advisory lock, which golang-migrate takes for migrations table of database
+//
+// https://github.com/golang-migrate/migrate/blob/master/database/util.go
+func migrationsLockID(database, schema string) string {
+	sum := crc32.ChecksumIEEE([]byte(strings.Join([]string{schema, migrationsTable, database}, "\x00")))
+
+	return fmt.Sprint(sum * migrationsLockSalt)
+}
+
+func migrationVersion(ctx context.Context, db DB) (int64, error) {
+	var version int64
+	var isDirty bool
+
+	err := db.QueryRowContext(ctx, "SELECT version, dirty FROM "+migrationsTable+" LIMIT 1").Scan(&version, &isDirty)
+	if err == sql.ErrNoRows {
+		return customdatabase.NoMigrationVersion, nil
+	}
+	if err != nil {
+		return customdatabase.NoMigrationVersion, err
+	}
+	if isDirty {
+		return version, customdatabase.ErrMigrationsDirty
+	}
+
+	return version, nil
+}
+
+func applyMigrationStep(ctx context.Context, db DB, step customdatabase.MigrationStep) error {
+	if _, err := db.ExecContext(ctx, "BEGIN"); err != nil {
+		return err
+	}
+
+	if err := execScript(ctx, db, step.Script); err != nil {
+		return err
+	}
+	if err := setMigrationVersion(ctx, db, step.ResultVersion, false); err != nil {
+		return err
+	}
+
+	_, err := db.ExecContext(ctx, "COMMIT")
+
+	return err
+}
+
+// setMigrationVersion replaces version of database, table contains only one row with the current version, like in
+// golang-migrate. Database without migrations has no row, unless it's dirty.
+func setMigrationVersion(ctx context.Context, db DB, version int64, isDirty bool) error {
+	if _, err := db.ExecContext(ctx, "DELETE FROM "+migrationsTable); err != nil {
+		return err
+	}
+	if version == customdatabase.NoMigrationVersion && !isDirty {
+		return nil
+	}
+
+	_, err := db.ExecContext(ctx,
+		"INSERT INTO "+migrationsTable+" (version, dirty) VALUES ($1, $2)", version, isDirty,
+	)
+
+	return err
+}
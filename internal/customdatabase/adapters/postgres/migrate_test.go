@@ -0,0 +1,11 @@
+package postgres
+
+import "testing"
+
+// TestMigrationsLockID checks, that controller and golang-migrate take the same lock, value is computed by
+// database.GenerateAdvisoryLockId("app", "public", "schema_migrations") of golang-migrate
+func TestMigrationsLockID(t *testing.T) {
+	if lockID := migrationsLockID("app", "public"); lockID != "1787120056" {
+		t.Errorf("wrong lock of migrations: expected 1787120056, given %s", lockID)
+	}
+}
@@ -0,0 +1,116 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+
+	"k8s.io/custom-database/internal/customdatabase"
+)
+
+// GetDatabaseOwnership reads ownership marker from comment of database
+//
+// https://www.postgresql.org/docs/current/functions-info.html#FUNCTIONS-INFO-COMMENT
+func (am *DbManager) GetDatabaseOwnership(ctx context.Context, database string) (customdatabase.Ownership, error) {
+	return am.getOwnership(ctx,
+		"SELECT shobj_description(oid, 'pg_database') FROM pg_database WHERE datname = $1", database,
+	)
+}
+
+// GetUserOwnership reads ownership marker from comment of role
+func (am *DbManager) GetUserOwnership(ctx context.Context, userName string) (customdatabase.Ownership, error) {
+	return am.getOwnership(ctx,
+		"SELECT shobj_description(oid, 'pg_authid') FROM pg_roles WHERE rolname = $1", userName,
+	)
+}
+
+// ListDatabaseOwnership returns ownership markers of all databases on server, which are managed by controller
+func (am *DbManager) ListDatabaseOwnership(ctx context.Context) (map[string]customdatabase.OwnershipMarker, error) {
+	return am.listOwnership(ctx,
+		"SELECT datname, shobj_description(oid, 'pg_database') FROM pg_database "+
+			"WHERE shobj_description(oid, 'pg_database') IS NOT NULL",
+	)
+}
+
+// ListUserOwnership returns ownership markers of all roles on server, which are managed by controller
+func (am *DbManager) ListUserOwnership(ctx context.Context) (map[string]customdatabase.OwnershipMarker, error) {
+	return am.listOwnership(ctx,
+		"SELECT rolname, shobj_description(oid, 'pg_authid') FROM pg_roles "+
+			"WHERE shobj_description(oid, 'pg_authid') IS NOT NULL",
+	)
+}
+
+// ListDatabases returns names of all databases on server, which users can connect to. Templates and maintenance
+// database postgres are skipped.
+func (am *DbManager) ListDatabases(ctx context.Context) ([]string, error) {
+	return queryStrings(ctx, am.db,
+		"SELECT datname FROM pg_database WHERE NOT datistemplate AND datname <> 'postgres' ORDER BY datname",
+	)
+}
+
+// SetDatabaseOwnership writes ownership marker to comment of database, previous comment is replaced
+//
+// https://www.postgresql.org/docs/current/sql-comment.html
+func (am *DbManager) SetDatabaseOwnership(
+	ctx context.Context, database string, marker customdatabase.OwnershipMarker,
+) error {
+	_, err := am.db.ExecContext(ctx,
+		"COMMENT ON DATABASE "+pq.QuoteIdentifier(database)+" IS "+pq.QuoteLiteral(marker.String()),
+	)
+
+	return err
+}
+
+// SetUserOwnership writes ownership marker to comment of role, previous comment is replaced
+func (am *DbManager) SetUserOwnership(
+	ctx context.Context, userName string, marker customdatabase.OwnershipMarker,
+) error {
+	_, err := am.db.ExecContext(ctx,
+		"COMMENT ON ROLE "+pq.QuoteIdentifier(userName)+" IS "+pq.QuoteLiteral(marker.String()),
+	)
+
+	return err
+}
+
+func (am *DbManager) getOwnership(ctx context.Context, query, name string) (customdatabase.Ownership, error) {
+	var comment sql.NullString
+
+	err := am.db.QueryRowContext(ctx, query, name).Scan(&comment)
+	if err == sql.ErrNoRows {
+		return customdatabase.Ownership{}, nil
+	}
+	if err != nil {
+		return customdatabase.Ownership{}, err
+	}
+
+	ownership := customdatabase.Ownership{Exists: true}
+	if comment.Valid {
+		ownership.Marker = customdatabase.ParseOwnershipMarker(comment.String)
+	}
+
+	return ownership, nil
+}
+
+func (am *DbManager) listOwnership(ctx context.Context, query string) (map[string]customdatabase.OwnershipMarker, error) {
+	rows, err := am.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	markers := make(map[string]customdatabase.OwnershipMarker)
+	for rows.Next() {
+		var name, comment string
+		if err = rows.Scan(&name, &comment); err != nil {
+			return nil, err
+		}
+
+		// comments of objects, which aren't managed by controller, are skipped
+		if marker := customdatabase.ParseOwnershipMarker(comment); marker != nil {
+			markers[name] = *marker
+		}
+	}
+
+	return markers, rows.Err()
+}
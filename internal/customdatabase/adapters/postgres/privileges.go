@@ -0,0 +1,163 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"k8s.io/custom-database/internal/customdatabase"
+)
+
+// objectTypes all types of objects, privileges on which can be granted to additional users
+var objectTypes = []customdatabase.ObjectType{ // nolint: gochecknoglobals
+	customdatabase.ObjectTypeTables, customdatabase.ObjectTypeSequences, customdatabase.ObjectTypeFunctions,
+}
+
+// ApplyUserPrivileges replaces privileges of additional user in database with the given ones.
+// Objects of database belong to its owner, so grants are executed in connection of owner. All statements are sent
+// as one query - Postgresql runs them in implicit transaction, so user doesn't lose privileges between revoke and grant.
+func (am *DbManager) ApplyUserPrivileges(
+	ctx context.Context, database customdatabase.Database, user customdatabase.DatabaseUser,
+) error {
+	// https://www.postgresql.org/docs/current/sql-grant.html#SQL-GRANT-DESCRIPTION-ROLES
+	membershipQuery := "REVOKE " + pq.QuoteIdentifier(database.User) + " FROM " + pq.QuoteIdentifier(user.Name)
+	if user.Privileges.Profile == customdatabase.PrivilegeProfileOwner {
+		membershipQuery = "GRANT " + pq.QuoteIdentifier(database.User) + " TO " + pq.QuoteIdentifier(user.Name)
+	}
+	if _, err := am.db.ExecContext(ctx, membershipQuery); err != nil {
+		return err
+	}
+
+	conn, err := am.connector.ConnectAs(ctx, database.Name, database.User, database.Password)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	schemas, err := listSchemas(ctx, conn.DB())
+	if err != nil {
+		return err
+	}
+
+	queries := revokeUserPrivilegesQueries(database, user.Name, schemas)
+	queries = append(queries, "GRANT "+strings.Join(user.Privileges.DatabasePrivileges(), ", ")+" ON DATABASE "+
+		pq.QuoteIdentifier(database.Name)+" TO "+pq.QuoteIdentifier(user.Name),
+	)
+	for _, schema := range schemas {
+		queries = append(queries, "GRANT USAGE ON SCHEMA "+pq.QuoteIdentifier(schema)+" TO "+pq.QuoteIdentifier(user.Name))
+	}
+
+	// https://www.postgresql.org/docs/current/sql-alterdefaultprivileges.html
+	for _, grant := range user.Privileges.ProfileGrants(schemas) {
+		privileges := strings.ToUpper(strings.Join(grant.Privileges, ", "))
+		objectType := strings.ToUpper(string(grant.ObjectType))
+
+		queries = append(queries,
+			"GRANT "+privileges+" ON ALL "+objectType+" IN SCHEMA "+pq.QuoteIdentifier(grant.Schema)+
+				" TO "+pq.QuoteIdentifier(user.Name),
+			"ALTER DEFAULT PRIVILEGES IN SCHEMA "+pq.QuoteIdentifier(grant.Schema)+
+				" GRANT "+privileges+" ON "+objectType+" TO "+pq.QuoteIdentifier(user.Name),
+		)
+	}
+	// default privileges without schema cover schemas, which are created after privileges are applied
+	defaultGrants := user.Privileges.DefaultGrants()
+	if len(defaultGrants) > 0 {
+		queries = append(queries, "ALTER DEFAULT PRIVILEGES FOR ROLE "+pq.QuoteIdentifier(database.User)+
+			" GRANT USAGE ON SCHEMAS TO "+pq.QuoteIdentifier(user.Name),
+		)
+	}
+	for _, grant := range defaultGrants {
+		queries = append(queries, "ALTER DEFAULT PRIVILEGES FOR ROLE "+pq.QuoteIdentifier(database.User)+
+			" GRANT "+strings.ToUpper(strings.Join(grant.Privileges, ", "))+" ON "+
+			strings.ToUpper(string(grant.ObjectType))+" TO "+pq.QuoteIdentifier(user.Name),
+		)
+	}
+
+	_, err = conn.DB().ExecContext(ctx, strings.Join(queries, ";\n"))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RevokeUserPrivileges removes all privileges of additional user in database, after that user can be dropped
+func (am *DbManager) RevokeUserPrivileges(
+	ctx context.Context, database customdatabase.Database, user customdatabase.DatabaseUser,
+) error {
+	_, err := am.db.ExecContext(ctx,
+		"REVOKE "+pq.QuoteIdentifier(database.User)+" FROM "+pq.QuoteIdentifier(user.Name),
+	)
+	if err != nil {
+		return err
+	}
+
+	conn, err := am.connector.ConnectAs(ctx, database.Name, database.User, database.Password)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	schemas, err := listSchemas(ctx, conn.DB())
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.DB().ExecContext(ctx, strings.Join(revokeUserPrivilegesQueries(database, user.Name, schemas), ";\n"))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func revokeUserPrivilegesQueries(database customdatabase.Database, userName string, schemas []string) []string {
+	queries := []string{
+		"REVOKE ALL ON DATABASE " + pq.QuoteIdentifier(database.Name) + " FROM " + pq.QuoteIdentifier(userName),
+		"ALTER DEFAULT PRIVILEGES FOR ROLE " + pq.QuoteIdentifier(database.User) + " REVOKE ALL ON SCHEMAS FROM " +
+			pq.QuoteIdentifier(userName),
+	}
+	for _, objectType := range objectTypes {
+		queries = append(queries, "ALTER DEFAULT PRIVILEGES FOR ROLE "+pq.QuoteIdentifier(database.User)+
+			" REVOKE ALL ON "+strings.ToUpper(string(objectType))+" FROM "+pq.QuoteIdentifier(userName),
+		)
+	}
+
+	for _, schema := range schemas {
+		for _, objectType := range objectTypes {
+			queries = append(queries,
+				"REVOKE ALL ON ALL "+strings.ToUpper(string(objectType))+" IN SCHEMA "+pq.QuoteIdentifier(schema)+
+					" FROM "+pq.QuoteIdentifier(userName),
+				"ALTER DEFAULT PRIVILEGES IN SCHEMA "+pq.QuoteIdentifier(schema)+
+					" REVOKE ALL ON "+strings.ToUpper(string(objectType))+" FROM "+pq.QuoteIdentifier(userName),
+			)
+		}
+		queries = append(queries, "REVOKE ALL ON SCHEMA "+pq.QuoteIdentifier(schema)+" FROM "+pq.QuoteIdentifier(userName))
+	}
+
+	return queries
+}
+
+// listSchemas returns all schemas of database except system ones
+func listSchemas(ctx context.Context, db DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx,
+		"SELECT nspname FROM pg_namespace WHERE nspname NOT LIKE 'pg\\_%' AND nspname <> 'information_schema' "+
+			"ORDER BY nspname",
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schemas []string
+	for rows.Next() {
+		var schema string
+		if err = rows.Scan(&schema); err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, schema)
+	}
+
+	return schemas, rows.Err()
+}
@@ -0,0 +1,224 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"k8s.io/custom-database/internal/customdatabase"
+)
+
+// initScriptsTable bookkeeping table of applied init scripts. It's changed in transaction of script, so applied script
+// isn't run again, even if controller fails to save status of CustomDatabase.
+const initScriptsTable = "customdatabase_init_scripts"
+
+// RunScript executes SQL script in database on behalf of its owner and returns checksum of applied script. Script is
+// executed in one transaction, so failed script leaves no changes and can be fixed and run again. Statements are sent
+// one by one, so failed statement and its line are known. Script, whose name is already in bookkeeping table, isn't
+// run again, checksum of applied version is returned for it.
+func (am *DbManager) RunScript(
+	ctx context.Context, database customdatabase.Database, script customdatabase.InitScript,
+) (string, error) {
+	conn, err := am.connector.ConnectAs(ctx, database.Name, database.User, database.Password)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	db := conn.DB()
+
+	// connection has only one session, so statements below are executed in the same transaction
+	if _, err = db.ExecContext(ctx, "BEGIN"); err != nil {
+		return "", err
+	}
+
+	checksum, err := runInitScript(ctx, db, script)
+	if err != nil {
+		_, _ = db.ExecContext(ctx, "ROLLBACK")
+		return "", err
+	}
+
+	_, err = db.ExecContext(ctx, "COMMIT")
+
+	return checksum, err
+}
+
+// runInitScript executes script, which isn't in bookkeeping table yet, and records it there
+func runInitScript(ctx context.Context, db DB, script customdatabase.InitScript) (string, error) {
+	_, err := db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+initScriptsTable+
+		" (name text NOT NULL PRIMARY KEY, checksum text NOT NULL, applied_at timestamptz NOT NULL DEFAULT now())",
+	)
+	if err != nil {
+		return "", err
+	}
+
+	var checksum string
+	err = db.QueryRowContext(ctx, "SELECT checksum FROM "+initScriptsTable+" WHERE name = $1", script.Name).
+		Scan(&checksum)
+	if err == nil {
+		return checksum, nil
+	} else if err != sql.ErrNoRows {
+		return "", err
+	}
+
+	if err = execScript(ctx, db, script.Content); err != nil {
+		return "", err
+	}
+
+	_, err = db.ExecContext(ctx,
+		"INSERT INTO "+initScriptsTable+" (name, checksum) VALUES ($1, $2)", script.Name, script.Checksum,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return script.Checksum, nil
+}
+
+// execScript executes statements of script one by one and stops at the first failed statement
+func execScript(ctx context.Context, db DB, script string) error {
+	for _, statement := range splitStatements(script) {
+		if _, err := db.ExecContext(ctx, statement.text); err != nil {
+			return &customdatabase.ScriptError{Line: statement.line, Statement: statement.text, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// scriptStatement statement of SQL script with number of its first line
+type scriptStatement struct {
+	text string
+	line int
+}
+
+// splitStatements splits SQL script by semicolons, which are outside of string literals, quoted identifiers,
+// dollar-quoted strings and comments. Comments between statements are dropped.
+//
+// https://www.postgresql.org/docs/current/sql-syntax-lexical.html
+func splitStatements(script string) []scriptStatement {
+	var statements []scriptStatement
+
+	line, start, startLine := 1, -1, 0
+	appendStatement := func(end int) {
+		if start >= 0 {
+			if text := strings.TrimSpace(script[start:end]); text != "" {
+				statements = append(statements, scriptStatement{text: text, line: startLine})
+			}
+		}
+		start = -1
+	}
+	// skip moves position to the end of token and counts lines inside it
+	skip := func(from, to int) int {
+		line += strings.Count(script[from:to], "\n")
+		return to
+	}
+
+	for i := 0; i < len(script); {
+		c := script[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+			continue
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+			continue
+		case strings.HasPrefix(script[i:], "--"):
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				end = len(script) - i
+			}
+			i += end
+			continue
+		case strings.HasPrefix(script[i:], "/*"):
+			i = skip(i, blockCommentEnd(script, i))
+			continue
+		case c == ';':
+			appendStatement(i)
+			i++
+			continue
+		}
+
+		if start < 0 {
+			start, startLine = i, line
+		}
+
+		switch {
+		case c == '\'':
+			isEscapeString := i > 0 && (script[i-1] == 'E' || script[i-1] == 'e')
+			i = skip(i, quotedEnd(script, i, '\'', isEscapeString))
+		case c == '"':
+			i = skip(i, quotedEnd(script, i, '"', false))
+		case c == '$':
+			if tag := dollarQuoteTag(script[i:]); tag != "" {
+				end := strings.Index(script[i+len(tag):], tag)
+				if end < 0 {
+					i = skip(i, len(script))
+				} else {
+					i = skip(i, i+len(tag)+end+len(tag))
+				}
+			} else {
+				i++
+			}
+		default:
+			i++
+		}
+	}
+	appendStatement(len(script))
+
+	return statements
+}
+
+// quotedEnd returns position after closing quote. Doubled quote is an escaped quote.
+func quotedEnd(script string, start int, quote byte, isEscapeString bool) int {
+	for i := start + 1; i < len(script); i++ {
+		switch {
+		case isEscapeString && script[i] == '\\':
+			i++
+		case script[i] == quote && i+1 < len(script) && script[i+1] == quote:
+			i++
+		case script[i] == quote:
+			return i + 1
+		}
+	}
+
+	return len(script)
+}
+
+// blockCommentEnd returns position after the end of block comment, block comments may be nested
+func blockCommentEnd(script string, start int) int {
+	depth := 0
+	for i := start; i < len(script)-1; i++ {
+		switch {
+		case script[i] == '/' && script[i+1] == '*':
+			depth++
+			i++
+		case script[i] == '*' && script[i+1] == '/':
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+
+	return len(script)
+}
+
+// dollarQuoteTag returns opening tag of dollar-quoted string like $$ or $body$, or empty string, if text isn't
+// a dollar-quoted string, e.g. it's a positional parameter $1
+func dollarQuoteTag(text string) string {
+	for i := 1; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '$':
+			return text[:i+1]
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80:
+		case c >= '0' && c <= '9' && i > 1:
+		default:
+			return ""
+		}
+	}
+
+	return ""
+}
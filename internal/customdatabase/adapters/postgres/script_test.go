@@ -0,0 +1,34 @@
+package postgres
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	script := `-- schema of application
+CREATE TABLE users (id serial, name text);
+
+/* fixtures; /* nested */ comment */
+INSERT INTO users (name) VALUES ('O''Brien; Jr.'), (E'semi\';colon');
+CREATE FUNCTION touch() RETURNS trigger AS $body$
+BEGIN
+  NEW.name := 'touched;';
+  RETURN NEW;
+END;
+$body$ LANGUAGE plpgsql;
+SELECT "weird;name" FROM users WHERE id = $1
+`
+
+	expected := []scriptStatement{
+		{text: "CREATE TABLE users (id serial, name text)", line: 2},
+		{text: `INSERT INTO users (name) VALUES ('O''Brien; Jr.'), (E'semi\';colon')`, line: 5},
+		{text: "CREATE FUNCTION touch() RETURNS trigger AS $body$\nBEGIN\n  NEW.name := 'touched;';\n  RETURN NEW;\n" +
+			"END;\n$body$ LANGUAGE plpgsql", line: 6},
+		{text: `SELECT "weird;name" FROM users WHERE id = $1`, line: 12},
+	}
+
+	if statements := splitStatements(script); !reflect.DeepEqual(statements, expected) {
+		t.Errorf("wrong statements:\nexpected %+v\ngiven    %+v", expected, statements)
+	}
+}
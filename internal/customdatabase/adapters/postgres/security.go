@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// HardenDatabase applies least-privilege defaults to tenant database: only owner can connect to it, other roles
+// can't create objects in public schema and the owner has no cluster-wide privileges.
+func (am *DbManager) HardenDatabase(ctx context.Context, database, owner string) error {
+	// https://www.postgresql.org/docs/current/ddl-priv.html
+	queries := []string{
+		"REVOKE ALL ON DATABASE " + pq.QuoteIdentifier(database) + " FROM PUBLIC",
+		"GRANT CONNECT, TEMPORARY ON DATABASE " + pq.QuoteIdentifier(database) + " TO " + pq.QuoteIdentifier(owner),
+		"ALTER ROLE " + pq.QuoteIdentifier(owner) + " WITH " + tenantRoleAttributes,
+	}
+	for _, query := range queries {
+		if _, err := am.db.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+
+	conn, err := am.connector.Connect(ctx, database)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Before Postgresql 15 every role can create objects in public schema
+	// https://www.postgresql.org/docs/current/ddl-schemas.html#DDL-SCHEMAS-PATTERNS
+	_, err = conn.DB().ExecContext(ctx, "REVOKE CREATE ON SCHEMA public FROM PUBLIC")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// AuditDatabase returns human-readable list of differences between actual privileges and least-privilege defaults,
+// which are applied by HardenDatabase
+func (am *DbManager) AuditDatabase(ctx context.Context, database, owner string) ([]string, error) {
+	var findings []string
+
+	// https://www.postgresql.org/docs/current/functions-info.html#FUNCTIONS-ACLITEM-FN-TABLE
+	rows, err := am.db.QueryContext(ctx,
+		"SELECT a.privilege_type FROM pg_database d, "+
+			"aclexplode(COALESCE(d.datacl, acldefault('d', d.datdba))) a "+
+			"WHERE d.datname = $1 AND a.grantee = 0 ORDER BY a.privilege_type",
+		database,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var privilege string
+		if err = rows.Scan(&privilege); err != nil {
+			return nil, err
+		}
+		findings = append(findings, fmt.Sprintf("PUBLIC has %s privilege on database", privilege))
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var isSuperuser, canCreateDB, canCreateRole, isInherit bool
+	err = am.db.QueryRowContext(ctx,
+		"SELECT rolsuper, rolcreatedb, rolcreaterole, rolinherit FROM pg_roles WHERE rolname = $1", owner,
+	).Scan(&isSuperuser, &canCreateDB, &canCreateRole, &isInherit)
+	if err != nil {
+		return nil, err
+	}
+	attributes := []struct {
+		name  string
+		isSet bool
+	}{
+		{"SUPERUSER", isSuperuser}, {"CREATEDB", canCreateDB}, {"CREATEROLE", canCreateRole}, {"INHERIT", isInherit},
+	}
+	for _, attribute := range attributes {
+		if attribute.isSet {
+			findings = append(findings, fmt.Sprintf("role %s has %s attribute", owner, attribute.name))
+		}
+	}
+
+	conn, err := am.connector.Connect(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var canPublicCreate bool
+	err = conn.DB().QueryRowContext(ctx,
+		"SELECT has_schema_privilege('public', 'public', 'CREATE')",
+	).Scan(&canPublicCreate)
+	if err != nil {
+		return nil, err
+	}
+	if canPublicCreate {
+		findings = append(findings, "PUBLIC has CREATE privilege on schema public")
+	}
+
+	return findings, nil
+}
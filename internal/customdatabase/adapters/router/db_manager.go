@@ -0,0 +1,313 @@
+// Package router lets controller manage databases on several Postgresql servers through one database manager
+package router
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/custom-database/internal/customdatabase"
+	"k8s.io/custom-database/internal/customdatabase/usecases"
+)
+
+// ErrUnknownServer is returned for calls with server, which isn't configured
+var ErrUnknownServer = fmt.Errorf("unknown server")
+
+// DbManager routes calls to database manager of server, which is stored in context by
+// customdatabase.ContextWithServer. Calls without server are routed to the default server.
+type DbManager struct {
+	defaultServer string
+	servers       map[string]usecases.DatabaseManager
+}
+
+func NewDbManager(defaultServer string, servers map[string]usecases.DatabaseManager) *DbManager {
+	return &DbManager{
+		defaultServer: defaultServer,
+		servers:       servers,
+	}
+}
+
+// server returns database manager of server from context
+func (m *DbManager) server(ctx context.Context) (usecases.DatabaseManager, error) {
+	name := customdatabase.ServerFromContext(ctx)
+	if name == "" {
+		name = m.defaultServer
+	}
+
+	server, ok := m.servers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownServer, name)
+	}
+
+	return server, nil
+}
+
+func (m *DbManager) CreateDatabase(
+	ctx context.Context, database string, options customdatabase.DatabaseOptions, marker customdatabase.OwnershipMarker,
+) error {
+	server, err := m.server(ctx)
+	if err != nil {
+		return err
+	}
+
+	return server.CreateDatabase(ctx, database, options, marker)
+}
+
+func (m *DbManager) CloneDatabase(
+	ctx context.Context, database string, source customdatabase.Database, options customdatabase.DatabaseOptions,
+	marker customdatabase.OwnershipMarker,
+) error {
+	server, err := m.server(ctx)
+	if err != nil {
+		return err
+	}
+
+	return server.CloneDatabase(ctx, database, source, options, marker)
+}
+
+func (m *DbManager) DropDatabase(ctx context.Context, database string, owner customdatabase.OwnershipMarker) error {
+	server, err := m.server(ctx)
+	if err != nil {
+		return err
+	}
+
+	return server.DropDatabase(ctx, database, owner)
+}
+
+func (m *DbManager) CreateUser(
+	ctx context.Context, userName, password string, marker customdatabase.OwnershipMarker,
+) error {
+	server, err := m.server(ctx)
+	if err != nil {
+		return err
+	}
+
+	return server.CreateUser(ctx, userName, password, marker)
+}
+
+func (m *DbManager) ChangeUserPassword(ctx context.Context, userName, password string) error {
+	server, err := m.server(ctx)
+	if err != nil {
+		return err
+	}
+
+	return server.ChangeUserPassword(ctx, userName, password)
+}
+
+func (m *DbManager) DropUser(ctx context.Context, userName string, owner customdatabase.OwnershipMarker) error {
+	server, err := m.server(ctx)
+	if err != nil {
+		return err
+	}
+
+	return server.DropUser(ctx, userName, owner)
+}
+
+func (m *DbManager) GrantUserToDatabase(ctx context.Context, userName, database string) error {
+	server, err := m.server(ctx)
+	if err != nil {
+		return err
+	}
+
+	return server.GrantUserToDatabase(ctx, userName, database)
+}
+
+func (m *DbManager) SetDatabaseLimits(ctx context.Context, database string, limits customdatabase.Limits) error {
+	server, err := m.server(ctx)
+	if err != nil {
+		return err
+	}
+
+	return server.SetDatabaseLimits(ctx, database, limits)
+}
+
+func (m *DbManager) GetDatabaseLimits(ctx context.Context, database string) (customdatabase.Limits, error) {
+	server, err := m.server(ctx)
+	if err != nil {
+		return customdatabase.Limits{}, err
+	}
+
+	return server.GetDatabaseLimits(ctx, database)
+}
+
+func (m *DbManager) GetDatabaseSize(ctx context.Context, database string) (int64, error) {
+	server, err := m.server(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return server.GetDatabaseSize(ctx, database)
+}
+
+func (m *DbManager) SetDatabaseReadOnly(ctx context.Context, database string, readOnly bool) error {
+	server, err := m.server(ctx)
+	if err != nil {
+		return err
+	}
+
+	return server.SetDatabaseReadOnly(ctx, database, readOnly)
+}
+
+func (m *DbManager) HardenDatabase(ctx context.Context, database, owner string) error {
+	server, err := m.server(ctx)
+	if err != nil {
+		return err
+	}
+
+	return server.HardenDatabase(ctx, database, owner)
+}
+
+func (m *DbManager) AuditDatabase(ctx context.Context, database, owner string) ([]string, error) {
+	server, err := m.server(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return server.AuditDatabase(ctx, database, owner)
+}
+
+func (m *DbManager) DetectDrift(ctx context.Context, database customdatabase.Database) ([]string, error) {
+	server, err := m.server(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return server.DetectDrift(ctx, database)
+}
+
+func (m *DbManager) RepairDrift(ctx context.Context, database customdatabase.Database) error {
+	server, err := m.server(ctx)
+	if err != nil {
+		return err
+	}
+
+	return server.RepairDrift(ctx, database)
+}
+
+func (m *DbManager) ApplyUserPrivileges(
+	ctx context.Context, database customdatabase.Database, user customdatabase.DatabaseUser,
+) error {
+	server, err := m.server(ctx)
+	if err != nil {
+		return err
+	}
+
+	return server.ApplyUserPrivileges(ctx, database, user)
+}
+
+func (m *DbManager) RevokeUserPrivileges(
+	ctx context.Context, database customdatabase.Database, user customdatabase.DatabaseUser,
+) error {
+	server, err := m.server(ctx)
+	if err != nil {
+		return err
+	}
+
+	return server.RevokeUserPrivileges(ctx, database, user)
+}
+
+func (m *DbManager) GetDatabaseOwnership(ctx context.Context, database string) (customdatabase.Ownership, error) {
+	server, err := m.server(ctx)
+	if err != nil {
+		return customdatabase.Ownership{}, err
+	}
+
+	return server.GetDatabaseOwnership(ctx, database)
+}
+
+func (m *DbManager) GetUserOwnership(ctx context.Context, userName string) (customdatabase.Ownership, error) {
+	server, err := m.server(ctx)
+	if err != nil {
+		return customdatabase.Ownership{}, err
+	}
+
+	return server.GetUserOwnership(ctx, userName)
+}
+
+func (m *DbManager) ListDatabaseOwnership(ctx context.Context) (map[string]customdatabase.OwnershipMarker, error) {
+	server, err := m.server(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return server.ListDatabaseOwnership(ctx)
+}
+
+func (m *DbManager) ListUserOwnership(ctx context.Context) (map[string]customdatabase.OwnershipMarker, error) {
+	server, err := m.server(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return server.ListUserOwnership(ctx)
+}
+
+func (m *DbManager) SetDatabaseOwnership(
+	ctx context.Context, database string, marker customdatabase.OwnershipMarker,
+) error {
+	server, err := m.server(ctx)
+	if err != nil {
+		return err
+	}
+
+	return server.SetDatabaseOwnership(ctx, database, marker)
+}
+
+func (m *DbManager) SetUserOwnership(
+	ctx context.Context, userName string, marker customdatabase.OwnershipMarker,
+) error {
+	server, err := m.server(ctx)
+	if err != nil {
+		return err
+	}
+
+	return server.SetUserOwnership(ctx, userName, marker)
+}
+
+func (m *DbManager) GetDatabaseActivity(ctx context.Context, database string) (customdatabase.DatabaseActivity, error) {
+	server, err := m.server(ctx)
+	if err != nil {
+		return customdatabase.DatabaseActivity{}, err
+	}
+
+	return server.GetDatabaseActivity(ctx, database)
+}
+
+func (m *DbManager) RevokeDatabaseConnect(ctx context.Context, database string) error {
+	server, err := m.server(ctx)
+	if err != nil {
+		return err
+	}
+
+	return server.RevokeDatabaseConnect(ctx, database)
+}
+
+func (m *DbManager) TerminateDatabaseSessions(ctx context.Context, database string) error {
+	server, err := m.server(ctx)
+	if err != nil {
+		return err
+	}
+
+	return server.TerminateDatabaseSessions(ctx, database)
+}
+
+func (m *DbManager) RunScript(
+	ctx context.Context, database customdatabase.Database, script customdatabase.InitScript,
+) (string, error) {
+	server, err := m.server(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return server.RunScript(ctx, database, script)
+}
+
+func (m *DbManager) Migrate(
+	ctx context.Context, database customdatabase.Database, migrations []customdatabase.Migration, target int64,
+) (int64, error) {
+	server, err := m.server(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return server.Migrate(ctx, database, migrations, target)
+}
@@ -0,0 +1,41 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"k8s.io/custom-database/internal/customdatabase"
+	"k8s.io/custom-database/internal/customdatabase/adapters/fake"
+	"k8s.io/custom-database/internal/customdatabase/usecases"
+)
+
+func TestRouteToServerOfContext(t *testing.T) {
+	defaultServer, another := fake.NewDbManager(), fake.NewDbManager()
+	m := NewDbManager("default", map[string]usecases.DatabaseManager{"default": defaultServer, "another": another})
+	marker := customdatabase.OwnershipMarker{Kind: customdatabase.OwnerKindCustomDatabase, Namespace: "default"}
+
+	if err := m.CreateDatabase(context.Background(), "first", customdatabase.DatabaseOptions{}, marker); err != nil {
+		t.Fatal(err)
+	}
+	ctx := customdatabase.ContextWithServer(context.Background(), "another")
+	if err := m.CreateDatabase(ctx, "second", customdatabase.DatabaseOptions{}, marker); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, isExists := defaultServer.Databases["first"]; !isExists || len(defaultServer.Databases) != 1 {
+		t.Errorf("database without server should be created on default server, got %v", defaultServer.Databases)
+	}
+	if _, isExists := another.Databases["second"]; !isExists || len(another.Databases) != 1 {
+		t.Errorf("database should be created on server of context, got %v", another.Databases)
+	}
+}
+
+func TestUnknownServer(t *testing.T) {
+	m := NewDbManager("default", map[string]usecases.DatabaseManager{"default": fake.NewDbManager()})
+	ctx := customdatabase.ContextWithServer(context.Background(), "unknown")
+
+	if _, err := m.GetDatabaseSize(ctx, "test"); !errors.Is(err, ErrUnknownServer) {
+		t.Errorf("unknown server should be reported, got %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+// Package tlscert issues client certificates for roles, which authenticate on Postgresql server by certificate
+package tlscert
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+
+	"k8s.io/utils/clock"
+)
+
+// clockSkew certificate is valid a bit earlier than it's issued, because clocks of servers may differ
+const clockSkew = 5 * time.Minute
+
+// Issuer signs client certificates by CA, which is trusted by Postgresql server for cert authentication
+type Issuer struct {
+	caCert   *x509.Certificate
+	caKey    crypto.Signer
+	validity time.Duration
+	clock    clock.PassiveClock
+}
+
+// NewIssuer parses PEM certificate and private key of CA. Key may be in PKCS#8, PKCS#1 or SEC 1 format.
+func NewIssuer(caCertPEM, caKeyPEM []byte, validity time.Duration) (*Issuer, error) {
+	certBlock, _ := pem.Decode(caCertPEM)
+	if certBlock == nil {
+		return nil, fmt.Errorf("CA certificate isn't PEM")
+	}
+	caCert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse CA certificate: %w", err)
+	}
+	if !caCert.IsCA {
+		return nil, fmt.Errorf("certificate %s isn't CA", caCert.Subject)
+	}
+
+	keyBlock, _ := pem.Decode(caKeyPEM)
+	if keyBlock == nil {
+		return nil, fmt.Errorf("CA key isn't PEM")
+	}
+	caKey, err := parsePrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Issuer{caCert: caCert, caKey: caKey, validity: validity, clock: clock.RealClock{}}, nil
+}
+
+// IssueClientCertificate returns PEM certificate with role name as common name and its new PKCS#8 private key
+func (i *Issuer) IssueClientCertificate(userName string) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := i.clock.Now()
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject:      pkix.Name{CommonName: userName},
+		NotBefore:    now.Add(-clockSkew),
+		NotAfter:     now.Add(i.validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, i.caCert, key.Public(), i.caKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot issue certificate of role %s: %w", userName, err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), nil
+}
+
+func parsePrivateKey(der []byte) (crypto.Signer, error) {
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("CA key of type %T can't sign certificates", key)
+		}
+		return signer, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("cannot parse CA key")
+}
@@ -0,0 +1,82 @@
+package tlscert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestIssueClientCertificate(t *testing.T) {
+	caCertPEM, caKeyPEM := newCA(t)
+	issuer, err := NewIssuer(caCertPEM, caKeyPEM, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPEM, keyPEM, err := issuer.IssueClientCertificate("tenant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("certificate and key should be a pair: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(pair.Certificate[0])
+	if cert.Subject.CommonName != "tenant" {
+		t.Errorf("common name should be name of role, got %s", cert.Subject.CommonName)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(caCertPEM)
+	_, err = cert.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+	if err != nil {
+		t.Errorf("certificate should be verified by CA: %v", err)
+	}
+}
+
+func TestRejectNotCACertificate(t *testing.T) {
+	caCertPEM, caKeyPEM := newCA(t)
+	issuer, _ := NewIssuer(caCertPEM, caKeyPEM, time.Hour)
+	certPEM, keyPEM, _ := issuer.IssueClientCertificate("tenant")
+
+	if _, err := NewIssuer(certPEM, keyPEM, time.Hour); err == nil {
+		t.Errorf("client certificate shouldn't issue certificates")
+	}
+}
+
+// newCA returns PEM certificate and SEC 1 key of self-signed CA
+func newCA(t *testing.T) ([]byte, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
@@ -0,0 +1,203 @@
+package customdatabase
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// maxIdentifierLength Postgresql truncates longer names of databases and roles
+const maxIdentifierLength = 63
+
+// PrivilegeProfile predefined set of privileges on objects of database
+type PrivilegeProfile string
+
+const (
+	// PrivilegeProfileReadOnly allows to read all tables and sequences
+	PrivilegeProfileReadOnly PrivilegeProfile = "readonly"
+	// PrivilegeProfileReadWrite allows to read and change data in all tables and to call all functions
+	PrivilegeProfileReadWrite PrivilegeProfile = "readwrite"
+	// PrivilegeProfileOwner makes user a member of database owner role, user gets owner privileges after SET ROLE
+	PrivilegeProfileOwner PrivilegeProfile = "owner"
+	// PrivilegeProfileCustom allows only explicitly listed grants
+	PrivilegeProfileCustom PrivilegeProfile = "custom"
+)
+
+// ObjectType type of objects in schema, privileges are granted on all objects of the type
+type ObjectType string
+
+const (
+	ObjectTypeTables    ObjectType = "tables"
+	ObjectTypeSequences ObjectType = "sequences"
+	ObjectTypeFunctions ObjectType = "functions"
+)
+
+// allowedPrivileges privileges, that can be granted on objects of type
+// https://www.postgresql.org/docs/current/ddl-priv.html#PRIVILEGE-ABBREVS-TABLE
+var allowedPrivileges = map[ObjectType][]string{ // nolint: gochecknoglobals
+	ObjectTypeTables:    {"SELECT", "INSERT", "UPDATE", "DELETE", "TRUNCATE", "REFERENCES", "TRIGGER"},
+	ObjectTypeSequences: {"USAGE", "SELECT", "UPDATE"},
+	ObjectTypeFunctions: {"EXECUTE"},
+}
+
+// Grant privileges on all objects of the given type in schema
+type Grant struct {
+	Schema     string
+	ObjectType ObjectType
+	Privileges []string
+}
+
+// Privileges of additional user of database
+type Privileges struct {
+	Profile PrivilegeProfile
+	// Grants used only with custom profile. Profiles readonly and readwrite are expanded to grants by ProfileGrants.
+	Grants []Grant
+}
+
+// DatabaseUser additional role of database, e.g. read-only role for BI tools
+type DatabaseUser struct {
+	Name       string
+	Password   string
+	Privileges Privileges
+}
+
+// Validate checks, that privileges can be safely used in GRANT statements
+func (p Privileges) Validate() error {
+	switch p.Profile {
+	case PrivilegeProfileReadOnly, PrivilegeProfileReadWrite, PrivilegeProfileOwner:
+		if len(p.Grants) > 0 {
+			return fmt.Errorf("grants can be used only with %s profile", PrivilegeProfileCustom)
+		}
+		return nil
+	case PrivilegeProfileCustom:
+	default:
+		return fmt.Errorf("unknown privilege profile %q", p.Profile)
+	}
+
+	for _, grant := range p.Grants {
+		allowed, isKnown := allowedPrivileges[grant.ObjectType]
+		if !isKnown {
+			return fmt.Errorf("unknown object type %q", grant.ObjectType)
+		}
+		if len(grant.Privileges) == 0 {
+			return fmt.Errorf("privileges on %s should be not empty", grant.ObjectType)
+		}
+
+		for _, privilege := range grant.Privileges {
+			if !containsString(allowed, strings.ToUpper(privilege)) {
+				return fmt.Errorf("privilege %q can't be granted on %s", privilege, grant.ObjectType)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ProfileGrants returns grants of profile for every given schema. Owner profile has no grants - it uses membership
+// in owner role instead.
+func (p Privileges) ProfileGrants(schemas []string) []Grant {
+	var grants []Grant
+
+	for _, schema := range schemas {
+		grants = append(grants, p.profileGrants(schema)...)
+	}
+
+	if p.Profile == PrivilegeProfileCustom {
+		for _, grant := range p.Grants {
+			if grant.Schema == "" {
+				grant.Schema = "public"
+			}
+			grants = append(grants, grant)
+		}
+	}
+
+	return grants
+}
+
+// DefaultGrants returns grants of profile on objects, which are created by owner later in any schema, including
+// schemas, that don't exist yet. Grants have empty schema. Custom grants are applied only to their schemas.
+func (p Privileges) DefaultGrants() []Grant {
+	return p.profileGrants("")
+}
+
+// DatabasePrivileges returns privileges of user on database itself. Read-only user can't create temporary tables.
+func (p Privileges) DatabasePrivileges() []string {
+	if p.Profile == PrivilegeProfileReadOnly {
+		return []string{"CONNECT"}
+	}
+
+	return []string{"CONNECT", "TEMPORARY"}
+}
+
+func (p Privileges) profileGrants(schema string) []Grant {
+	switch p.Profile {
+	case PrivilegeProfileReadOnly:
+		return []Grant{
+			{Schema: schema, ObjectType: ObjectTypeTables, Privileges: []string{"SELECT"}},
+			{Schema: schema, ObjectType: ObjectTypeSequences, Privileges: []string{"SELECT"}},
+		}
+	case PrivilegeProfileReadWrite:
+		return []Grant{
+			{Schema: schema, ObjectType: ObjectTypeTables, Privileges: []string{"SELECT", "INSERT", "UPDATE", "DELETE"}},
+			{Schema: schema, ObjectType: ObjectTypeSequences, Privileges: []string{"USAGE", "SELECT", "UPDATE"}},
+			{Schema: schema, ObjectType: ObjectTypeFunctions, Privileges: []string{"EXECUTE"}},
+		}
+	}
+
+	return nil
+}
+
+// CreateDatabaseUserEntity returns additional user of database. Roles are shared by all databases of server, so name
+// of role contains name of database.
+func (ds *DomainService) CreateDatabaseUserEntity(
+	database Database, userName, password string, privileges Privileges,
+) (DatabaseUser, error) {
+	name := database.Name + "_" + userName
+	if len(name) > maxIdentifierLength {
+		return DatabaseUser{}, fmt.Errorf("role name %s is longer than %d characters", name, maxIdentifierLength)
+	}
+
+	if err := privileges.Validate(); err != nil {
+		return DatabaseUser{}, err
+	}
+
+	return DatabaseUser{
+		Name:       name,
+		Password:   password,
+		Privileges: privileges,
+	}, nil
+}
+
+// CreateDatabaseGrantEntity returns role for sharing database with another namespace. Only read and write profiles
+// can be shared, because owner privileges allow to change database beyond its data.
+// Kubernetes names have no underscores, so role of grant never clashes with roles of additional users.
+func (ds *DomainService) CreateDatabaseGrantEntity(
+	database Database, grantName, password string, profile PrivilegeProfile,
+) (DatabaseUser, error) {
+	if profile != PrivilegeProfileReadOnly && profile != PrivilegeProfileReadWrite {
+		return DatabaseUser{}, fmt.Errorf("privilege profile %q can't be granted to another namespace", profile)
+	}
+
+	return ds.CreateDatabaseUserEntity(database, "grant_"+grantName, password, Privileges{Profile: profile})
+}
+
+// NewPassword generates random password for Postgresql role
+func NewPassword() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate password: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
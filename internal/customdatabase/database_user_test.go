@@ -0,0 +1,30 @@
+package customdatabase
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPrivilegesOfReadOnlyProfile(t *testing.T) {
+	privileges := Privileges{Profile: PrivilegeProfileReadOnly}
+
+	if databasePrivileges := privileges.DatabasePrivileges(); !reflect.DeepEqual(databasePrivileges, []string{"CONNECT"}) {
+		t.Errorf("read-only user shouldn't create temporary tables, given %v", databasePrivileges)
+	}
+	for _, grant := range privileges.DefaultGrants() {
+		if grant.Schema != "" {
+			t.Errorf("default grants should cover all schemas, given %+v", grant)
+		}
+	}
+	if grants := privileges.DefaultGrants(); len(grants) != 2 {
+		t.Errorf("default grants should contain grants of profile, given %+v", grants)
+	}
+
+	custom := Privileges{
+		Profile: PrivilegeProfileCustom,
+		Grants:  []Grant{{ObjectType: ObjectTypeTables, Privileges: []string{"SELECT"}}},
+	}
+	if grants := custom.DefaultGrants(); len(grants) != 0 {
+		t.Errorf("custom grants should be applied only to their schemas, given %+v", grants)
+	}
+}
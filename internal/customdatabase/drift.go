@@ -0,0 +1,24 @@
+package customdatabase
+
+import "fmt"
+
+// DriftPolicy what controller does, when database or its role on server differs from desired state, e.g. after
+// manual changes
+type DriftPolicy string
+
+const (
+	// DriftPolicyRepair desired state is applied again
+	DriftPolicyRepair DriftPolicy = "repair"
+	// DriftPolicyReport drift is only reported, database isn't changed until drift is resolved by human
+	DriftPolicyReport DriftPolicy = "report"
+)
+
+// ParseDriftPolicy returns policy by its name
+func ParseDriftPolicy(policy string) (DriftPolicy, error) {
+	switch DriftPolicy(policy) {
+	case DriftPolicyRepair, DriftPolicyReport:
+		return DriftPolicy(policy), nil
+	default:
+		return "", fmt.Errorf("unknown drift policy %q, expected %q or %q", policy, DriftPolicyRepair, DriftPolicyReport)
+	}
+}
@@ -5,10 +5,14 @@ import "fmt"
 var (
 	ErrDatabaseAlreadyExists = fmt.Errorf("database already exists")
 	ErrUserAlreadyExists     = fmt.Errorf("user already exists")
+	// ErrNotOwned is returned instead of dropping database or role, which doesn't belong to resource
+	ErrNotOwned = fmt.Errorf("object isn't owned by resource")
 )
 
 type Entity struct {
-	Host     Host
+	Host Host
+	// Server name of Postgresql server of controller, where database lives, empty for the default server
+	Server   string
 	Database Database
 }
 
@@ -21,14 +25,60 @@ type Database struct {
 	Name     string
 	User     string
 	Password string
+	Options  DatabaseOptions
+	Limits   Limits
+}
+
+// DatabaseOptions parameters of database, that can be set only once - at the moment of database creation
+type DatabaseOptions struct {
+	// Owner of database. It should be tenant role, because since Postgresql 15 only database owner can create objects
+	// in public schema by default
+	Owner      string
+	Encoding   string
+	LcCollate  string
+	LcCtype    string
+	Template   string
+	Tablespace string
+}
+
+// IsTemplateAllowed reports whether database of tenant may be created from template. Only system templates are
+// allowed, otherwise tenant could copy database of another tenant, cloning is checked by CloneFrom instead.
+func IsTemplateAllowed(template string) bool {
+	return template == "template0" || template == "template1"
+}
+
+// NoConnectionLimit value of Limits.ConnectionLimit, when database has no limit of connections
+const NoConnectionLimit = -1
+
+// Limits resource limits of database. Empty string values mean, that Postgresql server default is used.
+type Limits struct {
+	ConnectionLimit                 int
+	StatementTimeout                string
+	IdleInTransactionSessionTimeout string
+	WorkMem                         string
+}
+
+// StorageQuota max size of database in bytes
+type StorageQuota struct {
+	Limit                int64
+	ReadOnlyWhenExceeded bool
+}
+
+// IsExceeded reports whether database with given size violates quota
+func (q StorageQuota) IsExceeded(size int64) bool {
+	return size > q.Limit
 }
 
 type DomainService struct {
 	dbServerHost string
 	dbServerPort int
+	// clusterName is written to ownership markers, it may be empty, when server isn't shared between clusters
+	clusterName string
+	// namingStrategy how databases are named after CustomDatabases, NamingStrategyName by default
+	namingStrategy NamingStrategy
 }
 
-func NewDomainService(host string, port int) (*DomainService, error) {
+func NewDomainService(host string, port int, clusterName string, opts ...DomainServiceOption) (*DomainService, error) {
 	if host == "" {
 		return nil, fmt.Errorf("host should be not empty")
 	}
@@ -37,13 +87,41 @@ func NewDomainService(host string, port int) (*DomainService, error) {
 		return nil, fmt.Errorf("port should be not empty")
 	}
 
-	return &DomainService{
-		dbServerHost: host,
-		dbServerPort: port,
-	}, nil
+	ds := &DomainService{
+		dbServerHost:   host,
+		dbServerPort:   port,
+		clusterName:    clusterName,
+		namingStrategy: NamingStrategyName,
+	}
+	for _, opt := range opts {
+		opt(ds)
+	}
+
+	return ds, nil
+}
+
+// Host returns Postgresql server, which is used by default
+func (ds *DomainService) Host() Host {
+	return Host{Name: ds.dbServerHost, Port: ds.dbServerPort}
 }
 
-func (ds *DomainService) CreateCustomDatabaseEntity(name string) Entity {
+// CreateCustomDatabaseEntity returns database of CustomDatabase. databaseName is name of already created database,
+// database of new CustomDatabase, whose databaseName is empty, is named by naming strategy.
+func (ds *DomainService) CreateCustomDatabaseEntity(
+	namespace, customDatabaseName, databaseName string, options DatabaseOptions,
+) Entity {
+	name := databaseName
+	if name == "" {
+		name = ds.databaseName(namespace, customDatabaseName)
+	}
+	options.Owner = name
+
+	// Databases with custom encoding or locale can be copied only from template0, because template1 may contain
+	// encoding-specific or locale-specific data
+	if options.Template == "" && (options.Encoding != "" || options.LcCollate != "" || options.LcCtype != "") {
+		options.Template = "template0"
+	}
+
 	return Entity{
 		Host: Host{
 			Name: ds.dbServerHost,
@@ -54,6 +132,8 @@ func (ds *DomainService) CreateCustomDatabaseEntity(name string) Entity {
 			User: name,
 			// todo create random password
 			Password: name + name + name,
+			Options:  options,
+			Limits:   Limits{ConnectionLimit: NoConnectionLimit},
 		},
 	}
 }
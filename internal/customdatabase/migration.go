@@ -0,0 +1,174 @@
+package customdatabase
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// ErrMigrationsDirty is returned, when the last migration was interrupted outside of controller and database should
+// be fixed manually
+var ErrMigrationsDirty = fmt.Errorf("database is dirty after failed migration, fix it and reset version manually")
+
+// ErrMigrationVersionNotFound is returned, when current or target version of database isn't found in migrations
+var ErrMigrationVersionNotFound = fmt.Errorf("migration version doesn't exist")
+
+// ErrMigrationIrreversible is returned, when migration without down script should be reverted
+var ErrMigrationIrreversible = fmt.Errorf("migration has no down script")
+
+// NoMigrationVersion version of database without applied migrations
+const NoMigrationVersion int64 = 0
+
+// migrationFileName format of migration files of golang-migrate: {version}_{title}.up.sql and {version}_{title}.down.sql
+var migrationFileName = regexp.MustCompile(`^([0-9]+)_(.*)\.(up|down)\.sql$`) // nolint: gochecknoglobals
+
+// Migration versioned change of database schema
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	// Down reverts Up, migration without down script can't be reverted
+	Down    string
+	HasDown bool
+}
+
+// MigrationStep execution of up or down script of migration
+type MigrationStep struct {
+	Version int64
+	Down    bool
+	Script  string
+	// ResultVersion version of database after step
+	ResultVersion int64
+}
+
+// MigrationError failure of migration step
+type MigrationError struct {
+	Version int64
+	Down    bool
+	Err     error
+}
+
+func (e *MigrationError) Error() string {
+	direction := "up"
+	if e.Down {
+		direction = "down"
+	}
+
+	return fmt.Sprintf("migration %d (%s) failed: %v", e.Version, direction, e.Err)
+}
+
+func (e *MigrationError) Unwrap() error {
+	return e.Err
+}
+
+// ParseMigrations collects migrations from files by their names. Files with other names are ignored, e.g. README.
+func ParseMigrations(files map[string]string) ([]Migration, error) {
+	byVersion := make(map[int64]*Migration)
+	hasUp := make(map[int64]bool)
+
+	for fileName, content := range files {
+		match := migrationFileName.FindStringSubmatch(fileName)
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= NoMigrationVersion {
+			return nil, fmt.Errorf("migration %s has invalid version", fileName)
+		}
+
+		migration, isExists := byVersion[version]
+		if !isExists {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migrations %s and %s have the same version", migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up, hasUp[version] = content, true
+		} else {
+			migration.Down, migration.HasDown = content, true
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for version, migration := range byVersion {
+		if !hasUp[version] {
+			return nil, fmt.Errorf("migration %d has no up script", version)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// LatestMigrationVersion returns version of the last migration, migrations are sorted by version
+func LatestMigrationVersion(migrations []Migration) int64 {
+	if len(migrations) == 0 {
+		return NoMigrationVersion
+	}
+
+	return migrations[len(migrations)-1].Version
+}
+
+// PlanMigrations returns steps, which move database from current version to target one. Migrations are applied up
+// in ascending order or reverted down in descending order.
+func PlanMigrations(migrations []Migration, current, target int64) ([]MigrationStep, error) {
+	if !hasMigrationVersion(migrations, target) {
+		return nil, fmt.Errorf("target version %d: %w", target, ErrMigrationVersionNotFound)
+	}
+	if !hasMigrationVersion(migrations, current) {
+		return nil, fmt.Errorf("current version %d of database: %w", current, ErrMigrationVersionNotFound)
+	}
+
+	var steps []MigrationStep
+	if target >= current {
+		for _, migration := range migrations {
+			if migration.Version > current && migration.Version <= target {
+				steps = append(steps, MigrationStep{
+					Version: migration.Version, Script: migration.Up, ResultVersion: migration.Version,
+				})
+			}
+		}
+
+		return steps, nil
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		migration := migrations[i]
+		if migration.Version <= target || migration.Version > current {
+			continue
+		}
+		if !migration.HasDown {
+			return nil, fmt.Errorf("migration %d: %w", migration.Version, ErrMigrationIrreversible)
+		}
+
+		previous := NoMigrationVersion
+		if i > 0 {
+			previous = migrations[i-1].Version
+		}
+		steps = append(steps, MigrationStep{
+			Version: migration.Version, Down: true, Script: migration.Down, ResultVersion: previous,
+		})
+	}
+
+	return steps, nil
+}
+
+func hasMigrationVersion(migrations []Migration, version int64) bool {
+	if version == NoMigrationVersion {
+		return true
+	}
+
+	for _, migration := range migrations {
+		if migration.Version == version {
+			return true
+		}
+	}
+
+	return false
+}
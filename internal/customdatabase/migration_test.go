@@ -0,0 +1,58 @@
+package customdatabase
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPlanMigrations(t *testing.T) {
+	migrations, err := ParseMigrations(map[string]string{
+		"1_create_users.up.sql":   "CREATE TABLE users (id int);",
+		"1_create_users.down.sql": "DROP TABLE users;",
+		"3_add_name.up.sql":       "ALTER TABLE users ADD name text;",
+		"3_add_name.down.sql":     "ALTER TABLE users DROP name;",
+		"7_create_index.up.sql":   "CREATE INDEX users_name ON users (name);",
+		"README.md":               "not a migration",
+		"7_create_index.down.txt": "not a migration",
+		"10_seed_admins.up.sql":   "INSERT INTO users VALUES (1, 'admin');",
+		"10_seed_admins.down.sql": "DELETE FROM users WHERE id = 1;",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest := LatestMigrationVersion(migrations); latest != 10 {
+		t.Errorf("latest version should be 10, given %d", latest)
+	}
+
+	up, err := PlanMigrations(migrations, 1, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedUp := []MigrationStep{
+		{Version: 3, Script: "ALTER TABLE users ADD name text;", ResultVersion: 3},
+		{Version: 7, Script: "CREATE INDEX users_name ON users (name);", ResultVersion: 7},
+	}
+	if !reflect.DeepEqual(up, expectedUp) {
+		t.Errorf("wrong up steps:\nexpected %+v\ngiven    %+v", expectedUp, up)
+	}
+
+	down, err := PlanMigrations(migrations, 3, NoMigrationVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedDown := []MigrationStep{
+		{Version: 3, Down: true, Script: "ALTER TABLE users DROP name;", ResultVersion: 1},
+		{Version: 1, Down: true, Script: "DROP TABLE users;", ResultVersion: NoMigrationVersion},
+	}
+	if !reflect.DeepEqual(down, expectedDown) {
+		t.Errorf("wrong down steps:\nexpected %+v\ngiven    %+v", expectedDown, down)
+	}
+
+	if _, err = PlanMigrations(migrations, 10, 3); !errors.Is(err, ErrMigrationIrreversible) {
+		t.Errorf("migration without down script shouldn't be reverted, given %v", err)
+	}
+	if _, err = PlanMigrations(migrations, 1, 5); !errors.Is(err, ErrMigrationVersionNotFound) {
+		t.Errorf("unknown target version should be rejected, given %v", err)
+	}
+}
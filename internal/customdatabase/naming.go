@@ -0,0 +1,67 @@
+package customdatabase
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// NamingStrategy how database and its owner role are named after CustomDatabase
+type NamingStrategy string
+
+const (
+	// NamingStrategyName database is named as CustomDatabase, CustomDatabases of different namespaces can't share name
+	NamingStrategyName NamingStrategy = "name"
+	// NamingStrategyNamespaceName database is named <namespace>_<name>. Kubernetes names have no underscores, so
+	// databases of different namespaces never clash.
+	NamingStrategyNamespaceName NamingStrategy = "namespace-name"
+
+	// identifierHashLength amount of hex digits of hash, that suffixes cut name
+	identifierHashLength = 8
+)
+
+// ParseNamingStrategy returns naming strategy by its name
+func ParseNamingStrategy(strategy string) (NamingStrategy, error) {
+	switch NamingStrategy(strategy) {
+	case NamingStrategyName, NamingStrategyNamespaceName:
+		return NamingStrategy(strategy), nil
+	default:
+		return "", fmt.Errorf(
+			"unknown naming strategy %q, expected %q or %q", strategy, NamingStrategyName, NamingStrategyNamespaceName,
+		)
+	}
+}
+
+// DomainServiceOption configures optional behaviour of DomainService
+type DomainServiceOption func(*DomainService)
+
+// WithNamingStrategy sets how databases and owner roles of new CustomDatabases are named. Name of existing database is
+// passed to CreateCustomDatabaseEntity by caller, so strategy doesn't rename it.
+func WithNamingStrategy(strategy NamingStrategy) DomainServiceOption {
+	return func(ds *DomainService) {
+		ds.namingStrategy = strategy
+	}
+}
+
+// databaseName returns name of database and its owner role of new CustomDatabase
+func (ds *DomainService) databaseName(namespace, name string) string {
+	if ds.namingStrategy == NamingStrategyNamespaceName {
+		return shortenIdentifier(namespace + "_" + name)
+	}
+
+	return shortenIdentifier(name)
+}
+
+// shortenIdentifier returns name, that fits into identifier of Postgresql. Longer name is cut and suffixed by hash of
+// the whole name, so long names with the same beginning don't clash.
+func shortenIdentifier(name string) string {
+	if len(name) <= maxIdentifierLength {
+		return name
+	}
+
+	hash := sha256.Sum256([]byte(name))
+	suffix := "_" + hex.EncodeToString(hash[:])[:identifierHashLength]
+
+	// names of Kubernetes objects are ASCII, so cut doesn't split characters
+	return name[:maxIdentifierLength-len(suffix)] + suffix
+}
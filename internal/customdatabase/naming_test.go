@@ -0,0 +1,30 @@
+package customdatabase
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNameOfDatabase(t *testing.T) {
+	ds, err := NewDomainService("localhost", 5432, "", WithNamingStrategy(NamingStrategyNamespaceName))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if name := ds.CreateCustomDatabaseEntity("team", "orders", "", DatabaseOptions{}).Database.Name; name != "team_orders" {
+		t.Errorf("new database should be named by strategy, given %s", name)
+	}
+	if name := ds.CreateCustomDatabaseEntity("team", "orders", "orders", DatabaseOptions{}).Database.Name; name != "orders" {
+		t.Errorf("existing database shouldn't be renamed, given %s", name)
+	}
+
+	prefix := strings.Repeat("a", 60)
+	first := ds.CreateCustomDatabaseEntity("team", prefix+"-first", "", DatabaseOptions{}).Database
+	second := ds.CreateCustomDatabaseEntity("team", prefix+"-second", "", DatabaseOptions{}).Database
+	if len(first.Name) != maxIdentifierLength || len(second.Name) != maxIdentifierLength {
+		t.Errorf("long names should be cut to %d bytes, given %s and %s", maxIdentifierLength, first.Name, second.Name)
+	}
+	if first.Name == second.Name || first.User != first.Name {
+		t.Errorf("cut names should differ by hash and be used by role, given %+v and %+v", first, second)
+	}
+}
@@ -0,0 +1,112 @@
+package customdatabase
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// OwnershipMarkerManagedBy value of OwnershipMarker.ManagedBy, it distinguishes markers from other comments
+const OwnershipMarkerManagedBy = "customdatabase-controller"
+
+// Kinds of resources, which own databases and roles
+const (
+	OwnerKindCustomDatabase = "CustomDatabase"
+	OwnerKindDatabaseUser   = "DatabaseUser"
+	OwnerKindDatabaseGrant  = "DatabaseGrant"
+)
+
+// OwnershipMarker is stored in comment of database and role on Postgresql server, so owner of them is known from the
+// server alone
+type OwnershipMarker struct {
+	ManagedBy string `json:"managedBy"`
+	// Cluster name of Kubernetes cluster, several clusters may share one Postgresql server
+	Cluster   string `json:"cluster,omitempty"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	UID       string `json:"uid"`
+	// Adopted is true for database or role, that existed before CustomDatabase
+	Adopted bool `json:"adopted,omitempty"`
+	// Kept is true for database and role of paused CustomDatabase, they aren't dropped, when CustomDatabase is deleted
+	Kept bool `json:"kept,omitempty"`
+}
+
+// NewOwnershipMarker returns marker of objects of resource in cluster of controller
+func (ds *DomainService) NewOwnershipMarker(kind, namespace, name, uid string) OwnershipMarker {
+	return OwnershipMarker{
+		ManagedBy: OwnershipMarkerManagedBy,
+		Cluster:   ds.clusterName,
+		Kind:      kind,
+		Namespace: namespace,
+		Name:      name,
+		UID:       uid,
+	}
+}
+
+// IsOwnCluster reports whether marker was written by controller of the same cluster. Objects of other clusters, which
+// share Postgresql server, are never touched.
+func (ds *DomainService) IsOwnCluster(marker OwnershipMarker) bool {
+	return marker.Cluster == ds.clusterName
+}
+
+// ParseOwnershipMarker parses comment of object. Comment, that isn't ownership marker, e.g. written by human, gives
+// nil marker.
+func ParseOwnershipMarker(comment string) *OwnershipMarker {
+	var marker OwnershipMarker
+	if err := json.Unmarshal([]byte(comment), &marker); err != nil || marker.ManagedBy != OwnershipMarkerManagedBy {
+		return nil
+	}
+	// the first markers were written only for CustomDatabase and had no kind
+	if marker.Kind == "" {
+		marker.Kind = OwnerKindCustomDatabase
+	}
+
+	return &marker
+}
+
+// String returns marker as comment of object
+func (m OwnershipMarker) String() string {
+	comment, _ := json.Marshal(m)
+
+	return string(comment)
+}
+
+// IsOwnedBy reports whether marker belongs to the same resource as owner marker. UID isn't compared, because resource
+// may be recreated with the same name, e.g. from backup of cluster.
+func (m OwnershipMarker) IsOwnedBy(owner OwnershipMarker) bool {
+	return m.Cluster == owner.Cluster && m.Kind == owner.Kind && m.Namespace == owner.Namespace && m.Name == owner.Name
+}
+
+// Owner returns human-readable owner of marker
+func (m OwnershipMarker) Owner() string {
+	if m.Cluster == "" {
+		return fmt.Sprintf("%s %s/%s", m.Kind, m.Namespace, m.Name)
+	}
+
+	return fmt.Sprintf("%s %s/%s in cluster %s", m.Kind, m.Namespace, m.Name, m.Cluster)
+}
+
+// Ownership of database or role on Postgresql server
+type Ownership struct {
+	// Exists is false, when object doesn't exist yet
+	Exists bool
+	// Marker is nil, when object has no ownership marker, e.g. it was created manually
+	Marker *OwnershipMarker
+}
+
+// IsOwnedBy reports whether object belongs to resource of owner marker or doesn't exist yet
+func (o Ownership) IsOwnedBy(owner OwnershipMarker) bool {
+	return !o.Exists || o.Marker != nil && o.Marker.IsOwnedBy(owner)
+}
+
+// CheckDrop returns ErrNotOwned, when existing object can't be dropped on behalf of owner
+func (o Ownership) CheckDrop(object string, owner OwnershipMarker) error {
+	switch {
+	case o.IsOwnedBy(owner):
+		return nil
+	case o.Marker == nil:
+		return fmt.Errorf("%w: %s has no ownership marker", ErrNotOwned, object)
+	default:
+		return fmt.Errorf("%w: %s belongs to %s", ErrNotOwned, object, o.Marker.Owner())
+	}
+}
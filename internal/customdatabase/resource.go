@@ -0,0 +1,33 @@
+package customdatabase
+
+import "context"
+
+type resourceContextKey struct{}
+
+// ContextWithResource stores resource, which is synced with context, e.g. "CustomDatabase default/test". It lets
+// adapters attribute their actions to resource, e.g. in dry-run plan.
+func ContextWithResource(ctx context.Context, kind, key string) context.Context {
+	return context.WithValue(ctx, resourceContextKey{}, kind+" "+key)
+}
+
+// ResourceFromContext returns resource stored by ContextWithResource or empty string
+func ResourceFromContext(ctx context.Context) string {
+	resource, _ := ctx.Value(resourceContextKey{}).(string)
+
+	return resource
+}
+
+type serverContextKey struct{}
+
+// ContextWithServer stores name of Postgresql server, which database manager should use for calls with context.
+// Empty name means the default server.
+func ContextWithServer(ctx context.Context, server string) context.Context {
+	return context.WithValue(ctx, serverContextKey{}, server)
+}
+
+// ServerFromContext returns server stored by ContextWithServer or empty string for the default server
+func ServerFromContext(ctx context.Context) string {
+	server, _ := ctx.Value(serverContextKey{}).(string)
+
+	return server
+}
@@ -0,0 +1,28 @@
+package customdatabase
+
+import "fmt"
+
+// InitScript SQL script, that is applied to database once
+type InitScript struct {
+	// Name identifies script, script with the same name isn't applied again
+	Name string
+	// Checksum of content, it shows, that script was changed after it was applied
+	Checksum string
+	Content  string
+}
+
+// ScriptError failure of SQL script, that points to the failed statement
+type ScriptError struct {
+	// Line number of the first line of statement in script, starting from 1
+	Line      int
+	Statement string
+	Err       error
+}
+
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf("statement at line %d failed: %v", e.Line, e.Err)
+}
+
+func (e *ScriptError) Unwrap() error {
+	return e.Err
+}
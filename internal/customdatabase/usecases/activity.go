@@ -0,0 +1,132 @@
+package usecases
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/custom-database/internal/customdatabase"
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+)
+
+const (
+	defaultActivitySamplingPeriod = 10 * time.Minute
+
+	// ownActivitySettlePeriod server flushes statistics of closed sessions asynchronously, so counters are sampled
+	// again not earlier than this period after own connection of controller
+	ownActivitySettlePeriod = 10 * time.Second
+)
+
+// activitySample counters of pg_stat_database at the moment of sample
+type activitySample struct {
+	counters customdatabase.DatabaseActivity
+	// ownConnectionAt time, when controller connected to database after sample. Commits since sample may be made by
+	// controller itself, e.g. by security audit, so they aren't taken for activity of tenant.
+	ownConnectionAt time.Time
+	sampledAt       time.Time
+}
+
+// OwnSessions records, when controllers of DatabaseUser, DatabaseGrant, DatabaseBackup and DatabaseRestore connected
+// to database of CustomDatabase last time. Their sessions commit transactions too, so counters of such database aren't
+// compared with the previous sample, like after own connection of CustomDatabase controller.
+type OwnSessions struct {
+	mu       sync.Mutex
+	openedAt map[string]time.Time
+}
+
+func NewOwnSessions() *OwnSessions {
+	return &OwnSessions{openedAt: make(map[string]time.Time)}
+}
+
+// touch records session of controller in database of CustomDatabase namespace/name
+func (s *OwnSessions) touch(namespace, name string, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.openedAt[namespace+"/"+name] = now
+}
+
+// lastOpenedAt returns time of the last session in database of CustomDatabase by its key
+func (s *OwnSessions) lastOpenedAt(key string) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.openedAt[key]
+}
+
+// actualizeActivity samples counters of database once per sampling period and publishes time of the last activity
+// in status
+func (c *Controller) actualizeActivity(
+	ctx context.Context, customDatabaseReq *v1.CustomDatabase, database string, newStatus *v1.CustomDatabaseStatus,
+) error {
+	key := customDatabaseReq.Namespace + "/" + customDatabaseReq.Name
+	now := c.clock.Now()
+
+	// session of another controller after sample is handled like own connection
+	if sample, ok := c.activitySamples.get(key); ok && sample.ownConnectionAt.IsZero() {
+		if openedAt := c.ownSessions.lastOpenedAt(key); openedAt.After(sample.sampledAt) {
+			sample.ownConnectionAt = openedAt
+			c.activitySamples.update(key, sample)
+		}
+	}
+
+	if sample, ok := c.activitySamples.get(key); ok && !sample.ownConnectionAt.IsZero() {
+		if now.Sub(sample.ownConnectionAt) < ownActivitySettlePeriod {
+			return nil
+		}
+		return c.sampleActivity(ctx, key, database, newStatus)
+	}
+	if _, ok := c.activitySamples.getFresh(key, now, c.activitySamplingPeriod); ok {
+		return nil
+	}
+
+	return c.sampleActivity(ctx, key, database, newStatus)
+}
+
+// beforeOwnConnection is called before controller connects to database itself. Activity of tenant is sampled just
+// before connection, and the next sample doesn't compare commits with this one.
+func (c *Controller) beforeOwnConnection(
+	ctx context.Context, customDatabaseReq *v1.CustomDatabase, database string, newStatus *v1.CustomDatabaseStatus,
+) error {
+	key := customDatabaseReq.Namespace + "/" + customDatabaseReq.Name
+
+	if sample, ok := c.activitySamples.get(key); !ok || sample.ownConnectionAt.IsZero() {
+		if err := c.sampleActivity(ctx, key, database, newStatus); err != nil {
+			return err
+		}
+	}
+
+	sample, _ := c.activitySamples.get(key)
+	sample.ownConnectionAt = c.clock.Now()
+	c.activitySamples.update(key, sample)
+
+	return nil
+}
+
+// sampleActivity reads counters of database and moves time of the last activity, if database was used since
+// previous sample. Database without known activity is considered used at the moment of the first sample.
+func (c *Controller) sampleActivity(
+	ctx context.Context, key, database string, newStatus *v1.CustomDatabaseStatus,
+) error {
+	now := c.clock.Now()
+
+	counters, err := c.databaseManager.GetDatabaseActivity(ctx, database)
+	if err != nil {
+		return err
+	}
+
+	var previousCounters *customdatabase.DatabaseActivity
+	if previous, ok := c.activitySamples.get(key); ok && previous.ownConnectionAt.IsZero() {
+		previousCounters = &previous.counters
+	}
+	c.activitySamples.set(key, activitySample{counters: counters, sampledAt: now}, now)
+
+	if newStatus.LastActivityAt == nil || counters.IsActiveSince(previousCounters) {
+		lastActivityAt := metav1.NewTime(now)
+		newStatus.LastActivityAt = &lastActivityAt
+	}
+
+	return nil
+}
@@ -29,6 +29,53 @@ func (c *Controller) addOrUpdateHandler(
 		utilruntime.HandleError(fmt.Errorf("%s: secretName name must be specified", customDatabaseReq.Name))
 		return nil
 	}
+	if template := customDatabaseReq.Spec.DatabaseOptions.Template; template != "" &&
+		!customdatabase.IsTemplateAllowed(template) {
+		utilruntime.HandleError(fmt.Errorf("%s: template %q isn't allowed, only template0 and template1 may be used, "+
+			"use cloneFrom to copy another database", customDatabaseReq.Name, template,
+		))
+		return nil
+	}
+	if cloneFrom := customDatabaseReq.Spec.CloneFrom; cloneFrom != nil {
+		if customDatabaseReq.Spec.DatabaseOptions.Template != "" {
+			utilruntime.HandleError(fmt.Errorf("%s: cloneFrom and template can't be used together", customDatabaseReq.Name))
+			return nil
+		}
+		if cloneFrom.Name == customDatabaseReq.Name && cloneSourceNamespace(customDatabaseReq) == customDatabaseReq.Namespace {
+			utilruntime.HandleError(fmt.Errorf("%s: database can't be cloned from itself", customDatabaseReq.Name))
+			return nil
+		}
+	}
+
+	if _, ok := c.serverHost(c.desiredServer(customDatabaseReq)); !ok {
+		utilruntime.HandleError(fmt.Errorf("%s: unknown server %q in serverRef", customDatabaseReq.Name,
+			customDatabaseReq.Spec.ServerRef,
+		))
+		return nil
+	}
+
+	// paused CustomDatabase isn't changed, even when it's expired
+	if isCustomDatabasePaused(customDatabaseReq) || c.readOnly {
+		return c.syncPausedCustomDatabase(ctx, customDatabaseReq)
+	}
+
+	expiresAt, err := expiryOfCustomDatabase(customDatabaseReq)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("%s: %w", customDatabaseReq.Name, err))
+		return nil
+	}
+	// expired CustomDatabase isn't provisioned again, it's deleted
+	if expiresAt != nil && !c.clock.Now().Before(*expiresAt) {
+		return c.deleteExpiredCustomDatabase(ctx, customDatabaseReq)
+	}
+
+	newStatus := customDatabaseReq.Status.DeepCopy()
+	cloneSource, err := c.cloneSourceOfCustomDatabase(ctx, customDatabaseReq, newStatus)
+	if err == errCloneNotAllowed {
+		return c.updateCustomDatabaseStatus(ctx, customDatabaseReq, newStatus)
+	} else if err != nil {
+		return err
+	}
 
 	// Get the secret with the name specified in CustomDatabase.spec
 	storedSecret, err := c.secretLister.Secrets(customDatabaseReq.Namespace).Get(customDatabaseReq.Spec.SecretName)
@@ -39,18 +86,150 @@ func (c *Controller) addOrUpdateHandler(
 	}
 
 	isSecretNotExists := storedSecret == nil
-	customDatabase := c.domainService.CreateCustomDatabaseEntity(customDatabaseReq.Name)
+	customDatabase := c.domainService.CreateCustomDatabaseEntity(
+		customDatabaseReq.Namespace, customDatabaseReq.Name, databaseNameOfCustomDatabase(customDatabaseReq, storedSecret),
+		databaseOptionsFromSpec(customDatabaseReq.Spec.DatabaseOptions),
+	)
+	customDatabase.Database.Limits = limitsFromSpec(customDatabaseReq.Spec.Limits)
+
+	// database is managed on server, where it lives now, until migration to server of spec.serverRef is switched
+	customDatabase.Server = c.serverOfCustomDatabase(customDatabaseReq, storedSecret)
+	if host, ok := c.serverHost(customDatabase.Server); ok {
+		customDatabase.Host = host
+	}
+	ctx = customdatabase.ContextWithServer(ctx, customDatabase.Server)
+
+	// name is kept, so change of naming strategy doesn't rename database
+	newStatus.DatabaseName = customDatabase.Database.Name
+	c.actualizePause(customDatabaseReq, newStatus)
+
+	// hibernated database isn't provisioned, otherwise CONNECT would be granted again or dropped database recreated
+	isHibernated, err := c.syncHibernatedDatabase(ctx, customDatabaseReq, customDatabase.Database, newStatus)
+	if err != nil {
+		return err
+	}
+	if isHibernated {
+		c.actualizeExpiry(customDatabaseReq, expiresAt, newStatus)
+		return c.updateCustomDatabaseStatus(ctx, customDatabaseReq, newStatus)
+	}
+
+	// database is moved to server of spec.serverRef before it's provisioned there
+	newStatus.Server = customDatabase.Server
+	isMigrating, err := c.syncServerMigration(ctx, customDatabaseReq, customDatabase, storedSecret, newStatus)
+	if err != nil {
+		return err
+	}
+	if isMigrating {
+		c.actualizeExpiry(customDatabaseReq, expiresAt, newStatus)
+		return c.updateCustomDatabaseStatus(ctx, customDatabaseReq, newStatus)
+	}
+
+	// database or role with the same name may be created manually or belong to another CustomDatabase
+	isOwned, err := c.actualizeOwnership(ctx, customDatabaseReq, customDatabase, storedSecret, newStatus)
+	if err != nil {
+		return err
+	}
+	if !isOwned {
+		return c.updateCustomDatabaseStatus(ctx, customDatabaseReq, newStatus)
+	}
+
+	// manual changes on server are looked for before provisioning, which would overwrite some of them. Password is
+	// known only from Secret, without Secret it's set again by provisioning anyway.
+	if storedSecret != nil {
+		isInSync, err := c.actualizeDrift(ctx, customDatabaseReq, customDatabase, storedSecret, newStatus)
+		if err != nil {
+			return err
+		}
+		if !isInSync {
+			c.actualizeExpiry(customDatabaseReq, expiresAt, newStatus)
+			return c.updateCustomDatabaseStatus(ctx, customDatabaseReq, newStatus)
+		}
+	}
 
 	// actualize information about Database objects
-	err = c.actualizeDatabaseInStorage(ctx, customDatabase, isSecretNotExists)
+	isDatabaseCreated, err := c.actualizeDatabaseInStorage(
+		ctx, customDatabase, c.ownershipMarker(customDatabaseReq), cloneSource, isSecretNotExists,
+	)
 	if err != nil {
 		return err
 	}
 
 	// After all object in Database was created successful, store information about created database is Secret
-	err = c.actualizeSecretInStorage(
-		ctx, isSecretNotExists, secretWithDBInfo(newEmptySecret(customDatabaseReq), customDatabase),
-	)
+	secretNewState := secretWithDBInfo(newEmptySecret(customDatabaseReq), customDatabase)
+	if isSecretNotExists {
+		secretNewState, err = secretWithTLS(secretNewState, nil, c.tenantTLS, customDatabase.Database.User,
+			c.clock.Now(),
+		)
+	} else {
+		err = c.actualizeSecretTLS(ctx, storedSecret, customDatabase.Database.User)
+	}
+	if err != nil {
+		return err
+	}
+	err = c.actualizeSecretInStorage(ctx, isSecretNotExists, secretNewState)
+	if err != nil {
+		return err
+	}
+
+	// Limits can be changed by tenant (he is owner of database), so we show values, that are really applied
+	effectiveLimits, err := c.databaseManager.GetDatabaseLimits(ctx, customDatabase.Database.Name)
+	if err != nil {
+		return err
+	}
+	newStatus.Limits = limitsToStatus(effectiveLimits)
+
+	if cloneSource != nil {
+		// source isn't needed anymore, it may be changed or deleted
+		message := fmt.Sprintf("Database was cloned from %s/%s",
+			cloneSourceNamespace(customDatabaseReq), customDatabaseReq.Spec.CloneFrom.Name,
+		)
+		c.setCondition(newStatus, customDatabaseReq, v1.ConditionCloned, metav1.ConditionTrue, DatabaseCloned, message)
+		if isDatabaseCreated {
+			c.recorder.Event(customDatabaseReq, corev1.EventTypeNormal, DatabaseCloned, message)
+		}
+	}
+
+	err = c.actualizeStorageQuota(ctx, customDatabaseReq, customDatabase.Database.Name, newStatus)
+	if err != nil {
+		return err
+	}
+
+	// activity is sampled before own connections of controller below
+	err = c.actualizeActivity(ctx, customDatabaseReq, customDatabase.Database.Name, newStatus)
+	if err != nil {
+		return err
+	}
+
+	err = c.actualizeDatabaseSecurity(ctx, customDatabaseReq, customDatabase, isDatabaseCreated, newStatus)
+	if err != nil {
+		return err
+	}
+
+	// Scripts are run by owner with credentials, that tenant really has
+	owner := customDatabase
+	if !isSecretNotExists {
+		if owner, err = entityFromSecret(storedSecret); err != nil {
+			return err
+		}
+	}
+	err = c.actualizeInitScripts(ctx, customDatabaseReq, owner, newStatus)
+	if err != nil {
+		return err
+	}
+
+	err = c.actualizeMigrations(ctx, customDatabaseReq, owner, newStatus)
+	if err != nil {
+		return err
+	}
+
+	err = c.actualizeHibernation(ctx, customDatabaseReq, customDatabase.Database, newStatus)
+	if err != nil {
+		return err
+	}
+
+	c.actualizeExpiry(customDatabaseReq, expiresAt, newStatus)
+
+	err = c.updateCustomDatabaseStatus(ctx, customDatabaseReq, newStatus)
 	if err != nil {
 		return err
 	}
@@ -60,21 +239,15 @@ func (c *Controller) addOrUpdateHandler(
 }
 
 func (c *Controller) actualizeDatabaseInStorage(
-	ctx context.Context, customDatabase customdatabase.Entity, isSecretNotExists bool,
-) error {
+	ctx context.Context, customDatabase customdatabase.Entity, marker customdatabase.OwnershipMarker,
+	cloneSource *customdatabase.Database, isSecretNotExists bool,
+) (bool, error) {
 	var err error
+	isDatabaseCreated := false
 	logger := loggerFromHandlerContext(ctx)
 
-	// Create database in Postgresql
-	err = c.databaseManager.CreateDatabase(ctx, customDatabase.Database.Name)
-	if err == customdatabase.ErrDatabaseAlreadyExists {
-		logger.Info("database already exists", "db_name", customDatabase.Database.Name)
-	} else if err != nil {
-		return err
-	}
-
 	// Create Postgresql user for given CustomDatabase
-	err = c.databaseManager.CreateUser(ctx, customDatabase.Database.User, customDatabase.Database.Password)
+	err = c.databaseManager.CreateUser(ctx, customDatabase.Database.User, customDatabase.Database.Password, marker)
 	if err == customdatabase.ErrUserAlreadyExists {
 		logger.Info("user already exists", "user_name", customDatabase.Database.User)
 		if isSecretNotExists {
@@ -84,7 +257,7 @@ func (c *Controller) actualizeDatabaseInStorage(
 
 			err = c.databaseManager.ChangeUserPassword(ctx, customDatabase.Database.User, customDatabase.Database.Password)
 			if err != nil {
-				return err
+				return false, err
 			}
 		} else {
 			logger.Info("user already stored in actual secret",
@@ -92,16 +265,40 @@ func (c *Controller) actualizeDatabaseInStorage(
 			)
 		}
 	} else if err != nil {
-		return err
+		return false, err
 	}
 
-	// Connect user with database - grant all privileges to database for user
+	// Create database in Postgresql. User should be created before, because he is the owner of database
+	if cloneSource != nil {
+		err = c.databaseManager.CloneDatabase(
+			ctx, customDatabase.Database.Name, *cloneSource, customDatabase.Database.Options, marker,
+		)
+	} else {
+		err = c.databaseManager.CreateDatabase(
+			ctx, customDatabase.Database.Name, customDatabase.Database.Options, marker,
+		)
+	}
+	if err == customdatabase.ErrDatabaseAlreadyExists {
+		logger.Info("database already exists", "db_name", customDatabase.Database.Name)
+	} else if err != nil {
+		return false, err
+	} else {
+		isDatabaseCreated = true
+	}
+
+	// Connect user with database - owner has other privileges on database by ownership
 	err = c.databaseManager.GrantUserToDatabase(ctx, customDatabase.Database.User, customDatabase.Database.Name)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	return nil
+	// Limits are applied on every sync - it restores them, if somebody changed them manually
+	err = c.databaseManager.SetDatabaseLimits(ctx, customDatabase.Database.Name, customDatabase.Database.Limits)
+	if err != nil {
+		return false, err
+	}
+
+	return isDatabaseCreated, nil
 }
 
 // We only create new Secrets. If Secret exists - we expect that it contains actual CustomDatabase variables.
@@ -124,3 +321,56 @@ func (c *Controller) actualizeSecretInStorage(
 
 	return nil
 }
+
+// actualizeSecretTLS updates TLS settings and client certificate in existing Secret, other credentials are kept
+func (c *Controller) actualizeSecretTLS(ctx context.Context, storedSecret *corev1.Secret, userName string) error {
+	if c.tenantTLS == nil {
+		return nil
+	}
+
+	newSecret, err := secretWithTLS(storedSecret, storedSecret, c.tenantTLS, userName, c.clock.Now())
+	if err != nil || !isTLSOfSecretChanged(storedSecret, newSecret) {
+		return err
+	}
+
+	logger := loggerFromHandlerContext(ctx)
+	logger.Info("Update TLS settings in secret resource", "secretName", newSecret.Name)
+	_, err = c.kubeclientset.CoreV1().Secrets(newSecret.Namespace).Update(ctx, newSecret, metav1.UpdateOptions{})
+
+	return err
+}
+
+func databaseOptionsFromSpec(options v1.DatabaseOptions) customdatabase.DatabaseOptions {
+	return customdatabase.DatabaseOptions{
+		Encoding:   options.Encoding,
+		LcCollate:  options.LcCollate,
+		LcCtype:    options.LcCtype,
+		Template:   options.Template,
+		Tablespace: options.Tablespace,
+	}
+}
+
+func limitsFromSpec(limits v1.DatabaseLimits) customdatabase.Limits {
+	connectionLimit := customdatabase.NoConnectionLimit
+	if limits.ConnectionLimit != nil {
+		connectionLimit = int(*limits.ConnectionLimit)
+	}
+
+	return customdatabase.Limits{
+		ConnectionLimit:                 connectionLimit,
+		StatementTimeout:                limits.StatementTimeout,
+		IdleInTransactionSessionTimeout: limits.IdleInTransactionSessionTimeout,
+		WorkMem:                         limits.WorkMem,
+	}
+}
+
+func limitsToStatus(limits customdatabase.Limits) v1.DatabaseLimits {
+	connectionLimit := int32(limits.ConnectionLimit)
+
+	return v1.DatabaseLimits{
+		ConnectionLimit:                 &connectionLimit,
+		StatementTimeout:                limits.StatementTimeout,
+		IdleInTransactionSessionTimeout: limits.IdleInTransactionSessionTimeout,
+		WorkMem:                         limits.WorkMem,
+	}
+}
@@ -0,0 +1,59 @@
+package usecases
+
+import (
+	"context"
+	"sync"
+)
+
+// backgroundTasks runs long operations, e.g. dumps, outside of workers, so workers aren't blocked by them.
+// Tasks are identified by key of resource, they are cancelled when the resource is deleted.
+type backgroundTasks struct {
+	mu      sync.Mutex
+	running map[string]context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func newBackgroundTasks() *backgroundTasks {
+	return &backgroundTasks{running: make(map[string]context.CancelFunc)}
+}
+
+// start runs task in goroutine, context of task is cancelled with parent context or by cancel
+func (t *backgroundTasks) start(ctx context.Context, key string, task func(ctx context.Context)) {
+	taskCtx, cancel := context.WithCancel(ctx)
+
+	t.mu.Lock()
+	t.running[key] = cancel
+	t.mu.Unlock()
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		defer t.cancel(key)
+
+		task(taskCtx)
+	}()
+}
+
+func (t *backgroundTasks) isRunning(key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, isRunning := t.running[key]
+	return isRunning
+}
+
+// cancel stops task, if there is any
+func (t *backgroundTasks) cancel(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if cancel, isRunning := t.running[key]; isRunning {
+		cancel()
+		delete(t.running, key)
+	}
+}
+
+// wait blocks until all tasks are finished
+func (t *backgroundTasks) wait() {
+	t.wg.Wait()
+}
@@ -0,0 +1,81 @@
+package usecases
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// checksumHoldBack amount of the last bytes of dump, which are held back until its checksum is verified
+const checksumHoldBack = 4096
+
+// checksumReader passes dump to restore and verifies its checksum at the end of dump. The tail of dump is passed
+// only after checksum is verified, so restore can't reach the end of corrupted dump and commit it.
+type checksumReader struct {
+	reader   io.Reader
+	location string
+	expected string
+	hash     hash.Hash
+
+	buf []byte
+	// pending bytes, which are read from dump, but aren't passed yet
+	pending []byte
+	// err io.EOF after verified dump, error of dump or mismatch of checksum
+	err error
+}
+
+func newChecksumReader(reader io.Reader, location, expected string) *checksumReader {
+	return &checksumReader{
+		reader:   reader,
+		location: location,
+		expected: expected,
+		hash:     sha256.New(),
+		buf:      make([]byte, 32*1024),
+	}
+}
+
+func (r *checksumReader) Read(p []byte) (int, error) {
+	for r.err == nil && len(r.pending) <= checksumHoldBack {
+		n, err := r.reader.Read(r.buf)
+		r.hash.Write(r.buf[:n])
+		r.pending = append(r.pending, r.buf[:n]...)
+
+		switch {
+		case err == io.EOF:
+			r.err = io.EOF
+			if actual := "sha256:" + hex.EncodeToString(r.hash.Sum(nil)); actual != r.expected {
+				r.err = fmt.Errorf("checksum of dump %s is %s, expected %s", r.location, actual, r.expected)
+			}
+		case err != nil:
+			r.err = err
+		}
+	}
+
+	if r.err != nil && r.err != io.EOF {
+		return 0, r.err
+	}
+
+	available := len(r.pending)
+	if r.err == nil {
+		available -= checksumHoldBack
+	}
+	if available == 0 {
+		return 0, r.err
+	}
+
+	n := copy(p, r.pending[:available])
+	r.pending = r.pending[n:]
+
+	return n, nil
+}
+
+// verifyErr returns error of dump or mismatch of checksum, which stopped reading
+func (r *checksumReader) verifyErr() error {
+	if r.err == io.EOF {
+		return nil
+	}
+
+	return r.err
+}
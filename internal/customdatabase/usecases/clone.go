@@ -0,0 +1,103 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+
+	"k8s.io/custom-database/internal/customdatabase"
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+)
+
+const (
+	// CloneNotAllowed is used as part of the Event 'reason' when source of clone doesn't allow cloning into namespace
+	// of CustomDatabase
+	CloneNotAllowed = "CloneNotAllowed"
+	// DatabaseCloned is used as part of the Event 'reason' when database is created as a copy of another one
+	DatabaseCloned = "Cloned"
+)
+
+// errCloneNotAllowed is returned, when CustomDatabase can't be cloned. Error is already reported by Event and Cloned
+// condition, CustomDatabase shouldn't be requeued.
+var errCloneNotAllowed = fmt.Errorf("clone isn't allowed")
+
+// cloneSourceOfCustomDatabase returns database, that should be copied into database of CustomDatabase. It returns nil,
+// if CustomDatabase isn't a clone or its database was already cloned, so source may be deleted after cloning.
+func (c *Controller) cloneSourceOfCustomDatabase(
+	ctx context.Context, customDatabaseReq *v1.CustomDatabase, newStatus *v1.CustomDatabaseStatus,
+) (*customdatabase.Database, error) {
+	cloneFrom := customDatabaseReq.Spec.CloneFrom
+	if cloneFrom == nil || meta.IsStatusConditionTrue(customDatabaseReq.Status.Conditions, v1.ConditionCloned) {
+		return nil, nil
+	}
+
+	namespace := cloneSourceNamespace(customDatabaseReq)
+	source, owner, err := ownerOfCustomDatabase(c.customDatabasesLister, c.secretLister, namespace, cloneFrom.Name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// source is created later or isn't synced yet, CustomDatabase is requeued with backoff
+			return nil, fmt.Errorf("source of clone %s/%s isn't ready: %w", namespace, cloneFrom.Name, err)
+		}
+		return nil, err
+	}
+
+	if !isCloneAllowed(source, customDatabaseReq.Namespace) {
+		message := fmt.Sprintf("CustomDatabase %s/%s doesn't allow cloning into namespace %s, see annotation %s",
+			namespace, cloneFrom.Name, customDatabaseReq.Namespace, v1.AnnotationCloneAllowedNamespaces,
+		)
+		c.recorder.Event(customDatabaseReq, corev1.EventTypeWarning, CloneNotAllowed, message)
+		c.setCondition(newStatus, customDatabaseReq, v1.ConditionCloned, metav1.ConditionFalse, CloneNotAllowed, message)
+		utilruntime.HandleError(fmt.Errorf("%s: %s", customDatabaseReq.Name, message))
+		return nil, errCloneNotAllowed
+	}
+
+	// database is copied by server itself, so both databases live on the same server
+	sourceServer := owner.Server
+	if sourceServer == "" {
+		sourceServer = c.defaultServer
+	}
+	if sourceServer != c.desiredServer(customDatabaseReq) {
+		message := fmt.Sprintf("CustomDatabase %s/%s lives on server %s, it can be cloned only on the same server",
+			namespace, cloneFrom.Name, sourceServer,
+		)
+		c.recorder.Event(customDatabaseReq, corev1.EventTypeWarning, CloneNotAllowed, message)
+		c.setCondition(newStatus, customDatabaseReq, v1.ConditionCloned, metav1.ConditionFalse, CloneNotAllowed, message)
+		utilruntime.HandleError(fmt.Errorf("%s: %s", customDatabaseReq.Name, message))
+		return nil, errCloneNotAllowed
+	}
+
+	loggerFromHandlerContext(ctx).Info("Database will be cloned", "source", namespace+"/"+cloneFrom.Name)
+
+	return &owner.Database, nil
+}
+
+// cloneSourceNamespace returns namespace of source of clone
+func cloneSourceNamespace(customDatabase *v1.CustomDatabase) string {
+	if customDatabase.Spec.CloneFrom.Namespace != "" {
+		return customDatabase.Spec.CloneFrom.Namespace
+	}
+
+	return customDatabase.Namespace
+}
+
+// isCloneAllowed reports whether database of source can be cloned into the given namespace
+func isCloneAllowed(source *v1.CustomDatabase, namespace string) bool {
+	if source.Namespace == namespace {
+		return true
+	}
+
+	for _, allowed := range strings.Split(source.Annotations[v1.AnnotationCloneAllowedNamespaces], ",") {
+		allowed = strings.TrimSpace(allowed)
+		if allowed == "*" || allowed == namespace {
+			return true
+		}
+	}
+
+	return false
+}
@@ -3,10 +3,13 @@ package usecases
 import (
 	"context"
 	"fmt"
+	"io/fs"
+	"sync"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	informerscorev1 "k8s.io/client-go/informers/core/v1"
@@ -18,6 +21,7 @@ import (
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
 
 	"k8s.io/custom-database/internal/customdatabase"
 	clientset "k8s.io/custom-database/pkg/generated/clientset/versioned"
@@ -46,6 +50,10 @@ type Controller struct {
 	secretLister listerscorev1.SecretLister
 	secretSynced cache.InformerSynced
 
+	// configMapLister ConfigMaps of init scripts and migrations
+	configMapLister listerscorev1.ConfigMapLister
+	configMapSynced cache.InformerSynced
+
 	customDatabasesLister listers.CustomDatabaseLister
 	customDatabasesSynced cache.InformerSynced
 
@@ -61,6 +69,67 @@ type Controller struct {
 
 	databaseManager DatabaseManager
 
+	clock clock.PassiveClock
+
+	storageSamplingPeriod time.Duration
+	storageSamples        *sampleCache[int64]
+
+	securityAuditPeriod time.Duration
+	securityAudits      *sampleCache[[]string]
+
+	driftCheckPeriod time.Duration
+	driftPolicy      customdatabase.DriftPolicy
+	// driftChecks differences, that were found by the last check and weren't repaired
+	driftChecks *sampleCache[[]string]
+
+	activitySamplingPeriod time.Duration
+	activitySamples        *sampleCache[activitySample]
+	// ownSessions sessions of other controllers in databases, they aren't taken for activity of tenant
+	ownSessions *OwnSessions
+
+	// expiryWarningPeriod how long before expiry of CustomDatabase warning event is emitted
+	expiryWarningPeriod time.Duration
+
+	// adoptionAllowList patterns "<namespace>/<database>" of databases, that may be adopted by spec.adopt
+	adoptionAllowList []string
+
+	// migrationsFS directory with migrations of tenants, nil if migrations can be read only from ConfigMaps
+	migrationsFS fs.FS
+
+	orphanSweepPeriod time.Duration
+	// gcOrphans enables drop of orphans, which stay orphaned longer than orphanGracePeriod
+	gcOrphans         bool
+	orphanGracePeriod time.Duration
+	// orphans found by the last sweep by object and name, they are accessed only by sweeper
+	orphans map[string]orphan
+
+	// readOnly controller only observes databases and reports their state, nothing is changed on server
+	readOnly bool
+	// pausedDeletions keys of CustomDatabases, which were paused at the moment of deletion, their databases are kept
+	pausedDeletions sync.Map
+
+	// defaultServer name of server of domain service, it's used by CustomDatabases without spec.serverRef
+	defaultServer string
+	// servers additional servers by name, databaseManager routes calls to them
+	servers map[string]customdatabase.Host
+	// deletedServers servers, where database of deleted CustomDatabase may live, by key of CustomDatabase
+	deletedServers sync.Map
+	// deletedDatabaseNames names of databases of deleted CustomDatabases by key of CustomDatabase
+	deletedDatabaseNames sync.Map
+	// dumper and restorer copy database to another server, migration is disabled without them
+	dumper   DatabaseDumper
+	restorer DatabaseRestorer
+	// serverCopies copies of databases to another server, they are made outside of workers
+	serverCopies *backgroundTasks
+	// serverCopyResults results of finished copies by key of CustomDatabase, they are taken by the next sync
+	serverCopyResults sync.Map
+	// serverGracePeriod how long closed database is kept on the old server after Secret is switched to the new one
+	serverGracePeriod time.Duration
+	// tenantTLS TLS settings, which are published to Secrets, nil means that Secrets contain only credentials
+	tenantTLS *TenantTLS
+	// rateLimiter settings of rate limiter of workqueue, they are set by options before workqueue is created
+	rateLimiter RateLimiterConfig
+
 	// we use here concrete DomainService instead of interface, because this component - is a business logic, that can't
 	// be different or changed. Also this component - pure, without any side effects.
 	domainService *customdatabase.DomainService
@@ -68,14 +137,54 @@ type Controller struct {
 
 // DatabaseManager interface of component, that encapsulated Postgresql service for management databases and roles
 type DatabaseManager interface {
-	CreateDatabase(ctx context.Context, database string) error
-	DropDatabase(ctx context.Context, database string) error
-
-	CreateUser(ctx context.Context, userName, password string) error
+	// CreateDatabase, CloneDatabase and CreateUser write ownership marker on created object, DropDatabase and DropUser
+	// return customdatabase.ErrNotOwned instead of dropping object with another marker or without it
+	CreateDatabase(
+		ctx context.Context, database string, options customdatabase.DatabaseOptions,
+		marker customdatabase.OwnershipMarker,
+	) error
+	CloneDatabase(
+		ctx context.Context, database string, source customdatabase.Database, options customdatabase.DatabaseOptions,
+		marker customdatabase.OwnershipMarker,
+	) error
+	DropDatabase(ctx context.Context, database string, owner customdatabase.OwnershipMarker) error
+
+	CreateUser(ctx context.Context, userName, password string, marker customdatabase.OwnershipMarker) error
 	ChangeUserPassword(ctx context.Context, userName, password string) error
-	DropUser(ctx context.Context, userName string) error
+	DropUser(ctx context.Context, userName string, owner customdatabase.OwnershipMarker) error
 
 	GrantUserToDatabase(ctx context.Context, userName, database string) error
+
+	SetDatabaseLimits(ctx context.Context, database string, limits customdatabase.Limits) error
+	GetDatabaseLimits(ctx context.Context, database string) (customdatabase.Limits, error)
+
+	GetDatabaseSize(ctx context.Context, database string) (int64, error)
+	SetDatabaseReadOnly(ctx context.Context, database string, readOnly bool) error
+
+	HardenDatabase(ctx context.Context, database, owner string) error
+	AuditDatabase(ctx context.Context, database, owner string) ([]string, error)
+
+	DetectDrift(ctx context.Context, database customdatabase.Database) ([]string, error)
+	RepairDrift(ctx context.Context, database customdatabase.Database) error
+
+	ApplyUserPrivileges(ctx context.Context, database customdatabase.Database, user customdatabase.DatabaseUser) error
+	RevokeUserPrivileges(ctx context.Context, database customdatabase.Database, user customdatabase.DatabaseUser) error
+
+	GetDatabaseOwnership(ctx context.Context, database string) (customdatabase.Ownership, error)
+	GetUserOwnership(ctx context.Context, userName string) (customdatabase.Ownership, error)
+	ListDatabaseOwnership(ctx context.Context) (map[string]customdatabase.OwnershipMarker, error)
+	ListUserOwnership(ctx context.Context) (map[string]customdatabase.OwnershipMarker, error)
+	SetDatabaseOwnership(ctx context.Context, database string, marker customdatabase.OwnershipMarker) error
+	SetUserOwnership(ctx context.Context, userName string, marker customdatabase.OwnershipMarker) error
+
+	GetDatabaseActivity(ctx context.Context, database string) (customdatabase.DatabaseActivity, error)
+	RevokeDatabaseConnect(ctx context.Context, database string) error
+	TerminateDatabaseSessions(ctx context.Context, database string) error
+
+	RunScript(ctx context.Context, database customdatabase.Database, script customdatabase.InitScript) (string, error)
+	Migrate(
+		ctx context.Context, database customdatabase.Database, migrations []customdatabase.Migration, target int64,
+	) (int64, error)
 }
 
 // NewController returns a new sample controller
@@ -84,35 +193,52 @@ func NewController(
 	kubeclientset kubernetes.Interface,
 	sampleclientset clientset.Interface,
 	secretInformer informerscorev1.SecretInformer,
+	configMapInformer informerscorev1.ConfigMapInformer,
 	customDatabaseInformer informers.CustomDatabaseInformer,
 	databaseManager DatabaseManager,
 	domainService *customdatabase.DomainService,
+	opts ...ControllerOption,
 ) *Controller {
 	logger := klog.FromContext(ctx)
 
-	// Create event broadcaster
-	// Add custom-database-controller types to the default Kubernetes Scheme so Events can be
-	// logged for custom-database-controller types.
-	utilruntime.Must(samplescheme.AddToScheme(scheme.Scheme))
-	logger.V(4).Info("Creating event broadcaster")
-
-	eventBroadcaster := record.NewBroadcaster()
-	eventBroadcaster.StartStructuredLogging(0)
-	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubeclientset.CoreV1().Events("")})
-	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: controllerAgentName})
+	recorder := newEventRecorder(ctx, kubeclientset)
 
 	controller := &Controller{
-		kubeclientset:         kubeclientset,
-		sampleclientset:       sampleclientset,
-		customDatabasesLister: customDatabaseInformer.Lister(),
-		customDatabasesSynced: customDatabaseInformer.Informer().HasSynced,
-		secretLister:          secretInformer.Lister(),
-		secretSynced:          secretInformer.Informer().HasSynced,
-		workqueue:             workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "CustomDatabases"),
-		recorder:              recorder,
-		databaseManager:       databaseManager,
-		domainService:         domainService,
+		kubeclientset:          kubeclientset,
+		sampleclientset:        sampleclientset,
+		customDatabasesLister:  customDatabaseInformer.Lister(),
+		customDatabasesSynced:  customDatabaseInformer.Informer().HasSynced,
+		secretLister:           secretInformer.Lister(),
+		secretSynced:           secretInformer.Informer().HasSynced,
+		configMapLister:        configMapInformer.Lister(),
+		configMapSynced:        configMapInformer.Informer().HasSynced,
+		recorder:               recorder,
+		databaseManager:        databaseManager,
+		domainService:          domainService,
+		clock:                  clock.RealClock{},
+		storageSamplingPeriod:  defaultStorageSamplingPeriod,
+		storageSamples:         newSampleCache[int64](),
+		securityAuditPeriod:    defaultSecurityAuditPeriod,
+		securityAudits:         newSampleCache[[]string](),
+		driftCheckPeriod:       defaultDriftCheckPeriod,
+		driftPolicy:            customdatabase.DriftPolicyRepair,
+		driftChecks:            newSampleCache[[]string](),
+		activitySamplingPeriod: defaultActivitySamplingPeriod,
+		activitySamples:        newSampleCache[activitySample](),
+		ownSessions:            NewOwnSessions(),
+		expiryWarningPeriod:    defaultExpiryWarningPeriod,
+		orphanSweepPeriod:      defaultOrphanSweepPeriod,
+		orphanGracePeriod:      defaultOrphanGracePeriod,
+		orphans:                make(map[string]orphan),
+		serverCopies:           newBackgroundTasks(),
+		serverGracePeriod:      defaultServerGracePeriod,
+		rateLimiter:            DefaultRateLimiterConfig(),
+	}
+
+	for _, opt := range opts {
+		opt(controller)
 	}
+	controller.workqueue = workqueue.NewNamedRateLimitingQueue(controller.rateLimiter.newRateLimiter(), "CustomDatabases")
 
 	logger.Info("Setting up event handlers")
 	// Set up an event handler for when CustomDatabase resources change
@@ -121,7 +247,14 @@ func NewController(
 		UpdateFunc: func(old, new interface{}) {
 			controller.enqueueCustomDatabase(new)
 		},
-		DeleteFunc: controller.enqueueCustomDatabase,
+		DeleteFunc: controller.handleDeletedCustomDatabase,
+	})
+	// new migrations and init scripts are applied as soon as their ConfigMap is changed
+	configMapInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: controller.enqueueCustomDatabasesOfConfigMap,
+		UpdateFunc: func(old, new interface{}) {
+			controller.enqueueCustomDatabasesOfConfigMap(new)
+		},
 	})
 
 	return controller
@@ -142,7 +275,7 @@ func (c *Controller) Run(ctx context.Context, workers int) error {
 	// Wait for the caches to be synced before starting workers
 	logger.Info("Waiting for informer caches to sync")
 
-	if ok := cache.WaitForCacheSync(ctx.Done(), c.customDatabasesSynced, c.secretSynced); !ok {
+	if ok := cache.WaitForCacheSync(ctx.Done(), c.customDatabasesSynced, c.secretSynced, c.configMapSynced); !ok {
 		return fmt.Errorf("failed to wait for caches to sync")
 	}
 
@@ -153,8 +286,13 @@ func (c *Controller) Run(ctx context.Context, workers int) error {
 	}
 
 	logger.Info("Started workers")
+
+	// orphans are looked for only after caches are synced, otherwise every database would be orphaned
+	go wait.UntilWithContext(ctx, c.sweepOrphans, c.orphanSweepPeriod)
+
 	<-ctx.Done()
 	logger.Info("Shutting down workers")
+	c.serverCopies.wait()
 
 	return nil
 }
@@ -170,14 +308,99 @@ func (c *Controller) runWorker(ctx context.Context) {
 // processNextWorkItem will read a single work item off the workqueue and
 // attempt to process it, by calling the syncHandler.
 func (c *Controller) processNextWorkItem(ctx context.Context) bool {
-	obj, shutdown := c.workqueue.Get()
+	return processNextWorkItem(ctx, c.workqueue, c.syncHandler)
+}
+
+// syncHandler compares the actual state with the desired, and attempts to
+// converge the two.
+func (c *Controller) syncHandler(ctx context.Context, key string) error {
+	// Convert the namespace/name string into a distinct namespace and name
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("invalid resource key: %s", key))
+		return nil
+	}
+
+	ctx = contextWithResourceNameLogger(ctx, name)
+	ctx = customdatabase.ContextWithResource(ctx, "CustomDatabase", key)
+
+	// Get the CustomDatabase resource with this namespace/name
+	customDatabase, err := c.customDatabasesLister.CustomDatabases(namespace).Get(name)
+	if err != nil {
+		// The CustomDatabase resource may no longer exist, in this case we will delete database
+		if errors.IsNotFound(err) {
+			return c.deleteHandler(ctx, namespace, name)
+		}
+		return err
+	}
+
+	return c.addOrUpdateHandler(ctx, customDatabase)
+}
+
+// enqueueCustomDatabase takes a CustomDatabase resource and converts it into a namespace/name
+// string which is then put onto the work queue. This method should *not* be
+// passed resources of any type other than CustomDatabase.
+func (c *Controller) enqueueCustomDatabase(obj interface{}) {
+	var key string
+	var err error
+	if key, err = cache.MetaNamespaceKeyFunc(obj); err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.workqueue.Add(key)
+}
+
+// enqueueCustomDatabasesOfConfigMap requeues CustomDatabases, whose init scripts or migrations are kept in ConfigMap
+func (c *Controller) enqueueCustomDatabasesOfConfigMap(obj interface{}) {
+	configMap, ok := obj.(*corev1.ConfigMap)
+	if !ok {
+		return
+	}
+
+	customDatabases, err := c.customDatabasesLister.CustomDatabases(configMap.Namespace).List(labels.Everything())
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+
+	for _, customDatabase := range customDatabases {
+		if usesConfigMap(customDatabase, configMap.Name) {
+			c.enqueueCustomDatabase(customDatabase)
+		}
+	}
+}
+
+// newEventRecorder creates event broadcaster and recorder for events about resources of controller
+func newEventRecorder(ctx context.Context, kubeclientset kubernetes.Interface) record.EventRecorder {
+	logger := klog.FromContext(ctx)
+
+	// Create event broadcaster
+	// Add custom-database-controller types to the default Kubernetes Scheme so Events can be
+	// logged for custom-database-controller types.
+	utilruntime.Must(samplescheme.AddToScheme(scheme.Scheme))
+	logger.V(4).Info("Creating event broadcaster")
+
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartStructuredLogging(0)
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubeclientset.CoreV1().Events("")})
+
+	return eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: controllerAgentName})
+}
+
+// processNextWorkItem will read a single work item off the workqueue and
+// attempt to process it, by calling the syncHandler.
+// It's shared by all controllers of the package.
+func processNextWorkItem(
+	ctx context.Context, queue workqueue.RateLimitingInterface, syncHandler func(ctx context.Context, key string) error,
+) bool {
+	obj, shutdown := queue.Get()
 	if shutdown {
 		return false
 	}
 
 	logger := klog.FromContext(ctx)
 
-	// We wrap this block in a func so we can defer c.workqueue.Done.
+	// We wrap this block in a func so we can defer queue.Done.
 	err := func(obj interface{}) error {
 		// We call Done here so the workqueue knows we have finished
 		// processing this item. We also must remember to call Forget if we
@@ -185,7 +408,7 @@ func (c *Controller) processNextWorkItem(ctx context.Context) bool {
 		// not call Forget if a transient error occurs, instead the item is
 		// put back on the workqueue and attempted again after a back-off
 		// period.
-		defer c.workqueue.Done(obj)
+		defer queue.Done(obj)
 		var key string
 		var ok bool
 		// We expect strings to come off the workqueue. These are of the
@@ -197,20 +420,20 @@ func (c *Controller) processNextWorkItem(ctx context.Context) bool {
 			// As the item in the workqueue is actually invalid, we call
 			// Forget here else we'd go into a loop of attempting to
 			// process a work item that is invalid.
-			c.workqueue.Forget(obj)
+			queue.Forget(obj)
 			utilruntime.HandleError(fmt.Errorf("expected string in workqueue but got %#v", obj))
 			return nil
 		}
 		// Run the syncHandler, passing it the namespace/name string of the
-		// CustomDatabase resource to be synced.
-		if err := c.syncHandler(ctx, key); err != nil {
+		// resource to be synced.
+		if err := syncHandler(ctx, key); err != nil {
 			// Put the item back on the workqueue to handle any transient errors.
-			c.workqueue.AddRateLimited(key)
+			queue.AddRateLimited(key)
 			return fmt.Errorf("error syncing '%s': %s, requeuing", key, err.Error())
 		}
 		// Finally, if no error occurs we Forget this item so it does not
 		// get queued again until another change happens.
-		c.workqueue.Forget(obj)
+		queue.Forget(obj)
 		logger.Info("Successfully synced", "resourceName", key)
 		return nil
 	}(obj)
@@ -223,44 +446,6 @@ func (c *Controller) processNextWorkItem(ctx context.Context) bool {
 	return true
 }
 
-// syncHandler compares the actual state with the desired, and attempts to
-// converge the two.
-func (c *Controller) syncHandler(ctx context.Context, key string) error {
-	// Convert the namespace/name string into a distinct namespace and name
-	namespace, name, err := cache.SplitMetaNamespaceKey(key)
-	if err != nil {
-		utilruntime.HandleError(fmt.Errorf("invalid resource key: %s", key))
-		return nil
-	}
-
-	ctx = contextWithResourceNameLogger(ctx, name)
-
-	// Get the CustomDatabase resource with this namespace/name
-	customDatabase, err := c.customDatabasesLister.CustomDatabases(namespace).Get(name)
-	if err != nil {
-		// The CustomDatabase resource may no longer exist, in this case we will delete database
-		if errors.IsNotFound(err) {
-			return c.deleteHandler(ctx, name)
-		}
-		return err
-	}
-
-	return c.addOrUpdateHandler(ctx, customDatabase)
-}
-
-// enqueueCustomDatabase takes a CustomDatabase resource and converts it into a namespace/name
-// string which is then put onto the work queue. This method should *not* be
-// passed resources of any type other than CustomDatabase.
-func (c *Controller) enqueueCustomDatabase(obj interface{}) {
-	var key string
-	var err error
-	if key, err = cache.MetaNamespaceKeyFunc(obj); err != nil {
-		utilruntime.HandleError(err)
-		return
-	}
-	c.workqueue.Add(key)
-}
-
 func contextWithResourceNameLogger(ctx context.Context, name string) context.Context {
 	logger := klog.LoggerWithValues(klog.FromContext(ctx), "resourceName", name)
 
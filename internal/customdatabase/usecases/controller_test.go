@@ -2,12 +2,20 @@ package usecases
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io/fs"
 	"reflect"
+	"strings"
 	"testing"
+	"testing/fstest"
 	"time"
+	"unicode/utf8"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -18,6 +26,7 @@ import (
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2/ktesting"
+	testingclock "k8s.io/utils/clock/testing"
 
 	"k8s.io/custom-database/internal/customdatabase"
 	fakeadapter "k8s.io/custom-database/internal/customdatabase/adapters/fake"
@@ -35,95 +44,1897 @@ func TestCreateDatabaseAndSecret(t *testing.T) {
 	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
 	f.objects = append(f.objects, customDatabaseItem)
 
-	expCustomDb := customdatabase.Entity{
-		Host:     customdatabase.Host{"localhost", 5432},
-		Database: customdatabase.Database{Name: "test", User: "test", Password: "testtesttest"},
+	expCustomDb := newEntity("test")
+
+	expFinalSecret := secretWithDBInfo(newEmptySecret(customDatabaseItem), expCustomDb)
+
+	f.expectCreateSecretAction(expFinalSecret)
+	f.expectUpdateCustomDatabaseStatusAction(withStatus(customDatabaseItem, expCustomDb))
+	f.expectExistsDatabase(expCustomDb)
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestCreateDatabaseWithOptions(t *testing.T) {
+	f := newFixture(t)
+
+	customDatabaseItem := newCustomDatabase("test")
+	customDatabaseItem.Spec.DatabaseOptions = customdatabasecontroller.DatabaseOptions{
+		Encoding:   "UTF8",
+		LcCollate:  "C",
+		LcCtype:    "C",
+		Tablespace: "fast_ssd",
+	}
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.objects = append(f.objects, customDatabaseItem)
+
+	expCustomDb := newEntity("test")
+	expCustomDb.Database.Options = customdatabase.DatabaseOptions{
+		Owner:      "test",
+		Encoding:   "UTF8",
+		LcCollate:  "C",
+		LcCtype:    "C",
+		Template:   "template0",
+		Tablespace: "fast_ssd",
+	}
+
+	expFinalSecret := secretWithDBInfo(newEmptySecret(customDatabaseItem), expCustomDb)
+
+	f.expectCreateSecretAction(expFinalSecret)
+	f.expectUpdateCustomDatabaseStatusAction(withStatus(customDatabaseItem, expCustomDb))
+	f.expectExistsDatabase(expCustomDb)
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestRejectTemplateOfAnotherDatabase(t *testing.T) {
+	f := newFixture(t)
+
+	// database of another tenant is copied only by cloneFrom, which is allowed by its owner
+	customDatabaseItem := newCustomDatabase("test")
+	customDatabaseItem.Spec.DatabaseOptions.Template = "golden"
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.objects = append(f.objects, customDatabaseItem)
+	f.databases = append(f.databases, newEntity("golden"))
+	f.notExpectedDatabases = append(f.notExpectedDatabases, newEntity("test"))
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestCloneDatabase(t *testing.T) {
+	f := newFixture(t)
+
+	sourceEntity := newEntity("golden")
+	sourceItem := withStatus(newCustomDatabase("golden"), sourceEntity)
+	sourceSecret := secretWithDBInfo(newEmptySecret(sourceItem), sourceEntity)
+
+	customDatabaseItem := newCustomDatabase("preview")
+	customDatabaseItem.Spec.CloneFrom = &customdatabasecontroller.CloneSource{Name: "golden"}
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.customDatabaseLister = append(f.customDatabaseLister, sourceItem, customDatabaseItem)
+	f.objects = append(f.objects, sourceItem, customDatabaseItem)
+	f.secretLister = append(f.secretLister, sourceSecret)
+	f.kubeobjects = append(f.kubeobjects, sourceSecret)
+	f.databases = append(f.databases, sourceEntity)
+
+	expCustomDb := newEntity("preview")
+	expCustomDb.Database.Options.Template = "golden"
+
+	expStatus := withStatus(customDatabaseItem, expCustomDb)
+	expStatus.Status.Conditions = append([]metav1.Condition{{
+		Type:               customdatabasecontroller.ConditionCloned,
+		Status:             metav1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(testNow),
+		Reason:             DatabaseCloned,
+		Message:            "Database was cloned from default/golden",
+	}}, expStatus.Status.Conditions...)
+
+	f.expectCreateSecretAction(secretWithDBInfo(newEmptySecret(customDatabaseItem), newEntity("preview")))
+	f.expectUpdateCustomDatabaseStatusAction(expStatus)
+	f.expectExistsDatabase(expCustomDb)
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestCloneDatabaseFromNotAllowedNamespace(t *testing.T) {
+	f := newFixture(t)
+
+	sourceEntity := newEntity("golden")
+	sourceItem := withStatus(newCustomDatabase("golden"), sourceEntity)
+	sourceItem.Namespace = "staging"
+	sourceSecret := secretWithDBInfo(newEmptySecret(sourceItem), sourceEntity)
+
+	customDatabaseItem := newCustomDatabase("preview")
+	customDatabaseItem.Spec.CloneFrom = &customdatabasecontroller.CloneSource{Name: "golden", Namespace: "staging"}
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.customDatabaseLister = append(f.customDatabaseLister, sourceItem, customDatabaseItem)
+	f.objects = append(f.objects, sourceItem, customDatabaseItem)
+	f.secretLister = append(f.secretLister, sourceSecret)
+	f.kubeobjects = append(f.kubeobjects, sourceSecret)
+	f.databases = append(f.databases, sourceEntity)
+
+	// nothing is created, until source allows cloning by annotation
+	message := "CustomDatabase staging/golden doesn't allow cloning into namespace default, see annotation " +
+		customdatabasecontroller.AnnotationCloneAllowedNamespaces
+	expStatus := customDatabaseItem.DeepCopy()
+	expStatus.Status.Conditions = []metav1.Condition{{
+		Type:               customdatabasecontroller.ConditionCloned,
+		Status:             metav1.ConditionFalse,
+		LastTransitionTime: metav1.NewTime(testNow),
+		Reason:             CloneNotAllowed,
+		Message:            message,
+	}}
+
+	f.expectUpdateCustomDatabaseStatusAction(expStatus)
+	f.expectedEvents = append(f.expectedEvents, corev1.EventTypeWarning+" "+CloneNotAllowed+" "+message)
+	f.notExpectedDatabases = append(f.notExpectedDatabases, newEntity("preview"))
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestCloneDatabaseAllowedByAnnotation(t *testing.T) {
+	f := newFixture(t)
+
+	sourceEntity := newEntity("golden")
+	sourceItem := withStatus(newCustomDatabase("golden"), sourceEntity)
+	sourceItem.Namespace = "staging"
+	sourceItem.Annotations = map[string]string{customdatabasecontroller.AnnotationCloneAllowedNamespaces: "preview, default"}
+	sourceSecret := secretWithDBInfo(newEmptySecret(sourceItem), sourceEntity)
+
+	customDatabaseItem := newCustomDatabase("preview")
+	customDatabaseItem.Spec.CloneFrom = &customdatabasecontroller.CloneSource{Name: "golden", Namespace: "staging"}
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.customDatabaseLister = append(f.customDatabaseLister, sourceItem, customDatabaseItem)
+	f.objects = append(f.objects, sourceItem, customDatabaseItem)
+	f.secretLister = append(f.secretLister, sourceSecret)
+	f.kubeobjects = append(f.kubeobjects, sourceSecret)
+	f.databases = append(f.databases, sourceEntity)
+
+	expCustomDb := newEntity("preview")
+	expCustomDb.Database.Options.Template = "golden"
+
+	expStatus := withStatus(customDatabaseItem, expCustomDb)
+	expStatus.Status.Conditions = append([]metav1.Condition{{
+		Type:               customdatabasecontroller.ConditionCloned,
+		Status:             metav1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(testNow),
+		Reason:             DatabaseCloned,
+		Message:            "Database was cloned from staging/golden",
+	}}, expStatus.Status.Conditions...)
+
+	f.expectCreateSecretAction(secretWithDBInfo(newEmptySecret(customDatabaseItem), newEntity("preview")))
+	f.expectUpdateCustomDatabaseStatusAction(expStatus)
+	f.expectExistsDatabase(expCustomDb)
+	f.expectReassignedOwnership("preview", "golden")
+	f.expectedEvents = append(f.expectedEvents,
+		corev1.EventTypeNormal+" "+DatabaseCloned+" Database was cloned from staging/golden",
+	)
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestFinishCloneOfExistingDatabase(t *testing.T) {
+	f := newFixture(t)
+
+	sourceEntity := newEntity("golden")
+	sourceItem := withStatus(newCustomDatabase("golden"), sourceEntity)
+	sourceSecret := secretWithDBInfo(newEmptySecret(sourceItem), sourceEntity)
+
+	// previous sync copied database, but failed before Cloned condition was set
+	customDatabaseItem := newCustomDatabase("preview")
+	customDatabaseItem.Spec.CloneFrom = &customdatabasecontroller.CloneSource{Name: "golden"}
+	expCustomDb := newEntity("preview")
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.customDatabaseLister = append(f.customDatabaseLister, sourceItem, customDatabaseItem)
+	f.objects = append(f.objects, sourceItem, customDatabaseItem)
+	f.databases = append(f.databases, sourceEntity, expCustomDb)
+
+	expFinalSecret := secretWithDBInfo(newEmptySecret(customDatabaseItem), expCustomDb)
+	f.secretLister = append(f.secretLister, sourceSecret, expFinalSecret)
+	f.kubeobjects = append(f.kubeobjects, sourceSecret, expFinalSecret)
+
+	expStatus := withStatus(customDatabaseItem, expCustomDb)
+	expStatus.Status.Conditions = append([]metav1.Condition{{
+		Type:               customdatabasecontroller.ConditionCloned,
+		Status:             metav1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(testNow),
+		Reason:             DatabaseCloned,
+		Message:            "Database was cloned from default/golden",
+	}}, expStatus.Status.Conditions...)
+
+	f.expectUpdateCustomDatabaseStatusAction(expStatus)
+	f.expectExistsDatabase(expCustomDb)
+	f.expectReassignedOwnership("preview", "golden")
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestRunInitScripts(t *testing.T) {
+	f := newFixture(t)
+
+	customDatabaseItem := newCustomDatabase("test")
+	customDatabaseItem.Spec.Init = []customdatabasecontroller.InitScript{{
+		ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "fixtures"}, Key: "schema.sql",
+		},
+	}}
+	_, ctx := ktesting.NewTestContext(t)
+
+	script := "CREATE TABLE users (id serial);"
+	fixtures := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "fixtures", Namespace: metav1.NamespaceDefault},
+		Data:       map[string]string{"schema.sql": script},
+	}
+
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.objects = append(f.objects, customDatabaseItem)
+	f.configMapLister = append(f.configMapLister, fixtures)
+
+	expCustomDb := newEntity("test")
+	hash := sha256.Sum256([]byte(script))
+	expStatus := withStatus(customDatabaseItem, expCustomDb)
+	expStatus.Status.InitScripts = []customdatabasecontroller.AppliedInitScript{{
+		Name:      "configmap/fixtures/schema.sql",
+		Checksum:  "sha256:" + hex.EncodeToString(hash[:]),
+		AppliedAt: metav1.NewTime(testNow),
+	}}
+	expStatus.Status.Conditions = append(expStatus.Status.Conditions, metav1.Condition{
+		Type:               customdatabasecontroller.ConditionInitialized,
+		Status:             metav1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(testNow),
+		Reason:             "ScriptsApplied",
+		Message:            "All init scripts are applied",
+	})
+
+	f.expectCreateSecretAction(secretWithDBInfo(newEmptySecret(customDatabaseItem), expCustomDb))
+	f.expectUpdateCustomDatabaseStatusAction(expStatus)
+	f.expectExistsDatabase(expCustomDb)
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestSkipAppliedInitScripts(t *testing.T) {
+	f := newFixture(t)
+
+	expCustomDb := newEntity("test")
+	customDatabaseItem := withStatus(newCustomDatabase("test"), expCustomDb)
+	customDatabaseItem.Spec.Init = []customdatabasecontroller.InitScript{{
+		ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "fixtures"}, Key: "schema.sql",
+		},
+	}}
+	customDatabaseItem.Status.InitScripts = []customdatabasecontroller.AppliedInitScript{{
+		Name: "configmap/fixtures/schema.sql", Checksum: "sha256:0", AppliedAt: metav1.NewTime(testNow),
+	}}
+	customDatabaseItem.Status.Conditions = append(customDatabaseItem.Status.Conditions, metav1.Condition{
+		Type:               customdatabasecontroller.ConditionInitialized,
+		Status:             metav1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(testNow),
+		Reason:             "ScriptsApplied",
+		Message:            "All init scripts are applied",
+	})
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.objects = append(f.objects, customDatabaseItem)
+	f.databases = append(f.databases, expCustomDb)
+
+	expFinalSecret := secretWithDBInfo(newEmptySecret(customDatabaseItem), expCustomDb)
+	f.secretLister = append(f.secretLister, expFinalSecret)
+	f.kubeobjects = append(f.kubeobjects, expFinalSecret)
+
+	// ConfigMap of applied script may be deleted
+	f.expectExistsDatabase(expCustomDb)
+	f.expectRunScripts("test")
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestReportChangedInitScripts(t *testing.T) {
+	f := newFixture(t)
+
+	expCustomDb := newEntity("test")
+	customDatabaseItem := withStatus(newCustomDatabase("test"), expCustomDb)
+	customDatabaseItem.Spec.Init = []customdatabasecontroller.InitScript{{
+		ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "fixtures"}, Key: "schema.sql",
+		},
+	}, {
+		ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "fixtures"}, Key: "copy.sql",
+		},
+	}}
+	customDatabaseItem.Status.InitScripts = []customdatabasecontroller.AppliedInitScript{{
+		Name: "configmap/fixtures/schema.sql", Checksum: "sha256:0", AppliedAt: metav1.NewTime(testNow),
+	}}
+	_, ctx := ktesting.NewTestContext(t)
+
+	// script with the same content, but another name, is another script
+	script := "CREATE TABLE users (id serial);"
+	fixtures := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "fixtures", Namespace: metav1.NamespaceDefault},
+		Data:       map[string]string{"schema.sql": script, "copy.sql": script},
+	}
+
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.objects = append(f.objects, customDatabaseItem)
+	f.databases = append(f.databases, expCustomDb)
+
+	expFinalSecret := secretWithDBInfo(newEmptySecret(customDatabaseItem), expCustomDb)
+	f.secretLister = append(f.secretLister, expFinalSecret)
+	f.kubeobjects = append(f.kubeobjects, expFinalSecret)
+	f.configMapLister = append(f.configMapLister, fixtures)
+
+	hash := sha256.Sum256([]byte(script))
+	expStatus := customDatabaseItem.DeepCopy()
+	expStatus.Status.InitScripts = append(expStatus.Status.InitScripts, customdatabasecontroller.AppliedInitScript{
+		Name: "configmap/fixtures/copy.sql", Checksum: "sha256:" + hex.EncodeToString(hash[:]),
+		AppliedAt: metav1.NewTime(testNow),
+	})
+	expStatus.Status.Conditions = append(expStatus.Status.Conditions, metav1.Condition{
+		Type:               customdatabasecontroller.ConditionInitialized,
+		Status:             metav1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(testNow),
+		Reason:             "ScriptsChanged",
+		Message: "All init scripts are applied, changes of configmap/fixtures/schema.sql after they were applied " +
+			"are ignored",
+	})
+
+	f.expectUpdateCustomDatabaseStatusAction(expStatus)
+	f.expectExistsDatabase(expCustomDb)
+	f.expectRunScripts("test", script)
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestRecordInitScriptsAppliedBeforeStatus(t *testing.T) {
+	f := newFixture(t)
+
+	expCustomDb := newEntity("test")
+	customDatabaseItem := withStatus(newCustomDatabase("test"), expCustomDb)
+	customDatabaseItem.Spec.Init = []customdatabasecontroller.InitScript{{
+		ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "fixtures"}, Key: "schema.sql",
+		},
+	}}
+	_, ctx := ktesting.NewTestContext(t)
+
+	script := "CREATE TABLE users (id serial);"
+	fixtures := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "fixtures", Namespace: metav1.NamespaceDefault},
+		Data:       map[string]string{"schema.sql": script},
+	}
+
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.objects = append(f.objects, customDatabaseItem)
+	f.databases = append(f.databases, expCustomDb)
+	// previous sync applied script, but failed to save status
+	hash := sha256.Sum256([]byte(script))
+	checksum := "sha256:" + hex.EncodeToString(hash[:])
+	f.appliedScripts = map[string]map[string]string{"test": {"configmap/fixtures/schema.sql": checksum}}
+
+	expFinalSecret := secretWithDBInfo(newEmptySecret(customDatabaseItem), expCustomDb)
+	f.secretLister = append(f.secretLister, expFinalSecret)
+	f.kubeobjects = append(f.kubeobjects, expFinalSecret)
+	f.configMapLister = append(f.configMapLister, fixtures)
+
+	expStatus := customDatabaseItem.DeepCopy()
+	expStatus.Status.InitScripts = []customdatabasecontroller.AppliedInitScript{{
+		Name: "configmap/fixtures/schema.sql", Checksum: checksum, AppliedAt: metav1.NewTime(testNow),
+	}}
+	expStatus.Status.Conditions = append(expStatus.Status.Conditions, metav1.Condition{
+		Type:               customdatabasecontroller.ConditionInitialized,
+		Status:             metav1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(testNow),
+		Reason:             "ScriptsApplied",
+		Message:            "All init scripts are applied",
+	})
+
+	f.expectUpdateCustomDatabaseStatusAction(expStatus)
+	f.expectExistsDatabase(expCustomDb)
+	f.expectRunScripts("test")
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestTruncateStatementByCharacters(t *testing.T) {
+	statement := strings.Repeat("a", maxReportedStatementLength-1) + "ёж"
+
+	truncated := truncateStatement(statement)
+	if !utf8.ValidString(truncated) || truncated != strings.Repeat("a", maxReportedStatementLength-1)+"..." {
+		t.Errorf("statement should be cut before multibyte character, given %q", truncated)
+	}
+}
+
+func TestMigrateDatabase(t *testing.T) {
+	f := newFixture(t)
+
+	expCustomDb := newEntity("test")
+	customDatabaseItem := withStatus(newCustomDatabase("test"), expCustomDb)
+	customDatabaseItem.Spec.Migrations = &customdatabasecontroller.Migrations{ConfigMapName: "migrations"}
+	_, ctx := ktesting.NewTestContext(t)
+
+	migrations := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "migrations", Namespace: metav1.NamespaceDefault},
+		Data: map[string]string{
+			"1_create_users.up.sql":   "CREATE TABLE users (id int);",
+			"1_create_users.down.sql": "DROP TABLE users;",
+			"2_add_name.up.sql":       "ALTER TABLE users ADD name text;",
+		},
+	}
+
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.objects = append(f.objects, customDatabaseItem)
+	f.databases = append(f.databases, expCustomDb)
+
+	expFinalSecret := secretWithDBInfo(newEmptySecret(customDatabaseItem), expCustomDb)
+	f.secretLister = append(f.secretLister, expFinalSecret)
+	f.kubeobjects = append(f.kubeobjects, expFinalSecret)
+	f.configMapLister = append(f.configMapLister, migrations)
+
+	expStatus := customDatabaseItem.DeepCopy()
+	expStatus.Status.Migrations = &customdatabasecontroller.MigrationsStatus{CurrentVersion: 2, TargetVersion: 2}
+	expStatus.Status.Conditions = append(expStatus.Status.Conditions, metav1.Condition{
+		Type:               customdatabasecontroller.ConditionMigrated,
+		Status:             metav1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(testNow),
+		Reason:             "Migrated",
+		Message:            "Database is migrated to version 2",
+	})
+
+	f.expectUpdateCustomDatabaseStatusAction(expStatus)
+	f.expectExistsDatabase(expCustomDb)
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestRequeueCustomDatabasesOfChangedConfigMap(t *testing.T) {
+	f := newFixture(t)
+	_, ctx := ktesting.NewTestContext(t)
+
+	migrated := newCustomDatabase("test")
+	migrated.Spec.Migrations = &customdatabasecontroller.Migrations{ConfigMapName: "migrations"}
+	f.customDatabaseLister = append(f.customDatabaseLister, migrated, newCustomDatabase("other"))
+
+	c, _, _, _ := f.newController(ctx)
+	c.enqueueCustomDatabasesOfConfigMap(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "migrations", Namespace: metav1.NamespaceDefault},
+	})
+
+	if c.workqueue.Len() != 1 {
+		t.Fatalf("only CustomDatabase with migrations in ConfigMap should be requeued, given %d", c.workqueue.Len())
+	}
+	if key, _ := c.workqueue.Get(); key != metav1.NamespaceDefault+"/test" {
+		t.Errorf("wrong requeued CustomDatabase: %v", key)
+	}
+}
+
+func TestMigrationsPathOutsideOfDirectory(t *testing.T) {
+	c := &Controller{migrationsFS: fstest.MapFS{
+		"tenant/1_create_users.up.sql": {Data: []byte("CREATE TABLE users (id int);")},
+	}}
+
+	files, err := c.migrationFiles(metav1.NamespaceDefault, &customdatabasecontroller.Migrations{Path: "tenant/"})
+	if err != nil || len(files) != 1 {
+		t.Errorf("migrations should be read from directory, given %v, %v", files, err)
+	}
+
+	for _, migrationsPath := range []string{"../etc", "/etc", "tenant/../../etc"} {
+		_, err = c.migrationFiles(metav1.NamespaceDefault, &customdatabasecontroller.Migrations{Path: migrationsPath})
+		if !errors.Is(err, fs.ErrInvalid) {
+			t.Errorf("path %s outside of directory should be rejected, given %v", migrationsPath, err)
+		}
+	}
+}
+
+func TestDeleteExpiredCustomDatabase(t *testing.T) {
+	f := newFixture(t)
+
+	customDatabaseItem := newCustomDatabase("test")
+	customDatabaseItem.CreationTimestamp = metav1.NewTime(testNow.Add(-2 * time.Hour))
+	customDatabaseItem.Spec.TTL = &metav1.Duration{Duration: time.Hour}
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.objects = append(f.objects, customDatabaseItem)
+
+	// database isn't provisioned again, it's dropped after deletion of CustomDatabase by delete handler
+	f.expectDeleteCustomDatabaseAction(customDatabaseItem)
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestIgnoreNegativeTTL(t *testing.T) {
+	f := newFixture(t)
+
+	customDatabaseItem := newCustomDatabase("test")
+	customDatabaseItem.CreationTimestamp = metav1.NewTime(testNow.Add(-2 * time.Hour))
+	customDatabaseItem.Spec.TTL = &metav1.Duration{Duration: -time.Hour}
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.objects = append(f.objects, customDatabaseItem)
+
+	// CustomDatabase is neither deleted nor provisioned, until ttl is fixed
+	f.notExpectedDatabases = append(f.notExpectedDatabases, newEntity("test"))
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestWarnAboutExpiringCustomDatabase(t *testing.T) {
+	f := newFixture(t)
+
+	expCustomDb := newEntity("test")
+	customDatabaseItem := withStatus(newCustomDatabase("test"), expCustomDb)
+	customDatabaseItem.CreationTimestamp = metav1.NewTime(testNow.Add(-2 * time.Hour))
+	customDatabaseItem.Spec.TTL = &metav1.Duration{Duration: 3 * time.Hour}
+	// annotation is earlier than TTL, so it wins
+	customDatabaseItem.Annotations = map[string]string{
+		customdatabasecontroller.AnnotationExpiresAt: testNow.Add(30 * time.Minute).Format(time.RFC3339),
+	}
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.objects = append(f.objects, customDatabaseItem)
+	f.databases = append(f.databases, expCustomDb)
+
+	expFinalSecret := secretWithDBInfo(newEmptySecret(customDatabaseItem), expCustomDb)
+	f.secretLister = append(f.secretLister, expFinalSecret)
+	f.kubeobjects = append(f.kubeobjects, expFinalSecret)
+
+	expiresAt := metav1.NewTime(testNow.Add(30 * time.Minute))
+	expStatus := customDatabaseItem.DeepCopy()
+	expStatus.Status.ExpiresAt = &expiresAt
+	expStatus.Status.Conditions = append(expStatus.Status.Conditions, metav1.Condition{
+		Type:               customdatabasecontroller.ConditionExpiring,
+		Status:             metav1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(testNow),
+		Reason:             DatabaseExpiring,
+		Message:            "CustomDatabase expires at 2023-05-01T12:30:00Z and will be deleted together with database",
+	})
+
+	f.expectUpdateCustomDatabaseStatusAction(expStatus)
+	f.expectExistsDatabase(expCustomDb)
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func newHibernatingCustomDatabase(entity customdatabase.Entity, lastActivityAt time.Time) *customdatabasecontroller.CustomDatabase {
+	customDatabaseItem := withStatus(newCustomDatabase(entity.Database.Name), entity)
+	customDatabaseItem.Spec.Hibernation = &customdatabasecontroller.Hibernation{IdleDays: 7}
+	customDatabaseItem.Status.LastActivityAt = &metav1.Time{Time: lastActivityAt}
+
+	return customDatabaseItem
+}
+
+func TestHibernateIdleDatabase(t *testing.T) {
+	f := newFixture(t)
+
+	expCustomDb := newEntity("test")
+	customDatabaseItem := newHibernatingCustomDatabase(expCustomDb, testNow.Add(-8*24*time.Hour))
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.objects = append(f.objects, customDatabaseItem)
+	f.databases = append(f.databases, expCustomDb)
+
+	expFinalSecret := secretWithDBInfo(newEmptySecret(customDatabaseItem), expCustomDb)
+	f.secretLister = append(f.secretLister, expFinalSecret)
+	f.kubeobjects = append(f.kubeobjects, expFinalSecret)
+
+	expStatus := customDatabaseItem.DeepCopy()
+	expStatus.Status.Hibernation = &customdatabasecontroller.HibernationStatus{
+		Phase:        customdatabasecontroller.HibernationPhaseHibernated,
+		HibernatedAt: metav1.NewTime(testNow),
+	}
+	expStatus.Status.Conditions = append(expStatus.Status.Conditions, metav1.Condition{
+		Type:               customdatabasecontroller.ConditionHibernated,
+		Status:             metav1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(testNow),
+		Reason:             "Hibernated",
+		Message:            "Database is idle since 2023-04-23T12:00:00Z, CONNECT is revoked",
+	})
+
+	f.expectUpdateCustomDatabaseStatusAction(expStatus)
+	f.expectExistsDatabase(expCustomDb)
+	f.expectRevokedConnect(expCustomDb.Database.Name)
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestDatabaseWithSessionsIsActive(t *testing.T) {
+	f := newFixture(t)
+
+	expCustomDb := newEntity("test")
+	customDatabaseItem := newHibernatingCustomDatabase(expCustomDb, testNow.Add(-8*24*time.Hour))
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.objects = append(f.objects, customDatabaseItem)
+	f.databases = append(f.databases, expCustomDb)
+	f.databaseActivity[expCustomDb.Database.Name] = customdatabase.DatabaseActivity{XactCommit: 10, NumBackends: 1}
+
+	expFinalSecret := secretWithDBInfo(newEmptySecret(customDatabaseItem), expCustomDb)
+	f.secretLister = append(f.secretLister, expFinalSecret)
+	f.kubeobjects = append(f.kubeobjects, expFinalSecret)
+
+	expStatus := customDatabaseItem.DeepCopy()
+	expStatus.Status.LastActivityAt = &metav1.Time{Time: testNow}
+	expStatus.Status.Conditions = append(expStatus.Status.Conditions, metav1.Condition{
+		Type:               customdatabasecontroller.ConditionHibernated,
+		Status:             metav1.ConditionFalse,
+		LastTransitionTime: metav1.NewTime(testNow),
+		Reason:             "Active",
+		Message:            "Database is hibernated after 7 idle days",
+	})
+
+	f.expectUpdateCustomDatabaseStatusAction(expStatus)
+	f.expectExistsDatabase(expCustomDb)
+	f.expectGrantedConnect(expCustomDb.Database.Name)
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestSessionsOfOtherControllersAreNotActivity(t *testing.T) {
+	f := newFixture(t)
+
+	expCustomDb := newEntity("test")
+	lastActivityAt := testNow.Add(-8 * 24 * time.Hour)
+	customDatabaseItem := newHibernatingCustomDatabase(expCustomDb, lastActivityAt)
+	f.databases = append(f.databases, expCustomDb)
+	_, ctx := ktesting.NewTestContext(t)
+
+	c, _, _, databaseManager := f.newController(ctx)
+	fakeClock := testingclock.NewFakeClock(testNow)
+	c.clock = fakeClock
+	newStatus := customDatabaseItem.Status.DeepCopy()
+	database := expCustomDb.Database.Name
+
+	if err := c.actualizeActivity(ctx, customDatabaseItem, database, newStatus); err != nil {
+		t.Fatal(err)
+	}
+
+	// dump of DatabaseBackup commits transaction in database
+	fakeClock.Step(time.Minute)
+	c.ownSessions.touch(metav1.NamespaceDefault, customDatabaseItem.Name, fakeClock.Now())
+	databaseManager.Activity[database] = customdatabase.DatabaseActivity{XactCommit: 1}
+	fakeClock.Step(defaultActivitySamplingPeriod)
+	if err := c.actualizeActivity(ctx, customDatabaseItem, database, newStatus); err != nil {
+		t.Fatal(err)
+	}
+	if !newStatus.LastActivityAt.Time.Equal(lastActivityAt) {
+		t.Errorf("commits of controller shouldn't be taken for activity, given %v", newStatus.LastActivityAt)
+	}
+
+	// commits of tenant are activity
+	databaseManager.Activity[database] = customdatabase.DatabaseActivity{XactCommit: 2}
+	fakeClock.Step(defaultActivitySamplingPeriod)
+	if err := c.actualizeActivity(ctx, customDatabaseItem, database, newStatus); err != nil {
+		t.Fatal(err)
+	}
+	if !newStatus.LastActivityAt.Time.Equal(fakeClock.Now()) {
+		t.Errorf("commits of tenant should be taken for activity, given %v", newStatus.LastActivityAt)
+	}
+}
+
+func TestHibernatedDatabaseIsNotProvisioned(t *testing.T) {
+	f := newFixture(t)
+
+	expCustomDb := newEntity("test")
+	customDatabaseItem := newHibernatingCustomDatabase(expCustomDb, testNow.Add(-8*24*time.Hour))
+	customDatabaseItem.Status.Hibernation = &customdatabasecontroller.HibernationStatus{
+		Phase:        customdatabasecontroller.HibernationPhaseHibernated,
+		HibernatedAt: metav1.NewTime(testNow.Add(-time.Hour)),
+	}
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.objects = append(f.objects, customDatabaseItem)
+	f.databases = append(f.databases, expCustomDb)
+	f.revokedConnects = append(f.revokedConnects, expCustomDb.Database.Name)
+
+	expFinalSecret := secretWithDBInfo(newEmptySecret(customDatabaseItem), expCustomDb)
+	f.secretLister = append(f.secretLister, expFinalSecret)
+	f.kubeobjects = append(f.kubeobjects, expFinalSecret)
+
+	f.expectRevokedConnect(expCustomDb.Database.Name)
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestWakeUpHibernatedDatabase(t *testing.T) {
+	f := newFixture(t)
+
+	expCustomDb := newEntity("test")
+	customDatabaseItem := newHibernatingCustomDatabase(expCustomDb, testNow.Add(-8*24*time.Hour))
+	customDatabaseItem.Annotations = map[string]string{customdatabasecontroller.AnnotationWakeUp: "1"}
+	customDatabaseItem.Status.Hibernation = &customdatabasecontroller.HibernationStatus{
+		Phase:        customdatabasecontroller.HibernationPhaseHibernated,
+		HibernatedAt: metav1.NewTime(testNow.Add(-time.Hour)),
+	}
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.objects = append(f.objects, customDatabaseItem)
+	f.databases = append(f.databases, expCustomDb)
+	f.revokedConnects = append(f.revokedConnects, expCustomDb.Database.Name)
+
+	expFinalSecret := secretWithDBInfo(newEmptySecret(customDatabaseItem), expCustomDb)
+	f.secretLister = append(f.secretLister, expFinalSecret)
+	f.kubeobjects = append(f.kubeobjects, expFinalSecret)
+
+	// idle period starts again after wake-up
+	expStatus := customDatabaseItem.DeepCopy()
+	expStatus.Status.Hibernation = nil
+	expStatus.Status.LastActivityAt = &metav1.Time{Time: testNow}
+	expStatus.Status.Conditions = append(expStatus.Status.Conditions, metav1.Condition{
+		Type:               customdatabasecontroller.ConditionHibernated,
+		Status:             metav1.ConditionFalse,
+		LastTransitionTime: metav1.NewTime(testNow),
+		Reason:             "Active",
+		Message:            "Database is hibernated after 7 idle days",
+	})
+
+	f.expectListAction("databaseusers", metav1.NamespaceDefault)
+	f.expectListAction("databasegrants", metav1.NamespaceDefault)
+	f.expectUpdateCustomDatabaseStatusAction(expStatus)
+	f.expectExistsDatabase(expCustomDb)
+	f.expectGrantedConnect(expCustomDb.Database.Name)
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestWakeUpGrantsConnectToRolesOfDatabase(t *testing.T) {
+	f := newFixture(t)
+
+	expCustomDb := newEntity("test")
+	customDatabaseItem := newHibernatingCustomDatabase(expCustomDb, testNow.Add(-8*24*time.Hour))
+	customDatabaseItem.Annotations = map[string]string{customdatabasecontroller.AnnotationWakeUp: "1"}
+	customDatabaseItem.Status.Hibernation = &customdatabasecontroller.HibernationStatus{
+		Phase:        customdatabasecontroller.HibernationPhaseHibernated,
+		HibernatedAt: metav1.NewTime(testNow.Add(-time.Hour)),
+	}
+	_, ctx := ktesting.NewTestContext(t)
+
+	databaseUserItem := newDatabaseUser("reporting", "test")
+	databaseUserItem.Status.UserName = "test_reporting"
+	otherDatabaseUserItem := newDatabaseUser("reporting", "other")
+	otherDatabaseUserItem.Name = "other-reporting"
+	otherDatabaseUserItem.Status.UserName = "other_reporting"
+
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.objects = append(f.objects, customDatabaseItem, databaseUserItem, otherDatabaseUserItem)
+	f.databases = append(f.databases, expCustomDb)
+	f.revokedConnects = append(f.revokedConnects, expCustomDb.Database.Name)
+	f.securityDrift["test"] = []string{"PUBLIC has CONNECT privilege on database"}
+
+	expFinalSecret := secretWithDBInfo(newEmptySecret(customDatabaseItem), expCustomDb)
+	f.secretLister = append(f.secretLister, expFinalSecret)
+	f.kubeobjects = append(f.kubeobjects, expFinalSecret)
+
+	c, _, _, databaseManager := f.newController(ctx)
+	c.securityAudits.set(getKey(customDatabaseItem, t), nil, testNow)
+	if err := c.syncHandler(ctx, getKey(customDatabaseItem, t)); err != nil {
+		t.Fatalf("error syncing customDatabase: %v", err)
+	}
+
+	if databases := databaseManager.User2Database["test_reporting"]; len(databases) != 1 || databases[0] != "test" {
+		t.Errorf("CONNECT should be granted to role of DatabaseUser, given databases %v", databases)
+	}
+	if databases := databaseManager.User2Database["other_reporting"]; len(databases) != 0 {
+		t.Errorf("roles of other databases shouldn't be granted, given databases %v", databases)
+	}
+	if findings := databaseManager.SecurityDrift["test"]; len(findings) != 0 {
+		t.Errorf("security of woken up database should be audited without waiting for its period, given %v", findings)
+	}
+}
+
+func TestDropDumpedHibernatedDatabase(t *testing.T) {
+	f := newFixture(t)
+
+	expCustomDb := newEntity("test")
+	customDatabaseItem := newHibernatingCustomDatabase(expCustomDb, testNow.Add(-8*24*time.Hour))
+	customDatabaseItem.Spec.Hibernation.DumpAndDrop = true
+	customDatabaseItem.Status.Hibernation = &customdatabasecontroller.HibernationStatus{
+		Phase:        customdatabasecontroller.HibernationPhaseDumping,
+		HibernatedAt: metav1.NewTime(testNow.Add(-time.Hour)),
+		BackupName:   "test-hibernation",
+	}
+	_, ctx := ktesting.NewTestContext(t)
+
+	databaseBackup := &customdatabasecontroller.DatabaseBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-hibernation", Namespace: metav1.NamespaceDefault},
+		Spec:       customdatabasecontroller.DatabaseBackupSpec{CustomDatabaseName: "test"},
+		Status:     customdatabasecontroller.DatabaseBackupStatus{Phase: customdatabasecontroller.BackupPhaseCompleted},
+	}
+
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.objects = append(f.objects, customDatabaseItem, databaseBackup)
+	f.databases = append(f.databases, expCustomDb)
+
+	expFinalSecret := secretWithDBInfo(newEmptySecret(customDatabaseItem), expCustomDb)
+	f.secretLister = append(f.secretLister, expFinalSecret)
+	f.kubeobjects = append(f.kubeobjects, expFinalSecret)
+
+	expStatus := customDatabaseItem.DeepCopy()
+	expStatus.Status.Hibernation.Phase = customdatabasecontroller.HibernationPhaseDropped
+	expStatus.Status.Conditions = append(expStatus.Status.Conditions, metav1.Condition{
+		Type:               customdatabasecontroller.ConditionHibernated,
+		Status:             metav1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(testNow),
+		Reason:             "Dropped",
+		Message:            "Database is dropped, its dump is kept in DatabaseBackup test-hibernation",
+	})
+
+	f.actions = append(f.actions, core.NewGetAction(
+		schema.GroupVersionResource{Resource: "databasebackups"}, metav1.NamespaceDefault, "test-hibernation",
+	))
+	f.expectUpdateCustomDatabaseStatusAction(expStatus)
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestRefuseUnknownDatabase(t *testing.T) {
+	f := newFixture(t)
+
+	// database and role were created manually
+	expCustomDb := newEntity("test")
+	customDatabaseItem := newCustomDatabase("test")
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.objects = append(f.objects, customDatabaseItem)
+	f.databases = append(f.databases, expCustomDb)
+	f.unmarkedDatabases = append(f.unmarkedDatabases, "test")
+
+	expStatus := customDatabaseItem.DeepCopy()
+	expStatus.Status.DatabaseName = "test"
+	expStatus.Status.Conditions = []metav1.Condition{{
+		Type:               customdatabasecontroller.ConditionAdopted,
+		Status:             metav1.ConditionFalse,
+		LastTransitionTime: metav1.NewTime(testNow),
+		Reason:             "NotOwned",
+		Message: "Database test or role test already exists and isn't managed by controller, " +
+			"set spec.adopt to take it over",
+	}}
+
+	f.expectUpdateCustomDatabaseStatusAction(expStatus)
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestRefuseDatabaseOfAnotherCustomDatabase(t *testing.T) {
+	f := newFixture(t)
+
+	expCustomDb := newEntity("test")
+	customDatabaseItem := newCustomDatabase("test")
+	customDatabaseItem.Spec.Adopt = true
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.objects = append(f.objects, customDatabaseItem)
+	f.databases = append(f.databases, expCustomDb)
+	f.adoptionAllowList = []string{"default/*"}
+	otherMarker := newOwnershipMarker("other", "test")
+	otherMarker.UID = "uid"
+	f.ownershipMarkers["test"] = otherMarker
+
+	expStatus := customDatabaseItem.DeepCopy()
+	expStatus.Status.DatabaseName = "test"
+	expStatus.Status.Conditions = []metav1.Condition{{
+		Type:               customdatabasecontroller.ConditionAdopted,
+		Status:             metav1.ConditionFalse,
+		LastTransitionTime: metav1.NewTime(testNow),
+		Reason:             "OwnedByAnother",
+		Message:            "Database test or role test belongs to CustomDatabase other/test",
+	}}
+
+	f.expectUpdateCustomDatabaseStatusAction(expStatus)
+	f.expectOwnership("test", otherMarker)
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestAdoptionNotAllowed(t *testing.T) {
+	f := newFixture(t)
+
+	expCustomDb := newEntity("test")
+	customDatabaseItem := newCustomDatabase("test")
+	customDatabaseItem.Spec.Adopt = true
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.objects = append(f.objects, customDatabaseItem)
+	f.databases = append(f.databases, expCustomDb)
+	f.unmarkedDatabases = append(f.unmarkedDatabases, "test")
+	f.adoptionAllowList = []string{"legacy/*"}
+
+	expStatus := customDatabaseItem.DeepCopy()
+	expStatus.Status.DatabaseName = "test"
+	expStatus.Status.Conditions = []metav1.Condition{{
+		Type:               customdatabasecontroller.ConditionAdopted,
+		Status:             metav1.ConditionFalse,
+		LastTransitionTime: metav1.NewTime(testNow),
+		Reason:             "AdoptionNotAllowed",
+		Message:            "Database test isn't allowed for adoption in namespace default by administrator",
+	}}
+
+	f.expectUpdateCustomDatabaseStatusAction(expStatus)
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestAdoptDatabase(t *testing.T) {
+	f := newFixture(t)
+
+	expCustomDb := newEntity("test")
+	customDatabaseItem := withStatus(newCustomDatabase("test"), expCustomDb)
+	customDatabaseItem.Spec.Adopt = true
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.objects = append(f.objects, customDatabaseItem)
+	f.databases = append(f.databases, expCustomDb)
+	f.unmarkedDatabases = append(f.unmarkedDatabases, "test")
+	f.adoptionAllowList = []string{"default/*"}
+
+	// Secret with the current password was created by hand, so it isn't controlled by CustomDatabase
+	legacySecret := secretWithDBInfo(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: customDatabaseItem.Spec.SecretName, Namespace: metav1.NamespaceDefault},
+	}, expCustomDb)
+	f.secretLister = append(f.secretLister, legacySecret)
+	f.kubeobjects = append(f.kubeobjects, legacySecret)
+
+	expStatus := customDatabaseItem.DeepCopy()
+	expStatus.Status.Conditions = append(expStatus.Status.Conditions, metav1.Condition{
+		Type:               customdatabasecontroller.ConditionAdopted,
+		Status:             metav1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(testNow),
+		Reason:             DatabaseAdopted,
+		Message:            "Database test and role test are adopted",
+	})
+	expMarker := newOwnershipMarker(metav1.NamespaceDefault, "test")
+	expMarker.Adopted = true
+
+	f.expectUpdateCustomDatabaseStatusAction(expStatus)
+	f.expectExistsDatabase(expCustomDb)
+	f.expectOwnership("test", expMarker)
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestDoNothing(t *testing.T) {
+	f := newFixture(t)
+
+	expCustomDb := newEntity("test")
+	customDatabaseItem := withStatus(newCustomDatabase("test"), expCustomDb)
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.objects = append(f.objects, customDatabaseItem)
+
+	f.databases = append(f.databases, expCustomDb)
+
+	expFinalSecret := secretWithDBInfo(newEmptySecret(customDatabaseItem), expCustomDb)
+	f.secretLister = append(f.secretLister, expFinalSecret)
+	f.kubeobjects = append(f.kubeobjects, expFinalSecret)
+
+	f.expectExistsDatabase(expCustomDb)
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestKeepDatabaseNameWhenNamingStrategyChanges(t *testing.T) {
+	f := newFixture(t)
+
+	// database was created by name strategy before its name was kept in status
+	expCustomDb := newEntity("test")
+	expStatus := withStatus(newCustomDatabase("test"), expCustomDb)
+	customDatabaseItem := expStatus.DeepCopy()
+	customDatabaseItem.Status.DatabaseName = ""
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.namingStrategy = customdatabase.NamingStrategyNamespaceName
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.objects = append(f.objects, customDatabaseItem)
+	f.databases = append(f.databases, expCustomDb)
+
+	expFinalSecret := secretWithDBInfo(newEmptySecret(customDatabaseItem), expCustomDb)
+	f.secretLister = append(f.secretLister, expFinalSecret)
+	f.kubeobjects = append(f.kubeobjects, expFinalSecret)
+
+	f.expectUpdateCustomDatabaseStatusAction(expStatus)
+	f.expectExistsDatabase(expCustomDb)
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestRepairDrift(t *testing.T) {
+	f := newFixture(t)
+
+	expCustomDb := newEntity("test")
+	customDatabaseItem := withStatus(newCustomDatabase("test"), expCustomDb)
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.objects = append(f.objects, customDatabaseItem)
+
+	// password was changed by hand
+	changedCustomDb := newEntity("test")
+	changedCustomDb.Database.Password = "changed"
+	f.databases = append(f.databases, changedCustomDb)
+
+	expFinalSecret := secretWithDBInfo(newEmptySecret(customDatabaseItem), expCustomDb)
+	f.secretLister = append(f.secretLister, expFinalSecret)
+	f.kubeobjects = append(f.kubeobjects, expFinalSecret)
+
+	f.expectExistsDatabase(expCustomDb)
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestRepairDriftOfLimits(t *testing.T) {
+	f := newFixture(t)
+
+	expCustomDb := newEntity("test")
+	customDatabaseItem := withStatus(newCustomDatabase("test"), expCustomDb)
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.objects = append(f.objects, customDatabaseItem)
+
+	// only connection limit was changed by hand
+	changedCustomDb := newEntity("test")
+	changedCustomDb.Database.Limits.ConnectionLimit = 5
+	f.databases = append(f.databases, changedCustomDb)
+
+	expFinalSecret := secretWithDBInfo(newEmptySecret(customDatabaseItem), expCustomDb)
+	f.secretLister = append(f.secretLister, expFinalSecret)
+	f.kubeobjects = append(f.kubeobjects, expFinalSecret)
+
+	// no Drifted condition is set, limits are restored
+	f.expectExistsDatabase(expCustomDb)
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestReportDrift(t *testing.T) {
+	f := newFixture(t)
+	f.driftPolicy = customdatabase.DriftPolicyReport
+
+	expCustomDb := newEntity("test")
+	customDatabaseItem := withStatus(newCustomDatabase("test"), expCustomDb)
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.objects = append(f.objects, customDatabaseItem)
+
+	changedCustomDb := newEntity("test")
+	changedCustomDb.Database.Password = "changed"
+	f.databases = append(f.databases, changedCustomDb)
+
+	expFinalSecret := secretWithDBInfo(newEmptySecret(customDatabaseItem), expCustomDb)
+	f.secretLister = append(f.secretLister, expFinalSecret)
+	f.kubeobjects = append(f.kubeobjects, expFinalSecret)
+
+	expStatus := customDatabaseItem.DeepCopy()
+	expStatus.Status.Conditions = append(expStatus.Status.Conditions, metav1.Condition{
+		Type:               customdatabasecontroller.ConditionDrifted,
+		Status:             metav1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(testNow),
+		Reason:             "Reported",
+		Message: "Database differs from desired state: password of role test differs from Secret. " +
+			"Revert the changes or switch controller to repair policy",
+	})
+
+	f.expectUpdateCustomDatabaseStatusAction(expStatus)
+	// database isn't changed, while drift is reported
+	f.expectExistsDatabase(changedCustomDb)
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestConnectRevokedByControllerIsNotDrift(t *testing.T) {
+	f := newFixture(t)
+
+	expCustomDb := newEntity("test")
+	customDatabaseItem := withStatus(newCustomDatabase("test"), expCustomDb)
+	f.databases = append(f.databases, expCustomDb)
+	f.revokedConnects = append(f.revokedConnects, expCustomDb.Database.Name)
+	storedSecret := secretWithDBInfo(newEmptySecret(customDatabaseItem), expCustomDb)
+	_, ctx := ktesting.NewTestContext(t)
+
+	c, _, _, databaseManager := f.newController(ctx)
+	database := expCustomDb.Database.Name
+
+	// source database is closed, while it's copied to another server
+	newStatus := customDatabaseItem.Status.DeepCopy()
+	newStatus.ServerMigration = &customdatabasecontroller.ServerMigrationStatus{
+		Phase: customdatabasecontroller.ServerMigrationPhaseCopying,
+	}
+	isInSync, err := c.actualizeDrift(ctx, customDatabaseItem, expCustomDb, storedSecret, newStatus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isInSync || !databaseManager.ConnectRevoked[database] {
+		t.Errorf("CONNECT revoked by server migration shouldn't be repaired as drift")
+	}
+
+	// revoke by hand is repaired
+	newStatus.ServerMigration = nil
+	if _, err = c.actualizeDrift(ctx, customDatabaseItem, expCustomDb, storedSecret, newStatus); err != nil {
+		t.Fatal(err)
+	}
+	if databaseManager.ConnectRevoked[database] {
+		t.Errorf("CONNECT revoked by hand should be repaired as drift")
+	}
+}
+
+func TestReportDriftOfAdoptedDatabase(t *testing.T) {
+	f := newFixture(t)
+
+	expCustomDb := newEntity("test")
+	customDatabaseItem := withStatus(newCustomDatabase("test"), expCustomDb)
+	customDatabaseItem.Spec.Adopt = true
+	customDatabaseItem.Status.Conditions = append(customDatabaseItem.Status.Conditions, metav1.Condition{
+		Type:               customdatabasecontroller.ConditionAdopted,
+		Status:             metav1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(testNow),
+		Reason:             DatabaseAdopted,
+		Message:            "Database test and role test are adopted",
+	})
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.objects = append(f.objects, customDatabaseItem)
+
+	// legacy application connects with another password and relies on privileges of PUBLIC
+	changedCustomDb := newEntity("test")
+	changedCustomDb.Database.Password = "changed"
+	f.databases = append(f.databases, changedCustomDb)
+	f.securityDrift["test"] = []string{"PUBLIC has CONNECT privilege on database"}
+	adoptedMarker := newOwnershipMarker(metav1.NamespaceDefault, "test")
+	adoptedMarker.Adopted = true
+	f.ownershipMarkers["test"] = adoptedMarker
+
+	expFinalSecret := secretWithDBInfo(newEmptySecret(customDatabaseItem), expCustomDb)
+	f.secretLister = append(f.secretLister, expFinalSecret)
+	f.kubeobjects = append(f.kubeobjects, expFinalSecret)
+
+	expStatus := customDatabaseItem.DeepCopy()
+	expStatus.Status.Conditions[0] = metav1.Condition{
+		Type:               customdatabasecontroller.ConditionHardened,
+		Status:             metav1.ConditionFalse,
+		LastTransitionTime: metav1.NewTime(testNow),
+		Reason:             AdoptedNotHardened,
+		Message: "Privileges of adopted database drift from least-privilege defaults: PUBLIC has CONNECT privilege " +
+			"on database. Set annotation " + customdatabasecontroller.AnnotationAdoptManage + " to \"true\" to harden it",
+	}
+	expStatus.Status.Conditions = append(expStatus.Status.Conditions, metav1.Condition{
+		Type:               customdatabasecontroller.ConditionDrifted,
+		Status:             metav1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(testNow),
+		Reason:             AdoptedNotRepaired,
+		Message: "Database differs from desired state: password of role test differs from Secret. " +
+			"Set annotation " + customdatabasecontroller.AnnotationAdoptManage + " to \"true\" to repair it",
+	})
+
+	f.expectUpdateCustomDatabaseStatusAction(expStatus)
+	// findings are only reported, adopted database is still provisioned
+	f.expectExistsDatabase(changedCustomDb)
+	f.expectOwnership("test", adoptedMarker)
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestPausedCustomDatabaseIsNotProvisioned(t *testing.T) {
+	f := newFixture(t)
+
+	customDatabaseItem := newCustomDatabase("test")
+	customDatabaseItem.Annotations = map[string]string{customdatabasecontroller.AnnotationPaused: "true"}
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.objects = append(f.objects, customDatabaseItem)
+
+	expStatus := customDatabaseItem.DeepCopy()
+	expStatus.Status.Conditions = append(expStatus.Status.Conditions, metav1.Condition{
+		Type:               customdatabasecontroller.ConditionPaused,
+		Status:             metav1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(testNow),
+		Reason:             "Annotation",
+		Message:            "Reconciliation is paused by annotation " + customdatabasecontroller.AnnotationPaused,
+	})
+
+	f.expectUpdateCustomDatabaseStatusAction(expStatus)
+	f.notExpectExistsDatabase(newEntity("test"))
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestReadOnlyReportsDrift(t *testing.T) {
+	f := newFixture(t)
+	f.readOnly = true
+
+	expCustomDb := newEntity("test")
+	customDatabaseItem := withStatus(newCustomDatabase("test"), expCustomDb)
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.objects = append(f.objects, customDatabaseItem)
+
+	changedCustomDb := newEntity("test")
+	changedCustomDb.Database.Password = "changed"
+	f.databases = append(f.databases, changedCustomDb)
+
+	expFinalSecret := secretWithDBInfo(newEmptySecret(customDatabaseItem), expCustomDb)
+	f.secretLister = append(f.secretLister, expFinalSecret)
+	f.kubeobjects = append(f.kubeobjects, expFinalSecret)
+
+	expStatus := customDatabaseItem.DeepCopy()
+	expStatus.Status.Conditions = append(expStatus.Status.Conditions,
+		metav1.Condition{
+			Type:               customdatabasecontroller.ConditionDrifted,
+			Status:             metav1.ConditionTrue,
+			LastTransitionTime: metav1.NewTime(testNow),
+			Reason:             "Reported",
+			Message: "Database differs from desired state: password of role test differs from Secret. " +
+				"Revert the changes or switch controller to repair policy",
+		},
+		metav1.Condition{
+			Type:               customdatabasecontroller.ConditionPaused,
+			Status:             metav1.ConditionTrue,
+			LastTransitionTime: metav1.NewTime(testNow),
+			Reason:             "ReadOnly",
+			Message:            "Controller runs in read-only mode, database is only observed",
+		},
+	)
+
+	f.expectUpdateCustomDatabaseStatusAction(expStatus)
+	// drift isn't repaired in read-only mode, even by repair policy
+	f.expectExistsDatabase(changedCustomDb)
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestResumePausedCustomDatabase(t *testing.T) {
+	f := newFixture(t)
+
+	customDatabaseItem := newCustomDatabase("test")
+	customDatabaseItem.Status.Conditions = []metav1.Condition{{
+		Type:               customdatabasecontroller.ConditionPaused,
+		Status:             metav1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(testNow),
+		Reason:             "Annotation",
+	}}
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.objects = append(f.objects, customDatabaseItem)
+
+	expCustomDb := newEntity("test")
+	f.expectCreateSecretAction(secretWithDBInfo(newEmptySecret(customDatabaseItem), expCustomDb))
+	f.expectUpdateCustomDatabaseStatusAction(withStatus(newCustomDatabase("test"), expCustomDb))
+	f.expectExistsDatabase(expCustomDb)
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestUpdateSecret(t *testing.T) {
+	f := newFixture(t)
+
+	oldCustomDatabaseItem := newCustomDatabase("test")
+	updatedCustomDatabaseItem := newCustomDatabaseWithCustomSecret("test", "test-secret-updated")
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.customDatabaseLister = append(f.customDatabaseLister, updatedCustomDatabaseItem)
+	f.objects = append(f.objects, updatedCustomDatabaseItem)
+
+	expCustomDb := newEntity("test")
+	f.databases = append(f.databases, expCustomDb)
+
+	oldFinalSecret := secretWithDBInfo(newEmptySecret(oldCustomDatabaseItem), expCustomDb)
+	f.secretLister = append(f.secretLister, oldFinalSecret)
+	f.kubeobjects = append(f.kubeobjects, oldFinalSecret)
+
+	expFinalSecret := secretWithDBInfo(newEmptySecret(updatedCustomDatabaseItem), expCustomDb)
+	f.expectCreateSecretAction(expFinalSecret)
+	f.expectUpdateCustomDatabaseStatusAction(withStatus(updatedCustomDatabaseItem, expCustomDb))
+	f.expectExistsDatabase(expCustomDb)
+
+	f.run(ctx, getKey(updatedCustomDatabaseItem, t))
+}
+
+func TestPublishTLSToSecret(t *testing.T) {
+	f := newFixture(t)
+	_, ctx := ktesting.NewTestContext(t)
+
+	expCustomDb := newEntity("test")
+	customDatabaseItem := withStatus(newCustomDatabase("test"), expCustomDb)
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.objects = append(f.objects, customDatabaseItem)
+	f.databases = append(f.databases, expCustomDb)
+	storedSecret := secretWithDBInfo(newEmptySecret(customDatabaseItem), expCustomDb)
+	f.secretLister = append(f.secretLister, storedSecret)
+	f.kubeobjects = append(f.kubeobjects, storedSecret)
+
+	c, _, k8sI, _ := f.newController(ctx)
+	issuer := fakeadapter.NewCertificateIssuer(365 * 24 * time.Hour)
+	c.tenantTLS = &TenantTLS{SSLMode: "verify-full", CACert: []byte("ca bundle"), Issuer: issuer}
+	sync := func() *corev1.Secret {
+		t.Helper()
+		if err := c.syncHandler(ctx, getKey(customDatabaseItem, t)); err != nil {
+			t.Fatalf("error syncing customDatabase: %v", err)
+		}
+		secret, err := f.kubeclient.CoreV1().Secrets(metav1.NamespaceDefault).Get(ctx, "test-secret", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = k8sI.Core().V1().Secrets().Informer().GetIndexer().Update(secret)
+		return secret
+	}
+
+	secret := sync()
+	if string(secret.Data[SecretVarDbSSLMode]) != "verify-full" || string(secret.Data[SecretKeyCACert]) != "ca bundle" {
+		t.Errorf("sslmode and CA bundle should be published, got %s", secret.Data)
+	}
+	if !isClientCertificateValid(secret.Data[SecretKeyClientCert], "test", time.Now()) ||
+		len(secret.Data[SecretKeyClientKey]) == 0 {
+		t.Errorf("client certificate of owner should be published")
+	}
+	if string(secret.Data[SecretVarDbPassword]) != expCustomDb.Database.Password {
+		t.Errorf("credentials of Secret shouldn't be changed")
+	}
+
+	cert := secret.Data[SecretKeyClientCert]
+	if secret = sync(); string(secret.Data[SecretKeyClientCert]) != string(cert) || issuer.Issued["test"] != 1 {
+		t.Errorf("valid client certificate should be kept")
+	}
+
+	c.clock = testingclock.NewFakePassiveClock(time.Now().Add(360 * 24 * time.Hour))
+	if secret = sync(); string(secret.Data[SecretKeyClientCert]) == string(cert) || issuer.Issued["test"] != 2 {
+		t.Errorf("client certificate should be issued again before expiration")
+	}
+}
+
+func TestApplyLimits(t *testing.T) {
+	f := newFixture(t)
+
+	connectionLimit := int32(10)
+	customDatabaseItem := newCustomDatabase("test")
+	customDatabaseItem.Spec.Limits = customdatabasecontroller.DatabaseLimits{
+		ConnectionLimit:  &connectionLimit,
+		StatementTimeout: "30s",
+		WorkMem:          "4MB",
+	}
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.objects = append(f.objects, customDatabaseItem)
+
+	expCustomDb := newEntity("test")
+	f.databases = append(f.databases, expCustomDb)
+
+	expFinalSecret := secretWithDBInfo(newEmptySecret(customDatabaseItem), expCustomDb)
+	f.secretLister = append(f.secretLister, expFinalSecret)
+	f.kubeobjects = append(f.kubeobjects, expFinalSecret)
+
+	expCustomDb.Database.Limits = customdatabase.Limits{ConnectionLimit: 10, StatementTimeout: "30s", WorkMem: "4MB"}
+	f.expectUpdateCustomDatabaseStatusAction(withStatus(customDatabaseItem, expCustomDb))
+	f.expectExistsDatabase(expCustomDb)
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestStorageQuotaExceeded(t *testing.T) {
+	f := newFixture(t)
+
+	customDatabaseItem := newCustomDatabase("test")
+	customDatabaseItem.Spec.StorageQuota = &customdatabasecontroller.StorageQuota{
+		Limit:                resource.MustParse("1Mi"),
+		ReadOnlyWhenExceeded: true,
+	}
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.objects = append(f.objects, customDatabaseItem)
+
+	expCustomDb := newEntity("test")
+	f.databases = append(f.databases, expCustomDb)
+	f.databaseSizes["test"] = 2 * 1024 * 1024
+
+	expFinalSecret := secretWithDBInfo(newEmptySecret(customDatabaseItem), expCustomDb)
+	f.secretLister = append(f.secretLister, expFinalSecret)
+	f.kubeobjects = append(f.kubeobjects, expFinalSecret)
+
+	expCustomDatabase := withStatus(customDatabaseItem, expCustomDb)
+	expCustomDatabase.Status.Size = resource.NewQuantity(2*1024*1024, resource.BinarySI)
+	expCustomDatabase.Status.ReadOnlyByQuota = true
+	expCustomDatabase.Status.Conditions = append([]metav1.Condition{{
+		Type:               customdatabasecontroller.ConditionQuotaExceeded,
+		Status:             metav1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(testNow),
+		Reason:             customdatabasecontroller.ConditionQuotaExceeded,
+		Message:            "Database size 2Mi exceeds storage quota 1Mi, database is read-only",
+	}}, expCustomDatabase.Status.Conditions...)
+	f.expectUpdateCustomDatabaseStatusAction(expCustomDatabase)
+	f.expectExistsDatabase(expCustomDb)
+	f.expectReadOnlyDatabase("test")
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestReadOnlyDatabaseIsMadeWritableOnlyByQuota(t *testing.T) {
+	f := newFixture(t)
+
+	customDatabaseItem := newCustomDatabase("test")
+	customDatabaseItem.Spec.StorageQuota = &customdatabasecontroller.StorageQuota{
+		Limit:                resource.MustParse("1Mi"),
+		ReadOnlyWhenExceeded: true,
 	}
+	f.databases = append(f.databases, newEntity("test"))
+	f.databaseSizes["test"] = 2 * 1024 * 1024
+	_, ctx := ktesting.NewTestContext(t)
 
-	expFinalSecret := secretWithDBInfo(newEmptySecret(customDatabaseItem), expCustomDb)
+	c, _, _, databaseManager := f.newController(ctx)
+	c.storageSamplingPeriod = 0
+	newStatus := customDatabaseItem.Status.DeepCopy()
 
-	f.expectCreateSecretAction(expFinalSecret)
-	f.expectExistsDatabase(expCustomDb)
+	// database was made read-only by administrator
+	databaseManager.ReadOnly["test"] = true
+	databaseManager.Sizes["test"] = 1024
+	if err := c.actualizeStorageQuota(ctx, customDatabaseItem, "test", newStatus); err != nil {
+		t.Fatal(err)
+	}
+	if !databaseManager.ReadOnly["test"] || newStatus.ReadOnlyByQuota {
+		t.Errorf("database, which was made read-only by administrator, should stay read-only")
+	}
 
-	f.run(ctx, getKey(customDatabaseItem, t))
+	databaseManager.Sizes["test"] = 2 * 1024 * 1024
+	if err := c.actualizeStorageQuota(ctx, customDatabaseItem, "test", newStatus); err != nil {
+		t.Fatal(err)
+	}
+	if !newStatus.ReadOnlyByQuota {
+		t.Errorf("database should be made read-only by quota")
+	}
+
+	databaseManager.Sizes["test"] = 1024
+	if err := c.actualizeStorageQuota(ctx, customDatabaseItem, "test", newStatus); err != nil {
+		t.Fatal(err)
+	}
+	if databaseManager.ReadOnly["test"] || newStatus.ReadOnlyByQuota {
+		t.Errorf("database, which was made read-only by quota, should be writable within quota")
+	}
 }
 
-func TestDoNothing(t *testing.T) {
+func TestRepairSecurityDrift(t *testing.T) {
 	f := newFixture(t)
 
-	customDatabaseItem := newCustomDatabase("test")
+	expCustomDb := newEntity("test")
+	customDatabaseItem := withStatus(newCustomDatabase("test"), expCustomDb)
 	_, ctx := ktesting.NewTestContext(t)
 
 	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
 	f.objects = append(f.objects, customDatabaseItem)
 
-	expCustomDb := customdatabase.Entity{
-		Host:     customdatabase.Host{"localhost", 5432},
-		Database: customdatabase.Database{Name: "test", User: "test", Password: "testtesttest"},
-	}
 	f.databases = append(f.databases, expCustomDb)
+	f.securityDrift["test"] = []string{"PUBLIC has CONNECT privilege on database"}
 
 	expFinalSecret := secretWithDBInfo(newEmptySecret(customDatabaseItem), expCustomDb)
 	f.secretLister = append(f.secretLister, expFinalSecret)
 	f.kubeobjects = append(f.kubeobjects, expFinalSecret)
 
+	// drift is repaired, so status stays the same
 	f.expectExistsDatabase(expCustomDb)
+	f.expectHardenedDatabase("test")
 
 	f.run(ctx, getKey(customDatabaseItem, t))
 }
 
-func TestUpdateSecret(t *testing.T) {
+func TestDeleteDatabaseAndSecret(t *testing.T) {
 	f := newFixture(t)
 
-	oldCustomDatabaseItem := newCustomDatabase("test")
-	updatedCustomDatabaseItem := newCustomDatabaseWithCustomSecret("test", "test-secret-updated")
+	customDatabaseItem := newCustomDatabase("test")
 	_, ctx := ktesting.NewTestContext(t)
 
-	f.customDatabaseLister = append(f.customDatabaseLister, updatedCustomDatabaseItem)
-	f.objects = append(f.objects, updatedCustomDatabaseItem)
+	expCustomDb := newEntity("test")
+	f.databases = append(f.databases, expCustomDb)
 
-	expCustomDb := customdatabase.Entity{
-		Host:     customdatabase.Host{"localhost", 5432},
-		Database: customdatabase.Database{Name: "test", User: "test", Password: "testtesttest"},
-	}
+	expFinalSecret := secretWithDBInfo(newEmptySecret(customDatabaseItem), expCustomDb)
+	f.secretLister = append(f.secretLister, expFinalSecret)
+	f.kubeobjects = append(f.kubeobjects, expFinalSecret)
+
+	// secret will be deleted, because his owner was deleted. It will do k8s, not controller
+	f.notExpectExistsDatabase(expCustomDb)
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestRefuseToDropDatabaseOfAnotherCustomDatabase(t *testing.T) {
+	f := newFixture(t)
+
+	customDatabaseItem := newCustomDatabase("test")
+	_, ctx := ktesting.NewTestContext(t)
+
+	expCustomDb := newEntity("test")
 	f.databases = append(f.databases, expCustomDb)
+	f.ownershipMarkers["test"] = newOwnershipMarker("other", "test")
 
-	oldFinalSecret := secretWithDBInfo(newEmptySecret(oldCustomDatabaseItem), expCustomDb)
-	f.secretLister = append(f.secretLister, oldFinalSecret)
-	f.kubeobjects = append(f.kubeobjects, oldFinalSecret)
+	f.expectExistsDatabase(expCustomDb)
+
+	f.run(ctx, getKey(customDatabaseItem, t))
+}
+
+func TestRefuseToDropUnmarkedDatabase(t *testing.T) {
+	f := newFixture(t)
+
+	customDatabaseItem := newCustomDatabase("test")
+	_, ctx := ktesting.NewTestContext(t)
+
+	expCustomDb := newEntity("test")
+	f.databases = append(f.databases, expCustomDb)
+	f.unmarkedDatabases = append(f.unmarkedDatabases, "test")
 
-	expFinalSecret := secretWithDBInfo(newEmptySecret(updatedCustomDatabaseItem), expCustomDb)
-	f.expectCreateSecretAction(expFinalSecret)
 	f.expectExistsDatabase(expCustomDb)
 
-	f.run(ctx, getKey(updatedCustomDatabaseItem, t))
+	f.run(ctx, getKey(customDatabaseItem, t))
 }
 
-func TestDeleteDatabaseAndSecret(t *testing.T) {
+func TestKeepDatabaseOfCustomDatabaseDeletedWhilePaused(t *testing.T) {
 	f := newFixture(t)
 
 	customDatabaseItem := newCustomDatabase("test")
+	customDatabaseItem.Annotations = map[string]string{customdatabasecontroller.AnnotationPaused: "true"}
 	_, ctx := ktesting.NewTestContext(t)
 
-	expCustomDb := customdatabase.Entity{
-		Host:     customdatabase.Host{"localhost", 5432},
-		Database: customdatabase.Database{Name: "test", User: "test", Password: "testtesttest"},
+	expCustomDb := newEntity("test")
+	f.databases = append(f.databases, expCustomDb)
+
+	c, _, _, databaseManager := f.newController(ctx)
+	c.handleDeletedCustomDatabase(cache.DeletedFinalStateUnknown{
+		Key: getKey(customDatabaseItem, t), Obj: customDatabaseItem,
+	})
+
+	if err := c.syncHandler(ctx, getKey(customDatabaseItem, t)); err != nil {
+		t.Fatalf("error syncing customDatabase: %v", err)
+	}
+
+	if _, isExists := databaseManager.Databases["test"]; !isExists {
+		t.Errorf("database of CustomDatabase deleted while paused should be kept")
+	}
+	if _, isExists := databaseManager.Users["test"]; !isExists {
+		t.Errorf("role of CustomDatabase deleted while paused should be kept")
 	}
+}
+
+func TestKeepDatabaseOfPausedCustomDatabaseByOwnershipMarker(t *testing.T) {
+	f := newFixture(t)
+
+	customDatabaseItem := newCustomDatabase("test")
+	customDatabaseItem.Annotations = map[string]string{customdatabasecontroller.AnnotationPaused: "true"}
+	_, ctx := ktesting.NewTestContext(t)
+
+	expCustomDb := newEntity("test")
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.objects = append(f.objects, customDatabaseItem)
+	f.secretLister = append(f.secretLister, secretWithDBInfo(newEmptySecret(customDatabaseItem), expCustomDb))
 	f.databases = append(f.databases, expCustomDb)
 
-	expFinalSecret := secretWithDBInfo(newEmptySecret(customDatabaseItem), expCustomDb)
-	f.secretLister = append(f.secretLister, expFinalSecret)
-	f.kubeobjects = append(f.kubeobjects, expFinalSecret)
+	c, i, _, databaseManager := f.newController(ctx)
+	if err := c.syncHandler(ctx, getKey(customDatabaseItem, t)); err != nil {
+		t.Fatalf("error syncing customDatabase: %v", err)
+	}
 
-	// secret will be deleted, because his owner was deleted. It will do k8s, not controller
-	f.notExpectExistsDatabase(expCustomDb)
+	// paused CustomDatabase doesn't change server, paused state is kept in status
+	marker := newOwnershipMarker(metav1.NamespaceDefault, "test")
+	if ownership := databaseManager.DatabaseOwnership["test"]; ownership != marker {
+		t.Errorf("database of paused CustomDatabase shouldn't be changed, given %+v", ownership)
+	}
+	if ownership := databaseManager.UserOwnership["test"]; ownership != marker {
+		t.Errorf("role of paused CustomDatabase shouldn't be changed, given %+v", ownership)
+	}
+	pausedItem, err := f.client.IgorV1().CustomDatabases(metav1.NamespaceDefault).Get(ctx, "test", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	f.run(ctx, getKey(customDatabaseItem, t))
+	// annotation is missed by stale tombstone, but Paused condition is in status
+	pausedItem.Annotations = nil
+	if err = i.Igor().V1().CustomDatabases().Informer().GetIndexer().Delete(customDatabaseItem); err != nil {
+		t.Fatal(err)
+	}
+	c.handleDeletedCustomDatabase(pausedItem)
+	if err = c.syncHandler(ctx, getKey(customDatabaseItem, t)); err != nil {
+		t.Fatalf("error syncing customDatabase: %v", err)
+	}
+
+	if _, isExists := databaseManager.Databases["test"]; !isExists {
+		t.Errorf("database of CustomDatabase deleted while paused should not be dropped")
+	}
+	if _, isExists := databaseManager.Users["test"]; !isExists {
+		t.Errorf("role of CustomDatabase deleted while paused should not be dropped")
+	}
+	expMarker := marker
+	expMarker.Kept = true
+	if ownership := databaseManager.DatabaseOwnership["test"]; ownership != expMarker {
+		t.Errorf("database should be marked as kept at deletion, given %+v", ownership)
+	}
+	if ownership := databaseManager.UserOwnership["test"]; ownership != expMarker {
+		t.Errorf("role should be marked as kept at deletion, given %+v", ownership)
+	}
+}
+
+func TestReportOrphans(t *testing.T) {
+	f := newFixture(t)
+	_, ctx := ktesting.NewTestContext(t)
+
+	customDatabaseItem := newCustomDatabase("kept")
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.objects = append(f.objects, customDatabaseItem)
+	f.databases = append(f.databases, newEntity("kept"), newEntity("test"))
+
+	c, _, _, databaseManager := f.newController(ctx)
+	c.sweepOrphans(ctx)
+
+	if len(c.orphans) != 2 {
+		t.Fatalf("expected database and role of deleted CustomDatabase to be orphans, got %v", c.orphans)
+	}
+	for _, key := range []string{"database/test", "role/test"} {
+		if _, ok := c.orphans[key]; !ok {
+			t.Errorf("%s should be orphan", key)
+		}
+	}
+	if _, isExists := databaseManager.Databases["test"]; !isExists {
+		t.Errorf("orphan shouldn't be dropped without -gc-orphans")
+	}
+}
+
+func TestDropOrphansAfterGracePeriod(t *testing.T) {
+	f := newFixture(t)
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.databases = append(f.databases, newEntity("test"), newEntity("foreign"), newEntity("kept"))
+	foreignMarker := newOwnershipMarker(metav1.NamespaceDefault, "foreign")
+	foreignMarker.Cluster = "other"
+	f.ownershipMarkers["foreign"] = foreignMarker
+	// CustomDatabase was deleted while paused
+	keptMarker := newOwnershipMarker(metav1.NamespaceDefault, "kept")
+	keptMarker.Kept = true
+	f.ownershipMarkers["kept"] = keptMarker
+
+	c, _, _, databaseManager := f.newController(ctx)
+	c.gcOrphans = true
+	c.orphanGracePeriod = time.Hour
+
+	c.sweepOrphans(ctx)
+	if _, isExists := databaseManager.Databases["test"]; !isExists {
+		t.Fatalf("orphan shouldn't be dropped before grace period")
+	}
+
+	c.clock = testingclock.NewFakePassiveClock(testNow.Add(2 * time.Hour))
+	c.sweepOrphans(ctx)
+
+	if _, isExists := databaseManager.Databases["test"]; isExists {
+		t.Errorf("test database should be dropped after grace period")
+	}
+	if _, isExists := databaseManager.Users["test"]; isExists {
+		t.Errorf("test user should be dropped after grace period")
+	}
+	if _, isExists := databaseManager.Databases["foreign"]; !isExists {
+		t.Errorf("database of another cluster shouldn't be dropped")
+	}
+	if _, isExists := databaseManager.Databases["kept"]; !isExists || databaseManager.ConnectRevoked["kept"] {
+		t.Errorf("kept database shouldn't be dropped or closed")
+	}
+	if _, isExists := databaseManager.Users["kept"]; !isExists {
+		t.Errorf("kept user shouldn't be dropped")
+	}
+	if len(c.orphans) != 0 {
+		t.Errorf("dropped orphans should be forgotten, got %v", c.orphans)
+	}
+}
+
+func TestMoveDatabaseToAnotherServer(t *testing.T) {
+	f := newFixture(t)
+	_, ctx := ktesting.NewTestContext(t)
+
+	expCustomDb := newEntity("test")
+	customDatabaseItem := withStatus(newCustomDatabase("test"), expCustomDb)
+	customDatabaseItem.Spec.ServerRef = "another"
+	f.objects = append(f.objects, customDatabaseItem)
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.databases = append(f.databases, expCustomDb)
+	secret := secretWithDBInfo(newEmptySecret(customDatabaseItem), expCustomDb)
+	f.secretLister = append(f.secretLister, secret)
+	f.kubeobjects = append(f.kubeobjects, secret)
+
+	m := f.newServerMigrationController(ctx)
+	// copy is made in background, its result is taken by the next sync
+	phases := []customdatabasecontroller.ServerMigrationPhase{
+		customdatabasecontroller.ServerMigrationPhaseProvisioning,
+		customdatabasecontroller.ServerMigrationPhaseCopying,
+		customdatabasecontroller.ServerMigrationPhaseCopying,
+		customdatabasecontroller.ServerMigrationPhaseSwitching,
+		customdatabasecontroller.ServerMigrationPhaseDraining,
+	}
+	for _, phase := range phases {
+		customDatabase := m.sync(ctx, "default/test")
+		if migration := customDatabase.Status.ServerMigration; migration == nil || migration.Phase != phase {
+			t.Fatalf("expected phase %s, got %+v", phase, migration)
+		}
+	}
+
+	if m.another.Users["test"] != expCustomDb.Database.Password {
+		t.Errorf("role should be created on another server with password of Secret")
+	}
+	if restored := string(m.restorer.Restored["test"]); restored != "dump of test" {
+		t.Errorf("dump of the old server should be restored, got %q", restored)
+	}
+	if m.source.Users["test"] == expCustomDb.Database.Password || !m.source.SessionsTerminated["test"] {
+		t.Errorf("database on the old server should be closed for tenant without sessions")
+	}
+	secret, _ = f.kubeclient.CoreV1().Secrets(metav1.NamespaceDefault).Get(ctx, "test-secret", metav1.GetOptions{})
+	if string(secret.Data[SecretVarDbHost]) != "another" || string(secret.Data[SecretVarDbPort]) != "5433" {
+		t.Errorf("Secret should point to another server, got %s", secret.Data)
+	}
+
+	// database on the old server is kept for grace period
+	customDatabase := m.sync(ctx, "default/test")
+	if customDatabase.Status.Server != "another" || customDatabase.Status.ServerMigration == nil {
+		t.Fatalf("migration should be drained on another server, got %+v", customDatabase.Status)
+	}
+	if _, isExists := m.source.Databases["test"]; !isExists {
+		t.Fatalf("database on the old server shouldn't be dropped before grace period")
+	}
+
+	m.c.clock = testingclock.NewFakePassiveClock(testNow.Add(defaultServerGracePeriod + time.Hour))
+	customDatabase = m.sync(ctx, "default/test")
+	if customDatabase.Status.ServerMigration != nil {
+		t.Errorf("migration should be finished, got %+v", customDatabase.Status.ServerMigration)
+	}
+	if _, isExists := m.source.Databases["test"]; isExists {
+		t.Errorf("database on the old server should be dropped after grace period")
+	}
+	if _, isExists := m.source.Users["test"]; isExists {
+		t.Errorf("role on the old server should be dropped after grace period")
+	}
+	if _, isExists := m.another.Databases["test"]; !isExists {
+		t.Errorf("database should live on another server")
+	}
+}
+
+func TestCancelServerMigration(t *testing.T) {
+	f := newFixture(t)
+	_, ctx := ktesting.NewTestContext(t)
+
+	expCustomDb := newEntity("test")
+	customDatabaseItem := withStatus(newCustomDatabase("test"), expCustomDb)
+	customDatabaseItem.Spec.ServerRef = "another"
+	f.objects = append(f.objects, customDatabaseItem)
+	f.customDatabaseLister = append(f.customDatabaseLister, customDatabaseItem)
+	f.databases = append(f.databases, expCustomDb)
+	secret := secretWithDBInfo(newEmptySecret(customDatabaseItem), expCustomDb)
+	f.secretLister = append(f.secretLister, secret)
+	f.kubeobjects = append(f.kubeobjects, secret)
+
+	m := f.newServerMigrationController(ctx)
+	// copy fails, so database on the old server is opened again and migration stays in phase Copying
+	m.restorer.Err = fmt.Errorf("restore failed")
+	m.sync(ctx, "default/test")
+	m.sync(ctx, "default/test")
+	m.sync(ctx, "default/test")
+	if err := m.c.syncHandler(ctx, "default/test"); err == nil {
+		t.Fatalf("failed copy should be retried")
+	}
+	if m.source.Users["test"] != expCustomDb.Database.Password {
+		t.Errorf("database on the old server should be opened for tenant after failed copy")
+	}
+
+	customDatabase, _ := f.client.IgorV1().CustomDatabases(metav1.NamespaceDefault).Get(ctx, "test", metav1.GetOptions{})
+	customDatabase.Spec.ServerRef = ""
+	customDatabase = m.update(ctx, customDatabase)
+	customDatabase = m.sync(ctx, "default/test")
+
+	if customDatabase.Status.ServerMigration != nil || customDatabase.Status.Server != "default" {
+		t.Errorf("migration should be cancelled, got %+v", customDatabase.Status)
+	}
+	if _, isExists := m.another.Databases["test"]; isExists {
+		t.Errorf("database on another server should be dropped")
+	}
+	if _, isExists := m.another.Users["test"]; isExists {
+		t.Errorf("role on another server should be dropped")
+	}
+	if _, isExists := m.source.Databases["test"]; !isExists {
+		t.Errorf("database should stay on the old server")
+	}
+}
+
+// serverMigrationFixture controller with the default server of fixture and another server
+type serverMigrationFixture struct {
+	f        *fixture
+	c        *Controller
+	i        informers.SharedInformerFactory
+	k8sI     kubeinformers.SharedInformerFactory
+	source   *fakeadapter.DbManager
+	another  *fakeadapter.DbManager
+	restorer *fakeadapter.Restorer
+}
+
+func (f *fixture) newServerMigrationController(ctx context.Context) *serverMigrationFixture {
+	c, i, k8sI, databaseManager := f.newController(ctx)
+	another := fakeadapter.NewDbManager()
+	restorer := fakeadapter.NewRestorer()
+
+	c.defaultServer = "default"
+	c.servers = map[string]customdatabase.Host{"another": {Name: "another", Port: 5433}}
+	c.databaseManager = &serverRouter{
+		defaultServer: "default",
+		servers:       map[string]DatabaseManager{"default": databaseManager, "another": another},
+	}
+	c.dumper = fakeadapter.NewDumper()
+	c.restorer = restorer
+
+	return &serverMigrationFixture{
+		f: f, c: c, i: i, k8sI: k8sI, source: databaseManager, another: another, restorer: restorer,
+	}
+}
+
+// sync syncs CustomDatabase and puts its updated version and its Secret into informers
+func (m *serverMigrationFixture) sync(ctx context.Context, key string) *customdatabasecontroller.CustomDatabase {
+	m.f.t.Helper()
+
+	if err := m.c.syncHandler(ctx, key); err != nil {
+		m.f.t.Fatalf("error syncing customDatabase: %v", err)
+	}
+	m.c.serverCopies.wait()
+
+	customDatabase, err := m.f.client.IgorV1().CustomDatabases(metav1.NamespaceDefault).Get(
+		ctx, "test", metav1.GetOptions{},
+	)
+	if err != nil {
+		m.f.t.Fatal(err)
+	}
+	_ = m.i.Igor().V1().CustomDatabases().Informer().GetIndexer().Update(customDatabase)
+	secret, err := m.f.kubeclient.CoreV1().Secrets(metav1.NamespaceDefault).Get(
+		ctx, customDatabase.Spec.SecretName, metav1.GetOptions{},
+	)
+	if err != nil {
+		m.f.t.Fatal(err)
+	}
+	_ = m.k8sI.Core().V1().Secrets().Informer().GetIndexer().Update(secret)
+
+	return customDatabase
+}
+
+// update stores changed spec of CustomDatabase
+func (m *serverMigrationFixture) update(
+	ctx context.Context, customDatabase *customdatabasecontroller.CustomDatabase,
+) *customdatabasecontroller.CustomDatabase {
+	m.f.t.Helper()
+
+	customDatabase, err := m.f.client.IgorV1().CustomDatabases(customDatabase.Namespace).Update(
+		ctx, customDatabase, metav1.UpdateOptions{},
+	)
+	if err != nil {
+		m.f.t.Fatal(err)
+	}
+	_ = m.i.Igor().V1().CustomDatabases().Informer().GetIndexer().Update(customDatabase)
+
+	return customDatabase
 }
 
 var (
 	alwaysReady        = func() bool { return true }
 	noResyncPeriodFunc = func() time.Duration { return 0 }
+	testNow            = time.Date(2023, time.May, 1, 12, 0, 0, 0, time.UTC)
 )
 
 type fixture struct {
@@ -135,13 +1946,33 @@ type fixture struct {
 	// Objects to put in the store.
 	customDatabaseLister []*customdatabasecontroller.CustomDatabase
 	secretLister         []*corev1.Secret
+	configMapLister      []*corev1.ConfigMap
 	databases            []customdatabase.Entity
+	databaseSizes        map[string]int64
+	securityDrift        map[string][]string
+	databaseActivity     map[string]customdatabase.DatabaseActivity
+	revokedConnects      []string
+	ownershipMarkers     map[string]customdatabase.OwnershipMarker
+	unmarkedDatabases    []string
+	appliedScripts       map[string]map[string]string
+	adoptionAllowList    []string
+	namingStrategy       customdatabase.NamingStrategy
+	driftPolicy          customdatabase.DriftPolicy
+	readOnly             bool
 
 	// Actions expected to happen on the client.
 	kubeactions          []core.Action
 	actions              []core.Action
 	expectedDatabases    []customdatabase.Entity
 	notExpectedDatabases []customdatabase.Entity
+	readOnlyDatabases    []string
+	hardenedDatabases    []string
+	expectedRevoked      []string
+	expectedGranted      []string
+	expectedOwnership    map[string]customdatabase.OwnershipMarker
+	expectedReassigned   map[string]string
+	expectedScripts      map[string][]string
+	expectedEvents       []string
 
 	// Objects from here preloaded into NewSimpleFake.
 	kubeobjects []runtime.Object
@@ -153,6 +1984,14 @@ func newFixture(t *testing.T) *fixture {
 	f.t = t
 	f.objects = []runtime.Object{}
 	f.kubeobjects = []runtime.Object{}
+	f.databaseSizes = map[string]int64{}
+	f.securityDrift = map[string][]string{}
+	f.databaseActivity = map[string]customdatabase.DatabaseActivity{}
+	f.ownershipMarkers = map[string]customdatabase.OwnershipMarker{}
+	f.expectedOwnership = map[string]customdatabase.OwnershipMarker{}
+	f.expectedReassigned = map[string]string{}
+	f.expectedScripts = map[string][]string{}
+	f.driftPolicy = customdatabase.DriftPolicyRepair
 	return f
 }
 
@@ -182,19 +2021,27 @@ func (f *fixture) newController(ctx context.Context) (
 	i := informers.NewSharedInformerFactory(f.client, noResyncPeriodFunc())
 	k8sI := kubeinformers.NewSharedInformerFactory(f.kubeclient, noResyncPeriodFunc())
 
-	domainService, _ := customdatabase.NewDomainService("localhost", 5432)
+	domainService, _ := customdatabase.NewDomainService("localhost", 5432, "",
+		customdatabase.WithNamingStrategy(f.namingStrategy),
+	)
 	databaseManager := fakeadapter.NewDbManager()
 
 	c := NewController(ctx, f.kubeclient, f.client,
 		k8sI.Core().V1().Secrets(),
+		k8sI.Core().V1().ConfigMaps(),
 		i.Igor().V1().CustomDatabases(),
 		databaseManager,
 		domainService,
+		WithAdoptionAllowList(f.adoptionAllowList),
+		WithDriftPolicy(f.driftPolicy),
+		WithReadOnly(f.readOnly),
 	)
 
 	c.customDatabasesSynced = alwaysReady
 	c.secretSynced = alwaysReady
-	c.recorder = &record.FakeRecorder{}
+	c.configMapSynced = alwaysReady
+	c.recorder = record.NewFakeRecorder(100)
+	c.clock = testingclock.NewFakePassiveClock(testNow)
 
 	for _, f := range f.customDatabaseLister {
 		i.Igor().V1().CustomDatabases().Informer().GetIndexer().Add(f)
@@ -204,10 +2051,35 @@ func (f *fixture) newController(ctx context.Context) (
 		k8sI.Core().V1().Secrets().Informer().GetIndexer().Add(d)
 	}
 
+	for _, d := range f.configMapLister {
+		k8sI.Core().V1().ConfigMaps().Informer().GetIndexer().Add(d)
+	}
+
 	for _, d := range f.databases {
-		databaseManager.CreateDatabase(context.TODO(), d.Database.Name)
-		databaseManager.CreateUser(context.TODO(), d.Database.User, d.Database.Password)
+		marker := domainService.NewOwnershipMarker(
+			customdatabase.OwnerKindCustomDatabase, metav1.NamespaceDefault, d.Database.Name, "",
+		)
+		databaseManager.CreateUser(context.TODO(), d.Database.User, d.Database.Password, marker)
+		databaseManager.CreateDatabase(context.TODO(), d.Database.Name, d.Database.Options, marker)
+		databaseManager.Sizes[d.Database.Name] = f.databaseSizes[d.Database.Name]
+		databaseManager.SecurityDrift[d.Database.Name] = f.securityDrift[d.Database.Name]
 		databaseManager.GrantUserToDatabase(context.TODO(), d.Database.User, d.Database.Name)
+		databaseManager.SetDatabaseLimits(context.TODO(), d.Database.Name, d.Database.Limits)
+		databaseManager.Activity[d.Database.Name] = f.databaseActivity[d.Database.Name]
+		databaseManager.AppliedScripts[d.Database.Name] = f.appliedScripts[d.Database.Name]
+		if marker, ok := f.ownershipMarkers[d.Database.Name]; ok {
+			databaseManager.SetDatabaseOwnership(context.TODO(), d.Database.Name, marker)
+			databaseManager.SetUserOwnership(context.TODO(), d.Database.User, marker)
+		}
+	}
+
+	for _, database := range f.unmarkedDatabases {
+		delete(databaseManager.DatabaseOwnership, database)
+		delete(databaseManager.UserOwnership, database)
+	}
+
+	for _, database := range f.revokedConnects {
+		databaseManager.RevokeDatabaseConnect(context.TODO(), database)
 	}
 
 	return c, i, k8sI, databaseManager
@@ -267,7 +2139,11 @@ func (f *fixture) runController(ctx context.Context, customDatabaseName string,
 
 	// todo refactoring
 	for _, db := range f.expectedDatabases {
-		if _, isExists := databaseManager.Databases[db.Database.Name]; !isExists {
+		if options, isExists := databaseManager.Databases[db.Database.Name]; isExists {
+			if options != db.Database.Options {
+				f.t.Errorf("%s database's options wrong: expected %+v, given %+v", db.Database.Name, db.Database.Options, options)
+			}
+		} else {
 			f.t.Errorf("%s database didn't create", db.Database.Name)
 		}
 		if userPassword, isExists := databaseManager.Users[db.Database.User]; isExists {
@@ -277,6 +2153,9 @@ func (f *fixture) runController(ctx context.Context, customDatabaseName string,
 		} else {
 			f.t.Errorf("%s user didn't create", db.Database.User)
 		}
+		if limits, _ := databaseManager.GetDatabaseLimits(ctx, db.Database.Name); limits != db.Database.Limits {
+			f.t.Errorf("%s database's limits wrong: expected %+v, given %+v", db.Database.Name, db.Database.Limits, limits)
+		}
 		if grantedDBs, isExists := databaseManager.User2Database[db.Database.User]; isExists {
 			isDBGranted := false
 			for _, grantedDB := range grantedDBs {
@@ -292,6 +2171,37 @@ func (f *fixture) runController(ctx context.Context, customDatabaseName string,
 		}
 	}
 
+	for _, database := range f.readOnlyDatabases {
+		if !databaseManager.ReadOnly[database] {
+			f.t.Errorf("%s database should be read-only", database)
+		}
+	}
+
+	for _, database := range f.hardenedDatabases {
+		if drift := databaseManager.SecurityDrift[database]; len(drift) > 0 {
+			f.t.Errorf("%s database should be hardened, drift: %v", database, drift)
+		}
+	}
+
+	for _, database := range f.expectedRevoked {
+		if !databaseManager.ConnectRevoked[database] {
+			f.t.Errorf("CONNECT on %s database should be revoked", database)
+		}
+	}
+
+	for _, database := range f.expectedGranted {
+		if databaseManager.ConnectRevoked[database] {
+			f.t.Errorf("CONNECT on %s database should be granted", database)
+		}
+	}
+
+	for database, marker := range f.expectedOwnership {
+		if databaseManager.DatabaseOwnership[database] != marker {
+			f.t.Errorf("%s database has wrong ownership: expected %+v, given %+v",
+				database, marker, databaseManager.DatabaseOwnership[database])
+		}
+	}
+
 	for _, notExpectedDB := range f.notExpectedDatabases {
 		if _, isExists := databaseManager.Databases[notExpectedDB.Database.Name]; isExists {
 			f.t.Errorf("%s database shouldn't exist", notExpectedDB.Database.Name)
@@ -300,6 +2210,32 @@ func (f *fixture) runController(ctx context.Context, customDatabaseName string,
 			f.t.Errorf("%s user shouldn't exist", notExpectedDB.Database.User)
 		}
 	}
+
+	for database, scripts := range f.expectedScripts {
+		if !reflect.DeepEqual(databaseManager.Scripts[database], scripts) {
+			f.t.Errorf("wrong scripts are run in %s database: expected %q, given %q",
+				database, scripts, databaseManager.Scripts[database])
+		}
+	}
+
+	for database, fromUser := range f.expectedReassigned {
+		if databaseManager.ReassignedFrom[database] != fromUser {
+			f.t.Errorf("objects of %s should be transferred in %s database, given %q",
+				fromUser, database, databaseManager.ReassignedFrom[database])
+		}
+	}
+
+	events := c.recorder.(*record.FakeRecorder).Events
+	for _, expectedEvent := range f.expectedEvents {
+		select {
+		case event := <-events:
+			if event != expectedEvent {
+				f.t.Errorf("wrong event: expected %q, given %q", expectedEvent, event)
+			}
+		default:
+			f.t.Errorf("event %q wasn't recorded", expectedEvent)
+		}
+	}
 }
 
 // checkAction verifies that expected and actual actions are equal and both have
@@ -334,6 +2270,26 @@ func checkAction(expected, actual core.Action, t *testing.T) {
 			t.Errorf("Action %s %s has wrong object\nDiff:\n %s",
 				a.GetVerb(), a.GetResource().Resource, diff.ObjectGoPrintSideBySide(expObject, object))
 		}
+	case core.GetActionImpl:
+		e, _ := expected.(core.GetActionImpl)
+
+		if e.GetName() != a.GetName() {
+			t.Errorf("Action %s %s has wrong name: expected %s, given %s",
+				a.GetVerb(), a.GetResource().Resource, e.GetName(), a.GetName())
+		}
+	case core.ListActionImpl:
+		// lists are checked by verb, resource and namespace
+		if expected.GetNamespace() != a.GetNamespace() {
+			t.Errorf("Action %s %s has wrong namespace: expected %s, given %s",
+				a.GetVerb(), a.GetResource().Resource, expected.GetNamespace(), a.GetNamespace())
+		}
+	case core.DeleteActionImpl:
+		e, _ := expected.(core.DeleteActionImpl)
+
+		if e.GetName() != a.GetName() {
+			t.Errorf("Action %s %s has wrong name: expected %s, given %s",
+				a.GetVerb(), a.GetResource().Resource, e.GetName(), a.GetName())
+		}
 	case core.PatchActionImpl:
 		e, _ := expected.(core.PatchActionImpl)
 		expPatch := e.GetPatch()
@@ -358,8 +2314,18 @@ func filterInformerActions(actions []core.Action) []core.Action {
 		if len(action.GetNamespace()) == 0 &&
 			(action.Matches("list", "customdatabases") ||
 				action.Matches("watch", "customdatabases") ||
+				action.Matches("list", "databaseusers") ||
+				action.Matches("watch", "databaseusers") ||
+				action.Matches("list", "databasegrants") ||
+				action.Matches("watch", "databasegrants") ||
+				action.Matches("list", "databasebackups") ||
+				action.Matches("watch", "databasebackups") ||
+				action.Matches("list", "databaserestores") ||
+				action.Matches("watch", "databaserestores") ||
 				action.Matches("list", "secrets") ||
-				action.Matches("watch", "secrets")) {
+				action.Matches("watch", "secrets") ||
+				action.Matches("list", "configmaps") ||
+				action.Matches("watch", "configmaps")) {
 			continue
 		}
 		ret = append(ret, action)
@@ -380,14 +2346,97 @@ func (f *fixture) expectDeleteSecretAction(s *corev1.Secret) {
 	f.kubeactions = append(f.kubeactions, core.NewDeleteAction(schema.GroupVersionResource{Resource: "secrets"}, s.Namespace, s.Name))
 }
 
+func (f *fixture) expectListAction(resource, namespace string) {
+	action := core.NewListAction(schema.GroupVersionResource{Resource: resource}, schema.GroupVersionKind{}, namespace, metav1.ListOptions{})
+	f.actions = append(f.actions, action)
+}
+
+func (f *fixture) expectUpdateCustomDatabaseStatusAction(cd *customdatabasecontroller.CustomDatabase) {
+	action := core.NewUpdateSubresourceAction(
+		schema.GroupVersionResource{Resource: "customdatabases"}, "status", cd.Namespace, cd,
+	)
+	f.actions = append(f.actions, action)
+}
+
+func (f *fixture) expectDeleteCustomDatabaseAction(cd *customdatabasecontroller.CustomDatabase) {
+	action := core.NewDeleteAction(schema.GroupVersionResource{Resource: "customdatabases"}, cd.Namespace, cd.Name)
+	f.actions = append(f.actions, action)
+}
+
 func (f *fixture) expectExistsDatabase(cdr customdatabase.Entity) {
 	f.expectedDatabases = append(f.expectedDatabases, cdr)
 }
 
-func (f fixture) notExpectExistsDatabase(cdr customdatabase.Entity) {
+func (f *fixture) expectOwnership(database string, marker customdatabase.OwnershipMarker) {
+	f.expectedOwnership[database] = marker
+}
+
+func (f *fixture) expectRunScripts(database string, scripts ...string) {
+	f.expectedScripts[database] = scripts
+}
+
+func (f *fixture) expectReassignedOwnership(database, fromUser string) {
+	f.expectedReassigned[database] = fromUser
+}
+
+func (f *fixture) expectRevokedConnect(database string) {
+	f.expectedRevoked = append(f.expectedRevoked, database)
+}
+
+func (f *fixture) expectGrantedConnect(database string) {
+	f.expectedGranted = append(f.expectedGranted, database)
+}
+
+func (f *fixture) expectReadOnlyDatabase(database string) {
+	f.readOnlyDatabases = append(f.readOnlyDatabases, database)
+}
+
+func (f *fixture) expectHardenedDatabase(database string) {
+	f.hardenedDatabases = append(f.hardenedDatabases, database)
+}
+
+func (f *fixture) notExpectExistsDatabase(cdr customdatabase.Entity) {
 	f.notExpectedDatabases = append(f.notExpectedDatabases, cdr)
 }
 
+func newOwnershipMarker(namespace, name string) customdatabase.OwnershipMarker {
+	domainService, _ := customdatabase.NewDomainService("localhost", 5432, "")
+
+	return domainService.NewOwnershipMarker(customdatabase.OwnerKindCustomDatabase, namespace, name, "")
+}
+
+// withStatus returns copy of CustomDatabase with status, that corresponds to the given database
+func withStatus(
+	cd *customdatabasecontroller.CustomDatabase, db customdatabase.Entity,
+) *customdatabasecontroller.CustomDatabase {
+	cdWithStatus := cd.DeepCopy()
+	cdWithStatus.Status.DatabaseName = db.Database.Name
+	cdWithStatus.Status.Limits = limitsToStatus(db.Database.Limits)
+	cdWithStatus.Status.Size = resource.NewQuantity(0, resource.BinarySI)
+	lastActivityAt := metav1.NewTime(testNow)
+	cdWithStatus.Status.LastActivityAt = &lastActivityAt
+	cdWithStatus.Status.Conditions = []metav1.Condition{{
+		Type:               customdatabasecontroller.ConditionHardened,
+		Status:             metav1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(testNow),
+		Reason:             "LeastPrivilege",
+		Message:            "Privileges of database match least-privilege defaults",
+	}}
+
+	return cdWithStatus
+}
+
+func newEntity(name string) customdatabase.Entity {
+	return customdatabase.Entity{
+		Host: customdatabase.Host{Name: "localhost", Port: 5432},
+		Database: customdatabase.Database{
+			Name: name, User: name, Password: name + name + name,
+			Options: customdatabase.DatabaseOptions{Owner: name},
+			Limits:  customdatabase.Limits{ConnectionLimit: customdatabase.NoConnectionLimit},
+		},
+	}
+}
+
 func getKey(customDatabase *customdatabasecontroller.CustomDatabase, t *testing.T) string {
 	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(customDatabase)
 	if err != nil {
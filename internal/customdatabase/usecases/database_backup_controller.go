@@ -0,0 +1,232 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	informerscorev1 "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	listerscorev1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+
+	"k8s.io/custom-database/internal/customdatabase"
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+	clientset "k8s.io/custom-database/pkg/generated/clientset/versioned"
+	informers "k8s.io/custom-database/pkg/generated/informers/externalversions/cusotmdatabase/v1"
+	listers "k8s.io/custom-database/pkg/generated/listers/cusotmdatabase/v1"
+)
+
+const (
+	// FinalizerDatabaseBackup keeps DatabaseBackup until its dump is removed from backup storage
+	FinalizerDatabaseBackup = "customdatabase.igor.yatsevich.ru/database-backup"
+
+	// MessageDatabaseBackupCompleted is the message used for an Event fired when a dump of database is stored
+	MessageDatabaseBackupCompleted = "Backup of database completed successfully"
+
+	// MessageBackupReadOnly is the message of Ready condition of DatabaseBackup, when controller runs in read-only mode
+	MessageBackupReadOnly = "Controller runs in read-only mode, backup isn't made"
+)
+
+// BackupStorage interface of component, that keeps dumps of databases, e.g. directory or bucket
+type BackupStorage interface {
+	Put(ctx context.Context, location string, dump io.Reader) error
+	Get(ctx context.Context, location string) (io.ReadCloser, error)
+	Delete(ctx context.Context, location string) error
+}
+
+// DatabaseDumper interface of component, that makes logical dump of database
+type DatabaseDumper interface {
+	DumpDatabase(ctx context.Context, host customdatabase.Host, database customdatabase.Database, dump io.Writer) error
+}
+
+// DatabaseBackupController is the controller implementation for DatabaseBackup resources
+type DatabaseBackupController struct {
+	// kubeclientset is a standard kubernetes clientset
+	kubeclientset kubernetes.Interface
+	// sampleclientset is a clientset for our own API group
+	sampleclientset clientset.Interface
+
+	secretLister listerscorev1.SecretLister
+	secretSynced cache.InformerSynced
+
+	customDatabasesLister listers.CustomDatabaseLister
+	customDatabasesSynced cache.InformerSynced
+
+	databaseBackupsLister listers.DatabaseBackupLister
+	databaseBackupsSynced cache.InformerSynced
+
+	// databaseRestoresLister restores, which read dumps, dump isn't deleted until they are finished
+	databaseRestoresLister listers.DatabaseRestoreLister
+	databaseRestoresSynced cache.InformerSynced
+
+	workqueue workqueue.RateLimitingInterface
+	recorder  record.EventRecorder
+
+	dumper  DatabaseDumper
+	storage BackupStorage
+
+	// dumps in progress by key of DatabaseBackup
+	dumps *backgroundTasks
+
+	clock clock.PassiveClock
+	// ownSessions sessions of dumps and restores, they aren't taken for activity of tenant
+	ownSessions *OwnSessions
+	// readOnly controller only reports state of backups, dumps are neither made nor deleted from storage
+	readOnly bool
+}
+
+// NewDatabaseBackupController returns a new controller of logical backups
+func NewDatabaseBackupController(
+	ctx context.Context,
+	kubeclientset kubernetes.Interface,
+	sampleclientset clientset.Interface,
+	secretInformer informerscorev1.SecretInformer,
+	customDatabaseInformer informers.CustomDatabaseInformer,
+	databaseBackupInformer informers.DatabaseBackupInformer,
+	databaseRestoreInformer informers.DatabaseRestoreInformer,
+	dumper DatabaseDumper,
+	storage BackupStorage,
+	opts ...BackupControllerOption,
+) *DatabaseBackupController {
+	logger := klog.FromContext(ctx)
+	options := newBackupControllerOptions(opts)
+
+	controller := &DatabaseBackupController{
+		kubeclientset:          kubeclientset,
+		sampleclientset:        sampleclientset,
+		secretLister:           secretInformer.Lister(),
+		secretSynced:           secretInformer.Informer().HasSynced,
+		customDatabasesLister:  customDatabaseInformer.Lister(),
+		customDatabasesSynced:  customDatabaseInformer.Informer().HasSynced,
+		databaseBackupsLister:  databaseBackupInformer.Lister(),
+		databaseBackupsSynced:  databaseBackupInformer.Informer().HasSynced,
+		databaseRestoresLister: databaseRestoreInformer.Lister(),
+		databaseRestoresSynced: databaseRestoreInformer.Informer().HasSynced,
+		workqueue:              workqueue.NewNamedRateLimitingQueue(options.rateLimiter.newRateLimiter(), "DatabaseBackups"),
+		recorder:               newEventRecorder(ctx, kubeclientset),
+		dumper:                 dumper,
+		storage:                storage,
+		dumps:                  newBackgroundTasks(),
+		clock:                  clock.RealClock{},
+		ownSessions:            options.ownSessions,
+		readOnly:               options.readOnly,
+	}
+
+	logger.Info("Setting up DatabaseBackup event handlers")
+	databaseBackupInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: controller.enqueueDatabaseBackup,
+		UpdateFunc: func(old, new interface{}) {
+			controller.enqueueDatabaseBackup(new)
+		},
+		DeleteFunc: controller.enqueueDatabaseBackup,
+	})
+	// Pending backups wait for their CustomDatabase
+	customDatabaseInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: controller.enqueuePendingDatabaseBackupsOfCustomDatabase,
+		UpdateFunc: func(old, new interface{}) {
+			controller.enqueuePendingDatabaseBackupsOfCustomDatabase(new)
+		},
+	})
+	// Deleted backups wait for restores from their dumps
+	databaseRestoreInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(old, new interface{}) {
+			controller.enqueueDeletedDatabaseBackupOfDatabaseRestore(new)
+		},
+		DeleteFunc: controller.enqueueDeletedDatabaseBackupOfDatabaseRestore,
+	})
+
+	return controller
+}
+
+// Run waits for informer caches and starts workers. It will block until context is done and all dumps in progress
+// are cancelled.
+func (c *DatabaseBackupController) Run(ctx context.Context, workers int) error {
+	defer utilruntime.HandleCrash()
+	defer c.workqueue.ShutDown()
+	logger := klog.FromContext(ctx)
+
+	logger.Info("Starting DatabaseBackup controller")
+
+	logger.Info("Waiting for informer caches to sync")
+	if ok := cache.WaitForCacheSync(
+		ctx.Done(), c.databaseBackupsSynced, c.databaseRestoresSynced, c.customDatabasesSynced, c.secretSynced,
+	); !ok {
+		return fmt.Errorf("failed to wait for caches to sync")
+	}
+
+	logger.Info("Starting workers", "count", workers)
+	for i := 0; i < workers; i++ {
+		go wait.UntilWithContext(ctx, c.runWorker, time.Second)
+	}
+
+	logger.Info("Started workers")
+	<-ctx.Done()
+	logger.Info("Shutting down workers")
+	c.dumps.wait()
+
+	return nil
+}
+
+func (c *DatabaseBackupController) runWorker(ctx context.Context) {
+	for processNextWorkItem(ctx, c.workqueue, c.syncHandler) {
+	}
+}
+
+func (c *DatabaseBackupController) enqueueDatabaseBackup(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.workqueue.Add(key)
+}
+
+func (c *DatabaseBackupController) enqueuePendingDatabaseBackupsOfCustomDatabase(obj interface{}) {
+	customDatabase, ok := obj.(*v1.CustomDatabase)
+	if !ok {
+		return
+	}
+
+	databaseBackups, err := c.databaseBackupsLister.DatabaseBackups(customDatabase.Namespace).List(labels.Everything())
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+
+	for _, databaseBackup := range databaseBackups {
+		if databaseBackup.Spec.CustomDatabaseName == customDatabase.Name &&
+			databaseBackup.Status.Phase == v1.BackupPhasePending {
+			c.enqueueDatabaseBackup(databaseBackup)
+		}
+	}
+}
+
+func (c *DatabaseBackupController) enqueueDeletedDatabaseBackupOfDatabaseRestore(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	databaseRestore, ok := obj.(*v1.DatabaseRestore)
+	if !ok {
+		return
+	}
+
+	databaseBackup, err := c.databaseBackupsLister.DatabaseBackups(databaseRestore.Namespace).Get(
+		databaseRestore.Spec.BackupName,
+	)
+	if err != nil {
+		return
+	}
+	if databaseBackup.DeletionTimestamp != nil {
+		c.enqueueDatabaseBackup(databaseBackup)
+	}
+}
@@ -0,0 +1,291 @@
+package usecases
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeinformers "k8s.io/client-go/informers"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2/ktesting"
+	testingclock "k8s.io/utils/clock/testing"
+
+	fakeadapter "k8s.io/custom-database/internal/customdatabase/adapters/fake"
+	customdatabasecontroller "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+	"k8s.io/custom-database/pkg/generated/clientset/versioned/fake"
+	informers "k8s.io/custom-database/pkg/generated/informers/externalversions"
+)
+
+func TestDatabaseBackupCompleted(t *testing.T) {
+	f := newDatabaseBackupFixture(t)
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.databaseBackups = append(f.databaseBackups, newDatabaseBackup("before-migration"))
+
+	c := f.newController(ctx)
+	if err := c.syncHandler(ctx, metav1.NamespaceDefault+"/before-migration"); err != nil {
+		t.Fatalf("error syncing databaseBackup: %v", err)
+	}
+	c.dumps.wait()
+
+	f.checkActions([]string{
+		"update databasebackups",
+		"update databasebackups/status",
+		"get databasebackups",
+		"update databasebackups/status",
+	})
+
+	dump, isExists := f.storage.Dumps["default/test/before-migration.dump"]
+	if !isExists || string(dump) != "dump of test" {
+		t.Fatalf("dump should be stored, given %v", f.storage.Dumps)
+	}
+
+	status := f.lastDatabaseBackupStatus()
+	hash := sha256.Sum256(dump)
+	if status.Phase != customdatabasecontroller.BackupPhaseCompleted {
+		t.Errorf("backup should be completed, given %s", status.Phase)
+	}
+	if status.Checksum != "sha256:"+hex.EncodeToString(hash[:]) {
+		t.Errorf("wrong checksum of dump: %s", status.Checksum)
+	}
+	if status.Size.Value() != int64(len(dump)) {
+		t.Errorf("wrong size of dump: %s", status.Size)
+	}
+	if status.CompletedAt == nil || status.Duration == nil {
+		t.Errorf("completion time and duration should be set: %+v", status)
+	}
+}
+
+func TestDatabaseBackupFailed(t *testing.T) {
+	f := newDatabaseBackupFixture(t)
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.databaseBackups = append(f.databaseBackups, newDatabaseBackup("before-migration"))
+	f.dumper.Err = fmt.Errorf("connection refused")
+
+	c := f.newController(ctx)
+	if err := c.syncHandler(ctx, metav1.NamespaceDefault+"/before-migration"); err != nil {
+		t.Fatalf("error syncing databaseBackup: %v", err)
+	}
+	c.dumps.wait()
+
+	if len(f.storage.Dumps) > 0 {
+		t.Errorf("incomplete dump shouldn't be stored, given %v", f.storage.Dumps)
+	}
+
+	status := f.lastDatabaseBackupStatus()
+	if status.Phase != customdatabasecontroller.BackupPhaseFailed {
+		t.Errorf("backup should be failed, given %s", status.Phase)
+	}
+	if len(status.Conditions) != 1 || status.Conditions[0].Message != "connection refused" {
+		t.Errorf("condition should contain error of dump: %+v", status.Conditions)
+	}
+}
+
+func TestDatabaseBackupInterrupted(t *testing.T) {
+	f := newDatabaseBackupFixture(t)
+	_, ctx := ktesting.NewTestContext(t)
+
+	databaseBackupItem := newDatabaseBackup("before-migration")
+	databaseBackupItem.Finalizers = []string{FinalizerDatabaseBackup}
+	databaseBackupItem.Status.Phase = customdatabasecontroller.BackupPhaseRunning
+	f.databaseBackups = append(f.databaseBackups, databaseBackupItem)
+
+	c := f.newController(ctx)
+	if err := c.syncHandler(ctx, metav1.NamespaceDefault+"/before-migration"); err != nil {
+		t.Fatalf("error syncing databaseBackup: %v", err)
+	}
+
+	f.checkActions([]string{"update databasebackups/status"})
+
+	if status := f.lastDatabaseBackupStatus(); status.Phase != customdatabasecontroller.BackupPhaseFailed {
+		t.Errorf("interrupted backup should be failed, given %s", status.Phase)
+	}
+}
+
+func TestDeleteDatabaseBackup(t *testing.T) {
+	f := newDatabaseBackupFixture(t)
+	_, ctx := ktesting.NewTestContext(t)
+
+	deletedAt := metav1.NewTime(testNow)
+	databaseBackupItem := newDatabaseBackup("before-migration")
+	databaseBackupItem.Finalizers = []string{FinalizerDatabaseBackup}
+	databaseBackupItem.DeletionTimestamp = &deletedAt
+	databaseBackupItem.Status.Phase = customdatabasecontroller.BackupPhaseCompleted
+	databaseBackupItem.Status.Location = "default/test/before-migration.dump"
+	f.databaseBackups = append(f.databaseBackups, databaseBackupItem)
+	f.storage.Dumps["default/test/before-migration.dump"] = []byte("dump of test")
+
+	c := f.newController(ctx)
+	if err := c.syncHandler(ctx, metav1.NamespaceDefault+"/before-migration"); err != nil {
+		t.Fatalf("error syncing databaseBackup: %v", err)
+	}
+
+	f.checkActions([]string{"update databasebackups"})
+
+	if len(f.storage.Dumps) > 0 {
+		t.Errorf("dump should be deleted from storage, given %v", f.storage.Dumps)
+	}
+}
+
+func TestKeepDumpOfRunningRestore(t *testing.T) {
+	f := newDatabaseBackupFixture(t)
+	_, ctx := ktesting.NewTestContext(t)
+
+	deletedAt := metav1.NewTime(testNow)
+	databaseBackupItem := newDatabaseBackup("before-migration")
+	databaseBackupItem.Finalizers = []string{FinalizerDatabaseBackup}
+	databaseBackupItem.DeletionTimestamp = &deletedAt
+	databaseBackupItem.Status.Phase = customdatabasecontroller.BackupPhaseCompleted
+	databaseBackupItem.Status.Location = "default/test/before-migration.dump"
+	f.databaseBackups = append(f.databaseBackups, databaseBackupItem)
+	f.storage.Dumps["default/test/before-migration.dump"] = []byte("dump of test")
+
+	databaseRestoreItem := newDatabaseRestore("rollback")
+	databaseRestoreItem.Status.Phase = customdatabasecontroller.RestorePhaseRunning
+	f.databaseRestores = append(f.databaseRestores, databaseRestoreItem)
+
+	c := f.newController(ctx)
+	if err := c.syncHandler(ctx, metav1.NamespaceDefault+"/before-migration"); err != nil {
+		t.Fatalf("error syncing databaseBackup: %v", err)
+	}
+
+	// finalizer is kept until restore is finished
+	f.checkActions(nil)
+
+	if len(f.storage.Dumps) != 1 {
+		t.Errorf("dump shouldn't be deleted during restore, given %v", f.storage.Dumps)
+	}
+}
+
+func TestKeepDumpInReadOnlyMode(t *testing.T) {
+	f := newDatabaseBackupFixture(t)
+	f.readOnly = true
+	_, ctx := ktesting.NewTestContext(t)
+
+	deletedAt := metav1.NewTime(testNow)
+	databaseBackupItem := newDatabaseBackup("before-migration")
+	databaseBackupItem.Finalizers = []string{FinalizerDatabaseBackup}
+	databaseBackupItem.DeletionTimestamp = &deletedAt
+	databaseBackupItem.Status.Phase = customdatabasecontroller.BackupPhaseCompleted
+	databaseBackupItem.Status.Location = "default/test/before-migration.dump"
+	f.databaseBackups = append(f.databaseBackups, databaseBackupItem)
+	f.storage.Dumps["default/test/before-migration.dump"] = []byte("dump of test")
+
+	c := f.newController(ctx)
+	if err := c.syncHandler(ctx, metav1.NamespaceDefault+"/before-migration"); err != nil {
+		t.Fatalf("error syncing databaseBackup: %v", err)
+	}
+
+	// finalizer is removed, so deletion of namespace isn't blocked
+	f.checkActions([]string{"update databasebackups"})
+
+	if len(f.storage.Dumps) != 1 {
+		t.Errorf("dump shouldn't be deleted from storage in read-only mode, given %v", f.storage.Dumps)
+	}
+}
+
+type databaseBackupFixture struct {
+	t *testing.T
+
+	client     *fake.Clientset
+	kubeclient *k8sfake.Clientset
+
+	databaseBackups []*customdatabasecontroller.DatabaseBackup
+	// databaseRestores restores, which read dumps of backups
+	databaseRestores []*customdatabasecontroller.DatabaseRestore
+	dumper           *fakeadapter.Dumper
+	storage          *fakeadapter.BackupStorage
+	readOnly         bool
+}
+
+func newDatabaseBackupFixture(t *testing.T) *databaseBackupFixture {
+	return &databaseBackupFixture{
+		t:       t,
+		dumper:  fakeadapter.NewDumper(),
+		storage: fakeadapter.NewBackupStorage(),
+	}
+}
+
+func newDatabaseBackup(name string) *customdatabasecontroller.DatabaseBackup {
+	return &customdatabasecontroller.DatabaseBackup{
+		TypeMeta: metav1.TypeMeta{APIVersion: customdatabasecontroller.SchemeGroupVersion.String()},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: metav1.NamespaceDefault,
+		},
+		Spec: customdatabasecontroller.DatabaseBackupSpec{
+			CustomDatabaseName: "test",
+		},
+	}
+}
+
+// newController returns controller of backups for the existing "test" CustomDatabase
+func (f *databaseBackupFixture) newController(ctx context.Context) *DatabaseBackupController {
+	customDatabaseItem := newCustomDatabase("test")
+	ownerSecret := secretWithDBInfo(newEmptySecret(customDatabaseItem), newEntity("test"))
+
+	objects := []runtime.Object{customDatabaseItem}
+	for _, databaseBackup := range f.databaseBackups {
+		objects = append(objects, databaseBackup)
+	}
+	for _, databaseRestore := range f.databaseRestores {
+		objects = append(objects, databaseRestore)
+	}
+
+	f.client = fake.NewSimpleClientset(objects...)
+	f.kubeclient = k8sfake.NewSimpleClientset(ownerSecret)
+
+	i := informers.NewSharedInformerFactory(f.client, noResyncPeriodFunc())
+	k8sI := kubeinformers.NewSharedInformerFactory(f.kubeclient, noResyncPeriodFunc())
+
+	c := NewDatabaseBackupController(ctx, f.kubeclient, f.client,
+		k8sI.Core().V1().Secrets(),
+		i.Igor().V1().CustomDatabases(),
+		i.Igor().V1().DatabaseBackups(),
+		i.Igor().V1().DatabaseRestores(),
+		f.dumper,
+		f.storage,
+		WithBackupReadOnly(f.readOnly),
+	)
+	c.recorder = &record.FakeRecorder{}
+	c.clock = testingclock.NewFakePassiveClock(testNow)
+
+	i.Igor().V1().CustomDatabases().Informer().GetIndexer().Add(customDatabaseItem)
+	for _, databaseBackup := range f.databaseBackups {
+		i.Igor().V1().DatabaseBackups().Informer().GetIndexer().Add(databaseBackup)
+	}
+	for _, databaseRestore := range f.databaseRestores {
+		i.Igor().V1().DatabaseRestores().Informer().GetIndexer().Add(databaseRestore)
+	}
+	k8sI.Core().V1().Secrets().Informer().GetIndexer().Add(ownerSecret)
+
+	return c
+}
+
+func (f *databaseBackupFixture) checkActions(expectedActions []string) {
+	checkActionNames(f.t, expectedActions, filterInformerActions(f.client.Actions()))
+	checkActionNames(f.t, nil, filterInformerActions(f.kubeclient.Actions()))
+}
+
+func (f *databaseBackupFixture) lastDatabaseBackupStatus() customdatabasecontroller.DatabaseBackupStatus {
+	var status *customdatabasecontroller.DatabaseBackupStatus
+	for _, action := range f.client.Actions() {
+		if updateAction, ok := action.(core.UpdateActionImpl); ok && updateAction.GetSubresource() == "status" {
+			status = &updateAction.GetObject().(*customdatabasecontroller.DatabaseBackup).Status
+		}
+	}
+
+	if status == nil {
+		f.t.Fatalf("status of databaseBackup wasn't updated")
+		return customdatabasecontroller.DatabaseBackupStatus{}
+	}
+
+	return *status
+}
@@ -0,0 +1,362 @@
+package usecases
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+
+	"k8s.io/custom-database/internal/customdatabase"
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+)
+
+// syncHandler starts dump of database in background, result of dump is written to status by the dump itself
+func (c *DatabaseBackupController) syncHandler(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("invalid resource key: %s", key))
+		return nil
+	}
+
+	ctx = contextWithResourceNameLogger(ctx, name)
+	ctx = customdatabase.ContextWithResource(ctx, "DatabaseBackup", key)
+
+	databaseBackup, err := c.databaseBackupsLister.DatabaseBackups(namespace).Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.dumps.cancel(key)
+			return nil
+		}
+		return err
+	}
+
+	if databaseBackup.DeletionTimestamp != nil && c.readOnly {
+		// dump is left in backup storage, finalizer mustn't block deletion of resource
+		return c.removeDatabaseBackupFinalizer(ctx, databaseBackup)
+	}
+	if databaseBackup.DeletionTimestamp != nil {
+		return c.deleteDatabaseBackupHandler(ctx, key, databaseBackup)
+	}
+
+	phase := databaseBackup.Status.Phase
+	switch {
+	case phase == v1.BackupPhaseCompleted || phase == v1.BackupPhaseFailed:
+		// backup is made only once
+		return nil
+	case c.readOnly:
+		// dump would open session on server, backup waits until controller is started in normal mode
+		newStatus := databaseBackup.Status.DeepCopy()
+		c.setCondition(newStatus, databaseBackup, v1.ConditionReady, metav1.ConditionFalse, "ReadOnly",
+			MessageBackupReadOnly,
+		)
+		return c.updateDatabaseBackupStatus(ctx, databaseBackup, newStatus)
+	case phase == v1.BackupPhaseRunning:
+		if c.dumps.isRunning(key) {
+			return nil
+		}
+		// controller was restarted during dump, dump can't be continued
+		newStatus := databaseBackup.Status.DeepCopy()
+		c.failDatabaseBackup(newStatus, databaseBackup, "Interrupted", "Backup was interrupted by restart of controller")
+		return c.updateDatabaseBackupStatus(ctx, databaseBackup, newStatus)
+	}
+
+	return c.startDatabaseBackup(ctx, key, databaseBackup)
+}
+
+func (c *DatabaseBackupController) startDatabaseBackup(
+	ctx context.Context, key string, databaseBackupReq *v1.DatabaseBackup,
+) error {
+	logger := loggerFromHandlerContext(ctx)
+
+	if databaseBackupReq.Spec.CustomDatabaseName == "" {
+		utilruntime.HandleError(fmt.Errorf("%s: customDatabaseName must be specified", databaseBackupReq.Name))
+		return nil
+	}
+
+	databaseBackupReq, err := c.ensureDatabaseBackupFinalizer(ctx, databaseBackupReq)
+	if err != nil {
+		return err
+	}
+
+	newStatus := databaseBackupReq.Status.DeepCopy()
+
+	_, owner, err := ownerOfCustomDatabase(
+		c.customDatabasesLister, c.secretLister, databaseBackupReq.Namespace, databaseBackupReq.Spec.CustomDatabaseName,
+	)
+	if err != nil {
+		if err == errCustomDatabasePaused {
+			// pending backup is requeued by event handler, when annotation is removed
+			newStatus.Phase = v1.BackupPhasePending
+			c.setCondition(newStatus, databaseBackupReq, v1.ConditionReady, metav1.ConditionFalse,
+				"CustomDatabasePaused", err.Error(),
+			)
+			return c.updateDatabaseBackupStatus(ctx, databaseBackupReq, newStatus)
+		}
+		if errors.IsNotFound(err) {
+			newStatus.Phase = v1.BackupPhasePending
+			c.setCondition(newStatus, databaseBackupReq, v1.ConditionReady, metav1.ConditionFalse,
+				"CustomDatabaseNotReady", err.Error(),
+			)
+			return c.updateDatabaseBackupStatus(ctx, databaseBackupReq, newStatus)
+		}
+		return err
+	}
+
+	startedAt := metav1.NewTime(c.clock.Now())
+	newStatus.Phase = v1.BackupPhaseRunning
+	newStatus.StartedAt = &startedAt
+	newStatus.Location = backupLocation(databaseBackupReq)
+	c.setCondition(newStatus, databaseBackupReq, v1.ConditionReady, metav1.ConditionFalse,
+		"BackupRunning", "Dump of database is in progress",
+	)
+
+	// status is stored before dump, so dump is never started twice
+	if err = c.updateDatabaseBackupStatus(ctx, databaseBackupReq, newStatus); err != nil {
+		return err
+	}
+
+	logger.Info("Start backup of database", "database", owner.Database.Name, "location", newStatus.Location)
+
+	customDatabaseName := databaseBackupReq.Spec.CustomDatabaseName
+	c.dumps.start(ctx, key, func(dumpCtx context.Context) {
+		// dump commits transactions in database, they aren't activity of tenant
+		c.ownSessions.touch(databaseBackupReq.Namespace, customDatabaseName, c.clock.Now())
+		size, checksum, err := dumpDatabase(dumpCtx, c.dumper, c.storage, owner, newStatus.Location)
+		c.ownSessions.touch(databaseBackupReq.Namespace, customDatabaseName, c.clock.Now())
+		if dumpCtx.Err() != nil {
+			// DatabaseBackup was deleted or controller is stopped
+			logger.Info("Backup of database was cancelled", "database", owner.Database.Name)
+			return
+		}
+
+		if err = c.completeDatabaseBackup(dumpCtx, databaseBackupReq, size, checksum, err); err != nil {
+			utilruntime.HandleError(fmt.Errorf("%s: update status of backup: %w", key, err))
+		}
+	})
+
+	return nil
+}
+
+// completeDatabaseBackup writes result of dump to status
+func (c *DatabaseBackupController) completeDatabaseBackup(
+	ctx context.Context, databaseBackupReq *v1.DatabaseBackup, size int64, checksum string, dumpErr error,
+) error {
+	var databaseBackup *v1.DatabaseBackup
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var err error
+		// lister may be not updated yet, so the latest version is requested
+		databaseBackup, err = c.sampleclientset.IgorV1().DatabaseBackups(databaseBackupReq.Namespace).Get(
+			ctx, databaseBackupReq.Name, metav1.GetOptions{},
+		)
+		if err != nil {
+			return err
+		}
+
+		newStatus := databaseBackup.Status.DeepCopy()
+		if dumpErr != nil {
+			c.failDatabaseBackup(newStatus, databaseBackup, "BackupFailed", dumpErr.Error())
+		} else {
+			completedAt := metav1.NewTime(c.clock.Now())
+			newStatus.Phase = v1.BackupPhaseCompleted
+			newStatus.CompletedAt = &completedAt
+			if newStatus.StartedAt != nil {
+				newStatus.Duration = &metav1.Duration{Duration: completedAt.Sub(newStatus.StartedAt.Time)}
+			}
+			newStatus.Size = resource.NewQuantity(size, resource.BinarySI)
+			newStatus.Checksum = checksum
+			c.setCondition(newStatus, databaseBackup, v1.ConditionReady, metav1.ConditionTrue,
+				"BackupCompleted", MessageDatabaseBackupCompleted,
+			)
+		}
+
+		return c.updateDatabaseBackupStatus(ctx, databaseBackup, newStatus)
+	})
+	if err != nil {
+		return err
+	}
+
+	if dumpErr != nil {
+		c.recorder.Event(databaseBackup, corev1.EventTypeWarning, "BackupFailed", dumpErr.Error())
+	} else {
+		c.recorder.Event(databaseBackup, corev1.EventTypeNormal, "BackupCompleted", MessageDatabaseBackupCompleted)
+	}
+
+	return nil
+}
+
+func (c *DatabaseBackupController) deleteDatabaseBackupHandler(
+	ctx context.Context, key string, databaseBackupReq *v1.DatabaseBackup,
+) error {
+	logger := loggerFromHandlerContext(ctx)
+	logger.Info("Delete DatabaseBackup resource")
+
+	c.dumps.cancel(key)
+
+	if !containsString(databaseBackupReq.Finalizers, FinalizerDatabaseBackup) {
+		return nil
+	}
+
+	restoreName, err := c.runningRestoreOfDatabaseBackup(databaseBackupReq)
+	if err != nil {
+		return err
+	}
+	if restoreName != "" {
+		// backup is requeued by event handler, when restore is finished
+		logger.Info("Dump is kept until restore is finished", "databaseRestore", restoreName)
+		return nil
+	}
+
+	if location := databaseBackupReq.Status.Location; location != "" {
+		logger.Info("Delete dump from backup storage", "location", location)
+		if err := c.storage.Delete(ctx, location); err != nil {
+			return err
+		}
+	}
+
+	return c.removeDatabaseBackupFinalizer(ctx, databaseBackupReq)
+}
+
+// runningRestoreOfDatabaseBackup returns name of DatabaseRestore, which reads dump of backup now, or empty string
+func (c *DatabaseBackupController) runningRestoreOfDatabaseBackup(databaseBackupReq *v1.DatabaseBackup) (string, error) {
+	databaseRestores, err := c.databaseRestoresLister.DatabaseRestores(databaseBackupReq.Namespace).List(
+		labels.Everything(),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	for _, databaseRestore := range databaseRestores {
+		if databaseRestore.Spec.BackupName == databaseBackupReq.Name &&
+			databaseRestore.Status.Phase == v1.RestorePhaseRunning {
+			return databaseRestore.Name, nil
+		}
+	}
+
+	return "", nil
+}
+
+func (c *DatabaseBackupController) removeDatabaseBackupFinalizer(
+	ctx context.Context, databaseBackupReq *v1.DatabaseBackup,
+) error {
+	if !containsString(databaseBackupReq.Finalizers, FinalizerDatabaseBackup) {
+		return nil
+	}
+
+	databaseBackupCopy := databaseBackupReq.DeepCopy()
+	databaseBackupCopy.Finalizers = removeString(databaseBackupCopy.Finalizers, FinalizerDatabaseBackup)
+	_, err := c.sampleclientset.IgorV1().DatabaseBackups(databaseBackupCopy.Namespace).Update(
+		ctx, databaseBackupCopy, metav1.UpdateOptions{},
+	)
+
+	return err
+}
+
+func (c *DatabaseBackupController) ensureDatabaseBackupFinalizer(
+	ctx context.Context, databaseBackupReq *v1.DatabaseBackup,
+) (*v1.DatabaseBackup, error) {
+	if containsString(databaseBackupReq.Finalizers, FinalizerDatabaseBackup) {
+		return databaseBackupReq, nil
+	}
+
+	databaseBackupCopy := databaseBackupReq.DeepCopy()
+	databaseBackupCopy.Finalizers = append(databaseBackupCopy.Finalizers, FinalizerDatabaseBackup)
+
+	return c.sampleclientset.IgorV1().DatabaseBackups(databaseBackupCopy.Namespace).Update(
+		ctx, databaseBackupCopy, metav1.UpdateOptions{},
+	)
+}
+
+func (c *DatabaseBackupController) failDatabaseBackup(
+	newStatus *v1.DatabaseBackupStatus, databaseBackup *v1.DatabaseBackup, reason, message string,
+) {
+	completedAt := metav1.NewTime(c.clock.Now())
+	newStatus.Phase = v1.BackupPhaseFailed
+	newStatus.CompletedAt = &completedAt
+	c.setCondition(newStatus, databaseBackup, v1.ConditionReady, metav1.ConditionFalse, reason, message)
+}
+
+func (c *DatabaseBackupController) setCondition(
+	newStatus *v1.DatabaseBackupStatus, databaseBackup *v1.DatabaseBackup,
+	conditionType string, status metav1.ConditionStatus, reason, message string,
+) {
+	setStatusCondition(
+		&newStatus.Conditions, databaseBackup.Generation, c.clock.Now(), conditionType, status, reason, message,
+	)
+}
+
+func (c *DatabaseBackupController) updateDatabaseBackupStatus(
+	ctx context.Context, databaseBackup *v1.DatabaseBackup, newStatus *v1.DatabaseBackupStatus,
+) error {
+	if equality.Semantic.DeepEqual(databaseBackup.Status, *newStatus) {
+		return nil
+	}
+
+	databaseBackupCopy := databaseBackup.DeepCopy()
+	databaseBackupCopy.Status = *newStatus
+
+	_, err := c.sampleclientset.IgorV1().DatabaseBackups(databaseBackup.Namespace).UpdateStatus(
+		ctx, databaseBackupCopy, metav1.UpdateOptions{},
+	)
+
+	return err
+}
+
+// backupLocation returns key of dump in backup storage
+func backupLocation(databaseBackup *v1.DatabaseBackup) string {
+	return fmt.Sprintf("%s/%s/%s.dump",
+		databaseBackup.Namespace, databaseBackup.Spec.CustomDatabaseName, databaseBackup.Name,
+	)
+}
+
+// dumpDatabase streams dump of database to storage and returns its size and checksum. Dump is made by the owner of
+// database, because admin may have no privileges on objects of tenant.
+func dumpDatabase(
+	ctx context.Context, dumper DatabaseDumper, storage BackupStorage, owner customdatabase.Entity, location string,
+) (int64, string, error) {
+	reader, writer := io.Pipe()
+	hash := sha256.New()
+	counter := &countingWriter{}
+
+	dumpErrs := make(chan error, 1)
+	go func() {
+		err := dumper.DumpDatabase(ctx, owner.Host, owner.Database, io.MultiWriter(writer, hash, counter))
+		// storage gets error instead of EOF, so incomplete dump isn't stored
+		writer.CloseWithError(err)
+		dumpErrs <- err
+	}()
+
+	putErr := storage.Put(ctx, location, reader)
+	// dumper is unblocked, if storage stopped reading
+	reader.CloseWithError(putErr)
+
+	if dumpErr := <-dumpErrs; dumpErr != nil {
+		return 0, "", dumpErr
+	}
+	if putErr != nil {
+		return 0, "", putErr
+	}
+
+	return counter.written, "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// countingWriter counts written bytes
+type countingWriter struct {
+	written int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.written += int64(len(p))
+	return len(p), nil
+}
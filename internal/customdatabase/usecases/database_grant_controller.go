@@ -0,0 +1,206 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	informerscorev1 "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	listerscorev1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+
+	"k8s.io/custom-database/internal/customdatabase"
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+	clientset "k8s.io/custom-database/pkg/generated/clientset/versioned"
+	informers "k8s.io/custom-database/pkg/generated/informers/externalversions/cusotmdatabase/v1"
+	listers "k8s.io/custom-database/pkg/generated/listers/cusotmdatabase/v1"
+)
+
+const (
+	// FinalizerDatabaseGrant keeps DatabaseGrant until its role is dropped and Secret is deleted from grantee
+	// namespace. Kubernetes can't collect the Secret, because owner references don't work across namespaces.
+	FinalizerDatabaseGrant = "customdatabase.igor.yatsevich.ru/database-grant"
+
+	// MessageDatabaseGrantSynced is the message used for an Event fired when a DatabaseGrant
+	// is synced successfully
+	MessageDatabaseGrantSynced = "DatabaseGrant synced successfully"
+)
+
+// DatabaseGrantController is the controller implementation for DatabaseGrant resources
+type DatabaseGrantController struct {
+	// kubeclientset is a standard kubernetes clientset
+	kubeclientset kubernetes.Interface
+	// sampleclientset is a clientset for our own API group
+	sampleclientset clientset.Interface
+
+	secretLister listerscorev1.SecretLister
+	secretSynced cache.InformerSynced
+
+	customDatabasesLister listers.CustomDatabaseLister
+	customDatabasesSynced cache.InformerSynced
+
+	databaseGrantsLister listers.DatabaseGrantLister
+	databaseGrantsSynced cache.InformerSynced
+
+	workqueue workqueue.RateLimitingInterface
+	recorder  record.EventRecorder
+
+	databaseManager DatabaseManager
+	domainService   *customdatabase.DomainService
+
+	clock clock.PassiveClock
+	// tenantTLS TLS settings, which are published to Secrets, nil means that Secrets contain only credentials
+	tenantTLS *TenantTLS
+	// ownSessions sessions, which are opened to apply privileges, they aren't taken for activity of tenant
+	ownSessions *OwnSessions
+	// readOnly controller only reports state of roles, nothing is changed on server
+	readOnly bool
+	// adoptionAllowList patterns "<namespace>/<role>" of pre-existing roles, that may be adopted
+	adoptionAllowList []string
+}
+
+// NewDatabaseGrantController returns a new controller of databases shared with another namespaces
+func NewDatabaseGrantController(
+	ctx context.Context,
+	kubeclientset kubernetes.Interface,
+	sampleclientset clientset.Interface,
+	secretInformer informerscorev1.SecretInformer,
+	customDatabaseInformer informers.CustomDatabaseInformer,
+	databaseGrantInformer informers.DatabaseGrantInformer,
+	databaseManager DatabaseManager,
+	domainService *customdatabase.DomainService,
+	opts ...RoleControllerOption,
+) *DatabaseGrantController {
+	logger := klog.FromContext(ctx)
+	options := newRoleControllerOptions(opts)
+
+	controller := &DatabaseGrantController{
+		kubeclientset:         kubeclientset,
+		sampleclientset:       sampleclientset,
+		secretLister:          secretInformer.Lister(),
+		secretSynced:          secretInformer.Informer().HasSynced,
+		customDatabasesLister: customDatabaseInformer.Lister(),
+		customDatabasesSynced: customDatabaseInformer.Informer().HasSynced,
+		databaseGrantsLister:  databaseGrantInformer.Lister(),
+		databaseGrantsSynced:  databaseGrantInformer.Informer().HasSynced,
+		workqueue:             workqueue.NewNamedRateLimitingQueue(options.rateLimiter.newRateLimiter(), "DatabaseGrants"),
+		recorder:              newEventRecorder(ctx, kubeclientset),
+		databaseManager:       databaseManager,
+		domainService:         domainService,
+		clock:                 clock.RealClock{},
+		tenantTLS:             options.tenantTLS,
+		ownSessions:           options.ownSessions,
+		readOnly:              options.readOnly,
+		adoptionAllowList:     options.adoptionAllowList,
+	}
+
+	logger.Info("Setting up DatabaseGrant event handlers")
+	databaseGrantInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: controller.enqueueDatabaseGrant,
+		UpdateFunc: func(old, new interface{}) {
+			controller.enqueueDatabaseGrant(new)
+		},
+		DeleteFunc: controller.enqueueDatabaseGrant,
+	})
+	// Grants wait for their CustomDatabase, so we requeue them as soon as database is changed
+	customDatabaseInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: controller.enqueueDatabaseGrantsOfCustomDatabase,
+		UpdateFunc: func(old, new interface{}) {
+			controller.enqueueDatabaseGrantsOfCustomDatabase(new)
+		},
+	})
+	// Secret in grantee namespace is restored, if somebody deletes it
+	secretInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		DeleteFunc: controller.enqueueDatabaseGrantOfSecret,
+	})
+
+	return controller
+}
+
+// Run waits for informer caches and starts workers. It will block until context is done.
+func (c *DatabaseGrantController) Run(ctx context.Context, workers int) error {
+	defer utilruntime.HandleCrash()
+	defer c.workqueue.ShutDown()
+	logger := klog.FromContext(ctx)
+
+	logger.Info("Starting DatabaseGrant controller")
+
+	logger.Info("Waiting for informer caches to sync")
+	if ok := cache.WaitForCacheSync(
+		ctx.Done(), c.databaseGrantsSynced, c.customDatabasesSynced, c.secretSynced,
+	); !ok {
+		return fmt.Errorf("failed to wait for caches to sync")
+	}
+
+	logger.Info("Starting workers", "count", workers)
+	for i := 0; i < workers; i++ {
+		go wait.UntilWithContext(ctx, c.runWorker, time.Second)
+	}
+
+	logger.Info("Started workers")
+	<-ctx.Done()
+	logger.Info("Shutting down workers")
+
+	return nil
+}
+
+func (c *DatabaseGrantController) runWorker(ctx context.Context) {
+	for processNextWorkItem(ctx, c.workqueue, c.syncHandler) {
+	}
+}
+
+func (c *DatabaseGrantController) enqueueDatabaseGrant(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.workqueue.Add(key)
+}
+
+func (c *DatabaseGrantController) enqueueDatabaseGrantsOfCustomDatabase(obj interface{}) {
+	customDatabase, ok := obj.(*v1.CustomDatabase)
+	if !ok {
+		return
+	}
+
+	databaseGrants, err := c.databaseGrantsLister.DatabaseGrants(customDatabase.Namespace).List(labels.Everything())
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+
+	for _, databaseGrant := range databaseGrants {
+		if databaseGrant.Spec.CustomDatabaseName == customDatabase.Name {
+			c.enqueueDatabaseGrant(databaseGrant)
+		}
+	}
+}
+
+func (c *DatabaseGrantController) enqueueDatabaseGrantOfSecret(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	secret, ok := obj.(*corev1.Secret)
+	if !ok {
+		return
+	}
+
+	grantNamespace, grantName, found := strings.Cut(secret.Labels[v1.LabelDatabaseGrant], ".")
+	if !found {
+		return
+	}
+
+	c.workqueue.Add(grantNamespace + "/" + grantName)
+}
@@ -0,0 +1,220 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeinformers "k8s.io/client-go/informers"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2/ktesting"
+	testingclock "k8s.io/utils/clock/testing"
+
+	"k8s.io/custom-database/internal/customdatabase"
+	fakeadapter "k8s.io/custom-database/internal/customdatabase/adapters/fake"
+	customdatabasecontroller "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+	"k8s.io/custom-database/pkg/generated/clientset/versioned/fake"
+	informers "k8s.io/custom-database/pkg/generated/informers/externalversions"
+)
+
+func TestCreateDatabaseGrant(t *testing.T) {
+	f := newDatabaseGrantFixture(t)
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.databaseGrants = append(f.databaseGrants, newDatabaseGrant("analytics"))
+
+	c, databaseManager := f.newController(ctx)
+	if err := c.syncHandler(ctx, metav1.NamespaceDefault+"/analytics"); err != nil {
+		t.Fatalf("error syncing databaseGrant: %v", err)
+	}
+
+	f.checkActions(
+		[]string{"update databasegrants", "update databasegrants/status"},
+		[]string{"create secrets"},
+	)
+
+	var updatedGrant *customdatabasecontroller.DatabaseGrant
+	for _, action := range f.client.Actions() {
+		if updateAction, ok := action.(core.UpdateActionImpl); ok && updateAction.GetSubresource() == "" {
+			updatedGrant = updateAction.GetObject().(*customdatabasecontroller.DatabaseGrant)
+		}
+	}
+	if !containsString(updatedGrant.Finalizers, FinalizerDatabaseGrant) {
+		t.Errorf("databaseGrant should have finalizer")
+	}
+	if len(updatedGrant.OwnerReferences) != 1 || updatedGrant.OwnerReferences[0].Name != "test" {
+		t.Errorf("databaseGrant should be owned by CustomDatabase: %+v", updatedGrant.OwnerReferences)
+	}
+
+	var secret *corev1.Secret
+	for _, action := range f.kubeclient.Actions() {
+		if createAction, ok := action.(core.CreateActionImpl); ok {
+			secret = createAction.GetObject().(*corev1.Secret)
+		}
+	}
+	if secret.Namespace != "analytics" || secret.Name != "test-readonly" {
+		t.Errorf("secret should be delivered to grantee namespace, given %s/%s", secret.Namespace, secret.Name)
+	}
+	if userName := string(secret.Data[SecretVarDbUserName]); userName != "test_grant_analytics" {
+		t.Errorf("wrong user name in secret: expected test_grant_analytics, given %s", userName)
+	}
+	if password := string(secret.Data[SecretVarDbPassword]); databaseManager.Users["test_grant_analytics"] != password {
+		t.Errorf("password of user doesn't match secret")
+	}
+	if profile := databaseManager.UserPrivileges["test_grant_analytics"].Profile; profile != customdatabase.PrivilegeProfileReadOnly {
+		t.Errorf("wrong privilege profile: expected %s, given %s", customdatabase.PrivilegeProfileReadOnly, profile)
+	}
+}
+
+func TestDatabaseGrantSecretConflict(t *testing.T) {
+	f := newDatabaseGrantFixture(t)
+	_, ctx := ktesting.NewTestContext(t)
+
+	databaseGrantItem := newDatabaseGrant("analytics")
+	f.databaseGrants = append(f.databaseGrants, databaseGrantItem)
+	f.secrets = append(f.secrets, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-readonly", Namespace: "analytics"},
+	})
+
+	c, databaseManager := f.newController(ctx)
+	if err := c.syncHandler(ctx, metav1.NamespaceDefault+"/analytics"); err != nil {
+		t.Fatalf("error syncing databaseGrant: %v", err)
+	}
+
+	// foreign secret stays untouched
+	f.checkActions([]string{"update databasegrants", "update databasegrants/status"}, nil)
+
+	if _, isExists := databaseManager.Users["test_grant_analytics"]; isExists {
+		t.Errorf("test_grant_analytics user shouldn't exist")
+	}
+}
+
+func TestDeleteDatabaseGrant(t *testing.T) {
+	f := newDatabaseGrantFixture(t)
+	_, ctx := ktesting.NewTestContext(t)
+
+	deletedAt := metav1.NewTime(testNow)
+	databaseGrantItem := newDatabaseGrant("analytics")
+	databaseGrantItem.Finalizers = []string{FinalizerDatabaseGrant}
+	databaseGrantItem.DeletionTimestamp = &deletedAt
+	databaseGrantItem.Status.UserName = "test_grant_analytics"
+	f.databaseGrants = append(f.databaseGrants, databaseGrantItem)
+	f.users["test_grant_analytics"] = "password"
+
+	grantEntity := newEntity("test")
+	grantEntity.Database.User = "test_grant_analytics"
+	grantEntity.Database.Password = "password"
+	f.secrets = append(f.secrets, secretWithDBInfo(newDatabaseGrantSecret(databaseGrantItem), grantEntity))
+
+	c, databaseManager := f.newController(ctx)
+	if err := c.syncHandler(ctx, metav1.NamespaceDefault+"/analytics"); err != nil {
+		t.Fatalf("error syncing databaseGrant: %v", err)
+	}
+
+	f.checkActions([]string{"update databasegrants"}, []string{"delete secrets"})
+
+	if _, isExists := databaseManager.Users["test_grant_analytics"]; isExists {
+		t.Errorf("test_grant_analytics user shouldn't exist")
+	}
+}
+
+type databaseGrantFixture struct {
+	t *testing.T
+
+	client     *fake.Clientset
+	kubeclient *k8sfake.Clientset
+
+	databaseGrants []*customdatabasecontroller.DatabaseGrant
+	secrets        []*corev1.Secret
+	users          map[string]string
+}
+
+func newDatabaseGrantFixture(t *testing.T) *databaseGrantFixture {
+	return &databaseGrantFixture{t: t, users: map[string]string{}}
+}
+
+func newDatabaseGrant(name string) *customdatabasecontroller.DatabaseGrant {
+	return &customdatabasecontroller.DatabaseGrant{
+		TypeMeta: metav1.TypeMeta{APIVersion: customdatabasecontroller.SchemeGroupVersion.String()},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: metav1.NamespaceDefault,
+		},
+		Spec: customdatabasecontroller.DatabaseGrantSpec{
+			CustomDatabaseName: "test",
+			GranteeNamespace:   "analytics",
+			SecretName:         "test-readonly",
+			Profile:            customdatabasecontroller.PrivilegeProfileReadOnly,
+		},
+	}
+}
+
+// newController returns controller of grants for the existing "test" CustomDatabase
+func (f *databaseGrantFixture) newController(ctx context.Context) (*DatabaseGrantController, *fakeadapter.DbManager) {
+	customDatabaseItem := newCustomDatabase("test")
+	ownerEntity := newEntity("test")
+	ownerSecret := secretWithDBInfo(newEmptySecret(customDatabaseItem), ownerEntity)
+
+	objects := []runtime.Object{customDatabaseItem}
+	for _, databaseGrant := range f.databaseGrants {
+		objects = append(objects, databaseGrant)
+	}
+	kubeobjects := []runtime.Object{ownerSecret}
+	for _, secret := range f.secrets {
+		kubeobjects = append(kubeobjects, secret)
+	}
+
+	f.client = fake.NewSimpleClientset(objects...)
+	f.kubeclient = k8sfake.NewSimpleClientset(kubeobjects...)
+
+	i := informers.NewSharedInformerFactory(f.client, noResyncPeriodFunc())
+	k8sI := kubeinformers.NewSharedInformerFactory(f.kubeclient, noResyncPeriodFunc())
+
+	domainService, _ := customdatabase.NewDomainService("localhost", 5432, "")
+	databaseManager := fakeadapter.NewDbManager()
+	ownerMarker := domainService.NewOwnershipMarker(customdatabase.OwnerKindCustomDatabase, metav1.NamespaceDefault, "test", "")
+	databaseManager.CreateUser(ctx, ownerEntity.Database.User, ownerEntity.Database.Password, ownerMarker)
+	databaseManager.CreateDatabase(ctx, ownerEntity.Database.Name, ownerEntity.Database.Options, ownerMarker)
+	for userName, password := range f.users {
+		// role is marked by resource, which created it, otherwise it's unmarked
+		var marker customdatabase.OwnershipMarker
+		for _, item := range f.databaseGrants {
+			if item.Status.UserName == userName {
+				marker = domainService.NewOwnershipMarker(customdatabase.OwnerKindDatabaseGrant, item.Namespace, item.Name, "")
+			}
+		}
+		databaseManager.CreateUser(ctx, userName, password, marker)
+		if marker.Kind == "" {
+			delete(databaseManager.UserOwnership, userName)
+		}
+	}
+
+	c := NewDatabaseGrantController(ctx, f.kubeclient, f.client,
+		k8sI.Core().V1().Secrets(),
+		i.Igor().V1().CustomDatabases(),
+		i.Igor().V1().DatabaseGrants(),
+		databaseManager,
+		domainService,
+	)
+	c.recorder = &record.FakeRecorder{}
+	c.clock = testingclock.NewFakePassiveClock(testNow)
+
+	i.Igor().V1().CustomDatabases().Informer().GetIndexer().Add(customDatabaseItem)
+	for _, databaseGrant := range f.databaseGrants {
+		i.Igor().V1().DatabaseGrants().Informer().GetIndexer().Add(databaseGrant)
+	}
+	for _, secret := range kubeobjects {
+		k8sI.Core().V1().Secrets().Informer().GetIndexer().Add(secret)
+	}
+
+	return c, databaseManager
+}
+
+func (f *databaseGrantFixture) checkActions(expectedActions, expectedKubeActions []string) {
+	checkActionNames(f.t, expectedActions, filterInformerActions(f.client.Actions()))
+	checkActionNames(f.t, expectedKubeActions, filterInformerActions(f.kubeclient.Actions()))
+}
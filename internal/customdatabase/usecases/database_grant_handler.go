@@ -0,0 +1,370 @@
+package usecases
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	"k8s.io/custom-database/internal/customdatabase"
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+)
+
+// syncHandler creates role of DatabaseGrant and delivers its credentials to grantee namespace
+func (c *DatabaseGrantController) syncHandler(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("invalid resource key: %s", key))
+		return nil
+	}
+
+	ctx = contextWithResourceNameLogger(ctx, name)
+	ctx = customdatabase.ContextWithResource(ctx, "DatabaseGrant", key)
+
+	databaseGrant, err := c.databaseGrantsLister.DatabaseGrants(namespace).Get(name)
+	if err != nil {
+		// Role was already dropped before finalizer had been removed
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	if databaseGrant.DeletionTimestamp != nil && c.readOnly {
+		// role and Secret in grantee namespace are left, finalizer mustn't block deletion of resource
+		return c.removeDatabaseGrantFinalizer(ctx, databaseGrant)
+	}
+	if databaseGrant.DeletionTimestamp != nil {
+		return c.deleteDatabaseGrantHandler(ctx, databaseGrant)
+	}
+	if c.readOnly {
+		newStatus := databaseGrant.Status.DeepCopy()
+		c.setCondition(newStatus, databaseGrant, v1.ConditionReady, metav1.ConditionFalse, "ReadOnly", MessageRoleReadOnly)
+		return c.updateDatabaseGrantStatus(ctx, databaseGrant, newStatus)
+	}
+
+	return c.addOrUpdateDatabaseGrantHandler(ctx, databaseGrant)
+}
+
+func (c *DatabaseGrantController) addOrUpdateDatabaseGrantHandler(
+	ctx context.Context, databaseGrantReq *v1.DatabaseGrant,
+) error {
+	logger := loggerFromHandlerContext(ctx)
+	logger.Info("Add or update DatabaseGrant resource")
+
+	spec := databaseGrantReq.Spec
+	if spec.CustomDatabaseName == "" || spec.GranteeNamespace == "" || spec.SecretName == "" {
+		utilruntime.HandleError(fmt.Errorf(
+			"%s: customDatabaseName, granteeNamespace and secretName must be specified", databaseGrantReq.Name,
+		))
+		return nil
+	}
+
+	newStatus := databaseGrantReq.Status.DeepCopy()
+
+	customDatabaseReq, owner, err := ownerOfCustomDatabase(
+		c.customDatabasesLister, c.secretLister, databaseGrantReq.Namespace, spec.CustomDatabaseName,
+	)
+	if err != nil {
+		if err == errCustomDatabasePaused {
+			// grant is requeued by event handler, when annotation is removed
+			c.setCondition(newStatus, databaseGrantReq, v1.ConditionReady, metav1.ConditionFalse,
+				"CustomDatabasePaused", err.Error(),
+			)
+			return c.updateDatabaseGrantStatus(ctx, databaseGrantReq, newStatus)
+		}
+		if errors.IsNotFound(err) {
+			// CustomDatabase or its Secret will be created later, grant will be requeued by event handler
+			c.setCondition(newStatus, databaseGrantReq, v1.ConditionReady, metav1.ConditionFalse,
+				"CustomDatabaseNotReady", err.Error(),
+			)
+			return c.updateDatabaseGrantStatus(ctx, databaseGrantReq, newStatus)
+		}
+		return err
+	}
+	// role lives on server of database
+	ctx = customdatabase.ContextWithServer(ctx, owner.Server)
+
+	// Grant is owned by CustomDatabase, so k8s deletes it together with the database
+	databaseGrantReq, err = c.ensureDatabaseGrantFinalizerAndOwner(ctx, databaseGrantReq, customDatabaseReq)
+	if err != nil {
+		return err
+	}
+
+	if isCustomDatabaseHibernated(customDatabaseReq) {
+		// grant is requeued by event handler, when database is woken up
+		c.setCondition(newStatus, databaseGrantReq, v1.ConditionReady, metav1.ConditionFalse,
+			"CustomDatabaseHibernated", errCustomDatabaseHibernated.Error(),
+		)
+		return c.updateDatabaseGrantStatus(ctx, databaseGrantReq, newStatus)
+	}
+
+	storedSecret, err := c.secretLister.Secrets(spec.GranteeNamespace).Get(spec.SecretName)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	if storedSecret != nil && !isSecretOfDatabaseGrant(storedSecret, databaseGrantReq) {
+		// Secret of another application must not be overwritten
+		message := fmt.Sprintf("Secret %s/%s already exists and doesn't belong to grant", spec.GranteeNamespace, spec.SecretName)
+		c.setCondition(newStatus, databaseGrantReq, v1.ConditionReady, metav1.ConditionFalse, "SecretConflict", message)
+		c.recorder.Event(databaseGrantReq, corev1.EventTypeWarning, "SecretConflict", message)
+		return c.updateDatabaseGrantStatus(ctx, databaseGrantReq, newStatus)
+	}
+
+	var password string
+	if storedSecret != nil {
+		password = string(storedSecret.Data[SecretVarDbPassword])
+	}
+	isPasswordChanged := password == ""
+	if isPasswordChanged {
+		if password, err = customdatabase.NewPassword(); err != nil {
+			return err
+		}
+	}
+
+	grantUser, err := c.domainService.CreateDatabaseGrantEntity(
+		owner.Database, databaseGrantReq.Name, password, customdatabase.PrivilegeProfile(spec.Profile),
+	)
+	if err != nil {
+		// Spec is wrong, there is no reason to requeue resource until it's changed
+		utilruntime.HandleError(fmt.Errorf("%s: %w", databaseGrantReq.Name, err))
+		c.setCondition(newStatus, databaseGrantReq, v1.ConditionReady, metav1.ConditionFalse,
+			"InvalidSpec", err.Error(),
+		)
+		return c.updateDatabaseGrantStatus(ctx, databaseGrantReq, newStatus)
+	}
+
+	marker := c.domainService.NewOwnershipMarker(customdatabase.OwnerKindDatabaseGrant,
+		databaseGrantReq.Namespace, databaseGrantReq.Name, string(databaseGrantReq.UID),
+	)
+	isAdoptable := isRoleAdoptable(customDatabaseReq, c.adoptionAllowList, grantUser.Name,
+		storedSecret != nil && isSecretOfDatabaseGrant(storedSecret, databaseGrantReq),
+	)
+	err = ensureRole(ctx, c.databaseManager, grantUser, marker, isPasswordChanged, isAdoptable)
+	if stderrors.Is(err, customdatabase.ErrNotOwned) {
+		// role belongs to somebody else, there is no reason to requeue resource until role is dropped
+		utilruntime.HandleError(fmt.Errorf("%s: %w", databaseGrantReq.Name, err))
+		c.setCondition(newStatus, databaseGrantReq, v1.ConditionReady, metav1.ConditionFalse, "NotOwned", err.Error())
+		return c.updateDatabaseGrantStatus(ctx, databaseGrantReq, newStatus)
+	}
+	if err != nil {
+		return err
+	}
+
+	checksum := privilegesChecksum(owner, grantUser)
+	status := databaseGrantReq.Status
+	if isPrivilegesApplyDue(checksum, status.PrivilegesChecksum, status.PrivilegesAppliedAt, c.clock.Now()) {
+		c.ownSessions.touch(databaseGrantReq.Namespace, spec.CustomDatabaseName, c.clock.Now())
+		err = c.databaseManager.ApplyUserPrivileges(ctx, owner.Database, grantUser)
+		if err != nil {
+			return err
+		}
+		appliedAt := metav1.NewTime(c.clock.Now())
+		newStatus.PrivilegesAppliedAt = &appliedAt
+	}
+
+	grantEntity := customdatabase.Entity{
+		Host: owner.Host,
+		Database: customdatabase.Database{
+			Name:     owner.Database.Name,
+			User:     grantUser.Name,
+			Password: grantUser.Password,
+		},
+	}
+	secretNewState, err := secretWithTLS(secretWithDBInfo(newDatabaseGrantSecret(databaseGrantReq), grantEntity), storedSecret,
+		c.tenantTLS, grantEntity.Database.User, c.clock.Now(),
+	)
+	if err != nil {
+		return err
+	}
+	err = actualizeCredentialsSecret(ctx, c.kubeclientset, storedSecret, secretNewState)
+	if err != nil {
+		return err
+	}
+
+	newStatus.UserName = grantUser.Name
+	newStatus.PrivilegesChecksum = checksum
+	c.setCondition(newStatus, databaseGrantReq, v1.ConditionReady, metav1.ConditionTrue,
+		SuccessSynced, MessageDatabaseGrantSynced,
+	)
+
+	err = c.updateDatabaseGrantStatus(ctx, databaseGrantReq, newStatus)
+	if err != nil {
+		return err
+	}
+
+	c.recorder.Event(databaseGrantReq, corev1.EventTypeNormal, SuccessSynced, MessageDatabaseGrantSynced)
+	return nil
+}
+
+func (c *DatabaseGrantController) deleteDatabaseGrantHandler(
+	ctx context.Context, databaseGrantReq *v1.DatabaseGrant,
+) error {
+	logger := loggerFromHandlerContext(ctx)
+	logger.Info("Delete DatabaseGrant resource")
+
+	if !containsString(databaseGrantReq.Finalizers, FinalizerDatabaseGrant) {
+		return nil
+	}
+
+	spec := databaseGrantReq.Spec
+	userName := databaseGrantReq.Status.UserName
+	customDatabaseReq, owner, err := ownerOfCustomDatabase(
+		c.customDatabasesLister, c.secretLister, databaseGrantReq.Namespace, spec.CustomDatabaseName,
+	)
+	ctx = customdatabase.ContextWithServer(ctx, owner.Server)
+	switch {
+	case err == errCustomDatabasePaused:
+		// finalizer is kept, grant is requeued by event handler, when annotation is removed
+		logger.Info("CustomDatabase of grant is paused, role isn't dropped yet", "customDatabaseName",
+			spec.CustomDatabaseName,
+		)
+		return nil
+	case err == nil && isHibernatedDatabaseDropped(customDatabaseReq):
+		// database is dropped together with all privileges on its objects
+		logger.Info("Database of grant is dropped by hibernation", "customDatabaseName", spec.CustomDatabaseName)
+	case err == nil && isCustomDatabaseHibernated(customDatabaseReq):
+		// privileges in database can't be revoked, finalizer is kept until database is woken up
+		logger.Info("CustomDatabase of grant is hibernated, role isn't dropped yet", "customDatabaseName",
+			spec.CustomDatabaseName,
+		)
+		return nil
+	case err == nil && userName != "":
+		grantUser := customdatabase.DatabaseUser{Name: userName}
+		c.ownSessions.touch(databaseGrantReq.Namespace, spec.CustomDatabaseName, c.clock.Now())
+		if err = c.databaseManager.RevokeUserPrivileges(ctx, owner.Database, grantUser); err != nil {
+			return err
+		}
+	case errors.IsNotFound(err):
+		// grant is deleted together with CustomDatabase, database is already dropped with all privileges
+		logger.Info("CustomDatabase of grant is already deleted", "customDatabaseName", spec.CustomDatabaseName)
+	case err != nil:
+		return err
+	}
+
+	if userName != "" {
+		marker := c.domainService.NewOwnershipMarker(customdatabase.OwnerKindDatabaseGrant,
+			databaseGrantReq.Namespace, databaseGrantReq.Name, string(databaseGrantReq.UID),
+		)
+		err = c.databaseManager.DropUser(ctx, userName, marker)
+		if stderrors.Is(err, customdatabase.ErrNotOwned) {
+			// foreign role is left as is, it mustn't block deletion of resource
+			utilruntime.HandleError(fmt.Errorf("%s: role isn't dropped: %w", databaseGrantReq.Name, err))
+		} else if err != nil {
+			return err
+		}
+	}
+
+	storedSecret, err := c.secretLister.Secrets(spec.GranteeNamespace).Get(spec.SecretName)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if storedSecret != nil && isSecretOfDatabaseGrant(storedSecret, databaseGrantReq) {
+		logger.Info("Delete secret resource from grantee namespace",
+			"secretName", spec.SecretName, "namespace", spec.GranteeNamespace,
+		)
+		err = c.kubeclientset.CoreV1().Secrets(spec.GranteeNamespace).Delete(ctx, spec.SecretName, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return c.removeDatabaseGrantFinalizer(ctx, databaseGrantReq)
+}
+
+func (c *DatabaseGrantController) removeDatabaseGrantFinalizer(
+	ctx context.Context, databaseGrantReq *v1.DatabaseGrant,
+) error {
+	if !containsString(databaseGrantReq.Finalizers, FinalizerDatabaseGrant) {
+		return nil
+	}
+
+	databaseGrantCopy := databaseGrantReq.DeepCopy()
+	databaseGrantCopy.Finalizers = removeString(databaseGrantCopy.Finalizers, FinalizerDatabaseGrant)
+	_, err := c.sampleclientset.IgorV1().DatabaseGrants(databaseGrantCopy.Namespace).Update(
+		ctx, databaseGrantCopy, metav1.UpdateOptions{},
+	)
+
+	return err
+}
+
+func (c *DatabaseGrantController) ensureDatabaseGrantFinalizerAndOwner(
+	ctx context.Context, databaseGrantReq *v1.DatabaseGrant, customDatabaseReq *v1.CustomDatabase,
+) (*v1.DatabaseGrant, error) {
+	hasFinalizer := containsString(databaseGrantReq.Finalizers, FinalizerDatabaseGrant)
+	hasOwner := metav1.IsControlledBy(databaseGrantReq, customDatabaseReq)
+	if hasFinalizer && hasOwner {
+		return databaseGrantReq, nil
+	}
+
+	databaseGrantCopy := databaseGrantReq.DeepCopy()
+	if !hasFinalizer {
+		databaseGrantCopy.Finalizers = append(databaseGrantCopy.Finalizers, FinalizerDatabaseGrant)
+	}
+	if !hasOwner {
+		databaseGrantCopy.OwnerReferences = append(databaseGrantCopy.OwnerReferences,
+			*metav1.NewControllerRef(customDatabaseReq, v1.SchemeGroupVersion.WithKind("CustomDatabase")),
+		)
+	}
+
+	return c.sampleclientset.IgorV1().DatabaseGrants(databaseGrantCopy.Namespace).Update(
+		ctx, databaseGrantCopy, metav1.UpdateOptions{},
+	)
+}
+
+func (c *DatabaseGrantController) setCondition(
+	newStatus *v1.DatabaseGrantStatus, databaseGrant *v1.DatabaseGrant,
+	conditionType string, status metav1.ConditionStatus, reason, message string,
+) {
+	setStatusCondition(
+		&newStatus.Conditions, databaseGrant.Generation, c.clock.Now(), conditionType, status, reason, message,
+	)
+}
+
+func (c *DatabaseGrantController) updateDatabaseGrantStatus(
+	ctx context.Context, databaseGrant *v1.DatabaseGrant, newStatus *v1.DatabaseGrantStatus,
+) error {
+	if equality.Semantic.DeepEqual(databaseGrant.Status, *newStatus) {
+		return nil
+	}
+
+	databaseGrantCopy := databaseGrant.DeepCopy()
+	databaseGrantCopy.Status = *newStatus
+
+	_, err := c.sampleclientset.IgorV1().DatabaseGrants(databaseGrant.Namespace).UpdateStatus(
+		ctx, databaseGrantCopy, metav1.UpdateOptions{},
+	)
+
+	return err
+}
+
+// newDatabaseGrantSecret returns Secret for grantee namespace. It's marked by label instead of owner reference,
+// because owner references don't work across namespaces.
+func newDatabaseGrantSecret(databaseGrant *v1.DatabaseGrant) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      databaseGrant.Spec.SecretName,
+			Namespace: databaseGrant.Spec.GranteeNamespace,
+			Labels: map[string]string{
+				v1.LabelDatabaseGrant: databaseGrantLabelValue(databaseGrant),
+			},
+		},
+	}
+}
+
+func isSecretOfDatabaseGrant(secret *corev1.Secret, databaseGrant *v1.DatabaseGrant) bool {
+	return secret.Labels[v1.LabelDatabaseGrant] == databaseGrantLabelValue(databaseGrant)
+}
+
+func databaseGrantLabelValue(databaseGrant *v1.DatabaseGrant) string {
+	return databaseGrant.Namespace + "." + databaseGrant.Name
+}
@@ -0,0 +1,214 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	informerscorev1 "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	listerscorev1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+
+	"k8s.io/custom-database/internal/customdatabase"
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+	clientset "k8s.io/custom-database/pkg/generated/clientset/versioned"
+	informers "k8s.io/custom-database/pkg/generated/informers/externalversions/cusotmdatabase/v1"
+	listers "k8s.io/custom-database/pkg/generated/listers/cusotmdatabase/v1"
+)
+
+const (
+	// MessageDatabaseRestoreCompleted is the message used for an Event fired when a backup is restored
+	MessageDatabaseRestoreCompleted = "Backup restored successfully"
+
+	// MessageRestoreReadOnly is the message of Ready condition of DatabaseRestore, when controller runs in read-only mode
+	MessageRestoreReadOnly = "Controller runs in read-only mode, backup isn't restored"
+)
+
+// DatabaseRestorer interface of component, that restores logical dump into database
+type DatabaseRestorer interface {
+	RestoreDatabase(
+		ctx context.Context, host customdatabase.Host, database customdatabase.Database, dump io.Reader, clean bool,
+	) error
+}
+
+// DatabaseRestoreController is the controller implementation for DatabaseRestore resources
+type DatabaseRestoreController struct {
+	// kubeclientset is a standard kubernetes clientset
+	kubeclientset kubernetes.Interface
+	// sampleclientset is a clientset for our own API group
+	sampleclientset clientset.Interface
+
+	secretLister listerscorev1.SecretLister
+	secretSynced cache.InformerSynced
+
+	customDatabasesLister listers.CustomDatabaseLister
+	customDatabasesSynced cache.InformerSynced
+
+	databaseBackupsLister listers.DatabaseBackupLister
+	databaseBackupsSynced cache.InformerSynced
+
+	databaseRestoresLister listers.DatabaseRestoreLister
+	databaseRestoresSynced cache.InformerSynced
+
+	workqueue workqueue.RateLimitingInterface
+	recorder  record.EventRecorder
+
+	databaseManager DatabaseManager
+	restorer        DatabaseRestorer
+	storage         BackupStorage
+
+	// restores in progress by key of DatabaseRestore
+	restores *backgroundTasks
+
+	clock clock.PassiveClock
+	// ownSessions sessions of dumps and restores, they aren't taken for activity of tenant
+	ownSessions *OwnSessions
+	// readOnly controller only reports state of restores, backups aren't restored
+	readOnly bool
+}
+
+// NewDatabaseRestoreController returns a new controller of restores from backups
+func NewDatabaseRestoreController(
+	ctx context.Context,
+	kubeclientset kubernetes.Interface,
+	sampleclientset clientset.Interface,
+	secretInformer informerscorev1.SecretInformer,
+	customDatabaseInformer informers.CustomDatabaseInformer,
+	databaseBackupInformer informers.DatabaseBackupInformer,
+	databaseRestoreInformer informers.DatabaseRestoreInformer,
+	databaseManager DatabaseManager,
+	restorer DatabaseRestorer,
+	storage BackupStorage,
+	opts ...BackupControllerOption,
+) *DatabaseRestoreController {
+	logger := klog.FromContext(ctx)
+	options := newBackupControllerOptions(opts)
+
+	controller := &DatabaseRestoreController{
+		kubeclientset:          kubeclientset,
+		sampleclientset:        sampleclientset,
+		secretLister:           secretInformer.Lister(),
+		secretSynced:           secretInformer.Informer().HasSynced,
+		customDatabasesLister:  customDatabaseInformer.Lister(),
+		customDatabasesSynced:  customDatabaseInformer.Informer().HasSynced,
+		databaseBackupsLister:  databaseBackupInformer.Lister(),
+		databaseBackupsSynced:  databaseBackupInformer.Informer().HasSynced,
+		databaseRestoresLister: databaseRestoreInformer.Lister(),
+		databaseRestoresSynced: databaseRestoreInformer.Informer().HasSynced,
+		workqueue:              workqueue.NewNamedRateLimitingQueue(options.rateLimiter.newRateLimiter(), "DatabaseRestores"),
+		recorder:               newEventRecorder(ctx, kubeclientset),
+		databaseManager:        databaseManager,
+		restorer:               restorer,
+		storage:                storage,
+		restores:               newBackgroundTasks(),
+		clock:                  clock.RealClock{},
+		ownSessions:            options.ownSessions,
+		readOnly:               options.readOnly,
+	}
+
+	logger.Info("Setting up DatabaseRestore event handlers")
+	databaseRestoreInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: controller.enqueueDatabaseRestore,
+		UpdateFunc: func(old, new interface{}) {
+			controller.enqueueDatabaseRestore(new)
+		},
+		DeleteFunc: controller.enqueueDatabaseRestore,
+	})
+	// Restores wait for completion of backup and for readiness of target database
+	databaseBackupInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(old, new interface{}) {
+			controller.enqueueWaitingDatabaseRestores(new)
+		},
+	})
+	customDatabaseInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: controller.enqueueWaitingDatabaseRestores,
+		UpdateFunc: func(old, new interface{}) {
+			controller.enqueueWaitingDatabaseRestores(new)
+		},
+	})
+
+	return controller
+}
+
+// Run waits for informer caches and starts workers. It will block until context is done and all restores in progress
+// are cancelled.
+func (c *DatabaseRestoreController) Run(ctx context.Context, workers int) error {
+	defer utilruntime.HandleCrash()
+	defer c.workqueue.ShutDown()
+	logger := klog.FromContext(ctx)
+
+	logger.Info("Starting DatabaseRestore controller")
+
+	logger.Info("Waiting for informer caches to sync")
+	if ok := cache.WaitForCacheSync(
+		ctx.Done(), c.databaseRestoresSynced, c.databaseBackupsSynced, c.customDatabasesSynced, c.secretSynced,
+	); !ok {
+		return fmt.Errorf("failed to wait for caches to sync")
+	}
+
+	logger.Info("Starting workers", "count", workers)
+	for i := 0; i < workers; i++ {
+		go wait.UntilWithContext(ctx, c.runWorker, time.Second)
+	}
+
+	logger.Info("Started workers")
+	<-ctx.Done()
+	logger.Info("Shutting down workers")
+	c.restores.wait()
+
+	return nil
+}
+
+func (c *DatabaseRestoreController) runWorker(ctx context.Context) {
+	for processNextWorkItem(ctx, c.workqueue, c.syncHandler) {
+	}
+}
+
+func (c *DatabaseRestoreController) enqueueDatabaseRestore(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.workqueue.Add(key)
+}
+
+// enqueueWaitingDatabaseRestores requeues restores, that wait for the changed backup or CustomDatabase
+func (c *DatabaseRestoreController) enqueueWaitingDatabaseRestores(obj interface{}) {
+	var namespace, backupName, customDatabaseName string
+	switch object := obj.(type) {
+	case *v1.DatabaseBackup:
+		namespace, backupName = object.Namespace, object.Name
+	case *v1.CustomDatabase:
+		namespace, customDatabaseName = object.Namespace, object.Name
+	default:
+		return
+	}
+
+	databaseRestores, err := c.databaseRestoresLister.DatabaseRestores(namespace).List(labels.Everything())
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+
+	for _, databaseRestore := range databaseRestores {
+		phase := databaseRestore.Status.Phase
+		if phase != v1.RestorePhasePending && phase != v1.RestorePhaseWaitingForDatabase {
+			continue
+		}
+
+		if (backupName != "" && databaseRestore.Spec.BackupName == backupName) ||
+			(customDatabaseName != "" && restoreTargetName(databaseRestore.Spec) == customDatabaseName) {
+			c.enqueueDatabaseRestore(databaseRestore)
+		}
+	}
+}
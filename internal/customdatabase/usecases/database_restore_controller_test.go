@@ -0,0 +1,346 @@
+package usecases
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeinformers "k8s.io/client-go/informers"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2/ktesting"
+	testingclock "k8s.io/utils/clock/testing"
+
+	fakeadapter "k8s.io/custom-database/internal/customdatabase/adapters/fake"
+	customdatabasecontroller "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+	"k8s.io/custom-database/pkg/generated/clientset/versioned/fake"
+	informers "k8s.io/custom-database/pkg/generated/informers/externalversions"
+)
+
+func TestDatabaseRestoreIntoExistingDatabase(t *testing.T) {
+	f := newDatabaseRestoreFixture(t)
+	_, ctx := ktesting.NewTestContext(t)
+
+	databaseRestoreItem := newDatabaseRestore("rollback")
+	databaseRestoreItem.Spec.CustomDatabaseName = "test"
+	databaseRestoreItem.Spec.ConfirmOverwrite = "test"
+	f.databaseRestores = append(f.databaseRestores, databaseRestoreItem)
+
+	c := f.newController(ctx)
+	if err := c.syncHandler(ctx, metav1.NamespaceDefault+"/rollback"); err != nil {
+		t.Fatalf("error syncing databaseRestore: %v", err)
+	}
+	c.restores.wait()
+
+	f.checkActions([]string{
+		"update databaserestores/status",
+		"list databaseusers",
+		"list databasegrants",
+		"get databaserestores",
+		"update databaserestores/status",
+	})
+
+	if dump := f.restorer.Restored["test"]; string(dump) != "dump of test" {
+		t.Errorf("dump should be restored into database, given %q", dump)
+	}
+
+	status := f.lastDatabaseRestoreStatus()
+	if status.Phase != customdatabasecontroller.RestorePhaseCompleted {
+		t.Errorf("restore should be completed, given %s: %+v", status.Phase, status.Conditions)
+	}
+	if status.CustomDatabaseName != "test" {
+		t.Errorf("wrong target of restore: %s", status.CustomDatabaseName)
+	}
+}
+
+func TestReapplyPrivilegesOfRolesAfterRestore(t *testing.T) {
+	f := newDatabaseRestoreFixture(t)
+	_, ctx := ktesting.NewTestContext(t)
+
+	databaseRestoreItem := newDatabaseRestore("rollback")
+	databaseRestoreItem.Spec.CustomDatabaseName = "test"
+	databaseRestoreItem.Spec.ConfirmOverwrite = "test"
+	f.databaseRestores = append(f.databaseRestores, databaseRestoreItem)
+
+	databaseUserItem := newDatabaseUser("reporting", "test")
+	databaseUserItem.Status.PrivilegesChecksum = "sha256:0"
+	otherDatabaseUserItem := newDatabaseUser("analytics", "other")
+	otherDatabaseUserItem.Status.PrivilegesChecksum = "sha256:0"
+	f.objects = append(f.objects, databaseUserItem, otherDatabaseUserItem)
+
+	c := f.newController(ctx)
+	if err := c.syncHandler(ctx, metav1.NamespaceDefault+"/rollback"); err != nil {
+		t.Fatalf("error syncing databaseRestore: %v", err)
+	}
+	c.restores.wait()
+
+	databaseUser, _ := f.client.IgorV1().DatabaseUsers(metav1.NamespaceDefault).Get(ctx, "reporting", metav1.GetOptions{})
+	if databaseUser.Status.PrivilegesChecksum != "" {
+		t.Errorf("privileges of user of restored database should be applied again")
+	}
+	otherDatabaseUser, _ := f.client.IgorV1().DatabaseUsers(metav1.NamespaceDefault).Get(ctx, "analytics", metav1.GetOptions{})
+	if otherDatabaseUser.Status.PrivilegesChecksum == "" {
+		t.Errorf("privileges of user of another database shouldn't be touched")
+	}
+	if status := f.lastDatabaseRestoreStatus(); status.Phase != customdatabasecontroller.RestorePhaseCompleted {
+		t.Errorf("restore should be completed, given %s: %+v", status.Phase, status.Conditions)
+	}
+}
+
+func TestDatabaseRestoreOfDeletedBackup(t *testing.T) {
+	f := newDatabaseRestoreFixture(t)
+	f.isBackupDeleted = true
+	_, ctx := ktesting.NewTestContext(t)
+
+	databaseRestoreItem := newDatabaseRestore("rollback")
+	databaseRestoreItem.Spec.CustomDatabaseName = "test"
+	databaseRestoreItem.Spec.ConfirmOverwrite = "test"
+	f.databaseRestores = append(f.databaseRestores, databaseRestoreItem)
+
+	c := f.newController(ctx)
+	if err := c.syncHandler(ctx, metav1.NamespaceDefault+"/rollback"); err != nil {
+		t.Fatalf("error syncing databaseRestore: %v", err)
+	}
+	c.restores.wait()
+
+	if len(f.restorer.Restored) > 0 {
+		t.Errorf("dump of deleted backup shouldn't be restored")
+	}
+	status := f.lastDatabaseRestoreStatus()
+	if status.Phase != customdatabasecontroller.RestorePhaseFailed ||
+		len(status.Conditions) != 1 || status.Conditions[0].Reason != "BackupDeleted" {
+		t.Errorf("restore should be failed: %+v", status)
+	}
+}
+
+func TestDatabaseRestoreRequiresConfirmation(t *testing.T) {
+	f := newDatabaseRestoreFixture(t)
+	_, ctx := ktesting.NewTestContext(t)
+
+	databaseRestoreItem := newDatabaseRestore("rollback")
+	databaseRestoreItem.Spec.CustomDatabaseName = "test"
+	f.databaseRestores = append(f.databaseRestores, databaseRestoreItem)
+
+	c := f.newController(ctx)
+	if err := c.syncHandler(ctx, metav1.NamespaceDefault+"/rollback"); err != nil {
+		t.Fatalf("error syncing databaseRestore: %v", err)
+	}
+	c.restores.wait()
+
+	f.checkActions([]string{"update databaserestores/status"})
+
+	if len(f.restorer.Restored) > 0 {
+		t.Errorf("database shouldn't be overwritten without confirmation")
+	}
+
+	status := f.lastDatabaseRestoreStatus()
+	if status.Phase != customdatabasecontroller.RestorePhasePending ||
+		len(status.Conditions) != 1 || status.Conditions[0].Reason != "ConfirmationRequired" {
+		t.Errorf("restore should wait for confirmation: %+v", status)
+	}
+}
+
+func TestDatabaseRestoreCreatesNewDatabase(t *testing.T) {
+	f := newDatabaseRestoreFixture(t)
+	_, ctx := ktesting.NewTestContext(t)
+
+	databaseRestoreItem := newDatabaseRestore("copy")
+	databaseRestoreItem.Spec.NewCustomDatabase = &customdatabasecontroller.NewCustomDatabase{
+		Name: "test-copy",
+		Spec: customdatabasecontroller.CustomDatabaseSpec{SecretName: "test-copy"},
+	}
+	f.databaseRestores = append(f.databaseRestores, databaseRestoreItem)
+
+	c := f.newController(ctx)
+	if err := c.syncHandler(ctx, metav1.NamespaceDefault+"/copy"); err != nil {
+		t.Fatalf("error syncing databaseRestore: %v", err)
+	}
+
+	f.checkActions([]string{"create customdatabases", "update databaserestores/status"})
+
+	customDatabase, err := f.client.IgorV1().CustomDatabases(metav1.NamespaceDefault).Get(
+		ctx, "test-copy", metav1.GetOptions{},
+	)
+	if err != nil {
+		t.Fatalf("CustomDatabase should be created: %v", err)
+	}
+	if customDatabase.Labels[customdatabasecontroller.LabelDatabaseRestore] != "copy" {
+		t.Errorf("CustomDatabase should be marked by restore: %v", customDatabase.Labels)
+	}
+
+	if status := f.lastDatabaseRestoreStatus(); status.Phase != customdatabasecontroller.RestorePhaseWaitingForDatabase {
+		t.Errorf("restore should wait for new database, given %s", status.Phase)
+	}
+}
+
+func TestDatabaseRestoreChecksumMismatch(t *testing.T) {
+	f := newDatabaseRestoreFixture(t)
+	_, ctx := ktesting.NewTestContext(t)
+
+	databaseRestoreItem := newDatabaseRestore("rollback")
+	databaseRestoreItem.Spec.CustomDatabaseName = "test"
+	databaseRestoreItem.Spec.ConfirmOverwrite = "test"
+	f.databaseRestores = append(f.databaseRestores, databaseRestoreItem)
+	f.storage.Dumps["default/test/before-migration.dump"] = []byte("corrupted dump")
+
+	c := f.newController(ctx)
+	if err := c.syncHandler(ctx, metav1.NamespaceDefault+"/rollback"); err != nil {
+		t.Fatalf("error syncing databaseRestore: %v", err)
+	}
+	c.restores.wait()
+
+	if len(f.restorer.Restored) > 0 {
+		t.Errorf("corrupted dump shouldn't be restored")
+	}
+
+	status := f.lastDatabaseRestoreStatus()
+	if status.Phase != customdatabasecontroller.RestorePhaseFailed ||
+		len(status.Conditions) != 1 || status.Conditions[0].Reason != "RestoreFailed" {
+		t.Errorf("restore should be failed: %+v", status)
+	}
+}
+
+func TestChecksumReaderHoldsBackTailOfCorruptedDump(t *testing.T) {
+	dump := bytes.Repeat([]byte("dump of test;"), 1000)
+	hash := sha256.Sum256(dump)
+	checksum := "sha256:" + hex.EncodeToString(hash[:])
+
+	passed, err := io.ReadAll(newChecksumReader(bytes.NewReader(dump), "test.dump", checksum))
+	if err != nil || !bytes.Equal(passed, dump) {
+		t.Errorf("verified dump should be passed completely, given %d bytes, %v", len(passed), err)
+	}
+
+	corrupted := append([]byte{}, dump...)
+	corrupted[0] = 'D'
+	passed, err = io.ReadAll(newChecksumReader(bytes.NewReader(corrupted), "test.dump", checksum))
+	if err == nil {
+		t.Errorf("mismatch of checksum should be reported")
+	}
+	if len(passed) != len(dump)-checksumHoldBack {
+		t.Errorf("tail of corrupted dump should be held back, given %d of %d bytes", len(passed), len(dump))
+	}
+}
+
+type databaseRestoreFixture struct {
+	t *testing.T
+
+	client     *fake.Clientset
+	kubeclient *k8sfake.Clientset
+
+	databaseRestores []*customdatabasecontroller.DatabaseRestore
+	// objects other resources of namespace, e.g. DatabaseUsers
+	objects []runtime.Object
+	// isBackupDeleted sets deletion timestamp of backup
+	isBackupDeleted bool
+	restorer        *fakeadapter.Restorer
+	storage         *fakeadapter.BackupStorage
+}
+
+func newDatabaseRestoreFixture(t *testing.T) *databaseRestoreFixture {
+	storage := fakeadapter.NewBackupStorage()
+	storage.Dumps["default/test/before-migration.dump"] = []byte("dump of test")
+
+	return &databaseRestoreFixture{
+		t:        t,
+		restorer: fakeadapter.NewRestorer(),
+		storage:  storage,
+	}
+}
+
+func newDatabaseRestore(name string) *customdatabasecontroller.DatabaseRestore {
+	return &customdatabasecontroller.DatabaseRestore{
+		TypeMeta: metav1.TypeMeta{APIVersion: customdatabasecontroller.SchemeGroupVersion.String()},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: metav1.NamespaceDefault,
+		},
+		Spec: customdatabasecontroller.DatabaseRestoreSpec{
+			BackupName: "before-migration",
+		},
+	}
+}
+
+// newController returns controller of restores for the existing "test" CustomDatabase and its completed backup
+func (f *databaseRestoreFixture) newController(ctx context.Context) *DatabaseRestoreController {
+	customDatabaseItem := newCustomDatabase("test")
+	ownerEntity := newEntity("test")
+	ownerSecret := secretWithDBInfo(newEmptySecret(customDatabaseItem), ownerEntity)
+
+	dump := []byte("dump of test")
+	hash := sha256.Sum256(dump)
+	databaseBackupItem := newDatabaseBackup("before-migration")
+	databaseBackupItem.Status.Phase = customdatabasecontroller.BackupPhaseCompleted
+	databaseBackupItem.Status.Location = "default/test/before-migration.dump"
+	databaseBackupItem.Status.Checksum = "sha256:" + hex.EncodeToString(hash[:])
+	if f.isBackupDeleted {
+		deletedAt := metav1.NewTime(testNow)
+		databaseBackupItem.Finalizers = []string{FinalizerDatabaseBackup}
+		databaseBackupItem.DeletionTimestamp = &deletedAt
+	}
+
+	objects := append([]runtime.Object{customDatabaseItem, databaseBackupItem}, f.objects...)
+	for _, databaseRestore := range f.databaseRestores {
+		objects = append(objects, databaseRestore)
+	}
+
+	f.client = fake.NewSimpleClientset(objects...)
+	f.kubeclient = k8sfake.NewSimpleClientset(ownerSecret)
+
+	i := informers.NewSharedInformerFactory(f.client, noResyncPeriodFunc())
+	k8sI := kubeinformers.NewSharedInformerFactory(f.kubeclient, noResyncPeriodFunc())
+
+	databaseManager := fakeadapter.NewDbManager()
+	ownerMarker := newOwnershipMarker(metav1.NamespaceDefault, "test")
+	databaseManager.CreateUser(ctx, ownerEntity.Database.User, ownerEntity.Database.Password, ownerMarker)
+	databaseManager.CreateDatabase(ctx, ownerEntity.Database.Name, ownerEntity.Database.Options, ownerMarker)
+
+	c := NewDatabaseRestoreController(ctx, f.kubeclient, f.client,
+		k8sI.Core().V1().Secrets(),
+		i.Igor().V1().CustomDatabases(),
+		i.Igor().V1().DatabaseBackups(),
+		i.Igor().V1().DatabaseRestores(),
+		databaseManager,
+		f.restorer,
+		f.storage,
+	)
+	c.recorder = &record.FakeRecorder{}
+	c.clock = testingclock.NewFakePassiveClock(testNow)
+
+	i.Igor().V1().CustomDatabases().Informer().GetIndexer().Add(customDatabaseItem)
+	i.Igor().V1().DatabaseBackups().Informer().GetIndexer().Add(databaseBackupItem)
+	for _, databaseRestore := range f.databaseRestores {
+		i.Igor().V1().DatabaseRestores().Informer().GetIndexer().Add(databaseRestore)
+	}
+	k8sI.Core().V1().Secrets().Informer().GetIndexer().Add(ownerSecret)
+
+	return c
+}
+
+func (f *databaseRestoreFixture) checkActions(expectedActions []string) {
+	checkActionNames(f.t, expectedActions, filterInformerActions(f.client.Actions()))
+	checkActionNames(f.t, nil, filterInformerActions(f.kubeclient.Actions()))
+}
+
+func (f *databaseRestoreFixture) lastDatabaseRestoreStatus() customdatabasecontroller.DatabaseRestoreStatus {
+	var status *customdatabasecontroller.DatabaseRestoreStatus
+	for _, action := range f.client.Actions() {
+		if updateAction, ok := action.(core.UpdateActionImpl); ok && updateAction.GetSubresource() == "status" {
+			if databaseRestore, ok := updateAction.GetObject().(*customdatabasecontroller.DatabaseRestore); ok {
+				status = &databaseRestore.Status
+			}
+		}
+	}
+
+	if status == nil {
+		f.t.Fatalf("status of databaseRestore wasn't updated")
+		return customdatabasecontroller.DatabaseRestoreStatus{}
+	}
+
+	return *status
+}
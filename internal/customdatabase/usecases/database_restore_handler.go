@@ -0,0 +1,409 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+
+	"k8s.io/custom-database/internal/customdatabase"
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+)
+
+// syncHandler checks preconditions of restore and starts it in background, result of restore is written to status
+// by the restore itself
+func (c *DatabaseRestoreController) syncHandler(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("invalid resource key: %s", key))
+		return nil
+	}
+
+	ctx = contextWithResourceNameLogger(ctx, name)
+	ctx = customdatabase.ContextWithResource(ctx, "DatabaseRestore", key)
+
+	databaseRestore, err := c.databaseRestoresLister.DatabaseRestores(namespace).Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.restores.cancel(key)
+			return nil
+		}
+		return err
+	}
+
+	if databaseRestore.DeletionTimestamp != nil {
+		c.restores.cancel(key)
+		return nil
+	}
+
+	phase := databaseRestore.Status.Phase
+	switch {
+	case phase == v1.RestorePhaseCompleted || phase == v1.RestorePhaseFailed:
+		// restore is made only once
+		return nil
+	case c.readOnly:
+		// restore would change database, it waits until controller is started in normal mode
+		newStatus := databaseRestore.Status.DeepCopy()
+		c.setCondition(newStatus, databaseRestore, v1.ConditionReady, metav1.ConditionFalse, "ReadOnly",
+			MessageRestoreReadOnly,
+		)
+		return c.updateDatabaseRestoreStatus(ctx, databaseRestore, newStatus)
+	case phase == v1.RestorePhaseRunning:
+		if c.restores.isRunning(key) {
+			return nil
+		}
+		// controller was restarted during restore, transaction of restore was rolled back
+		newStatus := databaseRestore.Status.DeepCopy()
+		c.failDatabaseRestore(newStatus, databaseRestore, "Interrupted", "Restore was interrupted by restart of controller")
+		return c.updateDatabaseRestoreStatus(ctx, databaseRestore, newStatus)
+	}
+
+	return c.startDatabaseRestore(ctx, key, databaseRestore)
+}
+
+func (c *DatabaseRestoreController) startDatabaseRestore(
+	ctx context.Context, key string, databaseRestoreReq *v1.DatabaseRestore,
+) error {
+	logger := loggerFromHandlerContext(ctx)
+	spec := databaseRestoreReq.Spec
+	newStatus := databaseRestoreReq.Status.DeepCopy()
+
+	if err := validateDatabaseRestoreSpec(spec); err != nil {
+		// spec is immutable, so restore can't be made
+		c.failDatabaseRestore(newStatus, databaseRestoreReq, "InvalidSpec", err.Error())
+		return c.updateDatabaseRestoreStatus(ctx, databaseRestoreReq, newStatus)
+	}
+
+	databaseBackup, err := c.databaseBackupsLister.DatabaseBackups(databaseRestoreReq.Namespace).Get(spec.BackupName)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	switch {
+	case databaseBackup != nil && databaseBackup.DeletionTimestamp != nil:
+		c.failDatabaseRestore(newStatus, databaseRestoreReq, "BackupDeleted",
+			fmt.Sprintf("DatabaseBackup %s is being deleted", spec.BackupName),
+		)
+		return c.updateDatabaseRestoreStatus(ctx, databaseRestoreReq, newStatus)
+	case databaseBackup == nil || databaseBackup.Status.Phase == v1.BackupPhasePending ||
+		databaseBackup.Status.Phase == v1.BackupPhaseRunning || databaseBackup.Status.Phase == "":
+		newStatus.Phase = v1.RestorePhasePending
+		c.setCondition(newStatus, databaseRestoreReq, v1.ConditionReady, metav1.ConditionFalse,
+			"BackupNotReady", fmt.Sprintf("DatabaseBackup %s isn't completed yet", spec.BackupName),
+		)
+		return c.updateDatabaseRestoreStatus(ctx, databaseRestoreReq, newStatus)
+	case databaseBackup.Status.Phase == v1.BackupPhaseFailed:
+		c.failDatabaseRestore(newStatus, databaseRestoreReq, "BackupFailed",
+			fmt.Sprintf("DatabaseBackup %s is failed", spec.BackupName),
+		)
+		return c.updateDatabaseRestoreStatus(ctx, databaseRestoreReq, newStatus)
+	}
+
+	if spec.CustomDatabaseName != "" && spec.ConfirmOverwrite != spec.CustomDatabaseName {
+		newStatus.Phase = v1.RestorePhasePending
+		c.setCondition(newStatus, databaseRestoreReq, v1.ConditionReady, metav1.ConditionFalse, "ConfirmationRequired",
+			fmt.Sprintf("Data of %s will be lost, set confirmOverwrite to %q to restore", spec.CustomDatabaseName, spec.CustomDatabaseName),
+		)
+		return c.updateDatabaseRestoreStatus(ctx, databaseRestoreReq, newStatus)
+	}
+
+	if spec.NewCustomDatabase != nil {
+		isOwnDatabase, err := c.ensureNewCustomDatabase(ctx, databaseRestoreReq)
+		if err != nil {
+			return err
+		}
+		if !isOwnDatabase {
+			c.failDatabaseRestore(newStatus, databaseRestoreReq, "AlreadyExists", fmt.Sprintf(
+				"CustomDatabase %s already exists and wasn't created by restore", spec.NewCustomDatabase.Name,
+			))
+			return c.updateDatabaseRestoreStatus(ctx, databaseRestoreReq, newStatus)
+		}
+	}
+
+	targetName := restoreTargetName(spec)
+	newStatus.CustomDatabaseName = targetName
+
+	_, owner, err := ownerOfCustomDatabase(
+		c.customDatabasesLister, c.secretLister, databaseRestoreReq.Namespace, targetName,
+	)
+	if err != nil {
+		if err == errCustomDatabasePaused {
+			// restore waits for database, like for new one, it's requeued by event handler, when annotation is removed
+			newStatus.Phase = v1.RestorePhaseWaitingForDatabase
+			c.setCondition(newStatus, databaseRestoreReq, v1.ConditionReady, metav1.ConditionFalse,
+				"CustomDatabasePaused", err.Error(),
+			)
+			return c.updateDatabaseRestoreStatus(ctx, databaseRestoreReq, newStatus)
+		}
+		if errors.IsNotFound(err) {
+			// new database is created by CustomDatabase controller, restore is requeued when it's ready
+			newStatus.Phase = v1.RestorePhaseWaitingForDatabase
+			c.setCondition(newStatus, databaseRestoreReq, v1.ConditionReady, metav1.ConditionFalse,
+				"CustomDatabaseNotReady", err.Error(),
+			)
+			return c.updateDatabaseRestoreStatus(ctx, databaseRestoreReq, newStatus)
+		}
+		return err
+	}
+
+	startedAt := metav1.NewTime(c.clock.Now())
+	newStatus.Phase = v1.RestorePhaseRunning
+	newStatus.StartedAt = &startedAt
+	c.setCondition(newStatus, databaseRestoreReq, v1.ConditionReady, metav1.ConditionFalse,
+		"RestoreRunning", "Restore of database is in progress",
+	)
+
+	// status is stored before restore, so restore is never started twice
+	if err = c.updateDatabaseRestoreStatus(ctx, databaseRestoreReq, newStatus); err != nil {
+		return err
+	}
+
+	logger.Info("Start restore of database", "database", owner.Database.Name, "backup", spec.BackupName)
+
+	location, checksum := databaseBackup.Status.Location, databaseBackup.Status.Checksum
+	clean := spec.CustomDatabaseName != ""
+	c.restores.start(ctx, key, func(restoreCtx context.Context) {
+		// restore commits transactions in database, they aren't activity of tenant
+		c.ownSessions.touch(databaseRestoreReq.Namespace, targetName, c.clock.Now())
+		err := c.restoreDatabase(restoreCtx, owner, location, checksum, clean)
+		c.ownSessions.touch(databaseRestoreReq.Namespace, targetName, c.clock.Now())
+		if err == nil {
+			err = c.resetPrivilegesOfRoles(restoreCtx, databaseRestoreReq.Namespace, targetName)
+		}
+		if restoreCtx.Err() != nil {
+			// DatabaseRestore was deleted or controller is stopped, transaction of restore is rolled back
+			logger.Info("Restore of database was cancelled", "database", owner.Database.Name)
+			return
+		}
+
+		if err = c.completeDatabaseRestore(restoreCtx, databaseRestoreReq, err); err != nil {
+			utilruntime.HandleError(fmt.Errorf("%s: update status of restore: %w", key, err))
+		}
+	})
+
+	return nil
+}
+
+// restoreDatabase restores dump by owner of target database and verifies its checksum on the fly. Restored objects
+// belong to the owner, privileges of database are hardened again, because restore may recreate public schema.
+func (c *DatabaseRestoreController) restoreDatabase(
+	ctx context.Context, owner customdatabase.Entity, location, checksum string, clean bool,
+) error {
+	dump, err := c.storage.Get(ctx, location)
+	if err != nil {
+		return err
+	}
+	defer dump.Close()
+
+	verifiedDump := newChecksumReader(dump, location, checksum)
+	if err = c.restorer.RestoreDatabase(ctx, owner.Host, owner.Database, verifiedDump, clean); err != nil {
+		// restore reports broken input, mismatch of checksum explains it better
+		if verifyErr := verifiedDump.verifyErr(); verifyErr != nil {
+			return verifyErr
+		}
+		return err
+	}
+
+	ctx = customdatabase.ContextWithServer(ctx, owner.Server)
+	return c.databaseManager.HardenDatabase(ctx, owner.Database.Name, owner.Database.User)
+}
+
+// resetPrivilegesOfRoles clears checksums of privileges of DatabaseUsers and DatabaseGrants of CustomDatabase, so
+// their controllers grant privileges on restored objects again
+func (c *DatabaseRestoreController) resetPrivilegesOfRoles(ctx context.Context, namespace, customDatabaseName string) error {
+	databaseUsersClient := c.sampleclientset.IgorV1().DatabaseUsers(namespace)
+	databaseUsers, err := databaseUsersClient.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("reset privileges of users: %w", err)
+	}
+	for _, item := range databaseUsers.Items {
+		if item.Spec.CustomDatabaseName != customDatabaseName {
+			continue
+		}
+		err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			databaseUser, err := databaseUsersClient.Get(ctx, item.Name, metav1.GetOptions{})
+			if err != nil || databaseUser.Status.PrivilegesChecksum == "" {
+				return err
+			}
+			databaseUser.Status.PrivilegesChecksum = ""
+			_, err = databaseUsersClient.UpdateStatus(ctx, databaseUser, metav1.UpdateOptions{})
+			return err
+		})
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("reset privileges of user %s: %w", item.Name, err)
+		}
+	}
+
+	databaseGrantsClient := c.sampleclientset.IgorV1().DatabaseGrants(namespace)
+	databaseGrants, err := databaseGrantsClient.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("reset privileges of grants: %w", err)
+	}
+	for _, item := range databaseGrants.Items {
+		if item.Spec.CustomDatabaseName != customDatabaseName {
+			continue
+		}
+		err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			databaseGrant, err := databaseGrantsClient.Get(ctx, item.Name, metav1.GetOptions{})
+			if err != nil || databaseGrant.Status.PrivilegesChecksum == "" {
+				return err
+			}
+			databaseGrant.Status.PrivilegesChecksum = ""
+			_, err = databaseGrantsClient.UpdateStatus(ctx, databaseGrant, metav1.UpdateOptions{})
+			return err
+		})
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("reset privileges of grant %s: %w", item.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// completeDatabaseRestore writes result of restore to status
+func (c *DatabaseRestoreController) completeDatabaseRestore(
+	ctx context.Context, databaseRestoreReq *v1.DatabaseRestore, restoreErr error,
+) error {
+	var databaseRestore *v1.DatabaseRestore
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var err error
+		// lister may be not updated yet, so the latest version is requested
+		databaseRestore, err = c.sampleclientset.IgorV1().DatabaseRestores(databaseRestoreReq.Namespace).Get(
+			ctx, databaseRestoreReq.Name, metav1.GetOptions{},
+		)
+		if err != nil {
+			return err
+		}
+
+		newStatus := databaseRestore.Status.DeepCopy()
+		if restoreErr != nil {
+			c.failDatabaseRestore(newStatus, databaseRestore, "RestoreFailed", restoreErr.Error())
+		} else {
+			completedAt := metav1.NewTime(c.clock.Now())
+			newStatus.Phase = v1.RestorePhaseCompleted
+			newStatus.CompletedAt = &completedAt
+			c.setCondition(newStatus, databaseRestore, v1.ConditionReady, metav1.ConditionTrue,
+				"RestoreCompleted", MessageDatabaseRestoreCompleted,
+			)
+		}
+
+		return c.updateDatabaseRestoreStatus(ctx, databaseRestore, newStatus)
+	})
+	if err != nil {
+		return err
+	}
+
+	if restoreErr != nil {
+		c.recorder.Event(databaseRestore, corev1.EventTypeWarning, "RestoreFailed", restoreErr.Error())
+	} else {
+		c.recorder.Event(databaseRestore, corev1.EventTypeNormal, "RestoreCompleted", MessageDatabaseRestoreCompleted)
+	}
+
+	return nil
+}
+
+// ensureNewCustomDatabase creates CustomDatabase from template of restore. It returns false, if CustomDatabase with
+// the same name was created not by this restore.
+func (c *DatabaseRestoreController) ensureNewCustomDatabase(
+	ctx context.Context, databaseRestoreReq *v1.DatabaseRestore,
+) (bool, error) {
+	template := databaseRestoreReq.Spec.NewCustomDatabase
+
+	customDatabase, err := c.customDatabasesLister.CustomDatabases(databaseRestoreReq.Namespace).Get(template.Name)
+	if err == nil {
+		return customDatabase.Labels[v1.LabelDatabaseRestore] == databaseRestoreReq.Name, nil
+	}
+	if !errors.IsNotFound(err) {
+		return false, err
+	}
+
+	loggerFromHandlerContext(ctx).Info("Create CustomDatabase for restore", "customDatabaseName", template.Name)
+
+	customDatabase = &v1.CustomDatabase{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      template.Name,
+			Namespace: databaseRestoreReq.Namespace,
+			Labels: map[string]string{
+				v1.LabelDatabaseRestore: databaseRestoreReq.Name,
+			},
+		},
+		Spec: *template.Spec.DeepCopy(),
+	}
+	_, err = c.sampleclientset.IgorV1().CustomDatabases(customDatabase.Namespace).Create(
+		ctx, customDatabase, metav1.CreateOptions{},
+	)
+	if errors.IsAlreadyExists(err) {
+		// lister isn't updated yet, ownership is checked on the next sync
+		return true, nil
+	}
+
+	return err == nil, err
+}
+
+func (c *DatabaseRestoreController) failDatabaseRestore(
+	newStatus *v1.DatabaseRestoreStatus, databaseRestore *v1.DatabaseRestore, reason, message string,
+) {
+	completedAt := metav1.NewTime(c.clock.Now())
+	newStatus.Phase = v1.RestorePhaseFailed
+	newStatus.CompletedAt = &completedAt
+	c.setCondition(newStatus, databaseRestore, v1.ConditionReady, metav1.ConditionFalse, reason, message)
+}
+
+func (c *DatabaseRestoreController) setCondition(
+	newStatus *v1.DatabaseRestoreStatus, databaseRestore *v1.DatabaseRestore,
+	conditionType string, status metav1.ConditionStatus, reason, message string,
+) {
+	setStatusCondition(
+		&newStatus.Conditions, databaseRestore.Generation, c.clock.Now(), conditionType, status, reason, message,
+	)
+}
+
+func (c *DatabaseRestoreController) updateDatabaseRestoreStatus(
+	ctx context.Context, databaseRestore *v1.DatabaseRestore, newStatus *v1.DatabaseRestoreStatus,
+) error {
+	if equality.Semantic.DeepEqual(databaseRestore.Status, *newStatus) {
+		return nil
+	}
+
+	databaseRestoreCopy := databaseRestore.DeepCopy()
+	databaseRestoreCopy.Status = *newStatus
+
+	_, err := c.sampleclientset.IgorV1().DatabaseRestores(databaseRestore.Namespace).UpdateStatus(
+		ctx, databaseRestoreCopy, metav1.UpdateOptions{},
+	)
+
+	return err
+}
+
+func validateDatabaseRestoreSpec(spec v1.DatabaseRestoreSpec) error {
+	if spec.BackupName == "" {
+		return fmt.Errorf("backupName must be specified")
+	}
+
+	if (spec.CustomDatabaseName == "") == (spec.NewCustomDatabase == nil) {
+		return fmt.Errorf("exactly one of customDatabaseName and newCustomDatabase must be specified")
+	}
+
+	if spec.NewCustomDatabase != nil {
+		if spec.NewCustomDatabase.Name == "" || spec.NewCustomDatabase.Spec.SecretName == "" {
+			return fmt.Errorf("name and spec.secretName of newCustomDatabase must be specified")
+		}
+	}
+
+	return nil
+}
+
+// restoreTargetName returns name of CustomDatabase, that backup is restored into
+func restoreTargetName(spec v1.DatabaseRestoreSpec) string {
+	if spec.NewCustomDatabase != nil {
+		return spec.NewCustomDatabase.Name
+	}
+
+	return spec.CustomDatabaseName
+}
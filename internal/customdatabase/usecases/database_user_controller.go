@@ -0,0 +1,186 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	informerscorev1 "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	listerscorev1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+
+	"k8s.io/custom-database/internal/customdatabase"
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+	clientset "k8s.io/custom-database/pkg/generated/clientset/versioned"
+	informers "k8s.io/custom-database/pkg/generated/informers/externalversions/cusotmdatabase/v1"
+	listers "k8s.io/custom-database/pkg/generated/listers/cusotmdatabase/v1"
+)
+
+const (
+	// FinalizerDatabaseUser keeps DatabaseUser until its role is dropped from Postgresql. Role can't be found
+	// after the resource is deleted, because its name depends on spec.
+	FinalizerDatabaseUser = "customdatabase.igor.yatsevich.ru/database-user"
+
+	// MessageDatabaseUserSynced is the message used for an Event fired when a DatabaseUser
+	// is synced successfully
+	MessageDatabaseUserSynced = "DatabaseUser synced successfully"
+
+	// MessageRoleReadOnly is the message of Ready condition of DatabaseUser and DatabaseGrant, when controller runs in
+	// read-only mode
+	MessageRoleReadOnly = "Controller runs in read-only mode, role isn't changed"
+)
+
+// DatabaseUserController is the controller implementation for DatabaseUser resources
+type DatabaseUserController struct {
+	// kubeclientset is a standard kubernetes clientset
+	kubeclientset kubernetes.Interface
+	// sampleclientset is a clientset for our own API group
+	sampleclientset clientset.Interface
+
+	secretLister listerscorev1.SecretLister
+	secretSynced cache.InformerSynced
+
+	customDatabasesLister listers.CustomDatabaseLister
+	customDatabasesSynced cache.InformerSynced
+
+	databaseUsersLister listers.DatabaseUserLister
+	databaseUsersSynced cache.InformerSynced
+
+	workqueue workqueue.RateLimitingInterface
+	recorder  record.EventRecorder
+
+	databaseManager DatabaseManager
+	domainService   *customdatabase.DomainService
+
+	clock clock.PassiveClock
+	// tenantTLS TLS settings, which are published to Secrets, nil means that Secrets contain only credentials
+	tenantTLS *TenantTLS
+	// ownSessions sessions, which are opened to apply privileges, they aren't taken for activity of tenant
+	ownSessions *OwnSessions
+	// readOnly controller only reports state of roles, nothing is changed on server
+	readOnly bool
+	// adoptionAllowList patterns "<namespace>/<role>" of pre-existing roles, that may be adopted
+	adoptionAllowList []string
+}
+
+// NewDatabaseUserController returns a new controller of additional database users
+func NewDatabaseUserController(
+	ctx context.Context,
+	kubeclientset kubernetes.Interface,
+	sampleclientset clientset.Interface,
+	secretInformer informerscorev1.SecretInformer,
+	customDatabaseInformer informers.CustomDatabaseInformer,
+	databaseUserInformer informers.DatabaseUserInformer,
+	databaseManager DatabaseManager,
+	domainService *customdatabase.DomainService,
+	opts ...RoleControllerOption,
+) *DatabaseUserController {
+	logger := klog.FromContext(ctx)
+	options := newRoleControllerOptions(opts)
+
+	controller := &DatabaseUserController{
+		kubeclientset:         kubeclientset,
+		sampleclientset:       sampleclientset,
+		secretLister:          secretInformer.Lister(),
+		secretSynced:          secretInformer.Informer().HasSynced,
+		customDatabasesLister: customDatabaseInformer.Lister(),
+		customDatabasesSynced: customDatabaseInformer.Informer().HasSynced,
+		databaseUsersLister:   databaseUserInformer.Lister(),
+		databaseUsersSynced:   databaseUserInformer.Informer().HasSynced,
+		workqueue:             workqueue.NewNamedRateLimitingQueue(options.rateLimiter.newRateLimiter(), "DatabaseUsers"),
+		recorder:              newEventRecorder(ctx, kubeclientset),
+		databaseManager:       databaseManager,
+		domainService:         domainService,
+		clock:                 clock.RealClock{},
+		tenantTLS:             options.tenantTLS,
+		ownSessions:           options.ownSessions,
+		readOnly:              options.readOnly,
+		adoptionAllowList:     options.adoptionAllowList,
+	}
+
+	logger.Info("Setting up DatabaseUser event handlers")
+	databaseUserInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: controller.enqueueDatabaseUser,
+		UpdateFunc: func(old, new interface{}) {
+			controller.enqueueDatabaseUser(new)
+		},
+		DeleteFunc: controller.enqueueDatabaseUser,
+	})
+	// Users wait for their CustomDatabase, so we requeue them as soon as database is changed
+	customDatabaseInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: controller.enqueueDatabaseUsersOfCustomDatabase,
+		UpdateFunc: func(old, new interface{}) {
+			controller.enqueueDatabaseUsersOfCustomDatabase(new)
+		},
+	})
+
+	return controller
+}
+
+// Run waits for informer caches and starts workers. It will block until context is done.
+func (c *DatabaseUserController) Run(ctx context.Context, workers int) error {
+	defer utilruntime.HandleCrash()
+	defer c.workqueue.ShutDown()
+	logger := klog.FromContext(ctx)
+
+	logger.Info("Starting DatabaseUser controller")
+
+	logger.Info("Waiting for informer caches to sync")
+	if ok := cache.WaitForCacheSync(
+		ctx.Done(), c.databaseUsersSynced, c.customDatabasesSynced, c.secretSynced,
+	); !ok {
+		return fmt.Errorf("failed to wait for caches to sync")
+	}
+
+	logger.Info("Starting workers", "count", workers)
+	for i := 0; i < workers; i++ {
+		go wait.UntilWithContext(ctx, c.runWorker, time.Second)
+	}
+
+	logger.Info("Started workers")
+	<-ctx.Done()
+	logger.Info("Shutting down workers")
+
+	return nil
+}
+
+func (c *DatabaseUserController) runWorker(ctx context.Context) {
+	for processNextWorkItem(ctx, c.workqueue, c.syncHandler) {
+	}
+}
+
+func (c *DatabaseUserController) enqueueDatabaseUser(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.workqueue.Add(key)
+}
+
+func (c *DatabaseUserController) enqueueDatabaseUsersOfCustomDatabase(obj interface{}) {
+	customDatabase, ok := obj.(*v1.CustomDatabase)
+	if !ok {
+		return
+	}
+
+	databaseUsers, err := c.databaseUsersLister.DatabaseUsers(customDatabase.Namespace).List(labels.Everything())
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+
+	for _, databaseUser := range databaseUsers {
+		if databaseUser.Spec.CustomDatabaseName == customDatabase.Name {
+			c.enqueueDatabaseUser(databaseUser)
+		}
+	}
+}
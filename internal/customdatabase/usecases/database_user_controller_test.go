@@ -0,0 +1,540 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeinformers "k8s.io/client-go/informers"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2/ktesting"
+	testingclock "k8s.io/utils/clock/testing"
+
+	"k8s.io/custom-database/internal/customdatabase"
+	fakeadapter "k8s.io/custom-database/internal/customdatabase/adapters/fake"
+	customdatabasecontroller "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+	"k8s.io/custom-database/pkg/generated/clientset/versioned/fake"
+	informers "k8s.io/custom-database/pkg/generated/informers/externalversions"
+)
+
+func TestCreateDatabaseUser(t *testing.T) {
+	f := newDatabaseUserFixture(t)
+	_, ctx := ktesting.NewTestContext(t)
+
+	databaseUserItem := newDatabaseUser("reporting", "test")
+	f.databaseUsers = append(f.databaseUsers, databaseUserItem)
+
+	c, databaseManager := f.newController(ctx)
+	if err := c.syncHandler(ctx, metav1.NamespaceDefault+"/reporting"); err != nil {
+		t.Fatalf("error syncing databaseUser: %v", err)
+	}
+
+	f.checkActions(
+		[]string{"update databaseusers", "update databaseusers/status"},
+		[]string{"create secrets"},
+	)
+
+	secret := f.createdSecret()
+	if userName := string(secret.Data[SecretVarDbUserName]); userName != "test_reporting" {
+		t.Errorf("wrong user name in secret: expected test_reporting, given %s", userName)
+	}
+	if password := string(secret.Data[SecretVarDbPassword]); databaseManager.Users["test_reporting"] != password {
+		t.Errorf("password of user doesn't match secret")
+	}
+	if databaseName := string(secret.Data[SecretVarDbName]); databaseName != "test" {
+		t.Errorf("wrong database name in secret: expected test, given %s", databaseName)
+	}
+	if profile := databaseManager.UserPrivileges["test_reporting"].Profile; profile != customdatabase.PrivilegeProfileReadOnly {
+		t.Errorf("wrong privilege profile: expected %s, given %s", customdatabase.PrivilegeProfileReadOnly, profile)
+	}
+
+	status := f.updatedDatabaseUserStatus()
+	if status.UserName != "test_reporting" || !status.PasswordRotatedAt.Time.Equal(testNow) {
+		t.Errorf("wrong status of databaseUser: %+v", status)
+	}
+	if len(status.Conditions) != 1 || status.Conditions[0].Status != metav1.ConditionTrue {
+		t.Errorf("databaseUser should be ready: %+v", status.Conditions)
+	}
+}
+
+func TestRotateDatabaseUserPassword(t *testing.T) {
+	f := newDatabaseUserFixture(t)
+	_, ctx := ktesting.NewTestContext(t)
+
+	rotatedAt := metav1.NewTime(testNow.Add(-31 * 24 * time.Hour))
+	databaseUserItem := newDatabaseUser("reporting", "test")
+	databaseUserItem.Finalizers = []string{FinalizerDatabaseUser}
+	databaseUserItem.Spec.PasswordRotationPeriod = &metav1.Duration{Duration: 30 * 24 * time.Hour}
+	databaseUserItem.Status.UserName = "test_reporting"
+	databaseUserItem.Status.PasswordRotatedAt = &rotatedAt
+	f.databaseUsers = append(f.databaseUsers, databaseUserItem)
+	f.users["test_reporting"] = "old_password"
+
+	userEntity := newEntity("test")
+	userEntity.Database.User = "test_reporting"
+	userEntity.Database.Password = "old_password"
+	f.secrets = append(f.secrets, secretWithDBInfo(newDatabaseUserSecret(databaseUserItem), userEntity))
+
+	c, databaseManager := f.newController(ctx)
+	if err := c.syncHandler(ctx, metav1.NamespaceDefault+"/reporting"); err != nil {
+		t.Fatalf("error syncing databaseUser: %v", err)
+	}
+
+	f.checkActions([]string{"update databaseusers/status"}, []string{"update secrets"})
+
+	password := databaseManager.Users["test_reporting"]
+	if password == "old_password" {
+		t.Errorf("password of user wasn't rotated")
+	}
+	if updatedPassword := string(f.updatedSecret().Data[SecretVarDbPassword]); updatedPassword != password {
+		t.Errorf("password of user doesn't match secret")
+	}
+	if status := f.updatedDatabaseUserStatus(); !status.PasswordRotatedAt.Time.Equal(testNow) {
+		t.Errorf("wrong time of rotation: %v", status.PasswordRotatedAt)
+	}
+}
+
+func TestDeleteDatabaseUser(t *testing.T) {
+	f := newDatabaseUserFixture(t)
+	_, ctx := ktesting.NewTestContext(t)
+
+	deletedAt := metav1.NewTime(testNow)
+	databaseUserItem := newDatabaseUser("reporting", "test")
+	databaseUserItem.Finalizers = []string{FinalizerDatabaseUser}
+	databaseUserItem.DeletionTimestamp = &deletedAt
+	databaseUserItem.Status.UserName = "test_reporting"
+	f.databaseUsers = append(f.databaseUsers, databaseUserItem)
+	f.users["test_reporting"] = "password"
+
+	c, databaseManager := f.newController(ctx)
+	if err := c.syncHandler(ctx, metav1.NamespaceDefault+"/reporting"); err != nil {
+		t.Fatalf("error syncing databaseUser: %v", err)
+	}
+
+	f.checkActions([]string{"update databaseusers"}, nil)
+
+	if _, isExists := databaseManager.Users["test_reporting"]; isExists {
+		t.Errorf("test_reporting user shouldn't exist")
+	}
+	if _, isExists := databaseManager.UserPrivileges["test_reporting"]; isExists {
+		t.Errorf("privileges of test_reporting user should be revoked")
+	}
+}
+
+func TestRefuseUnmarkedRoleOfDatabaseUser(t *testing.T) {
+	f := newDatabaseUserFixture(t)
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.databaseUsers = append(f.databaseUsers, newDatabaseUser("reporting", "test"))
+	f.users["test_reporting"] = "password"
+
+	c, databaseManager := f.newController(ctx)
+	if err := c.syncHandler(ctx, metav1.NamespaceDefault+"/reporting"); err != nil {
+		t.Fatalf("error syncing databaseUser: %v", err)
+	}
+
+	f.checkActions([]string{"update databaseusers", "update databaseusers/status"}, nil)
+
+	status := f.updatedDatabaseUserStatus()
+	if len(status.Conditions) != 1 || status.Conditions[0].Reason != "NotOwned" {
+		t.Errorf("pre-existing role shouldn't be taken over: %+v", status.Conditions)
+	}
+	if databaseManager.Users["test_reporting"] != "password" {
+		t.Errorf("password of pre-existing role shouldn't be changed")
+	}
+	if _, isExists := databaseManager.UserOwnership["test_reporting"]; isExists {
+		t.Errorf("pre-existing role shouldn't be marked")
+	}
+}
+
+func TestAdoptUnmarkedRoleOfDatabaseUser(t *testing.T) {
+	f := newDatabaseUserFixture(t)
+	f.isCustomDatabaseAdopting = true
+	f.adoptionAllowList = []string{metav1.NamespaceDefault + "/test_*"}
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.databaseUsers = append(f.databaseUsers, newDatabaseUser("reporting", "test"))
+	f.users["test_reporting"] = "password"
+
+	c, databaseManager := f.newController(ctx)
+	if err := c.syncHandler(ctx, metav1.NamespaceDefault+"/reporting"); err != nil {
+		t.Fatalf("error syncing databaseUser: %v", err)
+	}
+
+	f.checkActions(
+		[]string{"update databaseusers", "update databaseusers/status"},
+		[]string{"create secrets"},
+	)
+
+	if ownership := databaseManager.UserOwnership["test_reporting"]; ownership.Kind != customdatabase.OwnerKindDatabaseUser {
+		t.Errorf("adopted role should be marked by DatabaseUser: %+v", ownership)
+	}
+	if password := string(f.createdSecret().Data[SecretVarDbPassword]); databaseManager.Users["test_reporting"] != password {
+		t.Errorf("password of adopted role doesn't match secret")
+	}
+}
+
+func TestKeepDatabaseUserOfPausedCustomDatabase(t *testing.T) {
+	f := newDatabaseUserFixture(t)
+	f.isCustomDatabasePaused = true
+	_, ctx := ktesting.NewTestContext(t)
+
+	deletedAt := metav1.NewTime(testNow)
+	databaseUserItem := newDatabaseUser("reporting", "test")
+	databaseUserItem.Finalizers = []string{FinalizerDatabaseUser}
+	databaseUserItem.DeletionTimestamp = &deletedAt
+	databaseUserItem.Status.UserName = "test_reporting"
+	f.databaseUsers = append(f.databaseUsers, databaseUserItem)
+	f.users["test_reporting"] = "password"
+
+	c, databaseManager := f.newController(ctx)
+	if err := c.syncHandler(ctx, metav1.NamespaceDefault+"/reporting"); err != nil {
+		t.Fatalf("error syncing databaseUser: %v", err)
+	}
+
+	// finalizer is kept until CustomDatabase is resumed
+	f.checkActions(nil, nil)
+
+	if _, isExists := databaseManager.Users["test_reporting"]; !isExists {
+		t.Errorf("test_reporting user shouldn't be dropped while CustomDatabase is paused")
+	}
+}
+
+func TestKeepDatabaseUserInReadOnlyMode(t *testing.T) {
+	f := newDatabaseUserFixture(t)
+	f.readOnly = true
+	_, ctx := ktesting.NewTestContext(t)
+
+	deletedAt := metav1.NewTime(testNow)
+	databaseUserItem := newDatabaseUser("reporting", "test")
+	databaseUserItem.Finalizers = []string{FinalizerDatabaseUser}
+	databaseUserItem.DeletionTimestamp = &deletedAt
+	databaseUserItem.Status.UserName = "test_reporting"
+	f.databaseUsers = append(f.databaseUsers, databaseUserItem)
+	f.users["test_reporting"] = "password"
+
+	c, databaseManager := f.newController(ctx)
+	if err := c.syncHandler(ctx, metav1.NamespaceDefault+"/reporting"); err != nil {
+		t.Fatalf("error syncing databaseUser: %v", err)
+	}
+
+	// finalizer is removed, so deletion of namespace isn't blocked
+	f.checkActions([]string{"update databaseusers"}, nil)
+
+	if _, isExists := databaseManager.Users["test_reporting"]; !isExists {
+		t.Errorf("test_reporting user shouldn't be dropped in read-only mode")
+	}
+}
+
+func TestObserveDatabaseUserInReadOnlyMode(t *testing.T) {
+	f := newDatabaseUserFixture(t)
+	f.readOnly = true
+	_, ctx := ktesting.NewTestContext(t)
+
+	f.databaseUsers = append(f.databaseUsers, newDatabaseUser("reporting", "test"))
+
+	c, databaseManager := f.newController(ctx)
+	if err := c.syncHandler(ctx, metav1.NamespaceDefault+"/reporting"); err != nil {
+		t.Fatalf("error syncing databaseUser: %v", err)
+	}
+
+	f.checkActions([]string{"update databaseusers/status"}, nil)
+
+	status := f.updatedDatabaseUserStatus()
+	if len(status.Conditions) != 1 || status.Conditions[0].Reason != "ReadOnly" {
+		t.Errorf("databaseUser should be reported as not reconciled: %+v", status.Conditions)
+	}
+	if _, isExists := databaseManager.Users["test_reporting"]; isExists {
+		t.Errorf("test_reporting user shouldn't be created in read-only mode")
+	}
+}
+
+func TestApplyDatabaseUserPrivilegesOnlyOnChange(t *testing.T) {
+	f := newDatabaseUserFixture(t)
+	_, ctx := ktesting.NewTestContext(t)
+
+	databaseUserItem := newDatabaseUser("reporting", "test")
+	f.databaseUsers = append(f.databaseUsers, databaseUserItem)
+
+	c, _ := f.newController(ctx)
+	if err := c.syncHandler(ctx, metav1.NamespaceDefault+"/reporting"); err != nil {
+		t.Fatalf("error syncing databaseUser: %v", err)
+	}
+	syncedStatus := f.updatedDatabaseUserStatus()
+	if syncedStatus.PrivilegesChecksum == "" {
+		t.Fatalf("checksum of applied privileges should be stored in status")
+	}
+
+	// the same privileges aren't applied again, every apply connects to database
+	f = newDatabaseUserFixture(t)
+	databaseUserItem = newDatabaseUser("reporting", "test")
+	databaseUserItem.Finalizers = []string{FinalizerDatabaseUser}
+	databaseUserItem.Status = syncedStatus
+	f.databaseUsers = append(f.databaseUsers, databaseUserItem)
+	f.users["test_reporting"] = "password"
+	userEntity := newEntity("test")
+	userEntity.Database.User = "test_reporting"
+	userEntity.Database.Password = "password"
+	f.secrets = append(f.secrets, secretWithDBInfo(newDatabaseUserSecret(databaseUserItem), userEntity))
+
+	c, databaseManager := f.newController(ctx)
+	if err := c.syncHandler(ctx, metav1.NamespaceDefault+"/reporting"); err != nil {
+		t.Fatalf("error syncing databaseUser: %v", err)
+	}
+	if _, isApplied := databaseManager.UserPrivileges["test_reporting"]; isApplied {
+		t.Errorf("privileges shouldn't be applied again without changes")
+	}
+
+	// changed profile is applied
+	f.client.ClearActions()
+	databaseUserItem.Spec.Profile = customdatabasecontroller.PrivilegeProfileReadWrite
+	if err := c.addOrUpdateDatabaseUserHandler(ctx, databaseUserItem); err != nil {
+		t.Fatalf("error syncing databaseUser: %v", err)
+	}
+	if profile := databaseManager.UserPrivileges["test_reporting"].Profile; profile != customdatabase.PrivilegeProfileReadWrite {
+		t.Errorf("changed privileges should be applied, given profile %s", profile)
+	}
+	if status := f.updatedDatabaseUserStatus(); status.PrivilegesChecksum == syncedStatus.PrivilegesChecksum {
+		t.Errorf("checksum of changed privileges should be stored in status")
+	}
+}
+
+func TestReapplyDatabaseUserPrivilegesPeriodically(t *testing.T) {
+	f := newDatabaseUserFixture(t)
+	_, ctx := ktesting.NewTestContext(t)
+
+	databaseUserItem := newDatabaseUser("reporting", "test")
+	f.databaseUsers = append(f.databaseUsers, databaseUserItem)
+
+	c, _ := f.newController(ctx)
+	if err := c.syncHandler(ctx, metav1.NamespaceDefault+"/reporting"); err != nil {
+		t.Fatalf("error syncing databaseUser: %v", err)
+	}
+	syncedStatus := f.updatedDatabaseUserStatus()
+	if syncedStatus.PrivilegesAppliedAt == nil || !syncedStatus.PrivilegesAppliedAt.Time.Equal(testNow) {
+		t.Fatalf("time of applied privileges should be stored in status, given %v", syncedStatus.PrivilegesAppliedAt)
+	}
+
+	// tables, which were created by other roles after the last apply, get privileges of profile
+	f = newDatabaseUserFixture(t)
+	databaseUserItem = newDatabaseUser("reporting", "test")
+	databaseUserItem.Finalizers = []string{FinalizerDatabaseUser}
+	databaseUserItem.Status = syncedStatus
+	databaseUserItem.Status.PrivilegesAppliedAt = &metav1.Time{Time: testNow.Add(-privilegesReapplyPeriod)}
+	f.databaseUsers = append(f.databaseUsers, databaseUserItem)
+	f.users["test_reporting"] = "password"
+	userEntity := newEntity("test")
+	userEntity.Database.User = "test_reporting"
+	userEntity.Database.Password = "password"
+	f.secrets = append(f.secrets, secretWithDBInfo(newDatabaseUserSecret(databaseUserItem), userEntity))
+
+	c, databaseManager := f.newController(ctx)
+	if err := c.syncHandler(ctx, metav1.NamespaceDefault+"/reporting"); err != nil {
+		t.Fatalf("error syncing databaseUser: %v", err)
+	}
+	if _, isApplied := databaseManager.UserPrivileges["test_reporting"]; !isApplied {
+		t.Errorf("privileges should be applied again after reapply period")
+	}
+	if status := f.updatedDatabaseUserStatus(); !status.PrivilegesAppliedAt.Time.Equal(testNow) {
+		t.Errorf("time of applied privileges should be updated, given %v", status.PrivilegesAppliedAt)
+	}
+}
+
+func TestSkipDatabaseUserOfHibernatedCustomDatabase(t *testing.T) {
+	f := newDatabaseUserFixture(t)
+	f.customDatabaseHibernation = &customdatabasecontroller.HibernationStatus{
+		Phase: customdatabasecontroller.HibernationPhaseHibernated,
+	}
+	_, ctx := ktesting.NewTestContext(t)
+
+	databaseUserItem := newDatabaseUser("reporting", "test")
+	databaseUserItem.Finalizers = []string{FinalizerDatabaseUser}
+	f.databaseUsers = append(f.databaseUsers, databaseUserItem)
+
+	c, databaseManager := f.newController(ctx)
+	if err := c.syncHandler(ctx, metav1.NamespaceDefault+"/reporting"); err != nil {
+		t.Fatalf("error syncing databaseUser: %v", err)
+	}
+
+	f.checkActions([]string{"update databaseusers/status"}, nil)
+
+	if _, isApplied := databaseManager.UserPrivileges["test_reporting"]; isApplied {
+		t.Errorf("privileges shouldn't be applied in hibernated database")
+	}
+	status := f.updatedDatabaseUserStatus()
+	if len(status.Conditions) != 1 || status.Conditions[0].Reason != "CustomDatabaseHibernated" {
+		t.Errorf("databaseUser should wait for wake up: %+v", status.Conditions)
+	}
+}
+
+type databaseUserFixture struct {
+	t *testing.T
+
+	client     *fake.Clientset
+	kubeclient *k8sfake.Clientset
+
+	databaseUsers []*customdatabasecontroller.DatabaseUser
+	secrets       []*corev1.Secret
+	users         map[string]string
+	// isCustomDatabasePaused sets annotation paused on "test" CustomDatabase
+	isCustomDatabasePaused bool
+	// customDatabaseHibernation sets hibernation in status of "test" CustomDatabase
+	customDatabaseHibernation *customdatabasecontroller.HibernationStatus
+	readOnly                  bool
+	// isCustomDatabaseAdopting sets spec.adopt of "test" CustomDatabase
+	isCustomDatabaseAdopting bool
+	adoptionAllowList        []string
+}
+
+func newDatabaseUserFixture(t *testing.T) *databaseUserFixture {
+	return &databaseUserFixture{t: t, users: map[string]string{}}
+}
+
+func newDatabaseUser(name, customDatabaseName string) *customdatabasecontroller.DatabaseUser {
+	return &customdatabasecontroller.DatabaseUser{
+		TypeMeta: metav1.TypeMeta{APIVersion: customdatabasecontroller.SchemeGroupVersion.String()},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: metav1.NamespaceDefault,
+		},
+		Spec: customdatabasecontroller.DatabaseUserSpec{
+			CustomDatabaseName: customDatabaseName,
+			SecretName:         name + "-secret",
+			Profile:            customdatabasecontroller.PrivilegeProfileReadOnly,
+		},
+	}
+}
+
+// newController returns controller of users for the existing "test" CustomDatabase
+func (f *databaseUserFixture) newController(ctx context.Context) (*DatabaseUserController, *fakeadapter.DbManager) {
+	customDatabaseItem := newCustomDatabase("test")
+	if f.isCustomDatabasePaused {
+		customDatabaseItem.Annotations = map[string]string{customdatabasecontroller.AnnotationPaused: "true"}
+	}
+	customDatabaseItem.Status.Hibernation = f.customDatabaseHibernation
+	customDatabaseItem.Spec.Adopt = f.isCustomDatabaseAdopting
+	ownerEntity := newEntity("test")
+	ownerSecret := secretWithDBInfo(newEmptySecret(customDatabaseItem), ownerEntity)
+
+	objects := []runtime.Object{customDatabaseItem}
+	for _, databaseUser := range f.databaseUsers {
+		objects = append(objects, databaseUser)
+	}
+	kubeobjects := []runtime.Object{ownerSecret}
+	for _, secret := range f.secrets {
+		kubeobjects = append(kubeobjects, secret)
+	}
+
+	f.client = fake.NewSimpleClientset(objects...)
+	f.kubeclient = k8sfake.NewSimpleClientset(kubeobjects...)
+
+	i := informers.NewSharedInformerFactory(f.client, noResyncPeriodFunc())
+	k8sI := kubeinformers.NewSharedInformerFactory(f.kubeclient, noResyncPeriodFunc())
+
+	domainService, _ := customdatabase.NewDomainService("localhost", 5432, "")
+	databaseManager := fakeadapter.NewDbManager()
+	ownerMarker := domainService.NewOwnershipMarker(customdatabase.OwnerKindCustomDatabase, metav1.NamespaceDefault, "test", "")
+	databaseManager.CreateUser(ctx, ownerEntity.Database.User, ownerEntity.Database.Password, ownerMarker)
+	databaseManager.CreateDatabase(ctx, ownerEntity.Database.Name, ownerEntity.Database.Options, ownerMarker)
+	for userName, password := range f.users {
+		// role is marked by resource, which created it, otherwise it's unmarked
+		var marker customdatabase.OwnershipMarker
+		for _, item := range f.databaseUsers {
+			if item.Status.UserName == userName {
+				marker = domainService.NewOwnershipMarker(customdatabase.OwnerKindDatabaseUser, item.Namespace, item.Name, "")
+			}
+		}
+		databaseManager.CreateUser(ctx, userName, password, marker)
+		if marker.Kind == "" {
+			delete(databaseManager.UserOwnership, userName)
+		}
+	}
+
+	c := NewDatabaseUserController(ctx, f.kubeclient, f.client,
+		k8sI.Core().V1().Secrets(),
+		i.Igor().V1().CustomDatabases(),
+		i.Igor().V1().DatabaseUsers(),
+		databaseManager,
+		domainService,
+		WithRoleReadOnly(f.readOnly),
+		WithRoleAdoptionAllowList(f.adoptionAllowList),
+	)
+	c.recorder = &record.FakeRecorder{}
+	c.clock = testingclock.NewFakePassiveClock(testNow)
+
+	i.Igor().V1().CustomDatabases().Informer().GetIndexer().Add(customDatabaseItem)
+	for _, databaseUser := range f.databaseUsers {
+		i.Igor().V1().DatabaseUsers().Informer().GetIndexer().Add(databaseUser)
+	}
+	for _, secret := range kubeobjects {
+		k8sI.Core().V1().Secrets().Informer().GetIndexer().Add(secret)
+	}
+
+	return c, databaseManager
+}
+
+// checkActions verifies verbs and resources of actions, objects are checked by tests, because passwords are random
+func (f *databaseUserFixture) checkActions(expectedActions, expectedKubeActions []string) {
+	checkActionNames(f.t, expectedActions, filterInformerActions(f.client.Actions()))
+	checkActionNames(f.t, expectedKubeActions, filterInformerActions(f.kubeclient.Actions()))
+}
+
+func checkActionNames(t *testing.T, expected []string, actions []core.Action) {
+	given := make([]string, 0, len(actions))
+	for _, action := range actions {
+		name := action.GetVerb() + " " + action.GetResource().Resource
+		if action.GetSubresource() != "" {
+			name += "/" + action.GetSubresource()
+		}
+		given = append(given, name)
+	}
+
+	if len(given) != len(expected) {
+		t.Errorf("wrong actions: expected %v, given %v", expected, given)
+		return
+	}
+	for i := range expected {
+		if given[i] != expected[i] {
+			t.Errorf("wrong actions: expected %v, given %v", expected, given)
+			return
+		}
+	}
+}
+
+func (f *databaseUserFixture) createdSecret() *corev1.Secret {
+	for _, action := range f.kubeclient.Actions() {
+		if createAction, ok := action.(core.CreateActionImpl); ok {
+			return createAction.GetObject().(*corev1.Secret)
+		}
+	}
+
+	f.t.Fatalf("secret wasn't created")
+	return nil
+}
+
+func (f *databaseUserFixture) updatedSecret() *corev1.Secret {
+	for _, action := range f.kubeclient.Actions() {
+		if updateAction, ok := action.(core.UpdateActionImpl); ok {
+			return updateAction.GetObject().(*corev1.Secret)
+		}
+	}
+
+	f.t.Fatalf("secret wasn't updated")
+	return nil
+}
+
+func (f *databaseUserFixture) updatedDatabaseUserStatus() customdatabasecontroller.DatabaseUserStatus {
+	for _, action := range f.client.Actions() {
+		if updateAction, ok := action.(core.UpdateActionImpl); ok && updateAction.GetSubresource() == "status" {
+			return updateAction.GetObject().(*customdatabasecontroller.DatabaseUser).Status
+		}
+	}
+
+	f.t.Fatalf("status of databaseUser wasn't updated")
+	return customdatabasecontroller.DatabaseUserStatus{}
+}
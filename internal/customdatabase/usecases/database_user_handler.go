@@ -0,0 +1,397 @@
+package usecases
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	"k8s.io/custom-database/internal/customdatabase"
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+)
+
+// syncHandler creates role of DatabaseUser, grants privileges and stores credentials in Secret
+func (c *DatabaseUserController) syncHandler(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("invalid resource key: %s", key))
+		return nil
+	}
+
+	ctx = contextWithResourceNameLogger(ctx, name)
+	ctx = customdatabase.ContextWithResource(ctx, "DatabaseUser", key)
+
+	databaseUser, err := c.databaseUsersLister.DatabaseUsers(namespace).Get(name)
+	if err != nil {
+		// Role was already dropped before finalizer had been removed
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	if databaseUser.DeletionTimestamp != nil && c.readOnly {
+		// role is left on server, finalizer mustn't block deletion of resource
+		return c.removeDatabaseUserFinalizer(ctx, databaseUser)
+	}
+	if databaseUser.DeletionTimestamp != nil {
+		return c.deleteDatabaseUserHandler(ctx, databaseUser)
+	}
+	if c.readOnly {
+		newStatus := databaseUser.Status.DeepCopy()
+		c.setCondition(newStatus, databaseUser, v1.ConditionReady, metav1.ConditionFalse, "ReadOnly", MessageRoleReadOnly)
+		return c.updateDatabaseUserStatus(ctx, databaseUser, newStatus)
+	}
+
+	return c.addOrUpdateDatabaseUserHandler(ctx, databaseUser)
+}
+
+func (c *DatabaseUserController) addOrUpdateDatabaseUserHandler(
+	ctx context.Context, databaseUserReq *v1.DatabaseUser,
+) error {
+	logger := loggerFromHandlerContext(ctx)
+	logger.Info("Add or update DatabaseUser resource")
+
+	if databaseUserReq.Spec.SecretName == "" || databaseUserReq.Spec.CustomDatabaseName == "" {
+		utilruntime.HandleError(
+			fmt.Errorf("%s: secretName and customDatabaseName must be specified", databaseUserReq.Name),
+		)
+		return nil
+	}
+
+	databaseUserReq, err := c.ensureDatabaseUserFinalizer(ctx, databaseUserReq)
+	if err != nil {
+		return err
+	}
+
+	newStatus := databaseUserReq.Status.DeepCopy()
+
+	customDatabaseReq, owner, err := c.ownerOfDatabaseUser(databaseUserReq)
+	// role lives on server of database
+	ctx = customdatabase.ContextWithServer(ctx, owner.Server)
+	if err != nil {
+		if err == errCustomDatabasePaused {
+			// user is requeued by event handler, when annotation is removed
+			c.setCondition(newStatus, databaseUserReq, v1.ConditionReady, metav1.ConditionFalse,
+				"CustomDatabasePaused", err.Error(),
+			)
+			return c.updateDatabaseUserStatus(ctx, databaseUserReq, newStatus)
+		}
+		if err == errCustomDatabaseHibernated {
+			// user is requeued by event handler, when database is woken up
+			c.setCondition(newStatus, databaseUserReq, v1.ConditionReady, metav1.ConditionFalse,
+				"CustomDatabaseHibernated", err.Error(),
+			)
+			return c.updateDatabaseUserStatus(ctx, databaseUserReq, newStatus)
+		}
+		if errors.IsNotFound(err) {
+			// CustomDatabase or its Secret will be created later, user will be requeued by event handler
+			c.setCondition(newStatus, databaseUserReq, v1.ConditionReady, metav1.ConditionFalse,
+				"CustomDatabaseNotReady", err.Error(),
+			)
+			return c.updateDatabaseUserStatus(ctx, databaseUserReq, newStatus)
+		}
+		return err
+	}
+
+	storedSecret, err := c.secretLister.Secrets(databaseUserReq.Namespace).Get(databaseUserReq.Spec.SecretName)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	password, isPasswordChanged, err := c.databaseUserPassword(databaseUserReq, storedSecret)
+	if err != nil {
+		return err
+	}
+
+	databaseUser, err := c.domainService.CreateDatabaseUserEntity(
+		owner.Database, databaseUserReq.Name, password, privilegesFromSpec(databaseUserReq.Spec),
+	)
+	if err != nil {
+		// Spec is wrong, there is no reason to requeue resource until it's changed
+		utilruntime.HandleError(fmt.Errorf("%s: %w", databaseUserReq.Name, err))
+		c.setCondition(newStatus, databaseUserReq, v1.ConditionReady, metav1.ConditionFalse,
+			"InvalidSpec", err.Error(),
+		)
+		return c.updateDatabaseUserStatus(ctx, databaseUserReq, newStatus)
+	}
+
+	marker := c.domainService.NewOwnershipMarker(customdatabase.OwnerKindDatabaseUser,
+		databaseUserReq.Namespace, databaseUserReq.Name, string(databaseUserReq.UID),
+	)
+	isAdoptable := isRoleAdoptable(customDatabaseReq, c.adoptionAllowList, databaseUser.Name,
+		storedSecret != nil && metav1.IsControlledBy(storedSecret, databaseUserReq),
+	)
+	err = ensureRole(ctx, c.databaseManager, databaseUser, marker, isPasswordChanged, isAdoptable)
+	if stderrors.Is(err, customdatabase.ErrNotOwned) {
+		// role belongs to somebody else, there is no reason to requeue resource until role is dropped
+		utilruntime.HandleError(fmt.Errorf("%s: %w", databaseUserReq.Name, err))
+		c.setCondition(newStatus, databaseUserReq, v1.ConditionReady, metav1.ConditionFalse, "NotOwned", err.Error())
+		return c.updateDatabaseUserStatus(ctx, databaseUserReq, newStatus)
+	}
+	if err != nil {
+		return err
+	}
+
+	checksum := privilegesChecksum(owner, databaseUser)
+	status := databaseUserReq.Status
+	if isPrivilegesApplyDue(checksum, status.PrivilegesChecksum, status.PrivilegesAppliedAt, c.clock.Now()) {
+		c.ownSessions.touch(databaseUserReq.Namespace, databaseUserReq.Spec.CustomDatabaseName, c.clock.Now())
+		err = c.databaseManager.ApplyUserPrivileges(ctx, owner.Database, databaseUser)
+		if err != nil {
+			return err
+		}
+		appliedAt := metav1.NewTime(c.clock.Now())
+		newStatus.PrivilegesAppliedAt = &appliedAt
+	}
+
+	userEntity := customdatabase.Entity{
+		Host: owner.Host,
+		Database: customdatabase.Database{
+			Name:     owner.Database.Name,
+			User:     databaseUser.Name,
+			Password: databaseUser.Password,
+		},
+	}
+	secretNewState, err := secretWithTLS(secretWithDBInfo(newDatabaseUserSecret(databaseUserReq), userEntity), storedSecret,
+		c.tenantTLS, userEntity.Database.User, c.clock.Now(),
+	)
+	if err != nil {
+		return err
+	}
+	err = actualizeCredentialsSecret(ctx, c.kubeclientset, storedSecret, secretNewState)
+	if err != nil {
+		return err
+	}
+
+	now := c.clock.Now()
+	newStatus.UserName = databaseUser.Name
+	newStatus.PrivilegesChecksum = checksum
+	newStatus.ObservedRotationToken = databaseUserReq.Annotations[v1.AnnotationRotatePassword]
+	if isPasswordChanged || newStatus.PasswordRotatedAt == nil {
+		rotatedAt := metav1.NewTime(now)
+		newStatus.PasswordRotatedAt = &rotatedAt
+	}
+	c.setCondition(newStatus, databaseUserReq, v1.ConditionReady, metav1.ConditionTrue,
+		SuccessSynced, MessageDatabaseUserSynced,
+	)
+
+	err = c.updateDatabaseUserStatus(ctx, databaseUserReq, newStatus)
+	if err != nil {
+		return err
+	}
+
+	// scheduled rotation - we wake up exactly when the password expires
+	if rotationPeriod := databaseUserReq.Spec.PasswordRotationPeriod; rotationPeriod != nil {
+		c.workqueue.AddAfter(
+			databaseUserReq.Namespace+"/"+databaseUserReq.Name,
+			newStatus.PasswordRotatedAt.Add(rotationPeriod.Duration).Sub(now),
+		)
+	}
+
+	c.recorder.Event(databaseUserReq, corev1.EventTypeNormal, SuccessSynced, MessageDatabaseUserSynced)
+	return nil
+}
+
+func (c *DatabaseUserController) deleteDatabaseUserHandler(ctx context.Context, databaseUserReq *v1.DatabaseUser) error {
+	logger := loggerFromHandlerContext(ctx)
+	logger.Info("Delete DatabaseUser resource")
+
+	if !containsString(databaseUserReq.Finalizers, FinalizerDatabaseUser) {
+		return nil
+	}
+
+	userName := databaseUserReq.Status.UserName
+	customDatabaseReq, owner, err := c.ownerOfDatabaseUser(databaseUserReq)
+	// role lives on server of database
+	ctx = customdatabase.ContextWithServer(ctx, owner.Server)
+	switch {
+	case err == errCustomDatabasePaused:
+		// finalizer is kept, user is requeued by event handler, when annotation is removed
+		logger.Info("CustomDatabase of user is paused, role isn't dropped yet", "customDatabaseName",
+			databaseUserReq.Spec.CustomDatabaseName,
+		)
+		return nil
+	case err == errCustomDatabaseHibernated && !isHibernatedDatabaseDropped(customDatabaseReq):
+		// privileges in database can't be revoked, finalizer is kept until database is woken up
+		logger.Info("CustomDatabase of user is hibernated, role isn't dropped yet", "customDatabaseName",
+			databaseUserReq.Spec.CustomDatabaseName,
+		)
+		return nil
+	case err == errCustomDatabaseHibernated:
+		// database is dropped together with all privileges on its objects
+		logger.Info("Database of user is dropped by hibernation", "customDatabaseName",
+			databaseUserReq.Spec.CustomDatabaseName,
+		)
+	case err == nil && userName != "":
+		databaseUser := customdatabase.DatabaseUser{Name: userName}
+		c.ownSessions.touch(databaseUserReq.Namespace, databaseUserReq.Spec.CustomDatabaseName, c.clock.Now())
+		if err = c.databaseManager.RevokeUserPrivileges(ctx, owner.Database, databaseUser); err != nil {
+			return err
+		}
+	case errors.IsNotFound(err):
+		// database is already dropped together with all privileges on its objects
+		logger.Info("CustomDatabase of user is already deleted", "customDatabaseName",
+			databaseUserReq.Spec.CustomDatabaseName,
+		)
+	case err != nil:
+		return err
+	}
+
+	if userName != "" {
+		marker := c.domainService.NewOwnershipMarker(customdatabase.OwnerKindDatabaseUser,
+			databaseUserReq.Namespace, databaseUserReq.Name, string(databaseUserReq.UID),
+		)
+		err = c.databaseManager.DropUser(ctx, userName, marker)
+		if stderrors.Is(err, customdatabase.ErrNotOwned) {
+			// foreign role is left as is, it mustn't block deletion of resource
+			utilruntime.HandleError(fmt.Errorf("%s: role isn't dropped: %w", databaseUserReq.Name, err))
+		} else if err != nil {
+			return err
+		}
+	}
+
+	// Secret will be deleted by k8s, because DatabaseUser is its owner
+	return c.removeDatabaseUserFinalizer(ctx, databaseUserReq)
+}
+
+func (c *DatabaseUserController) removeDatabaseUserFinalizer(ctx context.Context, databaseUserReq *v1.DatabaseUser) error {
+	if !containsString(databaseUserReq.Finalizers, FinalizerDatabaseUser) {
+		return nil
+	}
+
+	databaseUserCopy := databaseUserReq.DeepCopy()
+	databaseUserCopy.Finalizers = removeString(databaseUserCopy.Finalizers, FinalizerDatabaseUser)
+	_, err := c.sampleclientset.IgorV1().DatabaseUsers(databaseUserCopy.Namespace).Update(
+		ctx, databaseUserCopy, metav1.UpdateOptions{},
+	)
+
+	return err
+}
+
+// ownerOfDatabaseUser returns CustomDatabase of user together with database and credentials of its owner from Secret.
+// errCustomDatabaseHibernated is returned together with owner, when database is hibernated.
+func (c *DatabaseUserController) ownerOfDatabaseUser(
+	databaseUserReq *v1.DatabaseUser,
+) (*v1.CustomDatabase, customdatabase.Entity, error) {
+	customDatabaseReq, owner, err := ownerOfCustomDatabase(
+		c.customDatabasesLister, c.secretLister, databaseUserReq.Namespace, databaseUserReq.Spec.CustomDatabaseName,
+	)
+	if err == nil && isCustomDatabaseHibernated(customDatabaseReq) {
+		return customDatabaseReq, owner, errCustomDatabaseHibernated
+	}
+
+	return customDatabaseReq, owner, err
+}
+
+// databaseUserPassword returns current password of user from Secret or generates new one, if rotation was requested
+// by annotation or rotation period was expired
+func (c *DatabaseUserController) databaseUserPassword(
+	databaseUserReq *v1.DatabaseUser, storedSecret *corev1.Secret,
+) (string, bool, error) {
+	var password string
+	if storedSecret != nil {
+		password = string(storedSecret.Data[SecretVarDbPassword])
+	}
+
+	isRotationRequested := databaseUserReq.Annotations[v1.AnnotationRotatePassword] !=
+		databaseUserReq.Status.ObservedRotationToken
+
+	isRotationExpired := false
+	if rotationPeriod := databaseUserReq.Spec.PasswordRotationPeriod; rotationPeriod != nil {
+		rotatedAt := databaseUserReq.Status.PasswordRotatedAt
+		isRotationExpired = rotatedAt != nil && !c.clock.Now().Before(rotatedAt.Add(rotationPeriod.Duration))
+	}
+
+	if password != "" && !isRotationRequested && !isRotationExpired {
+		return password, false, nil
+	}
+
+	password, err := customdatabase.NewPassword()
+	if err != nil {
+		return "", false, err
+	}
+
+	return password, true, nil
+}
+
+func (c *DatabaseUserController) ensureDatabaseUserFinalizer(
+	ctx context.Context, databaseUserReq *v1.DatabaseUser,
+) (*v1.DatabaseUser, error) {
+	if containsString(databaseUserReq.Finalizers, FinalizerDatabaseUser) {
+		return databaseUserReq, nil
+	}
+
+	databaseUserCopy := databaseUserReq.DeepCopy()
+	databaseUserCopy.Finalizers = append(databaseUserCopy.Finalizers, FinalizerDatabaseUser)
+
+	return c.sampleclientset.IgorV1().DatabaseUsers(databaseUserCopy.Namespace).Update(
+		ctx, databaseUserCopy, metav1.UpdateOptions{},
+	)
+}
+
+func (c *DatabaseUserController) setCondition(
+	newStatus *v1.DatabaseUserStatus, databaseUser *v1.DatabaseUser,
+	conditionType string, status metav1.ConditionStatus, reason, message string,
+) {
+	setStatusCondition(
+		&newStatus.Conditions, databaseUser.Generation, c.clock.Now(), conditionType, status, reason, message,
+	)
+}
+
+func (c *DatabaseUserController) updateDatabaseUserStatus(
+	ctx context.Context, databaseUser *v1.DatabaseUser, newStatus *v1.DatabaseUserStatus,
+) error {
+	if equality.Semantic.DeepEqual(databaseUser.Status, *newStatus) {
+		return nil
+	}
+
+	databaseUserCopy := databaseUser.DeepCopy()
+	databaseUserCopy.Status = *newStatus
+
+	_, err := c.sampleclientset.IgorV1().DatabaseUsers(databaseUser.Namespace).UpdateStatus(
+		ctx, databaseUserCopy, metav1.UpdateOptions{},
+	)
+
+	return err
+}
+
+func newDatabaseUserSecret(databaseUser *v1.DatabaseUser) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      databaseUser.Spec.SecretName,
+			Namespace: databaseUser.Namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(databaseUser, v1.SchemeGroupVersion.WithKind("DatabaseUser")),
+			},
+			Labels: map[string]string{
+				"controller": databaseUser.Name,
+			},
+		},
+	}
+}
+
+func privilegesFromSpec(spec v1.DatabaseUserSpec) customdatabase.Privileges {
+	privileges := customdatabase.Privileges{
+		Profile: customdatabase.PrivilegeProfile(spec.Profile),
+	}
+
+	for _, grant := range spec.Grants {
+		privileges.Grants = append(privileges.Grants, customdatabase.Grant{
+			Schema:     grant.Schema,
+			ObjectType: customdatabase.ObjectType(strings.ToLower(grant.ObjectType)),
+			Privileges: grant.Privileges,
+		})
+	}
+
+	return privileges
+}
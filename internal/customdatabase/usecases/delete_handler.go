@@ -2,23 +2,150 @@ package usecases
 
 import (
 	"context"
+	stderrors "errors"
+	"fmt"
+
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+
+	"k8s.io/custom-database/internal/customdatabase"
 )
 
-func (c *Controller) deleteHandler(ctx context.Context, customDatabaseName string) error {
+// deleteHandler drops database and role of deleted CustomDatabase. Objects, which don't have ownership marker of
+// CustomDatabase, are left on server: they were created manually or belong to another resource or cluster. Objects of
+// CustomDatabase, which was deleted while paused or in read-only mode, are left too and may be found by orphan sweeper.
+// Objects are dropped on all servers, where database may live, e.g. on both servers of unfinished migration.
+func (c *Controller) deleteHandler(ctx context.Context, namespace, customDatabaseName string) error {
 	logger := loggerFromHandlerContext(ctx)
 	logger.Info("Delete CustomDatabase resource")
+	key := namespace + "/" + customDatabaseName
 
-	customDatabase := c.domainService.CreateCustomDatabaseEntity(customDatabaseName)
+	// servers are unknown, when CustomDatabase was deleted while controller was down
+	servers := c.serverNames()
+	if deletedServers, ok := c.deletedServers.Load(key); ok {
+		servers = deletedServers.([]string)
+	}
 
-	err := c.databaseManager.DropDatabase(ctx, customDatabase.Database.Name)
-	if err != nil {
-		return err
+	// name is unknown too, then database is looked up by current naming strategy
+	databaseName := ""
+	if deletedDatabaseName, ok := c.deletedDatabaseNames.Load(key); ok {
+		databaseName = deletedDatabaseName.(string)
 	}
 
-	err = c.databaseManager.DropUser(ctx, customDatabase.Database.User)
-	if err != nil {
-		return err
+	customDatabase := c.domainService.CreateCustomDatabaseEntity(
+		namespace, customDatabaseName, databaseName, customdatabase.DatabaseOptions{},
+	)
+	// UID of deleted CustomDatabase is unknown, but it isn't compared by ownership check
+	owner := c.domainService.NewOwnershipMarker(customdatabase.OwnerKindCustomDatabase, namespace, customDatabaseName, "")
+
+	if _, isPaused := c.pausedDeletions.Load(key); isPaused || c.readOnly {
+		logger.Info("CustomDatabase is deleted while paused, database and role are kept")
+		// objects are marked only now, paused CustomDatabase doesn't change server
+		if !c.readOnly {
+			if err := c.keepDatabase(ctx, servers, customDatabase.Database, owner); err != nil {
+				return err
+			}
+		}
+		c.pausedDeletions.Delete(key)
+		c.deletedServers.Delete(key)
+		c.deletedDatabaseNames.Delete(key)
+		c.forgetCustomDatabase(namespace, customDatabaseName)
+		return nil
+	}
+
+	dropped := make(map[string]bool, len(servers))
+	for _, server := range servers {
+		if dropped[server] {
+			continue
+		}
+		serverCtx := customdatabase.ContextWithServer(ctx, server)
+
+		// objects, which were kept at deletion of paused CustomDatabase with the same name, aren't dropped
+		isKeptOnServer, err := c.isDatabaseKept(serverCtx, customDatabase.Database, owner)
+		if err != nil {
+			return err
+		}
+		if isKeptOnServer {
+			logger.Info("CustomDatabase was paused, database and role are kept", "server", server)
+			dropped[server] = true
+			continue
+		}
+
+		err = c.databaseManager.DropDatabase(serverCtx, customDatabase.Database.Name, owner)
+		if stderrors.Is(err, customdatabase.ErrNotOwned) {
+			utilruntime.HandleError(fmt.Errorf("%s: database isn't dropped: %w", key, err))
+		} else if err != nil {
+			return err
+		}
+
+		err = c.databaseManager.DropUser(serverCtx, customDatabase.Database.User, owner)
+		if stderrors.Is(err, customdatabase.ErrNotOwned) {
+			utilruntime.HandleError(fmt.Errorf("%s: role isn't dropped: %w", key, err))
+		} else if err != nil {
+			return err
+		}
+		dropped[server] = true
+	}
+
+	c.deletedServers.Delete(key)
+	c.deletedDatabaseNames.Delete(key)
+	c.forgetCustomDatabase(namespace, customDatabaseName)
+
+	return nil
+}
+
+// keepDatabase marks database and role of CustomDatabase, which is deleted while paused, as kept on all servers, so
+// they aren't taken for orphans. Only ownership markers are changed.
+func (c *Controller) keepDatabase(
+	ctx context.Context, servers []string, database customdatabase.Database, owner customdatabase.OwnershipMarker,
+) error {
+	for _, server := range servers {
+		serverCtx := customdatabase.ContextWithServer(ctx, server)
+
+		databaseOwnership, err := c.databaseManager.GetDatabaseOwnership(serverCtx, database.Name)
+		if err != nil {
+			return err
+		}
+		if marker := keptMarker(databaseOwnership, owner); marker != nil {
+			if err = c.databaseManager.SetDatabaseOwnership(serverCtx, database.Name, *marker); err != nil {
+				return err
+			}
+		}
+
+		userOwnership, err := c.databaseManager.GetUserOwnership(serverCtx, database.User)
+		if err != nil {
+			return err
+		}
+		if marker := keptMarker(userOwnership, owner); marker != nil {
+			if err = c.databaseManager.SetUserOwnership(serverCtx, database.User, *marker); err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
 }
+
+// isDatabaseKept reports whether database or role of CustomDatabase is marked as kept by paused CustomDatabase
+func (c *Controller) isDatabaseKept(
+	ctx context.Context, database customdatabase.Database, owner customdatabase.OwnershipMarker,
+) (bool, error) {
+	databaseOwnership, err := c.databaseManager.GetDatabaseOwnership(ctx, database.Name)
+	if err != nil {
+		return false, err
+	}
+	userOwnership, err := c.databaseManager.GetUserOwnership(ctx, database.User)
+	if err != nil {
+		return false, err
+	}
+
+	return isKept(databaseOwnership, owner) || isKept(userOwnership, owner), nil
+}
+
+// forgetCustomDatabase removes samples and metrics of deleted CustomDatabase
+func (c *Controller) forgetCustomDatabase(namespace, customDatabaseName string) {
+	c.storageSamples.delete(namespace + "/" + customDatabaseName)
+	c.securityAudits.delete(namespace + "/" + customDatabaseName)
+	c.driftChecks.delete(namespace + "/" + customDatabaseName)
+	c.activitySamples.delete(namespace + "/" + customDatabaseName)
+	deleteCustomDatabaseMetrics(namespace, customDatabaseName)
+}
@@ -0,0 +1,124 @@
+package usecases
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/custom-database/internal/customdatabase"
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+)
+
+const (
+	defaultDriftCheckPeriod = 10 * time.Minute
+
+	// DatabaseDrifted is used as part of the Event 'reason' when database or its role was changed on server by hand
+	DatabaseDrifted = "Drifted"
+	// AdoptedNotRepaired is used as part of the condition 'reason' when drift of adopted database isn't repaired
+	// without annotation AnnotationAdoptManage
+	AdoptedNotRepaired = "AdoptedNotRepaired"
+)
+
+// actualizeDrift compares database and its role on server with desired state once per check period, differences are
+// repaired or reported by drift policy. It returns false, when drift is reported and database mustn't be changed by
+// provisioning, otherwise provisioning would silently overwrite some of the manual changes. Drift of adopted database
+// is only reported without annotation AnnotationAdoptManage, database is provisioned as before adoption. Database,
+// which is closed by controller itself, isn't checked, because revoked CONNECT isn't a manual change.
+func (c *Controller) actualizeDrift(
+	ctx context.Context, customDatabaseReq *v1.CustomDatabase, customDatabase customdatabase.Entity,
+	storedSecret *corev1.Secret, newStatus *v1.CustomDatabaseStatus,
+) (bool, error) {
+	logger := loggerFromHandlerContext(ctx)
+	key := customDatabaseReq.Namespace + "/" + customDatabaseReq.Name
+
+	if isClosedByController(newStatus) {
+		// database is checked as soon as it's opened again
+		c.driftChecks.delete(key)
+		return true, nil
+	}
+
+	desired := customDatabase.Database
+	desired.Password = string(storedSecret.Data[SecretVarDbPassword])
+	// limits, that were applied by the last sync, are compared, so change of spec.limits isn't taken for drift
+	if customDatabaseReq.Status.Limits.ConnectionLimit != nil {
+		desired.Limits = limitsFromSpec(customDatabaseReq.Status.Limits)
+	}
+
+	now := c.clock.Now()
+	findings, ok := c.driftChecks.getFresh(key, now, c.driftCheckPeriod)
+	if !ok {
+		// password is checked by connection of owner
+		if err := c.beforeOwnConnection(ctx, customDatabaseReq, desired.Name, newStatus); err != nil {
+			return false, err
+		}
+
+		var err error
+		if findings, err = c.databaseManager.DetectDrift(ctx, desired); err != nil {
+			return false, err
+		}
+
+		if len(findings) > 0 && c.driftPolicyInEffect() == customdatabase.DriftPolicyRepair &&
+			!isAdoptedUnmanaged(customDatabaseReq, newStatus) {
+			logger.Info("Drift found, repair it", "findings", findings)
+			c.recorder.Event(customDatabaseReq, corev1.EventTypeWarning, DatabaseDrifted,
+				"Database was changed on server, desired state is applied again: "+strings.Join(findings, "; "),
+			)
+
+			if err = c.databaseManager.RepairDrift(ctx, desired); err != nil {
+				return false, err
+			}
+			// limits aren't repaired by RepairDrift, otherwise their drift would be found again below
+			if err = c.databaseManager.SetDatabaseLimits(ctx, desired.Name, desired.Limits); err != nil {
+				return false, err
+			}
+			if findings, err = c.databaseManager.DetectDrift(ctx, desired); err != nil {
+				return false, err
+			}
+		}
+
+		c.driftChecks.set(key, findings, now)
+		driftFindings.WithLabelValues(customDatabaseReq.Namespace, customDatabaseReq.Name).Set(float64(len(findings)))
+	}
+
+	if len(findings) == 0 {
+		meta.RemoveStatusCondition(&newStatus.Conditions, v1.ConditionDrifted)
+		return true, nil
+	}
+
+	message := "Database differs from desired state: " + strings.Join(findings, "; ")
+	if isAdoptedUnmanaged(customDatabaseReq, newStatus) {
+		// adopted database is provisioned as before adoption, its drift is known to administrator
+		if !isConditionReason(customDatabaseReq.Status.Conditions, v1.ConditionDrifted, AdoptedNotRepaired) {
+			logger.Info("Drift found in adopted database, report it", "findings", findings)
+			c.recorder.Event(customDatabaseReq, corev1.EventTypeWarning, DatabaseDrifted, message)
+		}
+		c.setCondition(newStatus, customDatabaseReq, v1.ConditionDrifted, metav1.ConditionTrue, AdoptedNotRepaired,
+			message+". Set annotation "+v1.AnnotationAdoptManage+" to \"true\" to repair it",
+		)
+		return true, nil
+	}
+	if c.driftPolicyInEffect() == customdatabase.DriftPolicyRepair {
+		c.setCondition(newStatus, customDatabaseReq, v1.ConditionDrifted, metav1.ConditionTrue, "RepairFailed", message)
+		return true, nil
+	}
+
+	if !meta.IsStatusConditionTrue(customDatabaseReq.Status.Conditions, v1.ConditionDrifted) {
+		logger.Info("Drift found, report it", "findings", findings)
+		c.recorder.Event(customDatabaseReq, corev1.EventTypeWarning, DatabaseDrifted, message)
+	}
+	c.setCondition(newStatus, customDatabaseReq, v1.ConditionDrifted, metav1.ConditionTrue, "Reported",
+		message+". Revert the changes or switch controller to repair policy",
+	)
+
+	return false, nil
+}
+
+// isClosedByController reports whether CONNECT on database is revoked by controller: by hibernation until database is
+// woken up or by copy to another server until migration is completed or cancelled
+func isClosedByController(status *v1.CustomDatabaseStatus) bool {
+	return status.Hibernation != nil || status.ServerMigration != nil
+}
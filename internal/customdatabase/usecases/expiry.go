@@ -0,0 +1,103 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+)
+
+const defaultExpiryWarningPeriod = time.Hour
+
+const (
+	// DatabaseExpiring is used as part of the Event 'reason' when CustomDatabase expires soon
+	DatabaseExpiring = "Expiring"
+	// DatabaseExpired is used as part of the Event 'reason' when expired CustomDatabase is deleted
+	DatabaseExpired = "Expired"
+)
+
+// expiryOfCustomDatabase returns time, when CustomDatabase expires, or nil, if it lives forever
+func expiryOfCustomDatabase(customDatabase *v1.CustomDatabase) (*time.Time, error) {
+	var expiresAt *time.Time
+
+	if ttl := customDatabase.Spec.TTL; ttl != nil {
+		// CRD rejects negative ttl, but CustomDatabase may be created before it was validated
+		if ttl.Duration < 0 {
+			return nil, fmt.Errorf("spec.ttl %s must not be negative", ttl.Duration)
+		}
+		t := customDatabase.CreationTimestamp.Add(ttl.Duration)
+		expiresAt = &t
+	}
+
+	if value, ok := customDatabase.Annotations[v1.AnnotationExpiresAt]; ok {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s must be in RFC 3339 format: %w", v1.AnnotationExpiresAt, err)
+		}
+		if expiresAt == nil || t.Before(*expiresAt) {
+			expiresAt = &t
+		}
+	}
+
+	return expiresAt, nil
+}
+
+// deleteExpiredCustomDatabase deletes CustomDatabase, database and its user are dropped by the usual delete handler
+func (c *Controller) deleteExpiredCustomDatabase(ctx context.Context, customDatabaseReq *v1.CustomDatabase) error {
+	loggerFromHandlerContext(ctx).Info("CustomDatabase is expired, delete it")
+
+	// UID protects CustomDatabase, that was recreated with the same name after expiry
+	uid := customDatabaseReq.UID
+	err := c.sampleclientset.IgorV1().CustomDatabases(customDatabaseReq.Namespace).Delete(
+		ctx, customDatabaseReq.Name, metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &uid}},
+	)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	c.recorder.Event(customDatabaseReq, corev1.EventTypeWarning, DatabaseExpired, "CustomDatabase is expired and deleted")
+
+	return nil
+}
+
+// actualizeExpiry shows expiry time in status, warns about upcoming expiry and schedules the next sync, when warning
+// or deletion is due
+func (c *Controller) actualizeExpiry(
+	customDatabaseReq *v1.CustomDatabase, expiresAt *time.Time, newStatus *v1.CustomDatabaseStatus,
+) {
+	if expiresAt == nil {
+		newStatus.ExpiresAt = nil
+		meta.RemoveStatusCondition(&newStatus.Conditions, v1.ConditionExpiring)
+		return
+	}
+
+	expiresAtTime := metav1.NewTime(*expiresAt)
+	newStatus.ExpiresAt = &expiresAtTime
+
+	now := c.clock.Now()
+	warnAt := expiresAt.Add(-c.expiryWarningPeriod)
+	key := customDatabaseReq.Namespace + "/" + customDatabaseReq.Name
+
+	if now.Before(warnAt) {
+		c.setCondition(newStatus, customDatabaseReq, v1.ConditionExpiring, metav1.ConditionFalse,
+			"Scheduled", fmt.Sprintf("CustomDatabase expires at %s", expiresAt.UTC().Format(time.RFC3339)),
+		)
+		c.workqueue.AddAfter(key, warnAt.Sub(now))
+		return
+	}
+
+	message := fmt.Sprintf("CustomDatabase expires at %s and will be deleted together with database",
+		expiresAt.UTC().Format(time.RFC3339),
+	)
+	if !meta.IsStatusConditionTrue(customDatabaseReq.Status.Conditions, v1.ConditionExpiring) {
+		c.recorder.Event(customDatabaseReq, corev1.EventTypeWarning, DatabaseExpiring, message)
+	}
+	c.setCondition(newStatus, customDatabaseReq, v1.ConditionExpiring, metav1.ConditionTrue, DatabaseExpiring, message)
+	c.workqueue.AddAfter(key, expiresAt.Sub(now))
+}
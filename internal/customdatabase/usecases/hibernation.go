@@ -0,0 +1,312 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/custom-database/internal/customdatabase"
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+)
+
+const (
+	// DatabaseHibernated is used as part of the Event 'reason' when idle database is hibernated
+	DatabaseHibernated = "Hibernated"
+	// DatabaseWokenUp is used as part of the Event 'reason' when hibernated database is available again
+	DatabaseWokenUp = "WokenUp"
+	// HibernationFailed is used as part of the Event 'reason' when database can't be dumped or restored
+	HibernationFailed = "HibernationFailed"
+)
+
+// errCustomDatabaseHibernated is returned instead of owner of CustomDatabase, which is hibernated, so privileges of its
+// roles can't be applied until it's woken up
+var errCustomDatabaseHibernated = fmt.Errorf("CustomDatabase is hibernated")
+
+// syncHibernatedDatabase moves hibernated database through phases of hibernation and wakes it up, when it's
+// requested. It returns true, while database stays hibernated and mustn't be provisioned, otherwise CONNECT would be
+// granted again or dropped database would be recreated.
+func (c *Controller) syncHibernatedDatabase(
+	ctx context.Context, customDatabaseReq *v1.CustomDatabase, database customdatabase.Database,
+	newStatus *v1.CustomDatabaseStatus,
+) (bool, error) {
+	hibernation := newStatus.Hibernation
+	if hibernation == nil || hibernation.Phase == v1.HibernationPhaseRestoring {
+		return false, nil
+	}
+	logger := loggerFromHandlerContext(ctx)
+
+	isWakeUpRequested := customDatabaseReq.Spec.Hibernation == nil ||
+		customDatabaseReq.Annotations[v1.AnnotationWakeUp] != hibernation.ObservedWakeUpToken
+	if isWakeUpRequested {
+		logger.Info("Wake up hibernated database", "phase", hibernation.Phase)
+
+		switch hibernation.Phase {
+		case v1.HibernationPhaseDropped:
+			// database is created again by provisioning and dump is restored into it
+			hibernation.Phase = v1.HibernationPhaseRestoring
+			c.setCondition(newStatus, customDatabaseReq, v1.ConditionHibernated, metav1.ConditionTrue,
+				"Restoring", fmt.Sprintf("Database is restored from DatabaseBackup %s", hibernation.BackupName),
+			)
+			return false, nil
+		case v1.HibernationPhaseDumping:
+			if err := c.deleteHibernationBackup(ctx, customDatabaseReq.Namespace, hibernation.BackupName); err != nil {
+				return false, err
+			}
+		}
+
+		return false, c.wakeUpDatabase(ctx, customDatabaseReq, database, newStatus)
+	}
+
+	if hibernation.Phase != v1.HibernationPhaseDumping {
+		return true, nil
+	}
+
+	databaseBackup, err := c.sampleclientset.IgorV1().DatabaseBackups(customDatabaseReq.Namespace).Get(
+		ctx, hibernation.BackupName, metav1.GetOptions{},
+	)
+	if err != nil && !errors.IsNotFound(err) {
+		return true, err
+	}
+
+	switch {
+	case errors.IsNotFound(err) || databaseBackup.Status.Phase == v1.BackupPhaseFailed:
+		message := fmt.Sprintf("Database can't be dumped by DatabaseBackup %s, it stays awake", hibernation.BackupName)
+		c.recorder.Event(customDatabaseReq, corev1.EventTypeWarning, HibernationFailed, message)
+
+		// idle period starts again, so failed dump isn't repeated on every sync
+		lastActivityAt := metav1.NewTime(c.clock.Now())
+		newStatus.LastActivityAt = &lastActivityAt
+		newStatus.Hibernation = nil
+		c.setCondition(newStatus, customDatabaseReq, v1.ConditionHibernated, metav1.ConditionFalse,
+			"DumpFailed", message,
+		)
+		return false, nil
+	case databaseBackup.Status.Phase != v1.BackupPhaseCompleted:
+		return true, nil
+	}
+
+	logger.Info("Database is dumped, drop it", "backup", hibernation.BackupName)
+	// sessions are terminated, otherwise database can't be dropped
+	if err = c.databaseManager.RevokeDatabaseConnect(ctx, database.Name); err != nil {
+		return true, err
+	}
+	if err = c.databaseManager.DropDatabase(ctx, database.Name, c.ownershipMarker(customDatabaseReq)); err != nil {
+		return true, err
+	}
+
+	hibernation.Phase = v1.HibernationPhaseDropped
+	message := fmt.Sprintf("Database is dropped, its dump is kept in DatabaseBackup %s", hibernation.BackupName)
+	c.setCondition(newStatus, customDatabaseReq, v1.ConditionHibernated, metav1.ConditionTrue, "Dropped", message)
+	c.recorder.Event(customDatabaseReq, corev1.EventTypeNormal, DatabaseHibernated, message)
+
+	return true, nil
+}
+
+// actualizeHibernation completes restore of woken up database and hibernates database, which is idle for longer than
+// spec.hibernation.idleDays
+func (c *Controller) actualizeHibernation(
+	ctx context.Context, customDatabaseReq *v1.CustomDatabase, database customdatabase.Database,
+	newStatus *v1.CustomDatabaseStatus,
+) error {
+	if hibernation := newStatus.Hibernation; hibernation != nil && hibernation.Phase == v1.HibernationPhaseRestoring {
+		return c.restoreWokenUpDatabase(ctx, customDatabaseReq, database, newStatus)
+	}
+
+	spec := customDatabaseReq.Spec.Hibernation
+	if spec == nil {
+		meta.RemoveStatusCondition(&newStatus.Conditions, v1.ConditionHibernated)
+		return nil
+	}
+
+	policy := customdatabase.Hibernation{
+		IdleDuration: time.Duration(spec.IdleDays) * 24 * time.Hour,
+		DumpAndDrop:  spec.DumpAndDrop,
+	}
+	now := c.clock.Now()
+	if newStatus.LastActivityAt == nil || !policy.IsDue(newStatus.LastActivityAt.Time, now) {
+		c.setCondition(newStatus, customDatabaseReq, v1.ConditionHibernated, metav1.ConditionFalse,
+			"Active", fmt.Sprintf("Database is hibernated after %d idle days", spec.IdleDays),
+		)
+		return nil
+	}
+
+	loggerFromHandlerContext(ctx).Info("Database is idle, hibernate it",
+		"lastActivityAt", newStatus.LastActivityAt.Time, "dumpAndDrop", policy.DumpAndDrop,
+	)
+	newStatus.Hibernation = &v1.HibernationStatus{
+		HibernatedAt:        metav1.NewTime(now),
+		ObservedWakeUpToken: customDatabaseReq.Annotations[v1.AnnotationWakeUp],
+	}
+
+	if policy.DumpAndDrop {
+		// database stays available during dump, because dump is made by its owner
+		backupName := fmt.Sprintf("%s-hibernation-%d", customDatabaseReq.Name, now.Unix())
+		databaseBackup := &v1.DatabaseBackup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      backupName,
+				Namespace: customDatabaseReq.Namespace,
+				OwnerReferences: []metav1.OwnerReference{
+					*metav1.NewControllerRef(customDatabaseReq, v1.SchemeGroupVersion.WithKind("CustomDatabase")),
+				},
+			},
+			Spec: v1.DatabaseBackupSpec{CustomDatabaseName: customDatabaseReq.Name},
+		}
+		_, err := c.sampleclientset.IgorV1().DatabaseBackups(customDatabaseReq.Namespace).Create(
+			ctx, databaseBackup, metav1.CreateOptions{},
+		)
+		if err != nil {
+			return err
+		}
+
+		newStatus.Hibernation.Phase = v1.HibernationPhaseDumping
+		newStatus.Hibernation.BackupName = backupName
+		c.setCondition(newStatus, customDatabaseReq, v1.ConditionHibernated, metav1.ConditionTrue,
+			"Dumping", fmt.Sprintf("Database is idle and dumped by DatabaseBackup %s before drop", backupName),
+		)
+		return nil
+	}
+
+	if err := c.databaseManager.RevokeDatabaseConnect(ctx, database.Name); err != nil {
+		return err
+	}
+
+	newStatus.Hibernation.Phase = v1.HibernationPhaseHibernated
+	message := fmt.Sprintf("Database is idle since %s, CONNECT is revoked",
+		newStatus.LastActivityAt.UTC().Format(time.RFC3339),
+	)
+	c.setCondition(newStatus, customDatabaseReq, v1.ConditionHibernated, metav1.ConditionTrue, "Hibernated", message)
+	c.recorder.Event(customDatabaseReq, corev1.EventTypeNormal, DatabaseHibernated, message)
+
+	return nil
+}
+
+// restoreWokenUpDatabase restores dump of dropped database into the recreated one. Backup and restore are deleted
+// after restore, they are needed only for hibernation.
+func (c *Controller) restoreWokenUpDatabase(
+	ctx context.Context, customDatabaseReq *v1.CustomDatabase, database customdatabase.Database,
+	newStatus *v1.CustomDatabaseStatus,
+) error {
+	hibernation := newStatus.Hibernation
+	restores := c.sampleclientset.IgorV1().DatabaseRestores(customDatabaseReq.Namespace)
+	restoreName := hibernation.BackupName + "-wake-up"
+
+	databaseRestore, err := restores.Get(ctx, restoreName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		databaseRestore = &v1.DatabaseRestore{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      restoreName,
+				Namespace: customDatabaseReq.Namespace,
+				OwnerReferences: []metav1.OwnerReference{
+					*metav1.NewControllerRef(customDatabaseReq, v1.SchemeGroupVersion.WithKind("CustomDatabase")),
+				},
+			},
+			Spec: v1.DatabaseRestoreSpec{
+				BackupName:         hibernation.BackupName,
+				CustomDatabaseName: customDatabaseReq.Name,
+				ConfirmOverwrite:   customDatabaseReq.Name,
+			},
+		}
+		_, err = restores.Create(ctx, databaseRestore, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	switch databaseRestore.Status.Phase {
+	case v1.RestorePhaseFailed:
+		c.setCondition(newStatus, customDatabaseReq, v1.ConditionHibernated, metav1.ConditionTrue,
+			"RestoreFailed", fmt.Sprintf("Dump of database can't be restored, delete DatabaseRestore %s to retry", restoreName),
+		)
+		return nil
+	case v1.RestorePhaseCompleted:
+	default:
+		return nil
+	}
+
+	if err = restores.Delete(ctx, restoreName, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if err = c.deleteHibernationBackup(ctx, customDatabaseReq.Namespace, hibernation.BackupName); err != nil {
+		return err
+	}
+
+	return c.wakeUpDatabase(ctx, customDatabaseReq, database, newStatus)
+}
+
+// wakeUpDatabase grants CONNECT again to the owner and roles of DatabaseUsers and DatabaseGrants, because hibernation
+// revoked it from all roles, and clears hibernation in status, idle period starts again. Security audit and drift
+// check are repeated on the next sync, they don't wait for their period.
+func (c *Controller) wakeUpDatabase(
+	ctx context.Context, customDatabaseReq *v1.CustomDatabase, database customdatabase.Database,
+	newStatus *v1.CustomDatabaseStatus,
+) error {
+	roles, err := c.managedRolesOfCustomDatabase(ctx, customDatabaseReq)
+	if err != nil {
+		return err
+	}
+	for _, role := range append([]string{database.User}, roles...) {
+		if err = c.databaseManager.GrantUserToDatabase(ctx, role, database.Name); err != nil {
+			return err
+		}
+	}
+	c.securityAudits.delete(customDatabaseReq.Namespace + "/" + customDatabaseReq.Name)
+	c.driftChecks.delete(customDatabaseReq.Namespace + "/" + customDatabaseReq.Name)
+
+	lastActivityAt := metav1.NewTime(c.clock.Now())
+	newStatus.LastActivityAt = &lastActivityAt
+	newStatus.Hibernation = nil
+
+	message := "Database is woken up"
+	c.setCondition(newStatus, customDatabaseReq, v1.ConditionHibernated, metav1.ConditionFalse, DatabaseWokenUp, message)
+	c.recorder.Event(customDatabaseReq, corev1.EventTypeNormal, DatabaseWokenUp, message)
+
+	return nil
+}
+
+// managedRolesOfCustomDatabase returns roles of DatabaseUsers and DatabaseGrants of CustomDatabase, which are already
+// created
+func (c *Controller) managedRolesOfCustomDatabase(ctx context.Context, customDatabaseReq *v1.CustomDatabase) ([]string, error) {
+	var roles []string
+
+	databaseUsers, err := c.sampleclientset.IgorV1().DatabaseUsers(customDatabaseReq.Namespace).List(
+		ctx, metav1.ListOptions{},
+	)
+	if err != nil {
+		return nil, err
+	}
+	for _, databaseUser := range databaseUsers.Items {
+		if databaseUser.Spec.CustomDatabaseName == customDatabaseReq.Name && databaseUser.Status.UserName != "" {
+			roles = append(roles, databaseUser.Status.UserName)
+		}
+	}
+
+	databaseGrants, err := c.sampleclientset.IgorV1().DatabaseGrants(customDatabaseReq.Namespace).List(
+		ctx, metav1.ListOptions{},
+	)
+	if err != nil {
+		return nil, err
+	}
+	for _, databaseGrant := range databaseGrants.Items {
+		if databaseGrant.Spec.CustomDatabaseName == customDatabaseReq.Name && databaseGrant.Status.UserName != "" {
+			roles = append(roles, databaseGrant.Status.UserName)
+		}
+	}
+
+	return roles, nil
+}
+
+// deleteHibernationBackup deletes DatabaseBackup, its dump is removed from backup storage by its finalizer
+func (c *Controller) deleteHibernationBackup(ctx context.Context, namespace, backupName string) error {
+	err := c.sampleclientset.IgorV1().DatabaseBackups(namespace).Delete(ctx, backupName, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
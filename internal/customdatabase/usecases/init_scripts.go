@@ -0,0 +1,182 @@
+package usecases
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/custom-database/internal/customdatabase"
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+)
+
+const (
+	// InitScriptFailed is used as part of the Event 'reason' when init script of database fails
+	InitScriptFailed = "InitScriptFailed"
+
+	// maxReportedStatementLength statements in conditions and events are truncated to this length in bytes
+	maxReportedStatementLength = 200
+)
+
+// actualizeInitScripts runs scripts of spec.init, which weren't applied yet. Scripts are run in order, the first failed
+// script stops the others - later scripts may depend on it. Failure is reported in condition, script is retried on
+// resync, because transaction of failed script is rolled back. Scripts are identified by name, checksum only shows
+// scripts, which were changed after they were applied - they aren't run again.
+func (c *Controller) actualizeInitScripts(
+	ctx context.Context, customDatabaseReq *v1.CustomDatabase, owner customdatabase.Entity,
+	newStatus *v1.CustomDatabaseStatus,
+) error {
+	if len(customDatabaseReq.Spec.Init) == 0 {
+		return nil
+	}
+
+	logger := loggerFromHandlerContext(ctx)
+	var changedScripts []string
+
+	for _, initScript := range customDatabaseReq.Spec.Init {
+		name, err := initScriptName(initScript)
+		if err != nil {
+			c.setCondition(newStatus, customDatabaseReq, v1.ConditionInitialized, metav1.ConditionFalse,
+				"InvalidScript", err.Error(),
+			)
+			return nil
+		}
+		applied := findAppliedInitScript(newStatus.InitScripts, name)
+
+		script, err := c.initScriptContent(customDatabaseReq.Namespace, initScript)
+		if err != nil {
+			if errors.IsNotFound(err) && applied != nil {
+				// source of applied script isn't needed anymore
+				continue
+			}
+			if errors.IsNotFound(err) {
+				c.setCondition(newStatus, customDatabaseReq, v1.ConditionInitialized, metav1.ConditionFalse,
+					"ScriptNotFound", fmt.Sprintf("Script %s not found: %v", name, err),
+				)
+				return nil
+			}
+			return err
+		}
+
+		hash := sha256.Sum256([]byte(script))
+		checksum := "sha256:" + hex.EncodeToString(hash[:])
+		if applied != nil {
+			if applied.Checksum != checksum {
+				changedScripts = append(changedScripts, name)
+			}
+			continue
+		}
+
+		if err = c.beforeOwnConnection(ctx, customDatabaseReq, owner.Database.Name, newStatus); err != nil {
+			return err
+		}
+		logger.Info("Run init script", "script", name, "checksum", checksum)
+		appliedChecksum, err := c.databaseManager.RunScript(ctx, owner.Database, customdatabase.InitScript{
+			Name: name, Checksum: checksum, Content: script,
+		})
+		if scriptErr, ok := err.(*customdatabase.ScriptError); ok {
+			message := fmt.Sprintf("Script %s failed at line %d in statement %q: %v",
+				name, scriptErr.Line, truncateStatement(scriptErr.Statement), scriptErr.Err,
+			)
+			c.setCondition(newStatus, customDatabaseReq, v1.ConditionInitialized, metav1.ConditionFalse,
+				"ScriptFailed", message,
+			)
+			c.recorder.Event(customDatabaseReq, corev1.EventTypeWarning, InitScriptFailed, message)
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		// script may be applied by previous sync, which failed to save status
+		newStatus.InitScripts = append(newStatus.InitScripts, v1.AppliedInitScript{
+			Name: name, Checksum: appliedChecksum, AppliedAt: metav1.NewTime(c.clock.Now()),
+		})
+		if appliedChecksum != checksum {
+			changedScripts = append(changedScripts, name)
+		}
+	}
+
+	if len(changedScripts) > 0 {
+		c.setCondition(newStatus, customDatabaseReq, v1.ConditionInitialized, metav1.ConditionTrue, "ScriptsChanged",
+			fmt.Sprintf("All init scripts are applied, changes of %s after they were applied are ignored",
+				strings.Join(changedScripts, ", "),
+			),
+		)
+		return nil
+	}
+	c.setCondition(newStatus, customDatabaseReq, v1.ConditionInitialized, metav1.ConditionTrue,
+		"ScriptsApplied", "All init scripts are applied",
+	)
+
+	return nil
+}
+
+// initScriptContent reads script from ConfigMap or Secret
+func (c *Controller) initScriptContent(namespace string, initScript v1.InitScript) (string, error) {
+	if ref := initScript.ConfigMapKeyRef; ref != nil {
+		configMap, err := c.configMapLister.ConfigMaps(namespace).Get(ref.Name)
+		if err != nil {
+			return "", err
+		}
+		script, isExists := configMap.Data[ref.Key]
+		if !isExists {
+			return "", errors.NewNotFound(corev1.Resource("configmaps"), ref.Name+"/"+ref.Key)
+		}
+		return script, nil
+	}
+
+	ref := initScript.SecretKeyRef
+	secret, err := c.secretLister.Secrets(namespace).Get(ref.Name)
+	if err != nil {
+		return "", err
+	}
+	script, isExists := secret.Data[ref.Key]
+	if !isExists {
+		return "", errors.NewNotFound(corev1.Resource("secrets"), ref.Name+"/"+ref.Key)
+	}
+
+	return string(script), nil
+}
+
+// initScriptName returns name of script, that identifies it in status
+func initScriptName(initScript v1.InitScript) (string, error) {
+	switch {
+	case initScript.ConfigMapKeyRef != nil && initScript.SecretKeyRef == nil:
+		return "configmap/" + initScript.ConfigMapKeyRef.Name + "/" + initScript.ConfigMapKeyRef.Key, nil
+	case initScript.SecretKeyRef != nil && initScript.ConfigMapKeyRef == nil:
+		return "secret/" + initScript.SecretKeyRef.Name + "/" + initScript.SecretKeyRef.Key, nil
+	}
+
+	return "", fmt.Errorf("exactly one of configMapKeyRef and secretKeyRef must be specified in init script")
+}
+
+// findAppliedInitScript returns applied script by its name or nil, if script wasn't applied
+func findAppliedInitScript(scripts []v1.AppliedInitScript, name string) *v1.AppliedInitScript {
+	for i := range scripts {
+		if scripts[i].Name == name {
+			return &scripts[i]
+		}
+	}
+
+	return nil
+}
+
+// truncateStatement cuts long statement for conditions and events, multibyte characters aren't split
+func truncateStatement(statement string) string {
+	if len(statement) <= maxReportedStatementLength {
+		return statement
+	}
+
+	cut := maxReportedStatementLength
+	for cut > 0 && !utf8.RuneStart(statement[cut]) {
+		cut--
+	}
+
+	return statement[:cut] + "..."
+}
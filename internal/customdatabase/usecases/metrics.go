@@ -0,0 +1,41 @@
+package usecases
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	databaseSizeBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "customdatabase_size_bytes", Help: "Size of database on disk at the moment of the last sample",
+	}, []string{"namespace", "name"})
+	storageQuotaBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "customdatabase_storage_quota_bytes", Help: "Storage quota of database",
+	}, []string{"namespace", "name"})
+	storageQuotaExceeded = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "customdatabase_storage_quota_exceeded", Help: "1 if database size is greater than storage quota",
+	}, []string{"namespace", "name"})
+	securityAuditFindings = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "customdatabase_security_audit_findings",
+		Help: "Amount of privileges, that differ from least-privilege defaults",
+	}, []string{"namespace", "name"})
+	driftFindings = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "customdatabase_drift_findings",
+		Help: "Amount of differences between database on server and its desired state",
+	}, []string{"namespace", "name"})
+	orphanAgeSeconds = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "customdatabase_orphan_age_seconds",
+		Help: "How long database or role exists on server without its CustomDatabase",
+	}, []string{"server", "object", "object_name", "namespace", "name"})
+)
+
+// RegisterMetrics adds all controller metrics to the registry
+func RegisterMetrics(registry prometheus.Registerer) {
+	registry.MustRegister(databaseSizeBytes, storageQuotaBytes, storageQuotaExceeded, securityAuditFindings, driftFindings, orphanAgeSeconds)
+}
+
+// deleteCustomDatabaseMetrics removes metrics of deleted CustomDatabase
+func deleteCustomDatabaseMetrics(namespace, name string) {
+	databaseSizeBytes.DeleteLabelValues(namespace, name)
+	storageQuotaBytes.DeleteLabelValues(namespace, name)
+	storageQuotaExceeded.DeleteLabelValues(namespace, name)
+	securityAuditFindings.DeleteLabelValues(namespace, name)
+	driftFindings.DeleteLabelValues(namespace, name)
+}
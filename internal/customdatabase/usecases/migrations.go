@@ -0,0 +1,157 @@
+package usecases
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io/fs"
+	"path"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/custom-database/internal/customdatabase"
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+)
+
+// MigrationFailed is used as part of the Event 'reason' when migration of database fails
+const MigrationFailed = "MigrationFailed"
+
+// actualizeMigrations migrates database to the target version of spec.migrations. Failed migration is reported in
+// condition and retried on resync, its transaction is rolled back, so database stays at the previous version.
+func (c *Controller) actualizeMigrations(
+	ctx context.Context, customDatabaseReq *v1.CustomDatabase, owner customdatabase.Entity,
+	newStatus *v1.CustomDatabaseStatus,
+) error {
+	spec := customDatabaseReq.Spec.Migrations
+	if spec == nil {
+		newStatus.Migrations = nil
+		return nil
+	}
+
+	setMigratedCondition := func(status metav1.ConditionStatus, reason, message string) {
+		c.setCondition(newStatus, customDatabaseReq, v1.ConditionMigrated, status, reason, message)
+	}
+
+	files, err := c.migrationFiles(customDatabaseReq.Namespace, spec)
+	if err != nil {
+		if errors.IsNotFound(err) || stderrors.Is(err, fs.ErrNotExist) || stderrors.Is(err, fs.ErrInvalid) {
+			setMigratedCondition(metav1.ConditionFalse, "MigrationsNotFound", err.Error())
+			return nil
+		}
+		return err
+	}
+
+	migrations, err := customdatabase.ParseMigrations(files)
+	if err != nil {
+		setMigratedCondition(metav1.ConditionFalse, "InvalidMigrations", err.Error())
+		return nil
+	}
+
+	target := customdatabase.LatestMigrationVersion(migrations)
+	if spec.TargetVersion != nil {
+		target = *spec.TargetVersion
+	}
+
+	if newStatus.Migrations == nil {
+		newStatus.Migrations = &v1.MigrationsStatus{}
+	}
+	newStatus.Migrations.TargetVersion = target
+
+	// status is trusted, so controller doesn't connect to every database on every resync. Versions, which are set by
+	// migrate CLI, are noticed, when migrations or target version are changed.
+	if newStatus.Migrations.CurrentVersion == target &&
+		meta.IsStatusConditionTrue(newStatus.Conditions, v1.ConditionMigrated) {
+		return nil
+	}
+
+	if err = c.beforeOwnConnection(ctx, customDatabaseReq, owner.Database.Name, newStatus); err != nil {
+		return err
+	}
+	loggerFromHandlerContext(ctx).Info("Migrate database",
+		"currentVersion", newStatus.Migrations.CurrentVersion, "targetVersion", target,
+	)
+	version, err := c.databaseManager.Migrate(ctx, owner.Database, migrations, target)
+	newStatus.Migrations.CurrentVersion = version
+
+	if migrationErr, ok := err.(*customdatabase.MigrationError); ok {
+		message := migrationErr.Error()
+		if scriptErr, ok := migrationErr.Err.(*customdatabase.ScriptError); ok {
+			message = fmt.Sprintf("%s, statement %q", message, truncateStatement(scriptErr.Statement))
+		}
+		setMigratedCondition(metav1.ConditionFalse, "MigrationFailed", message)
+		c.recorder.Event(customDatabaseReq, corev1.EventTypeWarning, MigrationFailed, message)
+		return nil
+	}
+	switch {
+	case stderrors.Is(err, customdatabase.ErrMigrationsDirty):
+		setMigratedCondition(metav1.ConditionFalse, "Dirty", err.Error())
+		return nil
+	case stderrors.Is(err, customdatabase.ErrMigrationVersionNotFound),
+		stderrors.Is(err, customdatabase.ErrMigrationIrreversible):
+		setMigratedCondition(metav1.ConditionFalse, "InvalidTargetVersion", err.Error())
+		return nil
+	case err != nil:
+		return err
+	}
+
+	setMigratedCondition(metav1.ConditionTrue, "Migrated", fmt.Sprintf("Database is migrated to version %d", version))
+
+	return nil
+}
+
+// migrationFiles returns content of migration files by their names
+func (c *Controller) migrationFiles(namespace string, spec *v1.Migrations) (map[string]string, error) {
+	if spec.ConfigMapName != "" {
+		configMap, err := c.configMapLister.ConfigMaps(namespace).Get(spec.ConfigMapName)
+		if err != nil {
+			return nil, err
+		}
+		return configMap.Data, nil
+	}
+
+	if c.migrationsFS == nil {
+		return nil, fmt.Errorf("migrations path %q: directory of migrations isn't configured: %w", spec.Path, fs.ErrNotExist)
+	}
+	// path is set by tenant, so it's never resolved outside of directory of migrations
+	dir := path.Clean(spec.Path)
+	if !fs.ValidPath(dir) {
+		return nil, fmt.Errorf("migrations path %q: %w", spec.Path, fs.ErrInvalid)
+	}
+
+	entries, err := fs.ReadDir(c.migrationsFS, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		// symlinks are skipped, they may point outside of directory of migrations
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		content, err := fs.ReadFile(c.migrationsFS, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		files[entry.Name()] = string(content)
+	}
+
+	return files, nil
+}
+
+// usesConfigMap reports whether init scripts or migrations of CustomDatabase are kept in ConfigMap
+func usesConfigMap(customDatabaseReq *v1.CustomDatabase, name string) bool {
+	if migrations := customDatabaseReq.Spec.Migrations; migrations != nil && migrations.ConfigMapName == name {
+		return true
+	}
+	for _, initScript := range customDatabaseReq.Spec.Init {
+		if initScript.ConfigMapKeyRef != nil && initScript.ConfigMapKeyRef.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,236 @@
+package usecases
+
+import (
+	"io/fs"
+	"time"
+
+	"k8s.io/custom-database/internal/customdatabase"
+)
+
+// ControllerOption configures optional behaviour of Controller
+type ControllerOption func(*Controller)
+
+// WithStorageSamplingPeriod sets how often size of every database is sampled from Postgresql
+func WithStorageSamplingPeriod(period time.Duration) ControllerOption {
+	return func(c *Controller) {
+		c.storageSamplingPeriod = period
+	}
+}
+
+// WithSecurityAuditPeriod sets how often privileges of every database are compared with least-privilege defaults
+func WithSecurityAuditPeriod(period time.Duration) ControllerOption {
+	return func(c *Controller) {
+		c.securityAuditPeriod = period
+	}
+}
+
+// WithDriftCheckPeriod sets how often database and its role on server are compared with desired state
+func WithDriftCheckPeriod(period time.Duration) ControllerOption {
+	return func(c *Controller) {
+		c.driftCheckPeriod = period
+	}
+}
+
+// WithDriftPolicy sets whether manual changes of database and its role are repaired or only reported
+func WithDriftPolicy(policy customdatabase.DriftPolicy) ControllerOption {
+	return func(c *Controller) {
+		c.driftPolicy = policy
+	}
+}
+
+// WithActivitySamplingPeriod sets how often usage counters of every database are sampled to detect idle databases
+func WithActivitySamplingPeriod(period time.Duration) ControllerOption {
+	return func(c *Controller) {
+		c.activitySamplingPeriod = period
+	}
+}
+
+// WithExpiryWarningPeriod sets how long before expiry of CustomDatabase with TTL warning event is emitted
+func WithExpiryWarningPeriod(period time.Duration) ControllerOption {
+	return func(c *Controller) {
+		c.expiryWarningPeriod = period
+	}
+}
+
+// WithAdoptionAllowList sets patterns "<namespace>/<database>" of pre-existing databases, which CustomDatabase with
+// spec.adopt may take over. Patterns have syntax of path.Match, e.g. "legacy/*".
+func WithAdoptionAllowList(patterns []string) ControllerOption {
+	return func(c *Controller) {
+		c.adoptionAllowList = patterns
+	}
+}
+
+// WithMigrationsFS sets directory, where spec.migrations.path of CustomDatabase is looked up, e.g. mounted volume
+func WithMigrationsFS(fsys fs.FS) ControllerOption {
+	return func(c *Controller) {
+		c.migrationsFS = fsys
+	}
+}
+
+// WithOrphanSweepPeriod sets how often server is checked for databases and roles of deleted CustomDatabases
+func WithOrphanSweepPeriod(period time.Duration) ControllerOption {
+	return func(c *Controller) {
+		c.orphanSweepPeriod = period
+	}
+}
+
+// WithOrphanGC enables drop of databases and roles of deleted CustomDatabases, which stay orphaned longer than grace
+// period. Disabled collection only reports orphans.
+func WithOrphanGC(enabled bool, gracePeriod time.Duration) ControllerOption {
+	return func(c *Controller) {
+		c.gcOrphans = enabled
+		c.orphanGracePeriod = gracePeriod
+	}
+}
+
+// WithReadOnly makes controller only observe databases and report their state in status, e.g. during maintenance of
+// server. Databases and roles aren't created, changed or dropped.
+func WithReadOnly(readOnly bool) ControllerOption {
+	return func(c *Controller) {
+		c.readOnly = readOnly
+	}
+}
+
+// WithServers sets name of the default server of domain service and additional servers by name, which CustomDatabase
+// may choose by spec.serverRef. Database manager of controller routes calls to server stored in context by
+// customdatabase.ContextWithServer.
+func WithServers(defaultServer string, servers map[string]customdatabase.Host) ControllerOption {
+	return func(c *Controller) {
+		c.defaultServer = defaultServer
+		c.servers = servers
+	}
+}
+
+// WithServerMigration enables migration of database to another server, when spec.serverRef is changed. Database is
+// copied by dumper and restorer, closed database on the old server is dropped after grace period.
+func WithServerMigration(dumper DatabaseDumper, restorer DatabaseRestorer, gracePeriod time.Duration) ControllerOption {
+	return func(c *Controller) {
+		c.dumper = dumper
+		c.restorer = restorer
+		c.serverGracePeriod = gracePeriod
+	}
+}
+
+// WithTenantTLS publishes sslmode and CA bundle of servers into Secrets of CustomDatabases and issues client
+// certificates for their owners, when issuer is set
+func WithTenantTLS(tenantTLS *TenantTLS) ControllerOption {
+	return func(c *Controller) {
+		c.tenantTLS = tenantTLS
+	}
+}
+
+// WithOwnSessions sets sessions of other controllers in databases, which are shared with them, so their commits aren't
+// taken for activity of tenant
+func WithOwnSessions(ownSessions *OwnSessions) ControllerOption {
+	return func(c *Controller) {
+		c.ownSessions = ownSessions
+	}
+}
+
+// RoleControllerOption configures controllers of DatabaseUser and DatabaseGrant
+type RoleControllerOption func(*roleControllerOptions)
+
+type roleControllerOptions struct {
+	tenantTLS   *TenantTLS
+	rateLimiter RateLimiterConfig
+	ownSessions *OwnSessions
+	readOnly    bool
+	// adoptionAllowList patterns "<namespace>/<role>" of pre-existing roles, that may be adopted
+	adoptionAllowList []string
+}
+
+// newRoleControllerOptions returns options of role controller with defaults
+func newRoleControllerOptions(opts []RoleControllerOption) roleControllerOptions {
+	options := roleControllerOptions{rateLimiter: DefaultRateLimiterConfig(), ownSessions: NewOwnSessions()}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return options
+}
+
+// WithRoleTenantTLS publishes sslmode and CA bundle of servers into Secrets of roles and issues client certificates
+// for them, when issuer is set
+func WithRoleTenantTLS(tenantTLS *TenantTLS) RoleControllerOption {
+	return func(o *roleControllerOptions) {
+		o.tenantTLS = tenantTLS
+	}
+}
+
+// WithRoleRateLimiter sets how failed DatabaseUsers and DatabaseGrants are retried
+func WithRoleRateLimiter(config RateLimiterConfig) RoleControllerOption {
+	return func(o *roleControllerOptions) {
+		o.rateLimiter = config
+	}
+}
+
+// WithRoleOwnSessions records sessions, which are opened to apply privileges of roles, in ownSessions of Controller
+func WithRoleOwnSessions(ownSessions *OwnSessions) RoleControllerOption {
+	return func(o *roleControllerOptions) {
+		o.ownSessions = ownSessions
+	}
+}
+
+// WithRoleAdoptionAllowList sets patterns "<namespace>/<role>" of pre-existing roles without ownership marker, which
+// DatabaseUser or DatabaseGrant of CustomDatabase with spec.adopt may take over
+func WithRoleAdoptionAllowList(patterns []string) RoleControllerOption {
+	return func(o *roleControllerOptions) {
+		o.adoptionAllowList = patterns
+	}
+}
+
+// WithRoleReadOnly makes role controllers only report state of DatabaseUsers and DatabaseGrants, roles aren't created or
+// dropped, finalizers are only removed, so they don't block deletion of resources
+func WithRoleReadOnly(readOnly bool) RoleControllerOption {
+	return func(o *roleControllerOptions) {
+		o.readOnly = readOnly
+	}
+}
+
+// WithRateLimiter sets how failed CustomDatabases are retried
+func WithRateLimiter(config RateLimiterConfig) ControllerOption {
+	return func(c *Controller) {
+		c.rateLimiter = config
+	}
+}
+
+// BackupControllerOption configures controllers of DatabaseBackup and DatabaseRestore
+type BackupControllerOption func(*backupControllerOptions)
+
+type backupControllerOptions struct {
+	rateLimiter RateLimiterConfig
+	ownSessions *OwnSessions
+	readOnly    bool
+}
+
+// newBackupControllerOptions returns options of backup controller with defaults
+func newBackupControllerOptions(opts []BackupControllerOption) backupControllerOptions {
+	options := backupControllerOptions{rateLimiter: DefaultRateLimiterConfig(), ownSessions: NewOwnSessions()}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return options
+}
+
+// WithBackupRateLimiter sets how failed DatabaseBackups and DatabaseRestores are retried
+func WithBackupRateLimiter(config RateLimiterConfig) BackupControllerOption {
+	return func(o *backupControllerOptions) {
+		o.rateLimiter = config
+	}
+}
+
+// WithBackupOwnSessions records sessions of dumps and restores in ownSessions of Controller
+func WithBackupOwnSessions(ownSessions *OwnSessions) BackupControllerOption {
+	return func(o *backupControllerOptions) {
+		o.ownSessions = ownSessions
+	}
+}
+
+// WithBackupReadOnly makes backup controllers only report state of DatabaseBackups and DatabaseRestores, dumps aren't
+// made, restored or deleted from storage, finalizers are only removed, so they don't block deletion of resources
+func WithBackupReadOnly(readOnly bool) BackupControllerOption {
+	return func(o *backupControllerOptions) {
+		o.readOnly = readOnly
+	}
+}
@@ -0,0 +1,209 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/klog/v2"
+
+	"k8s.io/custom-database/internal/customdatabase"
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+)
+
+const (
+	defaultOrphanSweepPeriod = 10 * time.Minute
+	defaultOrphanGracePeriod = 24 * time.Hour
+
+	// ObjectOrphaned is used as part of the Event 'reason' when database or role of deleted CustomDatabase is found
+	ObjectOrphaned = "Orphaned"
+	// OrphanDropped is used as part of the Event 'reason' when orphaned database or role is dropped
+	OrphanDropped = "OrphanDropped"
+
+	orphanDatabase = "database"
+	orphanRole     = "role"
+)
+
+// orphan database or role, which has ownership marker of CustomDatabase, that doesn't exist or whose database doesn't
+// live on the server anymore
+type orphan struct {
+	server string
+	object string
+	name   string
+	marker customdatabase.OwnershipMarker
+	// since time, when orphan was found the first time
+	since time.Time
+}
+
+func (o orphan) key() string {
+	if o.server == "" {
+		return o.object + "/" + o.name
+	}
+
+	return o.server + "/" + o.object + "/" + o.name
+}
+
+// String describes orphan in events, e.g. "database test on server replica"
+func (o orphan) String() string {
+	if o.server == "" {
+		return o.object + " " + o.name
+	}
+
+	return o.object + " " + o.name + " on server " + o.server
+}
+
+// sweepOrphans finds databases and roles of CustomDatabases, which were deleted while controller was down, so
+// deleteHandler didn't drop them. Orphans are reported by metric and event, they are dropped only when collection of
+// orphans is enabled and they stay orphaned longer than grace period.
+func (c *Controller) sweepOrphans(ctx context.Context) {
+	logger := klog.FromContext(ctx)
+
+	orphans, err := c.findOrphans(ctx)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("orphans can't be found: %w", err))
+		return
+	}
+
+	now := c.clock.Now()
+	found := make(map[string]orphan, len(orphans))
+	for _, o := range orphans {
+		if known, ok := c.orphans[o.key()]; ok {
+			o.since = known.since
+		} else {
+			o.since = now
+			logger.Info("Found orphan", o.object, o.name, "server", o.server, "owner", o.marker.Owner())
+			c.recorder.Event(orphanOwnerReference(o.marker), corev1.EventTypeWarning, ObjectOrphaned,
+				fmt.Sprintf("%s exists, but CustomDatabase doesn't use it", o),
+			)
+		}
+		found[o.key()] = o
+		orphanAgeSeconds.WithLabelValues(o.server, o.object, o.name, o.marker.Namespace, o.marker.Name).Set(
+			now.Sub(o.since).Seconds(),
+		)
+	}
+
+	for key, o := range c.orphans {
+		if _, ok := found[key]; !ok {
+			orphanAgeSeconds.DeleteLabelValues(o.server, o.object, o.name, o.marker.Namespace, o.marker.Name)
+		}
+	}
+	c.orphans = found
+
+	if !c.gcOrphans || c.readOnly {
+		return
+	}
+
+	// databases are dropped before roles, because role can't be dropped while it owns database
+	for _, o := range orphans {
+		o = found[o.key()]
+		if now.Sub(o.since) < c.orphanGracePeriod {
+			continue
+		}
+
+		if err = c.dropOrphan(ctx, o); err != nil {
+			utilruntime.HandleError(fmt.Errorf("orphan %s can't be dropped: %w", o, err))
+			continue
+		}
+
+		logger.Info("Dropped orphan", o.object, o.name, "server", o.server, "owner", o.marker.Owner())
+		c.recorder.Event(orphanOwnerReference(o.marker), corev1.EventTypeNormal, OrphanDropped, fmt.Sprintf(
+			"%s is dropped after %s without CustomDatabase", o, now.Sub(o.since).Round(time.Second),
+		))
+		orphanAgeSeconds.DeleteLabelValues(o.server, o.object, o.name, o.marker.Namespace, o.marker.Name)
+		delete(c.orphans, o.key())
+	}
+}
+
+// findOrphans returns databases and then roles of every server, which are marked by CustomDatabase of this cluster,
+// that isn't in informer cache. Objects, which are left on server after database of CustomDatabase was moved to
+// another one, are orphans too.
+func (c *Controller) findOrphans(ctx context.Context) ([]orphan, error) {
+	var orphans []orphan
+	for _, server := range c.serverNames() {
+		serverOrphans, err := c.findServerOrphans(customdatabase.ContextWithServer(ctx, server), server)
+		if err != nil {
+			return nil, fmt.Errorf("server %s: %w", server, err)
+		}
+		orphans = append(orphans, serverOrphans...)
+	}
+
+	return orphans, nil
+}
+
+// findServerOrphans returns orphans of server from context
+func (c *Controller) findServerOrphans(ctx context.Context, server string) ([]orphan, error) {
+	databaseMarkers, err := c.databaseManager.ListDatabaseOwnership(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userMarkers, err := c.databaseManager.ListUserOwnership(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var orphans []orphan
+	for _, objects := range []struct {
+		object  string
+		markers map[string]customdatabase.OwnershipMarker
+	}{{orphanDatabase, databaseMarkers}, {orphanRole, userMarkers}} {
+		names := make([]string, 0, len(objects.markers))
+		for name := range objects.markers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			marker := objects.markers[name]
+			// roles of DatabaseUser and DatabaseGrant are dropped by their finalizers, objects of CustomDatabase deleted
+			// while paused are kept on purpose
+			if marker.Kind != customdatabase.OwnerKindCustomDatabase || !c.domainService.IsOwnCluster(marker) ||
+				marker.Kept {
+				continue
+			}
+
+			customDatabaseReq, err := c.customDatabasesLister.CustomDatabases(marker.Namespace).Get(marker.Name)
+			if err != nil && !errors.IsNotFound(err) {
+				return nil, err
+			}
+			if err == nil && containsString(c.serversOfCustomDatabase(customDatabaseReq), server) {
+				continue
+			}
+			orphans = append(orphans, orphan{server: server, object: objects.object, name: name, marker: marker})
+		}
+	}
+
+	return orphans, nil
+}
+
+// dropOrphan drops orphaned database or role, ownership marker is checked again by database manager
+func (c *Controller) dropOrphan(ctx context.Context, o orphan) error {
+	ctx = customdatabase.ContextWithResource(ctx, o.marker.Kind, o.marker.Namespace+"/"+o.marker.Name)
+	ctx = customdatabase.ContextWithServer(ctx, o.server)
+
+	if o.object == orphanRole {
+		return c.databaseManager.DropUser(ctx, o.name, o.marker)
+	}
+
+	// sessions are terminated, otherwise database can't be dropped
+	if err := c.databaseManager.RevokeDatabaseConnect(ctx, o.name); err != nil {
+		return err
+	}
+
+	return c.databaseManager.DropDatabase(ctx, o.name, o.marker)
+}
+
+// orphanOwnerReference refers to deleted CustomDatabase, so events about its orphans are listed in its namespace
+func orphanOwnerReference(marker customdatabase.OwnershipMarker) *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		APIVersion: v1.SchemeGroupVersion.String(),
+		Kind:       marker.Kind,
+		Namespace:  marker.Namespace,
+		Name:       marker.Name,
+		UID:        types.UID(marker.UID),
+	}
+}
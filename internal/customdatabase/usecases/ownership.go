@@ -0,0 +1,168 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"k8s.io/custom-database/internal/customdatabase"
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+)
+
+const (
+	// DatabaseAdopted is used as part of the Event 'reason' when pre-existing database is taken over
+	DatabaseAdopted = "Adopted"
+	// AdoptionRefused is used as part of the Event 'reason' when database or role exists and can't be taken over
+	AdoptionRefused = "AdoptionRefused"
+)
+
+// actualizeOwnership checks, that database and role either don't exist yet or belong to CustomDatabase. Objects
+// without ownership markers are taken over, when CustomDatabase created them before markers appeared or when they are
+// adopted by spec.adopt. It returns false, when objects belong to somebody else and mustn't be touched.
+func (c *Controller) actualizeOwnership(
+	ctx context.Context, customDatabaseReq *v1.CustomDatabase, customDatabase customdatabase.Entity,
+	storedSecret *corev1.Secret, newStatus *v1.CustomDatabaseStatus,
+) (bool, error) {
+	namespace := customDatabaseReq.Namespace
+	database, user := customDatabase.Database.Name, customDatabase.Database.User
+	owner := c.ownershipMarker(customDatabaseReq)
+
+	databaseOwnership, err := c.databaseManager.GetDatabaseOwnership(ctx, database)
+	if err != nil {
+		return false, err
+	}
+	userOwnership, err := c.databaseManager.GetUserOwnership(ctx, user)
+	if err != nil {
+		return false, err
+	}
+
+	refuse := func(reason, message string) (bool, error) {
+		if !isAdoptionRefused(customDatabaseReq.Status.Conditions, reason) {
+			c.recorder.Event(customDatabaseReq, corev1.EventTypeWarning, AdoptionRefused, message)
+		}
+		c.setCondition(newStatus, customDatabaseReq, v1.ConditionAdopted, metav1.ConditionFalse, reason, message)
+		return false, nil
+	}
+
+	for _, marker := range []*customdatabase.OwnershipMarker{databaseOwnership.Marker, userOwnership.Marker} {
+		if marker != nil && !marker.IsOwnedBy(owner) {
+			return refuse("OwnedByAnother",
+				fmt.Sprintf("Database %s or role %s belongs to %s", database, user, marker.Owner()),
+			)
+		}
+	}
+
+	isAdopted := false
+	if !databaseOwnership.IsOwnedBy(owner) || !userOwnership.IsOwnedBy(owner) {
+		isCreatedByController, err := c.hasOwnSecret(customDatabaseReq)
+		if err != nil {
+			return false, err
+		}
+
+		if !isCreatedByController {
+			switch {
+			case !customDatabaseReq.Spec.Adopt:
+				return refuse("NotOwned", fmt.Sprintf(
+					"Database %s or role %s already exists and isn't managed by controller, set spec.adopt to take it over",
+					database, user,
+				))
+			case !isAdoptionAllowed(c.adoptionAllowList, namespace, database):
+				return refuse("AdoptionNotAllowed", fmt.Sprintf(
+					"Database %s isn't allowed for adoption in namespace %s by administrator", database, namespace,
+				))
+			case userOwnership.Exists && storedSecret == nil &&
+				customDatabaseReq.Annotations[v1.AnnotationAdoptResetPassword] != "true":
+				return refuse("PasswordUnknown", fmt.Sprintf(
+					"Password of role %s is unknown, create Secret %s with it or set annotation %s to \"true\"",
+					user, customDatabaseReq.Spec.SecretName, v1.AnnotationAdoptResetPassword,
+				))
+			}
+			isAdopted = true
+		}
+	}
+
+	marker := owner
+	marker.Adopted = isAdopted ||
+		databaseOwnership.Marker != nil && databaseOwnership.Marker.Adopted ||
+		userOwnership.Marker != nil && userOwnership.Marker.Adopted
+
+	if databaseOwnership.Exists && !isSameMarker(databaseOwnership.Marker, marker) {
+		if err = c.databaseManager.SetDatabaseOwnership(ctx, database, marker); err != nil {
+			return false, err
+		}
+	}
+	if userOwnership.Exists && !isSameMarker(userOwnership.Marker, marker) {
+		if err = c.databaseManager.SetUserOwnership(ctx, user, marker); err != nil {
+			return false, err
+		}
+	}
+
+	if isAdopted {
+		message := fmt.Sprintf("Database %s and role %s are adopted", database, user)
+		c.recorder.Event(customDatabaseReq, corev1.EventTypeNormal, DatabaseAdopted, message)
+		c.setCondition(newStatus, customDatabaseReq, v1.ConditionAdopted, metav1.ConditionTrue, DatabaseAdopted, message)
+	} else if !meta.IsStatusConditionTrue(newStatus.Conditions, v1.ConditionAdopted) {
+		meta.RemoveStatusCondition(&newStatus.Conditions, v1.ConditionAdopted)
+	}
+
+	return true, nil
+}
+
+// ownershipMarker returns marker of database and role of CustomDatabase
+func (c *Controller) ownershipMarker(customDatabaseReq *v1.CustomDatabase) customdatabase.OwnershipMarker {
+	return c.domainService.NewOwnershipMarker(customdatabase.OwnerKindCustomDatabase,
+		customDatabaseReq.Namespace, customDatabaseReq.Name, string(customDatabaseReq.UID),
+	)
+}
+
+// hasOwnSecret reports whether controller created Secret for CustomDatabase, it means that controller created its
+// database and role before ownership markers appeared. Secret may be not referenced by spec.secretName anymore.
+func (c *Controller) hasOwnSecret(customDatabaseReq *v1.CustomDatabase) (bool, error) {
+	secrets, err := c.secretLister.Secrets(customDatabaseReq.Namespace).List(
+		labels.SelectorFromSet(labels.Set{"controller": customDatabaseReq.Name}),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	for _, secret := range secrets {
+		if metav1.IsControlledBy(secret, customDatabaseReq) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// isAdoptionAllowed reports whether administrator allowed namespace to adopt database or role
+func isAdoptionAllowed(adoptionAllowList []string, namespace, name string) bool {
+	for _, pattern := range adoptionAllowList {
+		if isMatched, _ := path.Match(pattern, namespace+"/"+name); isMatched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isAdoptedUnmanaged reports whether database is adopted and privileges and drift of it are only reported, because
+// administrator didn't allow to change them by annotation AnnotationAdoptManage
+func isAdoptedUnmanaged(customDatabaseReq *v1.CustomDatabase, newStatus *v1.CustomDatabaseStatus) bool {
+	return meta.IsStatusConditionTrue(newStatus.Conditions, v1.ConditionAdopted) &&
+		customDatabaseReq.Annotations[v1.AnnotationAdoptManage] != "true"
+}
+
+func isAdoptionRefused(conditions []metav1.Condition, reason string) bool {
+	condition := meta.FindStatusCondition(conditions, v1.ConditionAdopted)
+
+	return condition != nil && condition.Status == metav1.ConditionFalse && condition.Reason == reason
+}
+
+func isSameMarker(marker *customdatabase.OwnershipMarker, expected customdatabase.OwnershipMarker) bool {
+	return marker != nil && *marker == expected
+}
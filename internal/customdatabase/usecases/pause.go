@@ -0,0 +1,168 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	"k8s.io/custom-database/internal/customdatabase"
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+)
+
+const (
+	// ReconciliationPaused is used as part of the Event 'reason' when reconciliation of CustomDatabase is stopped
+	ReconciliationPaused = "Paused"
+	// ReconciliationResumed is used as part of the Event 'reason' when reconciliation of CustomDatabase is resumed
+	ReconciliationResumed = "Resumed"
+)
+
+// errCustomDatabasePaused is returned instead of owner of CustomDatabase, which is paused by annotation
+var errCustomDatabasePaused = fmt.Errorf("CustomDatabase is paused")
+
+func isCustomDatabasePaused(customDatabaseReq *v1.CustomDatabase) bool {
+	return customDatabaseReq.Annotations[v1.AnnotationPaused] == "true"
+}
+
+// handleDeletedCustomDatabase remembers, whether CustomDatabase was paused at the moment of deletion, servers and name
+// of its database, because deleteHandler gets only its name
+func (c *Controller) handleDeletedCustomDatabase(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	customDatabaseReq, ok := obj.(*v1.CustomDatabase)
+	if !ok {
+		utilruntime.HandleError(fmt.Errorf("expected CustomDatabase in delete event but got %#v", obj))
+		return
+	}
+
+	key := customDatabaseReq.Namespace + "/" + customDatabaseReq.Name
+	// annotation of stale tombstone may be missed, so paused state is taken from status too
+	if isCustomDatabasePaused(customDatabaseReq) ||
+		isConditionReason(customDatabaseReq.Status.Conditions, v1.ConditionPaused, "Annotation") {
+		c.pausedDeletions.Store(key, true)
+	} else {
+		c.pausedDeletions.Delete(key)
+	}
+	c.deletedServers.Store(key, c.serversOfCustomDatabase(customDatabaseReq))
+	c.deletedDatabaseNames.Store(key, customDatabaseReq.Status.DatabaseName)
+	// database is dropped on all servers of migration, copy mustn't restore it again
+	c.serverCopies.cancel(key)
+	c.serverCopyResults.Delete(key)
+
+	c.enqueueCustomDatabase(customDatabaseReq)
+}
+
+// syncPausedCustomDatabase only reports, that CustomDatabase isn't reconciled. Nothing is changed on server, paused
+// state is kept in Paused condition, and database is marked as kept, when CustomDatabase is deleted. In read-only mode
+// database is observed too: drift is reported and expiry is shown, but nothing is changed on server or in cluster.
+func (c *Controller) syncPausedCustomDatabase(ctx context.Context, customDatabaseReq *v1.CustomDatabase) error {
+	logger := loggerFromHandlerContext(ctx)
+	newStatus := customDatabaseReq.Status.DeepCopy()
+
+	reason := "Annotation"
+	message := fmt.Sprintf("Reconciliation is paused by annotation %s", v1.AnnotationPaused)
+	if c.readOnly {
+		reason, message = "ReadOnly", "Controller runs in read-only mode, database is only observed"
+
+		if err := c.observeCustomDatabase(ctx, customDatabaseReq, newStatus); err != nil {
+			return err
+		}
+	}
+
+	if !meta.IsStatusConditionTrue(customDatabaseReq.Status.Conditions, v1.ConditionPaused) {
+		logger.Info("Reconciliation is paused", "reason", reason)
+		c.recorder.Event(customDatabaseReq, corev1.EventTypeNormal, ReconciliationPaused, message)
+	}
+	c.setCondition(newStatus, customDatabaseReq, v1.ConditionPaused, metav1.ConditionTrue, reason, message)
+
+	return c.updateCustomDatabaseStatus(ctx, customDatabaseReq, newStatus)
+}
+
+// observeCustomDatabase reports drift and expiry of CustomDatabase without any changes
+func (c *Controller) observeCustomDatabase(
+	ctx context.Context, customDatabaseReq *v1.CustomDatabase, newStatus *v1.CustomDatabaseStatus,
+) error {
+	expiresAt, err := expiryOfCustomDatabase(customDatabaseReq)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("%s: %w", customDatabaseReq.Name, err))
+	} else {
+		c.actualizeExpiry(customDatabaseReq, expiresAt, newStatus)
+	}
+
+	customDatabase, storedSecret, err := c.provisionedCustomDatabase(customDatabaseReq)
+	if err != nil || storedSecret == nil {
+		return err
+	}
+	ctx = customdatabase.ContextWithServer(ctx, customDatabase.Server)
+
+	_, err = c.actualizeDrift(ctx, customDatabaseReq, customDatabase, storedSecret, newStatus)
+
+	return err
+}
+
+// keptMarker returns marker of object of owner with Kept flag, or nil, when object doesn't exist, belongs to somebody
+// else or is already kept
+func keptMarker(ownership customdatabase.Ownership, owner customdatabase.OwnershipMarker) *customdatabase.OwnershipMarker {
+	if !ownership.Exists || ownership.Marker == nil || !ownership.Marker.IsOwnedBy(owner) || ownership.Marker.Kept {
+		return nil
+	}
+
+	marker := *ownership.Marker
+	marker.Kept = true
+
+	return &marker
+}
+
+// isKept reports whether object of owner is marked as kept by paused CustomDatabase
+func isKept(ownership customdatabase.Ownership, owner customdatabase.OwnershipMarker) bool {
+	return ownership.Marker != nil && ownership.Marker.IsOwnedBy(owner) && ownership.Marker.Kept
+}
+
+// provisionedCustomDatabase returns database of CustomDatabase together with its Secret. Secret is nil, when database
+// isn't provisioned yet - desired password is unknown without it.
+func (c *Controller) provisionedCustomDatabase(
+	customDatabaseReq *v1.CustomDatabase,
+) (customdatabase.Entity, *corev1.Secret, error) {
+	storedSecret, err := c.secretLister.Secrets(customDatabaseReq.Namespace).Get(customDatabaseReq.Spec.SecretName)
+	if errors.IsNotFound(err) {
+		return customdatabase.Entity{}, nil, nil
+	}
+	if err != nil {
+		return customdatabase.Entity{}, nil, err
+	}
+
+	customDatabase := c.domainService.CreateCustomDatabaseEntity(
+		customDatabaseReq.Namespace, customDatabaseReq.Name, databaseNameOfCustomDatabase(customDatabaseReq, storedSecret),
+		databaseOptionsFromSpec(customDatabaseReq.Spec.DatabaseOptions),
+	)
+	customDatabase.Database.Limits = limitsFromSpec(customDatabaseReq.Spec.Limits)
+	customDatabase.Server = c.serverOfCustomDatabase(customDatabaseReq, storedSecret)
+
+	return customDatabase, storedSecret, nil
+}
+
+// actualizePause removes Paused condition of CustomDatabase, which is reconciled again
+func (c *Controller) actualizePause(customDatabaseReq *v1.CustomDatabase, newStatus *v1.CustomDatabaseStatus) {
+	if !meta.IsStatusConditionTrue(customDatabaseReq.Status.Conditions, v1.ConditionPaused) {
+		return
+	}
+
+	c.recorder.Event(customDatabaseReq, corev1.EventTypeNormal, ReconciliationResumed, "Reconciliation is resumed")
+	meta.RemoveStatusCondition(&newStatus.Conditions, v1.ConditionPaused)
+}
+
+// driftPolicyInEffect returns drift policy of controller, drift is only reported in read-only mode
+func (c *Controller) driftPolicyInEffect() customdatabase.DriftPolicy {
+	if c.readOnly {
+		return customdatabase.DriftPolicyReport
+	}
+
+	return c.driftPolicy
+}
@@ -0,0 +1,35 @@
+package usecases
+
+import (
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// RateLimiterConfig settings of rate limiter of workqueues. Failed item is retried with exponential backoff from
+// BaseDelay up to MaxDelay, all items are retried not often than QPS with bursts of Burst items.
+type RateLimiterConfig struct {
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	QPS       float64
+	Burst     int
+}
+
+// DefaultRateLimiterConfig returns settings of workqueue.DefaultControllerRateLimiter
+func DefaultRateLimiterConfig() RateLimiterConfig {
+	return RateLimiterConfig{
+		BaseDelay: 5 * time.Millisecond,
+		MaxDelay:  1000 * time.Second,
+		QPS:       10,
+		Burst:     100,
+	}
+}
+
+// newRateLimiter returns rate limiter of workqueue, which delays item by the longest delay of backoff and bucket
+func (c RateLimiterConfig) newRateLimiter() workqueue.RateLimiter {
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(c.BaseDelay, c.MaxDelay),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(c.QPS), c.Burst)},
+	)
+}
@@ -0,0 +1,178 @@
+package usecases
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	listerscorev1 "k8s.io/client-go/listers/core/v1"
+
+	"k8s.io/custom-database/internal/customdatabase"
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+	listers "k8s.io/custom-database/pkg/generated/listers/cusotmdatabase/v1"
+)
+
+// privilegesReapplyPeriod privileges of role are applied again after this period, even when they aren't changed
+const privilegesReapplyPeriod = time.Hour
+
+// ensureRole creates role of additional user or changes its password, if it was rotated. Existing role without
+// ownership marker gets marker of resource only when it's adoptable, otherwise it belongs to somebody else.
+func ensureRole(
+	ctx context.Context, databaseManager DatabaseManager, user customdatabase.DatabaseUser,
+	marker customdatabase.OwnershipMarker, isPasswordChanged, isAdoptable bool,
+) error {
+	logger := loggerFromHandlerContext(ctx)
+
+	err := databaseManager.CreateUser(ctx, user.Name, user.Password, marker)
+	if err != customdatabase.ErrUserAlreadyExists {
+		return err
+	}
+
+	ownership, err := databaseManager.GetUserOwnership(ctx, user.Name)
+	if err != nil {
+		return err
+	}
+	if ownership.Marker != nil && !ownership.Marker.IsOwnedBy(marker) {
+		return fmt.Errorf("%w: role %s belongs to %s", customdatabase.ErrNotOwned, user.Name, ownership.Marker.Owner())
+	}
+	if ownership.Marker == nil && !isAdoptable {
+		return fmt.Errorf("%w: role %s already exists and isn't managed by controller", customdatabase.ErrNotOwned,
+			user.Name,
+		)
+	}
+	if ownership.Marker == nil {
+		logger.Info("mark role of user", "user_name", user.Name)
+		if err = databaseManager.SetUserOwnership(ctx, user.Name, marker); err != nil {
+			return err
+		}
+	}
+
+	if !isPasswordChanged {
+		return nil
+	}
+
+	logger.Info("change password of user", "user_name", user.Name)
+
+	return databaseManager.ChangeUserPassword(ctx, user.Name, user.Password)
+}
+
+// isRoleAdoptable reports whether existing role without ownership marker may be taken over by resource. Controller
+// created role before markers appeared, when Secret of resource was created by controller. Otherwise CustomDatabase
+// must adopt pre-existing objects by spec.adopt and administrator must allow role "<namespace>/<role>" for adoption.
+func isRoleAdoptable(
+	customDatabaseReq *v1.CustomDatabase, adoptionAllowList []string, roleName string, hasOwnSecret bool,
+) bool {
+	return hasOwnSecret || customDatabaseReq.Spec.Adopt &&
+		isAdoptionAllowed(adoptionAllowList, customDatabaseReq.Namespace, roleName)
+}
+
+// privilegesChecksum identifies privileges of role in database on server. Privileges are applied only when checksum
+// in status differs or reapply period is over, because every apply connects to database and commits transactions, so
+// idle database would never be hibernated.
+func privilegesChecksum(owner customdatabase.Entity, user customdatabase.DatabaseUser) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s/%s/%s/%+v", owner.Server, owner.Database.Name, user.Name, user.Privileges)))
+
+	return "sha256:" + hex.EncodeToString(hash[:])
+}
+
+// isPrivilegesApplyDue reports whether privileges of role should be applied. Grants on all objects cover only objects,
+// which exist at the moment of apply, and default privileges cover only objects created by owner, so objects of
+// other roles, e.g. members of owner role, get privileges by periodic apply.
+func isPrivilegesApplyDue(checksum, appliedChecksum string, appliedAt *metav1.Time, now time.Time) bool {
+	return checksum != appliedChecksum || appliedAt == nil || !now.Before(appliedAt.Add(privilegesReapplyPeriod))
+}
+
+// isCustomDatabaseHibernated reports whether tenant can't connect to database of CustomDatabase, because it's
+// hibernated. Privileges of its roles are applied after wake up.
+func isCustomDatabaseHibernated(customDatabaseReq *v1.CustomDatabase) bool {
+	return customDatabaseReq.Status.Hibernation != nil
+}
+
+// isHibernatedDatabaseDropped reports whether database of hibernated CustomDatabase is dropped after dump, so roles
+// have no privileges in it
+func isHibernatedDatabaseDropped(customDatabaseReq *v1.CustomDatabase) bool {
+	hibernation := customDatabaseReq.Status.Hibernation
+	return hibernation != nil && hibernation.Phase == v1.HibernationPhaseDropped
+}
+
+// actualizeCredentialsSecret creates Secret or updates its data, e.g. after password rotation
+func actualizeCredentialsSecret(
+	ctx context.Context, kubeclientset kubernetes.Interface, storedSecret, secretNewState *corev1.Secret,
+) error {
+	var err error
+	logger := loggerFromHandlerContext(ctx)
+
+	if storedSecret == nil {
+		logger.Info("Create secret resource", "secretName", secretNewState.Name, "namespace", secretNewState.Namespace)
+		_, err = kubeclientset.CoreV1().Secrets(secretNewState.Namespace).Create(ctx, secretNewState, metav1.CreateOptions{})
+		return err
+	}
+
+	if equality.Semantic.DeepEqual(storedSecret.Data, secretNewState.Data) {
+		return nil
+	}
+
+	logger.Info("Update secret resource", "secretName", secretNewState.Name, "namespace", secretNewState.Namespace)
+	secretCopy := storedSecret.DeepCopy()
+	secretCopy.Data = secretNewState.Data
+	_, err = kubeclientset.CoreV1().Secrets(secretCopy.Namespace).Update(ctx, secretCopy, metav1.UpdateOptions{})
+
+	return err
+}
+
+// ownerOfCustomDatabase returns CustomDatabase together with database and credentials of its owner from Secret and
+// server, where database lives. errCustomDatabasePaused is returned for paused CustomDatabase, its database mustn't be
+// touched.
+func ownerOfCustomDatabase(
+	customDatabasesLister listers.CustomDatabaseLister, secretLister listerscorev1.SecretLister,
+	namespace, customDatabaseName string,
+) (*v1.CustomDatabase, customdatabase.Entity, error) {
+	customDatabaseReq, err := customDatabasesLister.CustomDatabases(namespace).Get(customDatabaseName)
+	if err != nil {
+		return nil, customdatabase.Entity{}, err
+	}
+
+	if isCustomDatabasePaused(customDatabaseReq) {
+		return customDatabaseReq, customdatabase.Entity{}, errCustomDatabasePaused
+	}
+
+	ownerSecret, err := secretLister.Secrets(customDatabaseReq.Namespace).Get(customDatabaseReq.Spec.SecretName)
+	if err != nil {
+		return nil, customdatabase.Entity{}, err
+	}
+
+	owner, err := entityFromSecret(ownerSecret)
+	if err != nil {
+		return nil, customdatabase.Entity{}, err
+	}
+	owner.Server = customDatabaseReq.Status.Server
+
+	return customDatabaseReq, owner, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func removeString(values []string, value string) []string {
+	var result []string
+	for _, v := range values {
+		if v != value {
+			result = append(result, v)
+		}
+	}
+
+	return result
+}
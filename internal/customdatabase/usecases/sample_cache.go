@@ -0,0 +1,70 @@
+package usecases
+
+import (
+	"sync"
+	"time"
+)
+
+type sample[T any] struct {
+	value     T
+	sampledAt time.Time
+}
+
+// sampleCache stores the last values, that are expensive to receive from Postgresql. They are refreshed once per
+// period, not on every sync.
+type sampleCache[T any] struct {
+	mu      sync.Mutex
+	samples map[string]sample[T]
+}
+
+func newSampleCache[T any]() *sampleCache[T] {
+	return &sampleCache[T]{samples: make(map[string]sample[T])}
+}
+
+// getFresh returns cached value, if it was sampled not earlier than period ago
+func (s *sampleCache[T]) getFresh(key string, now time.Time, period time.Duration) (T, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cached, ok := s.samples[key]
+	if !ok || now.Sub(cached.sampledAt) >= period {
+		var empty T
+		return empty, false
+	}
+
+	return cached.value, true
+}
+
+// get returns cached value regardless of its age
+func (s *sampleCache[T]) get(key string) (T, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cached, ok := s.samples[key]
+
+	return cached.value, ok
+}
+
+func (s *sampleCache[T]) set(key string, value T, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.samples[key] = sample[T]{value: value, sampledAt: now}
+}
+
+// update replaces cached value, but keeps time of sample
+func (s *sampleCache[T]) update(key string, value T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cached, ok := s.samples[key]; ok {
+		s.samples[key] = sample[T]{value: value, sampledAt: cached.sampledAt}
+	}
+}
+
+func (s *sampleCache[T]) delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.samples, key)
+}
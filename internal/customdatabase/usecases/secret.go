@@ -2,6 +2,7 @@ package usecases
 
 import (
 	"fmt"
+	"strconv"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -47,3 +48,23 @@ func secretWithDBInfo(secret *corev1.Secret, dbInfo customdatabase.Entity) *core
 
 	return newSecret
 }
+
+// entityFromSecret restores information about database from Secret, that was created by controller
+func entityFromSecret(secret *corev1.Secret) (customdatabase.Entity, error) {
+	port, err := strconv.Atoi(string(secret.Data[SecretVarDbPort]))
+	if err != nil {
+		return customdatabase.Entity{}, fmt.Errorf("secret %s has wrong %s: %w", secret.Name, SecretVarDbPort, err)
+	}
+
+	return customdatabase.Entity{
+		Host: customdatabase.Host{
+			Name: string(secret.Data[SecretVarDbHost]),
+			Port: port,
+		},
+		Database: customdatabase.Database{
+			Name:     string(secret.Data[SecretVarDbName]),
+			User:     string(secret.Data[SecretVarDbUserName]),
+			Password: string(secret.Data[SecretVarDbPassword]),
+		},
+	}, nil
+}
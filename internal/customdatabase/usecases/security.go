@@ -0,0 +1,100 @@
+package usecases
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/custom-database/internal/customdatabase"
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+)
+
+const (
+	defaultSecurityAuditPeriod = 10 * time.Minute
+
+	// SecurityDrift is used as part of the Event 'reason' when privileges of database differ from least-privilege
+	// defaults
+	SecurityDrift = "SecurityDrift"
+	// AdoptedNotHardened is used as part of the condition 'reason' when privileges of adopted database differ from
+	// least-privilege defaults and aren't repaired without annotation AnnotationAdoptManage
+	AdoptedNotHardened = "AdoptedNotHardened"
+)
+
+// actualizeDatabaseSecurity hardens newly created database and periodically audits privileges of existing one.
+// Drift found by audit is reported and repaired, drift of adopted database is only reported by default.
+func (c *Controller) actualizeDatabaseSecurity(
+	ctx context.Context, customDatabaseReq *v1.CustomDatabase, customDatabase customdatabase.Entity,
+	isDatabaseCreated bool, newStatus *v1.CustomDatabaseStatus,
+) error {
+	logger := loggerFromHandlerContext(ctx)
+	key := customDatabaseReq.Namespace + "/" + customDatabaseReq.Name
+	database, owner := customDatabase.Database.Name, customDatabase.Database.User
+
+	now := c.clock.Now()
+	if _, ok := c.securityAudits.getFresh(key, now, c.securityAuditPeriod); ok && !isDatabaseCreated {
+		return nil
+	}
+
+	// hardening and audit connect to database
+	if err := c.beforeOwnConnection(ctx, customDatabaseReq, database, newStatus); err != nil {
+		return err
+	}
+
+	if isDatabaseCreated {
+		if err := c.databaseManager.HardenDatabase(ctx, database, owner); err != nil {
+			return err
+		}
+	}
+
+	findings, err := c.databaseManager.AuditDatabase(ctx, database, owner)
+	if err != nil {
+		return err
+	}
+
+	if len(findings) > 0 && isAdoptedUnmanaged(customDatabaseReq, newStatus) {
+		c.securityAudits.set(key, findings, now)
+		securityAuditFindings.WithLabelValues(customDatabaseReq.Namespace, customDatabaseReq.Name).Set(float64(len(findings)))
+
+		message := "Privileges of adopted database drift from least-privilege defaults: " + strings.Join(findings, "; ")
+		if !isConditionReason(customDatabaseReq.Status.Conditions, v1.ConditionHardened, AdoptedNotHardened) {
+			logger.Info("Security drift found in adopted database, report it", "findings", findings)
+			c.recorder.Event(customDatabaseReq, corev1.EventTypeWarning, SecurityDrift, message)
+		}
+		c.setCondition(newStatus, customDatabaseReq, v1.ConditionHardened, metav1.ConditionFalse, AdoptedNotHardened,
+			message+". Set annotation "+v1.AnnotationAdoptManage+" to \"true\" to harden it",
+		)
+		return nil
+	}
+
+	if len(findings) > 0 {
+		message := "Privileges drift from least-privilege defaults: " + strings.Join(findings, "; ")
+		logger.Info("Security drift found, repair it", "findings", findings)
+		c.recorder.Event(customDatabaseReq, corev1.EventTypeWarning, SecurityDrift, message)
+
+		if err = c.databaseManager.HardenDatabase(ctx, database, owner); err != nil {
+			return err
+		}
+
+		findings, err = c.databaseManager.AuditDatabase(ctx, database, owner)
+		if err != nil {
+			return err
+		}
+	}
+	c.securityAudits.set(key, findings, now)
+	securityAuditFindings.WithLabelValues(customDatabaseReq.Namespace, customDatabaseReq.Name).Set(float64(len(findings)))
+
+	if len(findings) > 0 {
+		c.setCondition(newStatus, customDatabaseReq, v1.ConditionHardened, metav1.ConditionFalse,
+			SecurityDrift, "Privileges can't be repaired: "+strings.Join(findings, "; "),
+		)
+		return nil
+	}
+
+	c.setCondition(newStatus, customDatabaseReq, v1.ConditionHardened, metav1.ConditionTrue,
+		"LeastPrivilege", "Privileges of database match least-privilege defaults",
+	)
+	return nil
+}
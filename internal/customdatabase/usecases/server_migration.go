@@ -0,0 +1,597 @@
+package usecases
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+
+	"k8s.io/custom-database/internal/customdatabase"
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+)
+
+const (
+	defaultServerGracePeriod = 24 * time.Hour
+
+	// ServerMigrationStarted is used as part of the Event 'reason' when database starts moving to another server
+	ServerMigrationStarted = "ServerMigrationStarted"
+	// ServerSwitched is used as part of the Event 'reason' when Secret is switched to the new server
+	ServerSwitched = "ServerSwitched"
+	// ServerMigrated is used as part of the Event 'reason' when database on the old server is dropped
+	ServerMigrated = "ServerMigrated"
+	// ServerMigrationCancelled is used as part of the Event 'reason' when spec.serverRef is changed during migration
+	ServerMigrationCancelled = "ServerMigrationCancelled"
+	// ServerMigrationFailed is used as part of the Event 'reason' when database can't be moved to another server
+	ServerMigrationFailed = "ServerMigrationFailed"
+)
+
+// serverHost returns host of server by its name
+func (c *Controller) serverHost(server string) (customdatabase.Host, bool) {
+	if server == c.defaultServer {
+		return c.domainService.Host(), true
+	}
+
+	host, ok := c.servers[server]
+	return host, ok
+}
+
+// serverNames returns names of all servers of controller
+func (c *Controller) serverNames() []string {
+	names := []string{c.defaultServer}
+	for name := range c.servers {
+		if name != c.defaultServer {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// desiredServer returns server of spec.serverRef
+func (c *Controller) desiredServer(customDatabaseReq *v1.CustomDatabase) string {
+	if customDatabaseReq.Spec.ServerRef != "" {
+		return customDatabaseReq.Spec.ServerRef
+	}
+
+	return c.defaultServer
+}
+
+// serverOfCustomDatabase returns server, where database of CustomDatabase lives now. Database of CustomDatabase, which
+// was provisioned before its server was stored in status, is found by host of Secret. Database without Secret isn't
+// provisioned yet, it's created on the desired server.
+func (c *Controller) serverOfCustomDatabase(customDatabaseReq *v1.CustomDatabase, storedSecret *corev1.Secret) string {
+	if customDatabaseReq.Status.Server != "" {
+		return customDatabaseReq.Status.Server
+	}
+	if storedSecret == nil {
+		return c.desiredServer(customDatabaseReq)
+	}
+
+	if owner, err := entityFromSecret(storedSecret); err == nil {
+		for _, name := range c.serverNames() {
+			if host, _ := c.serverHost(name); host == owner.Host {
+				return name
+			}
+		}
+	}
+
+	return c.defaultServer
+}
+
+// databaseNameOfCustomDatabase returns name of existing database of CustomDatabase: it's kept in status, and Secret of
+// database, which was created before name was kept in status, contains it. Empty name means new database, which is
+// named by naming strategy.
+func databaseNameOfCustomDatabase(customDatabaseReq *v1.CustomDatabase, storedSecret *corev1.Secret) string {
+	if customDatabaseReq.Status.DatabaseName != "" {
+		return customDatabaseReq.Status.DatabaseName
+	}
+	if storedSecret == nil {
+		return ""
+	}
+
+	return string(storedSecret.Data[SecretVarDbName])
+}
+
+// serversOfCustomDatabase returns servers, where database of CustomDatabase may live: the current one and servers of
+// migration
+func (c *Controller) serversOfCustomDatabase(customDatabaseReq *v1.CustomDatabase) []string {
+	servers := []string{c.desiredServer(customDatabaseReq)}
+	if server := customDatabaseReq.Status.Server; server != "" {
+		servers = append(servers, server)
+	} else {
+		servers = append(servers, c.defaultServer)
+	}
+	if migration := customDatabaseReq.Status.ServerMigration; migration != nil {
+		servers = append(servers, migration.Source, migration.Target)
+	}
+
+	return servers
+}
+
+// syncServerMigration moves database to server of spec.serverRef. Every phase is stored in status before the next one
+// starts, so migration is resumed after restart of controller. It returns true, while database is being moved and
+// mustn't be provisioned on its current server. Database on the old server is kept closed for grace period after
+// Secret is switched, database is provisioned on the new server meanwhile.
+func (c *Controller) syncServerMigration(
+	ctx context.Context, customDatabaseReq *v1.CustomDatabase, customDatabase customdatabase.Entity,
+	storedSecret *corev1.Secret, newStatus *v1.CustomDatabaseStatus,
+) (bool, error) {
+	desired := c.desiredServer(customDatabaseReq)
+	migration := newStatus.ServerMigration
+
+	if migration != nil && migration.Phase == v1.ServerMigrationPhaseDraining {
+		if migration.Target == desired {
+			return false, c.drainServerMigration(ctx, customDatabaseReq, customDatabase, newStatus)
+		}
+		// database on the old server is left for orphan sweeper or reused, when it's moved back
+		migration = nil
+		newStatus.ServerMigration = nil
+	}
+
+	if migration == nil && customDatabase.Server == desired {
+		meta.RemoveStatusCondition(&newStatus.Conditions, v1.ConditionServerMigrating)
+		return false, nil
+	}
+	// password of owner is known only from Secret, database is provisioned on its current server first
+	if storedSecret == nil {
+		return false, nil
+	}
+
+	if migration == nil {
+		return c.startServerMigration(customDatabaseReq, customDatabase.Server, desired, newStatus), nil
+	}
+	if migration.Target != desired && migration.Phase != v1.ServerMigrationPhaseSwitching {
+		return true, c.cancelServerMigration(ctx, customDatabaseReq, customDatabase, newStatus)
+	}
+
+	owner, err := entityFromSecret(storedSecret)
+	if err != nil {
+		return true, err
+	}
+	owner.Database.Options = customDatabase.Database.Options
+	owner.Database.Limits = customDatabase.Database.Limits
+
+	switch migration.Phase {
+	case v1.ServerMigrationPhaseProvisioning:
+		return true, c.provisionServerMigrationTarget(ctx, customDatabaseReq, owner.Database, newStatus)
+	case v1.ServerMigrationPhaseCopying:
+		return true, c.copyToServerMigrationTarget(ctx, customDatabaseReq, owner.Database, newStatus)
+	case v1.ServerMigrationPhaseSwitching:
+		return true, c.switchToServerMigrationTarget(ctx, customDatabaseReq, storedSecret, newStatus)
+	default:
+		utilruntime.HandleError(fmt.Errorf("%s: unknown phase of server migration %q", customDatabaseReq.Name,
+			migration.Phase,
+		))
+		return true, nil
+	}
+}
+
+// startServerMigration stores migration in status, objects on target server are created by the next sync. Migration
+// is disabled, when controller can't copy databases, then database stays on its current server.
+func (c *Controller) startServerMigration(
+	customDatabaseReq *v1.CustomDatabase, source, target string, newStatus *v1.CustomDatabaseStatus,
+) bool {
+	if c.dumper == nil || c.restorer == nil {
+		c.setCondition(newStatus, customDatabaseReq, v1.ConditionServerMigrating, metav1.ConditionFalse,
+			"MigrationDisabled", fmt.Sprintf("Database can't be moved from server %s to server %s, migration "+
+				"between servers is disabled in controller", source, target),
+		)
+		return false
+	}
+
+	newStatus.ServerMigration = &v1.ServerMigrationStatus{
+		Source:    source,
+		Target:    target,
+		Phase:     v1.ServerMigrationPhaseProvisioning,
+		StartedAt: metav1.NewTime(c.clock.Now()),
+	}
+	message := fmt.Sprintf("Database is moved from server %s to server %s", source, target)
+	c.setCondition(newStatus, customDatabaseReq, v1.ConditionServerMigrating, metav1.ConditionTrue,
+		string(v1.ServerMigrationPhaseProvisioning), message,
+	)
+	c.recorder.Event(customDatabaseReq, corev1.EventTypeNormal, ServerMigrationStarted, message)
+
+	return true
+}
+
+// provisionServerMigrationTarget creates role and empty database on target server. Objects, which were created by
+// interrupted attempt or left by previous migration from target server, are reused.
+func (c *Controller) provisionServerMigrationTarget(
+	ctx context.Context, customDatabaseReq *v1.CustomDatabase, database customdatabase.Database,
+	newStatus *v1.CustomDatabaseStatus,
+) error {
+	migration := newStatus.ServerMigration
+	ctx = customdatabase.ContextWithServer(ctx, migration.Target)
+	marker := c.ownershipMarker(customDatabaseReq)
+
+	databaseOwnership, err := c.databaseManager.GetDatabaseOwnership(ctx, database.Name)
+	if err != nil {
+		return err
+	}
+	userOwnership, err := c.databaseManager.GetUserOwnership(ctx, database.User)
+	if err != nil {
+		return err
+	}
+	if !databaseOwnership.IsOwnedBy(marker) || !userOwnership.IsOwnedBy(marker) {
+		message := fmt.Sprintf("Database %s or role %s already exists on server %s and doesn't belong to "+
+			"CustomDatabase", database.Name, database.User, migration.Target,
+		)
+		if !isServerMigrationFailed(customDatabaseReq.Status.Conditions, "TargetNotOwned") {
+			c.recorder.Event(customDatabaseReq, corev1.EventTypeWarning, ServerMigrationFailed, message)
+		}
+		c.setCondition(newStatus, customDatabaseReq, v1.ConditionServerMigrating, metav1.ConditionFalse,
+			"TargetNotOwned", message,
+		)
+		return nil
+	}
+
+	err = c.databaseManager.CreateUser(ctx, database.User, database.Password, marker)
+	if err == customdatabase.ErrUserAlreadyExists {
+		err = c.databaseManager.ChangeUserPassword(ctx, database.User, database.Password)
+	}
+	if err != nil {
+		return err
+	}
+
+	// dump of another server may be restored only into empty database, which is copied from template0
+	options := database.Options
+	options.Template = "template0"
+	err = c.databaseManager.CreateDatabase(ctx, database.Name, options, marker)
+	if err != nil && err != customdatabase.ErrDatabaseAlreadyExists {
+		return err
+	}
+	if err = c.databaseManager.GrantUserToDatabase(ctx, database.User, database.Name); err != nil {
+		return err
+	}
+	if err = c.databaseManager.SetDatabaseLimits(ctx, database.Name, database.Limits); err != nil {
+		return err
+	}
+	// database, which is left by previous migration from target server, may be read-only, older versions of controller
+	// blocked writes by default_transaction_read_only
+	if err = c.databaseManager.SetDatabaseReadOnly(ctx, database.Name, false); err != nil {
+		return err
+	}
+
+	migration.Phase = v1.ServerMigrationPhaseCopying
+	c.setCondition(newStatus, customDatabaseReq, v1.ConditionServerMigrating, metav1.ConditionTrue,
+		string(v1.ServerMigrationPhaseCopying), fmt.Sprintf("Connections to database on server %s are blocked, "+
+			"database is copied to server %s", migration.Source, migration.Target),
+	)
+
+	return nil
+}
+
+// serverCopyResult result of copy of database to another server, nil error means that copy is complete
+type serverCopyResult struct {
+	err error
+}
+
+// copyToServerMigrationTarget closes database on source server and restores its dump on target server. Copy is made
+// in background, the next sync takes its result. Tenant can't connect to closed database: CONNECT is revoked from all
+// roles and password of owner is replaced by password of copy, so only dump connects to it. Objects of interrupted
+// attempt are replaced by restore. Database is opened again, when copy fails.
+func (c *Controller) copyToServerMigrationTarget(
+	ctx context.Context, customDatabaseReq *v1.CustomDatabase, database customdatabase.Database,
+	newStatus *v1.CustomDatabaseStatus,
+) error {
+	logger := loggerFromHandlerContext(ctx)
+	key := customDatabaseReq.Namespace + "/" + customDatabaseReq.Name
+	migration := newStatus.ServerMigration
+	sourceCtx := customdatabase.ContextWithServer(ctx, migration.Source)
+	targetCtx := customdatabase.ContextWithServer(ctx, migration.Target)
+
+	if c.serverCopies.isRunning(key) {
+		return nil
+	}
+	if result, ok := c.serverCopyResults.LoadAndDelete(key); ok {
+		if err := result.(serverCopyResult).err; err != nil {
+			c.recorder.Event(customDatabaseReq, corev1.EventTypeWarning, ServerMigrationFailed, fmt.Sprintf(
+				"Database can't be copied to server %s, it's retried: %v", migration.Target, err,
+			))
+			if openErr := c.openServerMigrationSource(sourceCtx, customDatabaseReq, database); openErr != nil {
+				return fmt.Errorf("%w, database stays closed: %v", err, openErr)
+			}
+			return err
+		}
+
+		migration.Phase = v1.ServerMigrationPhaseSwitching
+		c.setCondition(newStatus, customDatabaseReq, v1.ConditionServerMigrating, metav1.ConditionTrue,
+			string(v1.ServerMigrationPhaseSwitching), fmt.Sprintf("Database is copied to server %s, Secret is "+
+				"switched to it", migration.Target),
+		)
+		return nil
+	}
+
+	sourceHost, ok := c.serverHost(migration.Source)
+	if !ok {
+		return fmt.Errorf("source server %q of migration isn't configured", migration.Source)
+	}
+	targetHost, _ := c.serverHost(migration.Target)
+
+	// password of copy is known only to controller, so tenant can't connect as owner during copy
+	copyPassword, err := customdatabase.NewPassword()
+	if err != nil {
+		return err
+	}
+	if err = c.databaseManager.ChangeUserPassword(sourceCtx, database.User, copyPassword); err != nil {
+		return err
+	}
+	// existing sessions are terminated, so nothing is written after dump is started
+	if err = c.databaseManager.RevokeDatabaseConnect(sourceCtx, database.Name); err != nil {
+		return err
+	}
+	if err = c.databaseManager.GrantUserToDatabase(sourceCtx, database.User, database.Name); err != nil {
+		return err
+	}
+	// Secret may be recreated with new password after target was provisioned
+	if err = c.databaseManager.ChangeUserPassword(targetCtx, database.User, database.Password); err != nil {
+		return err
+	}
+
+	logger.Info("Copy database to another server", "source", migration.Source, "target", migration.Target)
+	source := database
+	source.Password = copyPassword
+	c.serverCopies.start(ctx, key, func(copyCtx context.Context) {
+		err := c.copyDatabase(copyCtx, sourceHost, targetHost, source, database)
+		if copyCtx.Err() != nil {
+			// migration was cancelled or controller is stopped, copy is repeated by the next sync
+			logger.Info("Copy of database was cancelled", "target", migration.Target)
+			return
+		}
+
+		c.serverCopyResults.Store(key, serverCopyResult{err: err})
+		c.workqueue.Add(key)
+	})
+
+	return nil
+}
+
+// openServerMigrationSource allows connections to database on source server again: password of owner is restored
+// from Secret and CONNECT is granted to owner and roles of DatabaseUsers and DatabaseGrants
+func (c *Controller) openServerMigrationSource(
+	ctx context.Context, customDatabaseReq *v1.CustomDatabase, database customdatabase.Database,
+) error {
+	if err := c.databaseManager.ChangeUserPassword(ctx, database.User, database.Password); err != nil {
+		return err
+	}
+
+	roles, err := c.managedRolesOfCustomDatabase(ctx, customDatabaseReq)
+	if err != nil {
+		return err
+	}
+	for _, role := range append([]string{database.User}, roles...) {
+		if err = c.databaseManager.GrantUserToDatabase(ctx, role, database.Name); err != nil {
+			return err
+		}
+	}
+	// findings of closed database mustn't be reported after it's opened
+	c.driftChecks.delete(customDatabaseReq.Namespace + "/" + customDatabaseReq.Name)
+
+	return nil
+}
+
+// copyDatabase streams dump of database on source server into restore on target server. Credentials of source and
+// target database differ during migration.
+func (c *Controller) copyDatabase(
+	ctx context.Context, sourceHost, targetHost customdatabase.Host, source, target customdatabase.Database,
+) error {
+	dump, dumpWriter := io.Pipe()
+	dumpErr := make(chan error, 1)
+	go func() {
+		err := c.dumper.DumpDatabase(ctx, sourceHost, source, dumpWriter)
+		_ = dumpWriter.CloseWithError(err)
+		dumpErr <- err
+	}()
+
+	restoreErr := c.restorer.RestoreDatabase(ctx, targetHost, target, dump, true)
+	// dump is interrupted, when restore fails
+	_ = dump.Close()
+	err := <-dumpErr
+	switch {
+	case restoreErr != nil && err != nil:
+		return fmt.Errorf("%w, dump: %v", restoreErr, err)
+	case restoreErr != nil:
+		return restoreErr
+	default:
+		return err
+	}
+}
+
+// switchToServerMigrationTarget points Secret to target server, database on source server stays closed until grace
+// period expires
+func (c *Controller) switchToServerMigrationTarget(
+	ctx context.Context, customDatabaseReq *v1.CustomDatabase, storedSecret *corev1.Secret,
+	newStatus *v1.CustomDatabaseStatus,
+) error {
+	logger := loggerFromHandlerContext(ctx)
+	migration := newStatus.ServerMigration
+	targetHost, _ := c.serverHost(migration.Target)
+
+	newSecret := storedSecret.DeepCopy()
+	newSecret.Data[SecretVarDbHost] = []byte(targetHost.Name)
+	newSecret.Data[SecretVarDbPort] = []byte(strconv.Itoa(targetHost.Port))
+	if string(storedSecret.Data[SecretVarDbHost]) != targetHost.Name ||
+		string(storedSecret.Data[SecretVarDbPort]) != strconv.Itoa(targetHost.Port) {
+		logger.Info("Switch Secret to another server", "secretName", newSecret.Name, "server", migration.Target)
+		_, err := c.kubeclientset.CoreV1().Secrets(newSecret.Namespace).Update(ctx, newSecret, metav1.UpdateOptions{})
+		if err != nil {
+			return err
+		}
+	}
+
+	now := c.clock.Now()
+	switchedAt := metav1.NewTime(now)
+	migration.SwitchedAt = &switchedAt
+	migration.Phase = v1.ServerMigrationPhaseDraining
+	newStatus.Server = migration.Target
+
+	dropAt := now.Add(c.serverGracePeriod)
+	message := fmt.Sprintf("Database lives on server %s, closed database on server %s is dropped at %s",
+		migration.Target, migration.Source, dropAt.UTC().Format(time.RFC3339),
+	)
+	c.setCondition(newStatus, customDatabaseReq, v1.ConditionServerMigrating, metav1.ConditionTrue,
+		string(v1.ServerMigrationPhaseDraining), message,
+	)
+	c.recorder.Event(customDatabaseReq, corev1.EventTypeNormal, ServerSwitched, message)
+	c.workqueue.AddAfter(customDatabaseReq.Namespace+"/"+customDatabaseReq.Name, dropAt.Sub(now))
+
+	return nil
+}
+
+// drainServerMigration drops database and roles on source server, when grace period after switch expires
+func (c *Controller) drainServerMigration(
+	ctx context.Context, customDatabaseReq *v1.CustomDatabase, customDatabase customdatabase.Entity,
+	newStatus *v1.CustomDatabaseStatus,
+) error {
+	migration := newStatus.ServerMigration
+	now := c.clock.Now()
+	if migration.SwitchedAt != nil {
+		if dropAt := migration.SwitchedAt.Add(c.serverGracePeriod); now.Before(dropAt) {
+			c.workqueue.AddAfter(customDatabaseReq.Namespace+"/"+customDatabaseReq.Name, dropAt.Sub(now))
+			return nil
+		}
+	}
+
+	logger := loggerFromHandlerContext(ctx)
+	logger.Info("Drop database on the old server", "server", migration.Source)
+	err := c.dropFromServer(customdatabase.ContextWithServer(ctx, migration.Source), customDatabaseReq, customDatabase)
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("Database is moved from server %s to server %s", migration.Source, migration.Target)
+	c.recorder.Event(customDatabaseReq, corev1.EventTypeNormal, ServerMigrated, message)
+	newStatus.ServerMigration = nil
+	meta.RemoveStatusCondition(&newStatus.Conditions, v1.ConditionServerMigrating)
+
+	return nil
+}
+
+// cancelServerMigration opens database on source server again and drops objects on target server, when
+// spec.serverRef is changed before Secret is switched
+func (c *Controller) cancelServerMigration(
+	ctx context.Context, customDatabaseReq *v1.CustomDatabase, customDatabase customdatabase.Entity,
+	newStatus *v1.CustomDatabaseStatus,
+) error {
+	migration := newStatus.ServerMigration
+	database := customDatabase.Database.Name
+	key := customDatabaseReq.Namespace + "/" + customDatabaseReq.Name
+
+	// copy can't be finished, its restore is replaced by drop of target
+	c.serverCopies.cancel(key)
+	c.serverCopyResults.Delete(key)
+
+	sourceCtx := customdatabase.ContextWithServer(ctx, migration.Source)
+	ownership, err := c.databaseManager.GetDatabaseOwnership(sourceCtx, database)
+	if err != nil {
+		return err
+	}
+	if ownership.Exists && migration.Phase == v1.ServerMigrationPhaseCopying {
+		if err = c.openServerMigrationSource(sourceCtx, customDatabaseReq, customDatabase.Database); err != nil {
+			return err
+		}
+	}
+
+	// target may be removed from configuration of controller together with spec.serverRef
+	if _, ok := c.serverHost(migration.Target); ok {
+		err = c.dropFromServer(customdatabase.ContextWithServer(ctx, migration.Target), customDatabaseReq,
+			customDatabase,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	message := fmt.Sprintf("Migration to server %s is cancelled, database stays on server %s", migration.Target,
+		migration.Source,
+	)
+	c.recorder.Event(customDatabaseReq, corev1.EventTypeNormal, ServerMigrationCancelled, message)
+	newStatus.ServerMigration = nil
+	meta.RemoveStatusCondition(&newStatus.Conditions, v1.ConditionServerMigrating)
+
+	return nil
+}
+
+// dropFromServer drops database and role of CustomDatabase and roles of its users and grants on server from context.
+// Objects, which don't belong to them, are left on server.
+func (c *Controller) dropFromServer(
+	ctx context.Context, customDatabaseReq *v1.CustomDatabase, customDatabase customdatabase.Entity,
+) error {
+	namespace := customDatabaseReq.Namespace
+	marker := c.ownershipMarker(customDatabaseReq)
+	database := customDatabase.Database.Name
+
+	ownership, err := c.databaseManager.GetDatabaseOwnership(ctx, database)
+	if err != nil {
+		return err
+	}
+	if ownership.Exists && ownership.IsOwnedBy(marker) {
+		// sessions are terminated, otherwise database can't be dropped
+		if err = c.databaseManager.RevokeDatabaseConnect(ctx, database); err != nil {
+			return err
+		}
+	}
+
+	roles := map[string]customdatabase.OwnershipMarker{customDatabase.Database.User: marker}
+	databaseUsers, err := c.sampleclientset.IgorV1().DatabaseUsers(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, item := range databaseUsers.Items {
+		if item.Spec.CustomDatabaseName == customDatabaseReq.Name && item.Status.UserName != "" {
+			roles[item.Status.UserName] = c.domainService.NewOwnershipMarker(customdatabase.OwnerKindDatabaseUser,
+				namespace, item.Name, string(item.UID),
+			)
+		}
+	}
+	databaseGrants, err := c.sampleclientset.IgorV1().DatabaseGrants(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, item := range databaseGrants.Items {
+		if item.Spec.CustomDatabaseName == customDatabaseReq.Name && item.Status.UserName != "" {
+			roles[item.Status.UserName] = c.domainService.NewOwnershipMarker(customdatabase.OwnerKindDatabaseGrant,
+				namespace, item.Name, string(item.UID),
+			)
+		}
+	}
+
+	// database is dropped before roles, because role can't be dropped while it owns database
+	err = c.databaseManager.DropDatabase(ctx, database, marker)
+	if stderrors.Is(err, customdatabase.ErrNotOwned) {
+		utilruntime.HandleError(fmt.Errorf("%s/%s: database isn't dropped: %w", namespace, customDatabaseReq.Name, err))
+	} else if err != nil {
+		return err
+	}
+
+	userNames := make([]string, 0, len(roles))
+	for userName := range roles {
+		userNames = append(userNames, userName)
+	}
+	sort.Strings(userNames)
+	for _, userName := range userNames {
+		err = c.databaseManager.DropUser(ctx, userName, roles[userName])
+		if stderrors.Is(err, customdatabase.ErrNotOwned) {
+			utilruntime.HandleError(fmt.Errorf("%s/%s: role isn't dropped: %w", namespace, customDatabaseReq.Name, err))
+		} else if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isServerMigrationFailed reports whether migration is already stopped for the same reason, so event isn't repeated
+func isServerMigrationFailed(conditions []metav1.Condition, reason string) bool {
+	condition := meta.FindStatusCondition(conditions, v1.ConditionServerMigrating)
+	return condition != nil && condition.Status == metav1.ConditionFalse && condition.Reason == reason
+}
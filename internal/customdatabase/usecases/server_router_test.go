@@ -0,0 +1,300 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/custom-database/internal/customdatabase"
+)
+
+// serverRouter routes calls to fake database manager of server from context like router.DbManager, which can't be
+// imported by tests of usecases
+type serverRouter struct {
+	defaultServer string
+	servers       map[string]DatabaseManager
+}
+
+func (m *serverRouter) server(ctx context.Context) (DatabaseManager, error) {
+	name := customdatabase.ServerFromContext(ctx)
+	if name == "" {
+		name = m.defaultServer
+	}
+
+	server, ok := m.servers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown server %q", name)
+	}
+
+	return server, nil
+}
+
+func (m *serverRouter) CreateDatabase(
+	ctx context.Context, database string, options customdatabase.DatabaseOptions, marker customdatabase.OwnershipMarker,
+) error {
+	server, err := m.server(ctx)
+	if err != nil {
+		return err
+	}
+
+	return server.CreateDatabase(ctx, database, options, marker)
+}
+
+func (m *serverRouter) CloneDatabase(
+	ctx context.Context, database string, source customdatabase.Database, options customdatabase.DatabaseOptions,
+	marker customdatabase.OwnershipMarker,
+) error {
+	server, err := m.server(ctx)
+	if err != nil {
+		return err
+	}
+
+	return server.CloneDatabase(ctx, database, source, options, marker)
+}
+
+func (m *serverRouter) DropDatabase(ctx context.Context, database string, owner customdatabase.OwnershipMarker) error {
+	server, err := m.server(ctx)
+	if err != nil {
+		return err
+	}
+
+	return server.DropDatabase(ctx, database, owner)
+}
+
+func (m *serverRouter) CreateUser(
+	ctx context.Context, userName, password string, marker customdatabase.OwnershipMarker,
+) error {
+	server, err := m.server(ctx)
+	if err != nil {
+		return err
+	}
+
+	return server.CreateUser(ctx, userName, password, marker)
+}
+
+func (m *serverRouter) ChangeUserPassword(ctx context.Context, userName, password string) error {
+	server, err := m.server(ctx)
+	if err != nil {
+		return err
+	}
+
+	return server.ChangeUserPassword(ctx, userName, password)
+}
+
+func (m *serverRouter) DropUser(ctx context.Context, userName string, owner customdatabase.OwnershipMarker) error {
+	server, err := m.server(ctx)
+	if err != nil {
+		return err
+	}
+
+	return server.DropUser(ctx, userName, owner)
+}
+
+func (m *serverRouter) GrantUserToDatabase(ctx context.Context, userName, database string) error {
+	server, err := m.server(ctx)
+	if err != nil {
+		return err
+	}
+
+	return server.GrantUserToDatabase(ctx, userName, database)
+}
+
+func (m *serverRouter) SetDatabaseLimits(ctx context.Context, database string, limits customdatabase.Limits) error {
+	server, err := m.server(ctx)
+	if err != nil {
+		return err
+	}
+
+	return server.SetDatabaseLimits(ctx, database, limits)
+}
+
+func (m *serverRouter) GetDatabaseLimits(ctx context.Context, database string) (customdatabase.Limits, error) {
+	server, err := m.server(ctx)
+	if err != nil {
+		return customdatabase.Limits{}, err
+	}
+
+	return server.GetDatabaseLimits(ctx, database)
+}
+
+func (m *serverRouter) GetDatabaseSize(ctx context.Context, database string) (int64, error) {
+	server, err := m.server(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return server.GetDatabaseSize(ctx, database)
+}
+
+func (m *serverRouter) SetDatabaseReadOnly(ctx context.Context, database string, readOnly bool) error {
+	server, err := m.server(ctx)
+	if err != nil {
+		return err
+	}
+
+	return server.SetDatabaseReadOnly(ctx, database, readOnly)
+}
+
+func (m *serverRouter) HardenDatabase(ctx context.Context, database, owner string) error {
+	server, err := m.server(ctx)
+	if err != nil {
+		return err
+	}
+
+	return server.HardenDatabase(ctx, database, owner)
+}
+
+func (m *serverRouter) AuditDatabase(ctx context.Context, database, owner string) ([]string, error) {
+	server, err := m.server(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return server.AuditDatabase(ctx, database, owner)
+}
+
+func (m *serverRouter) DetectDrift(ctx context.Context, database customdatabase.Database) ([]string, error) {
+	server, err := m.server(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return server.DetectDrift(ctx, database)
+}
+
+func (m *serverRouter) RepairDrift(ctx context.Context, database customdatabase.Database) error {
+	server, err := m.server(ctx)
+	if err != nil {
+		return err
+	}
+
+	return server.RepairDrift(ctx, database)
+}
+
+func (m *serverRouter) ApplyUserPrivileges(
+	ctx context.Context, database customdatabase.Database, user customdatabase.DatabaseUser,
+) error {
+	server, err := m.server(ctx)
+	if err != nil {
+		return err
+	}
+
+	return server.ApplyUserPrivileges(ctx, database, user)
+}
+
+func (m *serverRouter) RevokeUserPrivileges(
+	ctx context.Context, database customdatabase.Database, user customdatabase.DatabaseUser,
+) error {
+	server, err := m.server(ctx)
+	if err != nil {
+		return err
+	}
+
+	return server.RevokeUserPrivileges(ctx, database, user)
+}
+
+func (m *serverRouter) GetDatabaseOwnership(ctx context.Context, database string) (customdatabase.Ownership, error) {
+	server, err := m.server(ctx)
+	if err != nil {
+		return customdatabase.Ownership{}, err
+	}
+
+	return server.GetDatabaseOwnership(ctx, database)
+}
+
+func (m *serverRouter) GetUserOwnership(ctx context.Context, userName string) (customdatabase.Ownership, error) {
+	server, err := m.server(ctx)
+	if err != nil {
+		return customdatabase.Ownership{}, err
+	}
+
+	return server.GetUserOwnership(ctx, userName)
+}
+
+func (m *serverRouter) ListDatabaseOwnership(ctx context.Context) (map[string]customdatabase.OwnershipMarker, error) {
+	server, err := m.server(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return server.ListDatabaseOwnership(ctx)
+}
+
+func (m *serverRouter) ListUserOwnership(ctx context.Context) (map[string]customdatabase.OwnershipMarker, error) {
+	server, err := m.server(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return server.ListUserOwnership(ctx)
+}
+
+func (m *serverRouter) SetDatabaseOwnership(
+	ctx context.Context, database string, marker customdatabase.OwnershipMarker,
+) error {
+	server, err := m.server(ctx)
+	if err != nil {
+		return err
+	}
+
+	return server.SetDatabaseOwnership(ctx, database, marker)
+}
+
+func (m *serverRouter) SetUserOwnership(
+	ctx context.Context, userName string, marker customdatabase.OwnershipMarker,
+) error {
+	server, err := m.server(ctx)
+	if err != nil {
+		return err
+	}
+
+	return server.SetUserOwnership(ctx, userName, marker)
+}
+
+func (m *serverRouter) GetDatabaseActivity(ctx context.Context, database string) (customdatabase.DatabaseActivity, error) {
+	server, err := m.server(ctx)
+	if err != nil {
+		return customdatabase.DatabaseActivity{}, err
+	}
+
+	return server.GetDatabaseActivity(ctx, database)
+}
+
+func (m *serverRouter) RevokeDatabaseConnect(ctx context.Context, database string) error {
+	server, err := m.server(ctx)
+	if err != nil {
+		return err
+	}
+
+	return server.RevokeDatabaseConnect(ctx, database)
+}
+
+func (m *serverRouter) TerminateDatabaseSessions(ctx context.Context, database string) error {
+	server, err := m.server(ctx)
+	if err != nil {
+		return err
+	}
+
+	return server.TerminateDatabaseSessions(ctx, database)
+}
+
+func (m *serverRouter) RunScript(
+	ctx context.Context, database customdatabase.Database, script customdatabase.InitScript,
+) (string, error) {
+	server, err := m.server(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return server.RunScript(ctx, database, script)
+}
+
+func (m *serverRouter) Migrate(
+	ctx context.Context, database customdatabase.Database, migrations []customdatabase.Migration, target int64,
+) (int64, error) {
+	server, err := m.server(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return server.Migrate(ctx, database, migrations, target)
+}
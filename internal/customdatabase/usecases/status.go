@@ -0,0 +1,65 @@
+package usecases
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+)
+
+// updateCustomDatabaseStatus stores new status of CustomDatabase. Status subresource is updated only if it was changed,
+// otherwise every resync would produce a new version of the resource.
+func (c *Controller) updateCustomDatabaseStatus(
+	ctx context.Context, customDatabase *v1.CustomDatabase, newStatus *v1.CustomDatabaseStatus,
+) error {
+	if equality.Semantic.DeepEqual(customDatabase.Status, *newStatus) {
+		return nil
+	}
+
+	// NEVER modify objects from the store. It's a read-only, local cache.
+	customDatabaseCopy := customDatabase.DeepCopy()
+	customDatabaseCopy.Status = *newStatus
+
+	_, err := c.sampleclientset.IgorV1().CustomDatabases(customDatabase.Namespace).UpdateStatus(
+		ctx, customDatabaseCopy, metav1.UpdateOptions{},
+	)
+
+	return err
+}
+
+// setCondition adds or updates condition in status of CustomDatabase
+func (c *Controller) setCondition(
+	newStatus *v1.CustomDatabaseStatus, customDatabase *v1.CustomDatabase,
+	conditionType string, status metav1.ConditionStatus, reason, message string,
+) {
+	setStatusCondition(
+		&newStatus.Conditions, customDatabase.Generation, c.clock.Now(), conditionType, status, reason, message,
+	)
+}
+
+// setStatusCondition adds or updates condition in list. Transition time is changed only when status of condition
+// changes.
+func setStatusCondition(
+	conditions *[]metav1.Condition, generation int64, now time.Time,
+	conditionType string, status metav1.ConditionStatus, reason, message string,
+) {
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: generation,
+		LastTransitionTime: metav1.NewTime(now),
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// isConditionReason reports whether condition of the given type is set with the given reason
+func isConditionReason(conditions []metav1.Condition, conditionType, reason string) bool {
+	condition := meta.FindStatusCondition(conditions, conditionType)
+
+	return condition != nil && condition.Reason == reason
+}
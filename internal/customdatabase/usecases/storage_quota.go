@@ -0,0 +1,112 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/custom-database/internal/customdatabase"
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+)
+
+const defaultStorageSamplingPeriod = time.Minute
+
+// databaseSize returns cached size of database, if it's sampled not earlier than sampling period ago
+func (c *Controller) databaseSize(ctx context.Context, key, database string) (int64, error) {
+	// pg_database_size walks over all files of database, so we sample it once per sampling period
+	now := c.clock.Now()
+	if size, ok := c.storageSamples.getFresh(key, now, c.storageSamplingPeriod); ok {
+		return size, nil
+	}
+
+	size, err := c.databaseManager.GetDatabaseSize(ctx, database)
+	if err != nil {
+		return 0, err
+	}
+	c.storageSamples.set(key, size, now)
+
+	return size, nil
+}
+
+// actualizeStorageQuota samples database size, reflects it in status and metrics and enforces storage quota
+func (c *Controller) actualizeStorageQuota(
+	ctx context.Context, customDatabaseReq *v1.CustomDatabase, database string, newStatus *v1.CustomDatabaseStatus,
+) error {
+	key := customDatabaseReq.Namespace + "/" + customDatabaseReq.Name
+
+	size, err := c.databaseSize(ctx, key, database)
+	if err != nil {
+		return err
+	}
+	newStatus.Size = resource.NewQuantity(size, resource.BinarySI)
+	databaseSizeBytes.WithLabelValues(customDatabaseReq.Namespace, customDatabaseReq.Name).Set(float64(size))
+
+	if customDatabaseReq.Spec.StorageQuota == nil {
+		meta.RemoveStatusCondition(&newStatus.Conditions, v1.ConditionQuotaExceeded)
+		storageQuotaBytes.DeleteLabelValues(customDatabaseReq.Namespace, customDatabaseReq.Name)
+		storageQuotaExceeded.DeleteLabelValues(customDatabaseReq.Namespace, customDatabaseReq.Name)
+
+		return c.actualizeReadOnlyByQuota(ctx, database, false, newStatus)
+	}
+
+	quota := customdatabase.StorageQuota{
+		Limit:                customDatabaseReq.Spec.StorageQuota.Limit.Value(),
+		ReadOnlyWhenExceeded: customDatabaseReq.Spec.StorageQuota.ReadOnlyWhenExceeded,
+	}
+	isExceeded := quota.IsExceeded(size)
+
+	err = c.actualizeReadOnlyByQuota(ctx, database, isExceeded && quota.ReadOnlyWhenExceeded, newStatus)
+	if err != nil {
+		return err
+	}
+
+	storageQuotaBytes.WithLabelValues(customDatabaseReq.Namespace, customDatabaseReq.Name).Set(float64(quota.Limit))
+	if !isExceeded {
+		storageQuotaExceeded.WithLabelValues(customDatabaseReq.Namespace, customDatabaseReq.Name).Set(0)
+		c.setCondition(newStatus, customDatabaseReq, v1.ConditionQuotaExceeded, metav1.ConditionFalse,
+			"WithinQuota", "Database size is within storage quota",
+		)
+		return nil
+	}
+
+	storageQuotaExceeded.WithLabelValues(customDatabaseReq.Namespace, customDatabaseReq.Name).Set(1)
+	// String caches formatted value inside Quantity, so we format copies and don't touch objects from the store
+	limit := customDatabaseReq.Spec.StorageQuota.Limit.DeepCopy()
+	message := fmt.Sprintf("Database size %s exceeds storage quota %s",
+		resource.NewQuantity(size, resource.BinarySI).String(), limit.String(),
+	)
+	if quota.ReadOnlyWhenExceeded {
+		message += ", database is read-only"
+	}
+
+	if !meta.IsStatusConditionTrue(customDatabaseReq.Status.Conditions, v1.ConditionQuotaExceeded) {
+		c.recorder.Event(customDatabaseReq, corev1.EventTypeWarning, v1.ConditionQuotaExceeded, message)
+	}
+	c.setCondition(newStatus, customDatabaseReq, v1.ConditionQuotaExceeded, metav1.ConditionTrue,
+		v1.ConditionQuotaExceeded, message,
+	)
+
+	return nil
+}
+
+// actualizeReadOnlyByQuota makes database read-only, when its quota is exceeded, and writable again only if it was made
+// read-only by quota, so database, which was made read-only by administrator, stays read-only
+func (c *Controller) actualizeReadOnlyByQuota(
+	ctx context.Context, database string, isReadOnly bool, newStatus *v1.CustomDatabaseStatus,
+) error {
+	if !isReadOnly && !newStatus.ReadOnlyByQuota {
+		return nil
+	}
+
+	if err := c.databaseManager.SetDatabaseReadOnly(ctx, database, isReadOnly); err != nil {
+		return err
+	}
+	newStatus.ReadOnlyByQuota = isReadOnly
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package usecases
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	SecretVarDbSSLMode = "DB_SSLMODE"
+	// SecretKeyCACert CA bundle, which certificate of server is verified by
+	SecretKeyCACert = "ca.crt"
+	// SecretKeyClientCert and SecretKeyClientKey client certificate of role, when it authenticates by certificate
+	SecretKeyClientCert = "tls.crt"
+	SecretKeyClientKey  = "tls.key"
+
+	// ClientCertificateRenewBefore client certificate is issued again, when it expires sooner
+	ClientCertificateRenewBefore = 30 * 24 * time.Hour
+)
+
+// CertificateIssuer issues client certificates, which common name is name of role
+type CertificateIssuer interface {
+	IssueClientCertificate(userName string) (cert, key []byte, err error)
+}
+
+// TenantTLS TLS settings of Postgresql servers, which are published to Secrets of tenants
+type TenantTLS struct {
+	// SSLMode sslmode of libpq, which tenants should use
+	SSLMode string
+	// CACert PEM bundle of CAs, which certificates of servers are verified by, it isn't published, when it's empty
+	CACert []byte
+	// Issuer issues client certificates for roles, roles authenticate only by password without it
+	Issuer CertificateIssuer
+}
+
+// secretWithTLS adds TLS settings and client certificate of role to Secret. Client certificate of stored Secret is
+// kept, until it expires or belongs to another role.
+func secretWithTLS(
+	secret, storedSecret *corev1.Secret, tenantTLS *TenantTLS, userName string, now time.Time,
+) (*corev1.Secret, error) {
+	if tenantTLS == nil {
+		return secret, nil
+	}
+
+	newSecret := secret.DeepCopy()
+	if newSecret.Data == nil {
+		newSecret.Data = make(map[string][]byte)
+	}
+	newSecret.Data[SecretVarDbSSLMode] = []byte(tenantTLS.SSLMode)
+	if len(tenantTLS.CACert) > 0 {
+		newSecret.Data[SecretKeyCACert] = tenantTLS.CACert
+	}
+	if tenantTLS.Issuer == nil {
+		return newSecret, nil
+	}
+
+	if storedSecret != nil && isClientCertificateValid(storedSecret.Data[SecretKeyClientCert], userName, now) {
+		newSecret.Data[SecretKeyClientCert] = storedSecret.Data[SecretKeyClientCert]
+		newSecret.Data[SecretKeyClientKey] = storedSecret.Data[SecretKeyClientKey]
+		return newSecret, nil
+	}
+
+	cert, key, err := tenantTLS.Issuer.IssueClientCertificate(userName)
+	if err != nil {
+		return nil, err
+	}
+	newSecret.Data[SecretKeyClientCert] = cert
+	newSecret.Data[SecretKeyClientKey] = key
+
+	return newSecret, nil
+}
+
+// isTLSOfSecretChanged reports whether TLS settings or client certificate of Secret should be updated
+func isTLSOfSecretChanged(storedSecret, newSecret *corev1.Secret) bool {
+	for _, key := range []string{SecretVarDbSSLMode, SecretKeyCACert, SecretKeyClientCert, SecretKeyClientKey} {
+		if !bytes.Equal(storedSecret.Data[key], newSecret.Data[key]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isClientCertificateValid reports whether PEM certificate is issued for role and doesn't expire soon
+func isClientCertificateValid(certPEM []byte, userName string, now time.Time) bool {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return false
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return false
+	}
+
+	return cert.Subject.CommonName == userName && now.Add(ClientCertificateRenewBefore).Before(cert.NotAfter)
+}
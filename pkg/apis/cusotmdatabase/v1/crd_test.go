@@ -0,0 +1,55 @@
+package v1
+
+import (
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+func TestTTLPatternOfCRD(t *testing.T) {
+	content, err := os.ReadFile("../../../../artifacts/crd.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var crd struct {
+		Spec struct {
+			Versions []struct {
+				Schema struct {
+					OpenAPIV3Schema struct {
+						Properties struct {
+							Spec struct {
+								Properties struct {
+									TTL struct {
+										Pattern string `json:"pattern"`
+									} `json:"ttl"`
+								} `json:"properties"`
+							} `json:"spec"`
+						} `json:"properties"`
+					} `json:"openAPIV3Schema"`
+				} `json:"schema"`
+			} `json:"versions"`
+		} `json:"spec"`
+	}
+	if err = yaml.Unmarshal(content, &crd); err != nil {
+		t.Fatal(err)
+	}
+	pattern := regexp.MustCompile(crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties.Spec.Properties.TTL.Pattern)
+
+	for _, ttl := range []string{"72h", "1h30m", "1.5h", "90s", "500ms"} {
+		if !pattern.MatchString(ttl) {
+			t.Errorf("ttl %s should be accepted", ttl)
+		}
+		if _, err := time.ParseDuration(ttl); err != nil {
+			t.Errorf("accepted ttl %s should be parsed: %v", ttl, err)
+		}
+	}
+	for _, ttl := range []string{"", "-1h", "72", "3 days", "1d", "h"} {
+		if pattern.MatchString(ttl) {
+			t.Errorf("ttl %q should be rejected", ttl)
+		}
+	}
+}
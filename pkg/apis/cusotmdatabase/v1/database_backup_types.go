@@ -0,0 +1,70 @@
+package v1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DatabaseBackup is logical dump of database of CustomDatabase in the same namespace. Dump is made once, resource
+// should be recreated to make new dump.
+type DatabaseBackup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DatabaseBackupSpec   `json:"spec"`
+	Status DatabaseBackupStatus `json:"status,omitempty"`
+}
+
+type DatabaseBackupSpec struct {
+	// CustomDatabaseName name of CustomDatabase in the same namespace
+	CustomDatabaseName string `json:"customDatabaseName"`
+}
+
+// BackupPhase stage of backup lifecycle
+type BackupPhase string
+
+const (
+	BackupPhasePending   BackupPhase = "Pending"
+	BackupPhaseRunning   BackupPhase = "Running"
+	BackupPhaseCompleted BackupPhase = "Completed"
+	BackupPhaseFailed    BackupPhase = "Failed"
+)
+
+type DatabaseBackupStatus struct {
+	// +optional
+	Phase BackupPhase `json:"phase,omitempty"`
+	// Location key of dump in backup storage
+	// +optional
+	Location string `json:"location,omitempty"`
+	// Size of dump
+	// +optional
+	Size *resource.Quantity `json:"size,omitempty"`
+	// Checksum of dump in form "sha256:<hex>"
+	// +optional
+	Checksum string `json:"checksum,omitempty"`
+	// +optional
+	StartedAt *metav1.Time `json:"startedAt,omitempty"`
+	// +optional
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
+	// Duration of dump
+	// +optional
+	Duration *metav1.Duration `json:"duration,omitempty"`
+
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DatabaseBackupList is a list of DatabaseBackup resources
+type DatabaseBackupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []DatabaseBackup `json:"items"`
+}
@@ -0,0 +1,64 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// LabelDatabaseGrant marks Secret, that was delivered to grantee namespace. Value is "<namespace>.<name>" of
+	// DatabaseGrant.
+	LabelDatabaseGrant = "customdatabase.igor.yatsevich.ru/grant"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DatabaseGrant shares database of CustomDatabase with another namespace. It's created in namespace of
+// CustomDatabase, so only owner of database can share it.
+type DatabaseGrant struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DatabaseGrantSpec   `json:"spec"`
+	Status DatabaseGrantStatus `json:"status,omitempty"`
+}
+
+type DatabaseGrantSpec struct {
+	// CustomDatabaseName name of CustomDatabase in the same namespace
+	CustomDatabaseName string `json:"customDatabaseName"`
+	// GranteeNamespace namespace, that gets Secret with credentials
+	GranteeNamespace string `json:"granteeNamespace"`
+	// SecretName name of Secret in grantee namespace
+	SecretName string `json:"secretName"`
+
+	// Profile one of "readonly" or "readwrite"
+	Profile PrivilegeProfile `json:"profile"`
+}
+
+type DatabaseGrantStatus struct {
+	// UserName name of role in Postgresql
+	// +optional
+	UserName string `json:"userName,omitempty"`
+	// PrivilegesChecksum checksum of privileges, which were applied last time in format "sha256:<hex>". Privileges are
+	// applied again when it's changed or cleared and periodically, so objects created by other roles are covered.
+	// +optional
+	PrivilegesChecksum string `json:"privilegesChecksum,omitempty"`
+	// PrivilegesAppliedAt time, when privileges were applied last time
+	// +optional
+	PrivilegesAppliedAt *metav1.Time `json:"privilegesAppliedAt,omitempty"`
+
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DatabaseGrantList is a list of DatabaseGrant resources
+type DatabaseGrantList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []DatabaseGrant `json:"items"`
+}
@@ -0,0 +1,84 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// LabelDatabaseRestore marks CustomDatabase, that was created by DatabaseRestore. Value is name of DatabaseRestore.
+	LabelDatabaseRestore = "customdatabase.igor.yatsevich.ru/restore"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DatabaseRestore restores DatabaseBackup into existing CustomDatabase or into a new one. Restore is made once,
+// resource should be recreated to restore again.
+type DatabaseRestore struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DatabaseRestoreSpec   `json:"spec"`
+	Status DatabaseRestoreStatus `json:"status,omitempty"`
+}
+
+// DatabaseRestoreSpec has exactly one target: customDatabaseName or newCustomDatabase
+type DatabaseRestoreSpec struct {
+	// BackupName name of completed DatabaseBackup in the same namespace
+	BackupName string `json:"backupName"`
+
+	// CustomDatabaseName existing CustomDatabase, objects of backup replace its objects
+	// +optional
+	CustomDatabaseName string `json:"customDatabaseName,omitempty"`
+	// ConfirmOverwrite should be equal to customDatabaseName, because restore into existing database loses its data
+	// +optional
+	ConfirmOverwrite string `json:"confirmOverwrite,omitempty"`
+
+	// NewCustomDatabase CustomDatabase, that is created from backup
+	// +optional
+	NewCustomDatabase *NewCustomDatabase `json:"newCustomDatabase,omitempty"`
+}
+
+// NewCustomDatabase template of CustomDatabase, that is created by restore
+type NewCustomDatabase struct {
+	Name string             `json:"name"`
+	Spec CustomDatabaseSpec `json:"spec"`
+}
+
+// RestorePhase stage of restore lifecycle
+type RestorePhase string
+
+const (
+	RestorePhasePending            RestorePhase = "Pending"
+	RestorePhaseWaitingForDatabase RestorePhase = "WaitingForDatabase"
+	RestorePhaseRunning            RestorePhase = "Running"
+	RestorePhaseCompleted          RestorePhase = "Completed"
+	RestorePhaseFailed             RestorePhase = "Failed"
+)
+
+type DatabaseRestoreStatus struct {
+	// +optional
+	Phase RestorePhase `json:"phase,omitempty"`
+	// CustomDatabaseName name of CustomDatabase, that backup is restored into
+	// +optional
+	CustomDatabaseName string `json:"customDatabaseName,omitempty"`
+	// +optional
+	StartedAt *metav1.Time `json:"startedAt,omitempty"`
+	// +optional
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
+
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DatabaseRestoreList is a list of DatabaseRestore resources
+type DatabaseRestoreList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []DatabaseRestore `json:"items"`
+}
@@ -0,0 +1,100 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// AnnotationRotatePassword requests new password for the role. Every new value of annotation leads to rotation.
+	AnnotationRotatePassword = "customdatabase.igor.yatsevich.ru/rotate-password"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DatabaseUser is additional Postgresql role for database of CustomDatabase in the same namespace,
+// e.g. read-only role for BI tools or separate migration user.
+type DatabaseUser struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DatabaseUserSpec   `json:"spec"`
+	Status DatabaseUserStatus `json:"status,omitempty"`
+}
+
+type DatabaseUserSpec struct {
+	// CustomDatabaseName name of CustomDatabase in the same namespace
+	CustomDatabaseName string `json:"customDatabaseName"`
+	// SecretName name of Secret with credentials of the role
+	SecretName string `json:"secretName"`
+
+	Profile PrivilegeProfile `json:"profile"`
+	// Grants explicit privileges, they are used only with "custom" profile
+	// +optional
+	Grants []Grant `json:"grants,omitempty"`
+
+	// PasswordRotationPeriod enables scheduled rotation of password, e.g. "720h"
+	// +optional
+	PasswordRotationPeriod *metav1.Duration `json:"passwordRotationPeriod,omitempty"`
+}
+
+// PrivilegeProfile predefined set of privileges on all objects of database
+type PrivilegeProfile string
+
+const (
+	PrivilegeProfileReadOnly  PrivilegeProfile = "readonly"
+	PrivilegeProfileReadWrite PrivilegeProfile = "readwrite"
+	PrivilegeProfileOwner     PrivilegeProfile = "owner"
+	PrivilegeProfileCustom    PrivilegeProfile = "custom"
+)
+
+// Grant privileges on all objects of the given type in schema. Privileges are also granted by default on objects,
+// that will be created in future.
+type Grant struct {
+	// Schema name, "public" by default
+	// +optional
+	Schema string `json:"schema,omitempty"`
+	// ObjectType one of "tables", "sequences", "functions"
+	ObjectType string `json:"objectType"`
+	// Privileges e.g. "SELECT" or "INSERT"
+	Privileges []string `json:"privileges"`
+}
+
+type DatabaseUserStatus struct {
+	// UserName name of role in Postgresql
+	// +optional
+	UserName string `json:"userName,omitempty"`
+	// PasswordRotatedAt time of the last password change
+	// +optional
+	PasswordRotatedAt *metav1.Time `json:"passwordRotatedAt,omitempty"`
+	// ObservedRotationToken the last handled value of rotate-password annotation
+	// +optional
+	ObservedRotationToken string `json:"observedRotationToken,omitempty"`
+	// PrivilegesChecksum checksum of privileges, which were applied last time in format "sha256:<hex>". Privileges are
+	// applied again when it's changed or cleared and periodically, so objects created by other roles are covered.
+	// +optional
+	PrivilegesChecksum string `json:"privilegesChecksum,omitempty"`
+	// PrivilegesAppliedAt time, when privileges were applied last time
+	// +optional
+	PrivilegesAppliedAt *metav1.Time `json:"privilegesAppliedAt,omitempty"`
+
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+const (
+	// ConditionReady is True when all objects of resource are created in Postgresql and Kubernetes
+	ConditionReady = "Ready"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DatabaseUserList is a list of DatabaseUser resources
+type DatabaseUserList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []DatabaseUser `json:"items"`
+}
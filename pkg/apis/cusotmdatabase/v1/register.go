@@ -33,6 +33,14 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&CustomDatabase{},
 		&CustomDatabaseList{},
+		&DatabaseUser{},
+		&DatabaseUserList{},
+		&DatabaseGrant{},
+		&DatabaseGrantList{},
+		&DatabaseBackup{},
+		&DatabaseBackupList{},
+		&DatabaseRestore{},
+		&DatabaseRestoreList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
@@ -1,6 +1,8 @@
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -11,11 +13,300 @@ type CustomDatabase struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec CustomDatabaseSpec `json:"spec"`
+	Spec   CustomDatabaseSpec   `json:"spec"`
+	Status CustomDatabaseStatus `json:"status,omitempty"`
 }
 
 type CustomDatabaseSpec struct {
 	SecretName string `json:"secretName"`
+
+	// DatabaseOptions are used only once, when database is created. They can't be changed later.
+	// +optional
+	DatabaseOptions DatabaseOptions `json:"databaseOptions,omitempty"`
+
+	// Limits are reconciled on every sync, so they can be changed at any time
+	// +optional
+	Limits DatabaseLimits `json:"limits,omitempty"`
+
+	// StorageQuota max size of database on disk
+	// +optional
+	StorageQuota *StorageQuota `json:"storageQuota,omitempty"`
+
+	// CloneFrom CustomDatabase, which database is copied into the new one. Like DatabaseOptions, it's used only once,
+	// when database is created.
+	// +optional
+	CloneFrom *CloneSource `json:"cloneFrom,omitempty"`
+
+	// Init SQL scripts, which are run in the given order by owner of database. Every script is run only once, scripts
+	// appended later are run on the next sync.
+	// +optional
+	Init []InitScript `json:"init,omitempty"`
+
+	// Migrations versioned migrations in format of golang-migrate, which are applied after init scripts
+	// +optional
+	Migrations *Migrations `json:"migrations,omitempty"`
+
+	// TTL lifetime of CustomDatabase since its creation, CustomDatabase is deleted together with database, when it
+	// expires. Annotation AnnotationExpiresAt sets exact time instead, the earliest of them is used.
+	// +optional
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+
+	// Hibernation policy of idle database, database is never hibernated by default
+	// +optional
+	Hibernation *Hibernation `json:"hibernation,omitempty"`
+
+	// Adopt takes over database and role with names of CustomDatabase, which were created outside of controller.
+	// Database must be allowed for adoption by administrator. Password of role isn't changed, unless it's requested
+	// by annotation AnnotationAdoptResetPassword, so Secret with the current password should exist.
+	// +optional
+	Adopt bool `json:"adopt,omitempty"`
+
+	// ServerRef name of Postgresql server of controller, where database lives, the default server of controller if
+	// empty. Database of existing CustomDatabase is moved to the new server, when it's changed.
+	// +optional
+	ServerRef string `json:"serverRef,omitempty"`
+}
+
+// Hibernation hibernates database, which had no sessions and no committed transactions for idleDays. Hibernated
+// database is woken up by new value of annotation AnnotationWakeUp.
+type Hibernation struct {
+	// IdleDays after the last activity, when database is hibernated
+	IdleDays int32 `json:"idleDays"`
+	// DumpAndDrop dumps database to backup storage and drops it. Otherwise, CONNECT privilege is revoked and database
+	// stays on the server. Dump is restored, when database is woken up.
+	// +optional
+	DumpAndDrop bool `json:"dumpAndDrop,omitempty"`
+}
+
+// Migrations source of migration files {version}_{title}.up.sql and {version}_{title}.down.sql. Exactly one of
+// configMapName and path is set.
+type Migrations struct {
+	// ConfigMapName ConfigMap of the same namespace, keys of which are names of migration files
+	// +optional
+	ConfigMapName string `json:"configMapName,omitempty"`
+	// Path directory of migration files relative to directory of migrations of controller, e.g. volume with unpacked
+	// OCI image or tarball
+	// +optional
+	Path string `json:"path,omitempty"`
+	// TargetVersion version, that database is migrated to, the latest version by default. Version lower than current
+	// one reverts migrations by their down scripts. 0 reverts all migrations.
+	// +optional
+	TargetVersion *int64 `json:"targetVersion,omitempty"`
+}
+
+// InitScript reference to SQL script in ConfigMap or Secret of the same namespace. Exactly one of fields is set.
+type InitScript struct {
+	// +optional
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+	// +optional
+	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+}
+
+const (
+	// AnnotationCloneAllowedNamespaces comma-separated list of namespaces, that may clone database of CustomDatabase.
+	// "*" allows all namespaces. Database can always be cloned in its own namespace.
+	AnnotationCloneAllowedNamespaces = "customdatabase.igor.yatsevich.ru/clone-allowed-namespaces"
+	// AnnotationExpiresAt time in RFC 3339 format, when CustomDatabase is deleted together with database
+	AnnotationExpiresAt = "customdatabase.igor.yatsevich.ru/expires-at"
+	// AnnotationWakeUp wakes up hibernated database. Every new value of annotation leads to wake-up.
+	AnnotationWakeUp = "customdatabase.igor.yatsevich.ru/wake-up"
+	// AnnotationAdoptResetPassword "true" allows to generate new password for adopted role, when Secret doesn't exist
+	AnnotationAdoptResetPassword = "customdatabase.igor.yatsevich.ru/adopt-reset-password"
+	// AnnotationAdoptManage "true" allows to harden privileges of adopted database and repair its drift. Without it
+	// findings are only reported, because applications of adopted database may depend on them.
+	AnnotationAdoptManage = "customdatabase.igor.yatsevich.ru/adopt-manage"
+	// AnnotationPaused "true" stops reconciliation of CustomDatabase and its users, grants, backups and restores, e.g.
+	// during maintenance of server. CustomDatabase, which is deleted while paused, keeps its database and role.
+	AnnotationPaused = "customdatabase.igor.yatsevich.ru/paused"
+)
+
+// CloneSource reference to CustomDatabase, that is used as template of new database
+type CloneSource struct {
+	Name string `json:"name"`
+	// Namespace of source CustomDatabase, namespace of the clone by default
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// DatabaseOptions parameters of CREATE DATABASE statement. Owner of database is always the tenant role.
+type DatabaseOptions struct {
+	// +optional
+	Encoding string `json:"encoding,omitempty"`
+	// +optional
+	LcCollate string `json:"lcCollate,omitempty"`
+	// +optional
+	LcCtype string `json:"lcCtype,omitempty"`
+	// Template template0 or template1, another database is copied by CustomDatabaseSpec.CloneFrom
+	// +optional
+	Template string `json:"template,omitempty"`
+	// +optional
+	Tablespace string `json:"tablespace,omitempty"`
+}
+
+// DatabaseLimits resource limits of database, which protect shared Postgresql instance from a single noisy tenant.
+// Empty value means that Postgresql server default is used.
+type DatabaseLimits struct {
+	// ConnectionLimit max amount of concurrent connections to database, -1 means no limit
+	// +optional
+	ConnectionLimit *int32 `json:"connectionLimit,omitempty"`
+	// StatementTimeout in Postgresql format, e.g. "30s" or "5min"
+	// +optional
+	StatementTimeout string `json:"statementTimeout,omitempty"`
+	// IdleInTransactionSessionTimeout in Postgresql format, e.g. "30s" or "5min"
+	// +optional
+	IdleInTransactionSessionTimeout string `json:"idleInTransactionSessionTimeout,omitempty"`
+	// WorkMem in Postgresql format, e.g. "4MB"
+	// +optional
+	WorkMem string `json:"workMem,omitempty"`
+}
+
+// StorageQuota limit of database size. Size is sampled periodically, so database may exceed quota for a short time.
+type StorageQuota struct {
+	Limit resource.Quantity `json:"limit"`
+	// ReadOnlyWhenExceeded makes new transactions in database read-only, while quota is exceeded
+	// +optional
+	ReadOnlyWhenExceeded bool `json:"readOnlyWhenExceeded,omitempty"`
+}
+
+const (
+	// ConditionQuotaExceeded is True when database size is greater than spec.storageQuota.limit
+	ConditionQuotaExceeded = "QuotaExceeded"
+	// ConditionHardened is True when privileges of database and its role match least-privilege defaults
+	ConditionHardened = "Hardened"
+	// ConditionCloned is True when database was created as a copy of spec.cloneFrom
+	ConditionCloned = "Cloned"
+	// ConditionInitialized is True when all scripts of spec.init are applied
+	ConditionInitialized = "Initialized"
+	// ConditionMigrated is True when database is migrated to the target version
+	ConditionMigrated = "Migrated"
+	// ConditionExpiring is True when CustomDatabase expires soon and will be deleted
+	ConditionExpiring = "Expiring"
+	// ConditionHibernated is True when idle database is hibernated and tenant can't connect to it
+	ConditionHibernated = "Hibernated"
+	// ConditionAdopted is True when pre-existing database and role are taken over by spec.adopt. It's False, when
+	// database or role exists and can't be taken over.
+	ConditionAdopted = "Adopted"
+	// ConditionDrifted is True when database or its role was changed on server by hand and the difference isn't repaired
+	ConditionDrifted = "Drifted"
+	// ConditionPaused is True when CustomDatabase isn't reconciled by annotation AnnotationPaused or because controller
+	// runs in read-only mode
+	ConditionPaused = "Paused"
+	// ConditionServerMigrating is True while database is moved to server of spec.serverRef. It's False, when migration
+	// can't proceed.
+	ConditionServerMigrating = "ServerMigrating"
+)
+
+type CustomDatabaseStatus struct {
+	// Limits effective values of limits, that was read from Postgresql server
+	// +optional
+	Limits DatabaseLimits `json:"limits,omitempty"`
+	// Size of database on disk at the moment of the last sample
+	// +optional
+	Size *resource.Quantity `json:"size,omitempty"`
+	// ReadOnlyByQuota database is made read-only by controller, because its size exceeds storage quota. Read-only
+	// database is made writable again only by controller, which made it read-only.
+	// +optional
+	ReadOnlyByQuota bool `json:"readOnlyByQuota,omitempty"`
+	// InitScripts scripts of spec.init, that are already applied
+	// +optional
+	InitScripts []AppliedInitScript `json:"initScripts,omitempty"`
+	// Migrations versions of database schema
+	// +optional
+	Migrations *MigrationsStatus `json:"migrations,omitempty"`
+	// ExpiresAt time, when CustomDatabase is deleted, by spec.ttl or annotation
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+	// LastActivityAt time, when database had sessions or committed transactions last time. Activity is sampled
+	// periodically, so it's accurate to sampling period.
+	// +optional
+	LastActivityAt *metav1.Time `json:"lastActivityAt,omitempty"`
+	// Hibernation state of hibernated database, empty for database, that is awake
+	// +optional
+	Hibernation *HibernationStatus `json:"hibernation,omitempty"`
+	// Server name of Postgresql server, where database lives now
+	// +optional
+	Server string `json:"server,omitempty"`
+	// DatabaseName name of database and its owner role. It's chosen by naming strategy of controller, when database is
+	// created, and kept afterwards, so change of strategy doesn't rename databases.
+	// +optional
+	DatabaseName string `json:"databaseName,omitempty"`
+	// ServerMigration state of migration to server of spec.serverRef, empty when database isn't moved
+	// +optional
+	ServerMigration *ServerMigrationStatus `json:"serverMigration,omitempty"`
+
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// AppliedInitScript record about applied init script
+type AppliedInitScript struct {
+	// Name of script in format "<configmap|secret>/<name>/<key>"
+	Name string `json:"name"`
+	// Checksum of applied content of script in format "sha256:<hex>", later changes of script aren't applied
+	Checksum  string      `json:"checksum"`
+	AppliedAt metav1.Time `json:"appliedAt"`
+}
+
+// MigrationsStatus versions of database schema, 0 means no applied migrations
+type MigrationsStatus struct {
+	CurrentVersion int64 `json:"currentVersion"`
+	TargetVersion  int64 `json:"targetVersion"`
+}
+
+// HibernationPhase stage of hibernation lifecycle
+type HibernationPhase string
+
+const (
+	// HibernationPhaseHibernated CONNECT privilege on database is revoked
+	HibernationPhaseHibernated HibernationPhase = "Hibernated"
+	// HibernationPhaseDumping database is dumped before drop
+	HibernationPhaseDumping HibernationPhase = "Dumping"
+	// HibernationPhaseDropped database is dropped, its dump is kept in backup storage
+	HibernationPhaseDropped HibernationPhase = "Dropped"
+	// HibernationPhaseRestoring database is recreated and its dump is restored
+	HibernationPhaseRestoring HibernationPhase = "Restoring"
+)
+
+type HibernationStatus struct {
+	Phase HibernationPhase `json:"phase"`
+	// HibernatedAt time, when hibernation started
+	HibernatedAt metav1.Time `json:"hibernatedAt"`
+	// BackupName DatabaseBackup with dump of dropped database
+	// +optional
+	BackupName string `json:"backupName,omitempty"`
+	// ObservedWakeUpToken value of wake-up annotation at the moment of hibernation
+	// +optional
+	ObservedWakeUpToken string `json:"observedWakeUpToken,omitempty"`
+}
+
+// ServerMigrationPhase stage of migration of database to another server
+type ServerMigrationPhase string
+
+const (
+	// ServerMigrationPhaseProvisioning database and role are created on target server
+	ServerMigrationPhaseProvisioning ServerMigrationPhase = "Provisioning"
+	// ServerMigrationPhaseCopying writes to source database are blocked and its dump is restored on target server
+	ServerMigrationPhaseCopying ServerMigrationPhase = "Copying"
+	// ServerMigrationPhaseSwitching Secret is switched to target server
+	ServerMigrationPhaseSwitching ServerMigrationPhase = "Switching"
+	// ServerMigrationPhaseDraining database lives on target server, read-only source database is kept until grace
+	// period expires and then dropped
+	ServerMigrationPhaseDraining ServerMigrationPhase = "Draining"
+)
+
+type ServerMigrationStatus struct {
+	// Source server, where database lived before migration
+	Source string `json:"source"`
+	// Target server of spec.serverRef
+	Target string               `json:"target"`
+	Phase  ServerMigrationPhase `json:"phase"`
+	// StartedAt time, when migration started
+	StartedAt metav1.Time `json:"startedAt"`
+	// SwitchedAt time, when Secret was switched to target server
+	// +optional
+	SwitchedAt *metav1.Time `json:"switchedAt,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
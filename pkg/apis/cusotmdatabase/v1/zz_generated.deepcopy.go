@@ -9,15 +9,51 @@
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AppliedInitScript) DeepCopyInto(out *AppliedInitScript) {
+	*out = *in
+	in.AppliedAt.DeepCopyInto(&out.AppliedAt)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppliedInitScript.
+func (in *AppliedInitScript) DeepCopy() *AppliedInitScript {
+	if in == nil {
+		return nil
+	}
+	out := new(AppliedInitScript)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CloneSource) DeepCopyInto(out *CloneSource) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CloneSource.
+func (in *CloneSource) DeepCopy() *CloneSource {
+	if in == nil {
+		return nil
+	}
+	out := new(CloneSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CustomDatabase) DeepCopyInto(out *CustomDatabase) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -75,6 +111,40 @@ func (in *CustomDatabaseList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CustomDatabaseSpec) DeepCopyInto(out *CustomDatabaseSpec) {
 	*out = *in
+	out.DatabaseOptions = in.DatabaseOptions
+	in.Limits.DeepCopyInto(&out.Limits)
+	if in.StorageQuota != nil {
+		in, out := &in.StorageQuota, &out.StorageQuota
+		*out = new(StorageQuota)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CloneFrom != nil {
+		in, out := &in.CloneFrom, &out.CloneFrom
+		*out = new(CloneSource)
+		**out = **in
+	}
+	if in.Init != nil {
+		in, out := &in.Init, &out.Init
+		*out = make([]InitScript, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Migrations != nil {
+		in, out := &in.Migrations, &out.Migrations
+		*out = new(Migrations)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TTL != nil {
+		in, out := &in.TTL, &out.TTL
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Hibernation != nil {
+		in, out := &in.Hibernation, &out.Hibernation
+		*out = new(Hibernation)
+		**out = **in
+	}
 	return
 }
 
@@ -87,3 +157,726 @@ func (in *CustomDatabaseSpec) DeepCopy() *CustomDatabaseSpec {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CustomDatabaseStatus) DeepCopyInto(out *CustomDatabaseStatus) {
+	*out = *in
+	in.Limits.DeepCopyInto(&out.Limits)
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.InitScripts != nil {
+		in, out := &in.InitScripts, &out.InitScripts
+		*out = make([]AppliedInitScript, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Migrations != nil {
+		in, out := &in.Migrations, &out.Migrations
+		*out = new(MigrationsStatus)
+		**out = **in
+	}
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
+	if in.LastActivityAt != nil {
+		in, out := &in.LastActivityAt, &out.LastActivityAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Hibernation != nil {
+		in, out := &in.Hibernation, &out.Hibernation
+		*out = new(HibernationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ServerMigration != nil {
+		in, out := &in.ServerMigration, &out.ServerMigration
+		*out = new(ServerMigrationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CustomDatabaseStatus.
+func (in *CustomDatabaseStatus) DeepCopy() *CustomDatabaseStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CustomDatabaseStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseBackup) DeepCopyInto(out *DatabaseBackup) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseBackup.
+func (in *DatabaseBackup) DeepCopy() *DatabaseBackup {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseBackup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseBackup) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseBackupList) DeepCopyInto(out *DatabaseBackupList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DatabaseBackup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseBackupList.
+func (in *DatabaseBackupList) DeepCopy() *DatabaseBackupList {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseBackupList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseBackupList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseBackupSpec) DeepCopyInto(out *DatabaseBackupSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseBackupSpec.
+func (in *DatabaseBackupSpec) DeepCopy() *DatabaseBackupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseBackupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseBackupStatus) DeepCopyInto(out *DatabaseBackupStatus) {
+	*out = *in
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.StartedAt != nil {
+		in, out := &in.StartedAt, &out.StartedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletedAt != nil {
+		in, out := &in.CompletedAt, &out.CompletedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Duration != nil {
+		in, out := &in.Duration, &out.Duration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseBackupStatus.
+func (in *DatabaseBackupStatus) DeepCopy() *DatabaseBackupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseBackupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseGrant) DeepCopyInto(out *DatabaseGrant) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseGrant.
+func (in *DatabaseGrant) DeepCopy() *DatabaseGrant {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseGrant)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseGrant) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseGrantList) DeepCopyInto(out *DatabaseGrantList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DatabaseGrant, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseGrantList.
+func (in *DatabaseGrantList) DeepCopy() *DatabaseGrantList {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseGrantList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseGrantList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseGrantSpec) DeepCopyInto(out *DatabaseGrantSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseGrantSpec.
+func (in *DatabaseGrantSpec) DeepCopy() *DatabaseGrantSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseGrantSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseGrantStatus) DeepCopyInto(out *DatabaseGrantStatus) {
+	*out = *in
+	if in.PrivilegesAppliedAt != nil {
+		in, out := &in.PrivilegesAppliedAt, &out.PrivilegesAppliedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseGrantStatus.
+func (in *DatabaseGrantStatus) DeepCopy() *DatabaseGrantStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseGrantStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseLimits) DeepCopyInto(out *DatabaseLimits) {
+	*out = *in
+	if in.ConnectionLimit != nil {
+		in, out := &in.ConnectionLimit, &out.ConnectionLimit
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseLimits.
+func (in *DatabaseLimits) DeepCopy() *DatabaseLimits {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseLimits)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseOptions) DeepCopyInto(out *DatabaseOptions) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseOptions.
+func (in *DatabaseOptions) DeepCopy() *DatabaseOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseRestore) DeepCopyInto(out *DatabaseRestore) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseRestore.
+func (in *DatabaseRestore) DeepCopy() *DatabaseRestore {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseRestore)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseRestore) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseRestoreList) DeepCopyInto(out *DatabaseRestoreList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DatabaseRestore, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseRestoreList.
+func (in *DatabaseRestoreList) DeepCopy() *DatabaseRestoreList {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseRestoreList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseRestoreList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseRestoreSpec) DeepCopyInto(out *DatabaseRestoreSpec) {
+	*out = *in
+	if in.NewCustomDatabase != nil {
+		in, out := &in.NewCustomDatabase, &out.NewCustomDatabase
+		*out = new(NewCustomDatabase)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseRestoreSpec.
+func (in *DatabaseRestoreSpec) DeepCopy() *DatabaseRestoreSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseRestoreSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseRestoreStatus) DeepCopyInto(out *DatabaseRestoreStatus) {
+	*out = *in
+	if in.StartedAt != nil {
+		in, out := &in.StartedAt, &out.StartedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletedAt != nil {
+		in, out := &in.CompletedAt, &out.CompletedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseRestoreStatus.
+func (in *DatabaseRestoreStatus) DeepCopy() *DatabaseRestoreStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseRestoreStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseUser) DeepCopyInto(out *DatabaseUser) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseUser.
+func (in *DatabaseUser) DeepCopy() *DatabaseUser {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseUser)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseUser) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseUserList) DeepCopyInto(out *DatabaseUserList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DatabaseUser, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseUserList.
+func (in *DatabaseUserList) DeepCopy() *DatabaseUserList {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseUserList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseUserList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseUserSpec) DeepCopyInto(out *DatabaseUserSpec) {
+	*out = *in
+	if in.Grants != nil {
+		in, out := &in.Grants, &out.Grants
+		*out = make([]Grant, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PasswordRotationPeriod != nil {
+		in, out := &in.PasswordRotationPeriod, &out.PasswordRotationPeriod
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseUserSpec.
+func (in *DatabaseUserSpec) DeepCopy() *DatabaseUserSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseUserSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseUserStatus) DeepCopyInto(out *DatabaseUserStatus) {
+	*out = *in
+	if in.PasswordRotatedAt != nil {
+		in, out := &in.PasswordRotatedAt, &out.PasswordRotatedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.PrivilegesAppliedAt != nil {
+		in, out := &in.PrivilegesAppliedAt, &out.PrivilegesAppliedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseUserStatus.
+func (in *DatabaseUserStatus) DeepCopy() *DatabaseUserStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseUserStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Grant) DeepCopyInto(out *Grant) {
+	*out = *in
+	if in.Privileges != nil {
+		in, out := &in.Privileges, &out.Privileges
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Grant.
+func (in *Grant) DeepCopy() *Grant {
+	if in == nil {
+		return nil
+	}
+	out := new(Grant)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Hibernation) DeepCopyInto(out *Hibernation) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Hibernation.
+func (in *Hibernation) DeepCopy() *Hibernation {
+	if in == nil {
+		return nil
+	}
+	out := new(Hibernation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HibernationStatus) DeepCopyInto(out *HibernationStatus) {
+	*out = *in
+	in.HibernatedAt.DeepCopyInto(&out.HibernatedAt)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HibernationStatus.
+func (in *HibernationStatus) DeepCopy() *HibernationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HibernationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InitScript) DeepCopyInto(out *InitScript) {
+	*out = *in
+	if in.ConfigMapKeyRef != nil {
+		in, out := &in.ConfigMapKeyRef, &out.ConfigMapKeyRef
+		*out = new(corev1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SecretKeyRef != nil {
+		in, out := &in.SecretKeyRef, &out.SecretKeyRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InitScript.
+func (in *InitScript) DeepCopy() *InitScript {
+	if in == nil {
+		return nil
+	}
+	out := new(InitScript)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Migrations) DeepCopyInto(out *Migrations) {
+	*out = *in
+	if in.TargetVersion != nil {
+		in, out := &in.TargetVersion, &out.TargetVersion
+		*out = new(int64)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Migrations.
+func (in *Migrations) DeepCopy() *Migrations {
+	if in == nil {
+		return nil
+	}
+	out := new(Migrations)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationsStatus) DeepCopyInto(out *MigrationsStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationsStatus.
+func (in *MigrationsStatus) DeepCopy() *MigrationsStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationsStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NewCustomDatabase) DeepCopyInto(out *NewCustomDatabase) {
+	*out = *in
+	in.Spec.DeepCopyInto(&out.Spec)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NewCustomDatabase.
+func (in *NewCustomDatabase) DeepCopy() *NewCustomDatabase {
+	if in == nil {
+		return nil
+	}
+	out := new(NewCustomDatabase)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServerMigrationStatus) DeepCopyInto(out *ServerMigrationStatus) {
+	*out = *in
+	in.StartedAt.DeepCopyInto(&out.StartedAt)
+	if in.SwitchedAt != nil {
+		in, out := &in.SwitchedAt, &out.SwitchedAt
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerMigrationStatus.
+func (in *ServerMigrationStatus) DeepCopy() *ServerMigrationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ServerMigrationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageQuota) DeepCopyInto(out *StorageQuota) {
+	*out = *in
+	out.Limit = in.Limit.DeepCopy()
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageQuota.
+func (in *StorageQuota) DeepCopy() *StorageQuota {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageQuota)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -16,6 +16,10 @@ import (
 type IgorV1Interface interface {
 	RESTClient() rest.Interface
 	CustomDatabasesGetter
+	DatabaseBackupsGetter
+	DatabaseGrantsGetter
+	DatabaseRestoresGetter
+	DatabaseUsersGetter
 }
 
 // IgorV1Client is used to interact with features provided by the igor.yatsevich.ru group.
@@ -27,6 +31,22 @@ func (c *IgorV1Client) CustomDatabases(namespace string) CustomDatabaseInterface
 	return newCustomDatabases(c, namespace)
 }
 
+func (c *IgorV1Client) DatabaseBackups(namespace string) DatabaseBackupInterface {
+	return newDatabaseBackups(c, namespace)
+}
+
+func (c *IgorV1Client) DatabaseGrants(namespace string) DatabaseGrantInterface {
+	return newDatabaseGrants(c, namespace)
+}
+
+func (c *IgorV1Client) DatabaseRestores(namespace string) DatabaseRestoreInterface {
+	return newDatabaseRestores(c, namespace)
+}
+
+func (c *IgorV1Client) DatabaseUsers(namespace string) DatabaseUserInterface {
+	return newDatabaseUsers(c, namespace)
+}
+
 // NewForConfig creates a new IgorV1Client for the given config.
 // NewForConfig is equivalent to NewForConfigAndClient(c, httpClient),
 // where httpClient was generated with rest.HTTPClientFor(c).
@@ -27,6 +27,7 @@ type CustomDatabasesGetter interface {
 type CustomDatabaseInterface interface {
 	Create(ctx context.Context, customDatabase *v1.CustomDatabase, opts metav1.CreateOptions) (*v1.CustomDatabase, error)
 	Update(ctx context.Context, customDatabase *v1.CustomDatabase, opts metav1.UpdateOptions) (*v1.CustomDatabase, error)
+	UpdateStatus(ctx context.Context, customDatabase *v1.CustomDatabase, opts metav1.UpdateOptions) (*v1.CustomDatabase, error)
 	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
 	DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error
 	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1.CustomDatabase, error)
@@ -122,6 +123,22 @@ func (c *customDatabases) Update(ctx context.Context, customDatabase *v1.CustomD
 	return
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *customDatabases) UpdateStatus(ctx context.Context, customDatabase *v1.CustomDatabase, opts metav1.UpdateOptions) (result *v1.CustomDatabase, err error) {
+	result = &v1.CustomDatabase{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("customdatabases").
+		Name(customDatabase.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(customDatabase).
+		Do(ctx).
+		Into(result)
+	return
+}
+
 // Delete takes name of the customDatabase and deletes it. Returns an error if one occurs.
 func (c *customDatabases) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
 	return c.client.Delete().
@@ -0,0 +1,182 @@
+/*
+
+ */
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+	scheme "k8s.io/custom-database/pkg/generated/clientset/versioned/scheme"
+)
+
+// DatabaseBackupsGetter has a method to return a DatabaseBackupInterface.
+// A group's client should implement this interface.
+type DatabaseBackupsGetter interface {
+	DatabaseBackups(namespace string) DatabaseBackupInterface
+}
+
+// DatabaseBackupInterface has methods to work with DatabaseBackup resources.
+type DatabaseBackupInterface interface {
+	Create(ctx context.Context, databaseBackup *v1.DatabaseBackup, opts metav1.CreateOptions) (*v1.DatabaseBackup, error)
+	Update(ctx context.Context, databaseBackup *v1.DatabaseBackup, opts metav1.UpdateOptions) (*v1.DatabaseBackup, error)
+	UpdateStatus(ctx context.Context, databaseBackup *v1.DatabaseBackup, opts metav1.UpdateOptions) (*v1.DatabaseBackup, error)
+	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1.DatabaseBackup, error)
+	List(ctx context.Context, opts metav1.ListOptions) (*v1.DatabaseBackupList, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (result *v1.DatabaseBackup, err error)
+	DatabaseBackupExpansion
+}
+
+// databaseBackups implements DatabaseBackupInterface
+type databaseBackups struct {
+	client rest.Interface
+	ns     string
+}
+
+// newDatabaseBackups returns a DatabaseBackups
+func newDatabaseBackups(c *IgorV1Client, namespace string) *databaseBackups {
+	return &databaseBackups{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the databaseBackup, and returns the corresponding databaseBackup object, and an error if there is any.
+func (c *databaseBackups) Get(ctx context.Context, name string, options metav1.GetOptions) (result *v1.DatabaseBackup, err error) {
+	result = &v1.DatabaseBackup{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("databasebackups").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of DatabaseBackups that match those selectors.
+func (c *databaseBackups) List(ctx context.Context, opts metav1.ListOptions) (result *v1.DatabaseBackupList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1.DatabaseBackupList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("databasebackups").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested databaseBackups.
+func (c *databaseBackups) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("databasebackups").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch(ctx)
+}
+
+// Create takes the representation of a databaseBackup and creates it.  Returns the server's representation of the databaseBackup, and an error, if there is any.
+func (c *databaseBackups) Create(ctx context.Context, databaseBackup *v1.DatabaseBackup, opts metav1.CreateOptions) (result *v1.DatabaseBackup, err error) {
+	result = &v1.DatabaseBackup{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("databasebackups").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(databaseBackup).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Update takes the representation of a databaseBackup and updates it. Returns the server's representation of the databaseBackup, and an error, if there is any.
+func (c *databaseBackups) Update(ctx context.Context, databaseBackup *v1.DatabaseBackup, opts metav1.UpdateOptions) (result *v1.DatabaseBackup, err error) {
+	result = &v1.DatabaseBackup{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("databasebackups").
+		Name(databaseBackup.Name).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(databaseBackup).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *databaseBackups) UpdateStatus(ctx context.Context, databaseBackup *v1.DatabaseBackup, opts metav1.UpdateOptions) (result *v1.DatabaseBackup, err error) {
+	result = &v1.DatabaseBackup{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("databasebackups").
+		Name(databaseBackup.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(databaseBackup).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Delete takes name of the databaseBackup and deletes it. Returns an error if one occurs.
+func (c *databaseBackups) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("databasebackups").
+		Name(name).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *databaseBackups) DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error {
+	var timeout time.Duration
+	if listOpts.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOpts.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("databasebackups").
+		VersionedParams(&listOpts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// Patch applies the patch and returns the patched databaseBackup.
+func (c *databaseBackups) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (result *v1.DatabaseBackup, err error) {
+	result = &v1.DatabaseBackup{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("databasebackups").
+		Name(name).
+		SubResource(subresources...).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(data).
+		Do(ctx).
+		Into(result)
+	return
+}
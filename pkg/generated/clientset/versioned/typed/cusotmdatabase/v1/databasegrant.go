@@ -0,0 +1,182 @@
+/*
+
+ */
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+	scheme "k8s.io/custom-database/pkg/generated/clientset/versioned/scheme"
+)
+
+// DatabaseGrantsGetter has a method to return a DatabaseGrantInterface.
+// A group's client should implement this interface.
+type DatabaseGrantsGetter interface {
+	DatabaseGrants(namespace string) DatabaseGrantInterface
+}
+
+// DatabaseGrantInterface has methods to work with DatabaseGrant resources.
+type DatabaseGrantInterface interface {
+	Create(ctx context.Context, databaseGrant *v1.DatabaseGrant, opts metav1.CreateOptions) (*v1.DatabaseGrant, error)
+	Update(ctx context.Context, databaseGrant *v1.DatabaseGrant, opts metav1.UpdateOptions) (*v1.DatabaseGrant, error)
+	UpdateStatus(ctx context.Context, databaseGrant *v1.DatabaseGrant, opts metav1.UpdateOptions) (*v1.DatabaseGrant, error)
+	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1.DatabaseGrant, error)
+	List(ctx context.Context, opts metav1.ListOptions) (*v1.DatabaseGrantList, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (result *v1.DatabaseGrant, err error)
+	DatabaseGrantExpansion
+}
+
+// databaseGrants implements DatabaseGrantInterface
+type databaseGrants struct {
+	client rest.Interface
+	ns     string
+}
+
+// newDatabaseGrants returns a DatabaseGrants
+func newDatabaseGrants(c *IgorV1Client, namespace string) *databaseGrants {
+	return &databaseGrants{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the databaseGrant, and returns the corresponding databaseGrant object, and an error if there is any.
+func (c *databaseGrants) Get(ctx context.Context, name string, options metav1.GetOptions) (result *v1.DatabaseGrant, err error) {
+	result = &v1.DatabaseGrant{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("databasegrants").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of DatabaseGrants that match those selectors.
+func (c *databaseGrants) List(ctx context.Context, opts metav1.ListOptions) (result *v1.DatabaseGrantList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1.DatabaseGrantList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("databasegrants").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested databaseGrants.
+func (c *databaseGrants) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("databasegrants").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch(ctx)
+}
+
+// Create takes the representation of a databaseGrant and creates it.  Returns the server's representation of the databaseGrant, and an error, if there is any.
+func (c *databaseGrants) Create(ctx context.Context, databaseGrant *v1.DatabaseGrant, opts metav1.CreateOptions) (result *v1.DatabaseGrant, err error) {
+	result = &v1.DatabaseGrant{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("databasegrants").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(databaseGrant).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Update takes the representation of a databaseGrant and updates it. Returns the server's representation of the databaseGrant, and an error, if there is any.
+func (c *databaseGrants) Update(ctx context.Context, databaseGrant *v1.DatabaseGrant, opts metav1.UpdateOptions) (result *v1.DatabaseGrant, err error) {
+	result = &v1.DatabaseGrant{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("databasegrants").
+		Name(databaseGrant.Name).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(databaseGrant).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *databaseGrants) UpdateStatus(ctx context.Context, databaseGrant *v1.DatabaseGrant, opts metav1.UpdateOptions) (result *v1.DatabaseGrant, err error) {
+	result = &v1.DatabaseGrant{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("databasegrants").
+		Name(databaseGrant.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(databaseGrant).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Delete takes name of the databaseGrant and deletes it. Returns an error if one occurs.
+func (c *databaseGrants) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("databasegrants").
+		Name(name).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *databaseGrants) DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error {
+	var timeout time.Duration
+	if listOpts.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOpts.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("databasegrants").
+		VersionedParams(&listOpts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// Patch applies the patch and returns the patched databaseGrant.
+func (c *databaseGrants) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (result *v1.DatabaseGrant, err error) {
+	result = &v1.DatabaseGrant{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("databasegrants").
+		Name(name).
+		SubResource(subresources...).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(data).
+		Do(ctx).
+		Into(result)
+	return
+}
@@ -0,0 +1,182 @@
+/*
+
+ */
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+	scheme "k8s.io/custom-database/pkg/generated/clientset/versioned/scheme"
+)
+
+// DatabaseRestoresGetter has a method to return a DatabaseRestoreInterface.
+// A group's client should implement this interface.
+type DatabaseRestoresGetter interface {
+	DatabaseRestores(namespace string) DatabaseRestoreInterface
+}
+
+// DatabaseRestoreInterface has methods to work with DatabaseRestore resources.
+type DatabaseRestoreInterface interface {
+	Create(ctx context.Context, databaseRestore *v1.DatabaseRestore, opts metav1.CreateOptions) (*v1.DatabaseRestore, error)
+	Update(ctx context.Context, databaseRestore *v1.DatabaseRestore, opts metav1.UpdateOptions) (*v1.DatabaseRestore, error)
+	UpdateStatus(ctx context.Context, databaseRestore *v1.DatabaseRestore, opts metav1.UpdateOptions) (*v1.DatabaseRestore, error)
+	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1.DatabaseRestore, error)
+	List(ctx context.Context, opts metav1.ListOptions) (*v1.DatabaseRestoreList, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (result *v1.DatabaseRestore, err error)
+	DatabaseRestoreExpansion
+}
+
+// databaseRestores implements DatabaseRestoreInterface
+type databaseRestores struct {
+	client rest.Interface
+	ns     string
+}
+
+// newDatabaseRestores returns a DatabaseRestores
+func newDatabaseRestores(c *IgorV1Client, namespace string) *databaseRestores {
+	return &databaseRestores{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the databaseRestore, and returns the corresponding databaseRestore object, and an error if there is any.
+func (c *databaseRestores) Get(ctx context.Context, name string, options metav1.GetOptions) (result *v1.DatabaseRestore, err error) {
+	result = &v1.DatabaseRestore{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("databaserestores").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of DatabaseRestores that match those selectors.
+func (c *databaseRestores) List(ctx context.Context, opts metav1.ListOptions) (result *v1.DatabaseRestoreList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1.DatabaseRestoreList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("databaserestores").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested databaseRestores.
+func (c *databaseRestores) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("databaserestores").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch(ctx)
+}
+
+// Create takes the representation of a databaseRestore and creates it.  Returns the server's representation of the databaseRestore, and an error, if there is any.
+func (c *databaseRestores) Create(ctx context.Context, databaseRestore *v1.DatabaseRestore, opts metav1.CreateOptions) (result *v1.DatabaseRestore, err error) {
+	result = &v1.DatabaseRestore{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("databaserestores").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(databaseRestore).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Update takes the representation of a databaseRestore and updates it. Returns the server's representation of the databaseRestore, and an error, if there is any.
+func (c *databaseRestores) Update(ctx context.Context, databaseRestore *v1.DatabaseRestore, opts metav1.UpdateOptions) (result *v1.DatabaseRestore, err error) {
+	result = &v1.DatabaseRestore{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("databaserestores").
+		Name(databaseRestore.Name).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(databaseRestore).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *databaseRestores) UpdateStatus(ctx context.Context, databaseRestore *v1.DatabaseRestore, opts metav1.UpdateOptions) (result *v1.DatabaseRestore, err error) {
+	result = &v1.DatabaseRestore{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("databaserestores").
+		Name(databaseRestore.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(databaseRestore).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Delete takes name of the databaseRestore and deletes it. Returns an error if one occurs.
+func (c *databaseRestores) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("databaserestores").
+		Name(name).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *databaseRestores) DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error {
+	var timeout time.Duration
+	if listOpts.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOpts.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("databaserestores").
+		VersionedParams(&listOpts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// Patch applies the patch and returns the patched databaseRestore.
+func (c *databaseRestores) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (result *v1.DatabaseRestore, err error) {
+	result = &v1.DatabaseRestore{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("databaserestores").
+		Name(name).
+		SubResource(subresources...).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(data).
+		Do(ctx).
+		Into(result)
+	return
+}
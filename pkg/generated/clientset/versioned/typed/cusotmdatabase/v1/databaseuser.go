@@ -0,0 +1,182 @@
+/*
+
+ */
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+	scheme "k8s.io/custom-database/pkg/generated/clientset/versioned/scheme"
+)
+
+// DatabaseUsersGetter has a method to return a DatabaseUserInterface.
+// A group's client should implement this interface.
+type DatabaseUsersGetter interface {
+	DatabaseUsers(namespace string) DatabaseUserInterface
+}
+
+// DatabaseUserInterface has methods to work with DatabaseUser resources.
+type DatabaseUserInterface interface {
+	Create(ctx context.Context, databaseUser *v1.DatabaseUser, opts metav1.CreateOptions) (*v1.DatabaseUser, error)
+	Update(ctx context.Context, databaseUser *v1.DatabaseUser, opts metav1.UpdateOptions) (*v1.DatabaseUser, error)
+	UpdateStatus(ctx context.Context, databaseUser *v1.DatabaseUser, opts metav1.UpdateOptions) (*v1.DatabaseUser, error)
+	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1.DatabaseUser, error)
+	List(ctx context.Context, opts metav1.ListOptions) (*v1.DatabaseUserList, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (result *v1.DatabaseUser, err error)
+	DatabaseUserExpansion
+}
+
+// databaseUsers implements DatabaseUserInterface
+type databaseUsers struct {
+	client rest.Interface
+	ns     string
+}
+
+// newDatabaseUsers returns a DatabaseUsers
+func newDatabaseUsers(c *IgorV1Client, namespace string) *databaseUsers {
+	return &databaseUsers{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the databaseUser, and returns the corresponding databaseUser object, and an error if there is any.
+func (c *databaseUsers) Get(ctx context.Context, name string, options metav1.GetOptions) (result *v1.DatabaseUser, err error) {
+	result = &v1.DatabaseUser{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("databaseusers").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of DatabaseUsers that match those selectors.
+func (c *databaseUsers) List(ctx context.Context, opts metav1.ListOptions) (result *v1.DatabaseUserList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1.DatabaseUserList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("databaseusers").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested databaseUsers.
+func (c *databaseUsers) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("databaseusers").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch(ctx)
+}
+
+// Create takes the representation of a databaseUser and creates it.  Returns the server's representation of the databaseUser, and an error, if there is any.
+func (c *databaseUsers) Create(ctx context.Context, databaseUser *v1.DatabaseUser, opts metav1.CreateOptions) (result *v1.DatabaseUser, err error) {
+	result = &v1.DatabaseUser{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("databaseusers").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(databaseUser).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Update takes the representation of a databaseUser and updates it. Returns the server's representation of the databaseUser, and an error, if there is any.
+func (c *databaseUsers) Update(ctx context.Context, databaseUser *v1.DatabaseUser, opts metav1.UpdateOptions) (result *v1.DatabaseUser, err error) {
+	result = &v1.DatabaseUser{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("databaseusers").
+		Name(databaseUser.Name).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(databaseUser).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *databaseUsers) UpdateStatus(ctx context.Context, databaseUser *v1.DatabaseUser, opts metav1.UpdateOptions) (result *v1.DatabaseUser, err error) {
+	result = &v1.DatabaseUser{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("databaseusers").
+		Name(databaseUser.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(databaseUser).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Delete takes name of the databaseUser and deletes it. Returns an error if one occurs.
+func (c *databaseUsers) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("databaseusers").
+		Name(name).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *databaseUsers) DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error {
+	var timeout time.Duration
+	if listOpts.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOpts.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("databaseusers").
+		VersionedParams(&listOpts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// Patch applies the patch and returns the patched databaseUser.
+func (c *databaseUsers) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (result *v1.DatabaseUser, err error) {
+	result = &v1.DatabaseUser{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("databaseusers").
+		Name(name).
+		SubResource(subresources...).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(data).
+		Do(ctx).
+		Into(result)
+	return
+}
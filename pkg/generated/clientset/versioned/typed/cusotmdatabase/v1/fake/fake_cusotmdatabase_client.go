@@ -19,6 +19,22 @@ func (c *FakeIgorV1) CustomDatabases(namespace string) v1.CustomDatabaseInterfac
 	return &FakeCustomDatabases{c, namespace}
 }
 
+func (c *FakeIgorV1) DatabaseBackups(namespace string) v1.DatabaseBackupInterface {
+	return &FakeDatabaseBackups{c, namespace}
+}
+
+func (c *FakeIgorV1) DatabaseGrants(namespace string) v1.DatabaseGrantInterface {
+	return &FakeDatabaseGrants{c, namespace}
+}
+
+func (c *FakeIgorV1) DatabaseRestores(namespace string) v1.DatabaseRestoreInterface {
+	return &FakeDatabaseRestores{c, namespace}
+}
+
+func (c *FakeIgorV1) DatabaseUsers(namespace string) v1.DatabaseUserInterface {
+	return &FakeDatabaseUsers{c, namespace}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeIgorV1) RESTClient() rest.Interface {
@@ -88,6 +88,18 @@ func (c *FakeCustomDatabases) Update(ctx context.Context, customDatabase *v1.Cus
 	return obj.(*v1.CustomDatabase), err
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeCustomDatabases) UpdateStatus(ctx context.Context, customDatabase *v1.CustomDatabase, opts metav1.UpdateOptions) (*v1.CustomDatabase, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(customdatabasesResource, "status", c.ns, customDatabase), &v1.CustomDatabase{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.CustomDatabase), err
+}
+
 // Delete takes name of the customDatabase and deletes it. Returns an error if one occurs.
 func (c *FakeCustomDatabases) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
 	_, err := c.Fake.
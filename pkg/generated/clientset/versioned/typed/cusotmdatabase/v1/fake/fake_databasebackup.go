@@ -0,0 +1,128 @@
+/*
+
+ */
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+)
+
+// FakeDatabaseBackups implements DatabaseBackupInterface
+type FakeDatabaseBackups struct {
+	Fake *FakeIgorV1
+	ns   string
+}
+
+var databasebackupsResource = v1.SchemeGroupVersion.WithResource("databasebackups")
+
+var databasebackupsKind = v1.SchemeGroupVersion.WithKind("DatabaseBackup")
+
+// Get takes name of the databaseBackup, and returns the corresponding databaseBackup object, and an error if there is any.
+func (c *FakeDatabaseBackups) Get(ctx context.Context, name string, options metav1.GetOptions) (result *v1.DatabaseBackup, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(databasebackupsResource, c.ns, name), &v1.DatabaseBackup{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.DatabaseBackup), err
+}
+
+// List takes label and field selectors, and returns the list of DatabaseBackups that match those selectors.
+func (c *FakeDatabaseBackups) List(ctx context.Context, opts metav1.ListOptions) (result *v1.DatabaseBackupList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(databasebackupsResource, databasebackupsKind, c.ns, opts), &v1.DatabaseBackupList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1.DatabaseBackupList{ListMeta: obj.(*v1.DatabaseBackupList).ListMeta}
+	for _, item := range obj.(*v1.DatabaseBackupList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested databaseBackups.
+func (c *FakeDatabaseBackups) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(databasebackupsResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a databaseBackup and creates it.  Returns the server's representation of the databaseBackup, and an error, if there is any.
+func (c *FakeDatabaseBackups) Create(ctx context.Context, databaseBackup *v1.DatabaseBackup, opts metav1.CreateOptions) (result *v1.DatabaseBackup, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(databasebackupsResource, c.ns, databaseBackup), &v1.DatabaseBackup{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.DatabaseBackup), err
+}
+
+// Update takes the representation of a databaseBackup and updates it. Returns the server's representation of the databaseBackup, and an error, if there is any.
+func (c *FakeDatabaseBackups) Update(ctx context.Context, databaseBackup *v1.DatabaseBackup, opts metav1.UpdateOptions) (result *v1.DatabaseBackup, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(databasebackupsResource, c.ns, databaseBackup), &v1.DatabaseBackup{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.DatabaseBackup), err
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeDatabaseBackups) UpdateStatus(ctx context.Context, databaseBackup *v1.DatabaseBackup, opts metav1.UpdateOptions) (*v1.DatabaseBackup, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(databasebackupsResource, "status", c.ns, databaseBackup), &v1.DatabaseBackup{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.DatabaseBackup), err
+}
+
+// Delete takes name of the databaseBackup and deletes it. Returns an error if one occurs.
+func (c *FakeDatabaseBackups) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteActionWithOptions(databasebackupsResource, c.ns, name, opts), &v1.DatabaseBackup{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeDatabaseBackups) DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(databasebackupsResource, c.ns, listOpts)
+
+	_, err := c.Fake.Invokes(action, &v1.DatabaseBackupList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched databaseBackup.
+func (c *FakeDatabaseBackups) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (result *v1.DatabaseBackup, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(databasebackupsResource, c.ns, name, pt, data, subresources...), &v1.DatabaseBackup{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.DatabaseBackup), err
+}
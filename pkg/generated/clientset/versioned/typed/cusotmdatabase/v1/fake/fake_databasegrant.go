@@ -0,0 +1,128 @@
+/*
+
+ */
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+)
+
+// FakeDatabaseGrants implements DatabaseGrantInterface
+type FakeDatabaseGrants struct {
+	Fake *FakeIgorV1
+	ns   string
+}
+
+var databasegrantsResource = v1.SchemeGroupVersion.WithResource("databasegrants")
+
+var databasegrantsKind = v1.SchemeGroupVersion.WithKind("DatabaseGrant")
+
+// Get takes name of the databaseGrant, and returns the corresponding databaseGrant object, and an error if there is any.
+func (c *FakeDatabaseGrants) Get(ctx context.Context, name string, options metav1.GetOptions) (result *v1.DatabaseGrant, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(databasegrantsResource, c.ns, name), &v1.DatabaseGrant{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.DatabaseGrant), err
+}
+
+// List takes label and field selectors, and returns the list of DatabaseGrants that match those selectors.
+func (c *FakeDatabaseGrants) List(ctx context.Context, opts metav1.ListOptions) (result *v1.DatabaseGrantList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(databasegrantsResource, databasegrantsKind, c.ns, opts), &v1.DatabaseGrantList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1.DatabaseGrantList{ListMeta: obj.(*v1.DatabaseGrantList).ListMeta}
+	for _, item := range obj.(*v1.DatabaseGrantList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested databaseGrants.
+func (c *FakeDatabaseGrants) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(databasegrantsResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a databaseGrant and creates it.  Returns the server's representation of the databaseGrant, and an error, if there is any.
+func (c *FakeDatabaseGrants) Create(ctx context.Context, databaseGrant *v1.DatabaseGrant, opts metav1.CreateOptions) (result *v1.DatabaseGrant, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(databasegrantsResource, c.ns, databaseGrant), &v1.DatabaseGrant{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.DatabaseGrant), err
+}
+
+// Update takes the representation of a databaseGrant and updates it. Returns the server's representation of the databaseGrant, and an error, if there is any.
+func (c *FakeDatabaseGrants) Update(ctx context.Context, databaseGrant *v1.DatabaseGrant, opts metav1.UpdateOptions) (result *v1.DatabaseGrant, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(databasegrantsResource, c.ns, databaseGrant), &v1.DatabaseGrant{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.DatabaseGrant), err
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeDatabaseGrants) UpdateStatus(ctx context.Context, databaseGrant *v1.DatabaseGrant, opts metav1.UpdateOptions) (*v1.DatabaseGrant, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(databasegrantsResource, "status", c.ns, databaseGrant), &v1.DatabaseGrant{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.DatabaseGrant), err
+}
+
+// Delete takes name of the databaseGrant and deletes it. Returns an error if one occurs.
+func (c *FakeDatabaseGrants) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteActionWithOptions(databasegrantsResource, c.ns, name, opts), &v1.DatabaseGrant{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeDatabaseGrants) DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(databasegrantsResource, c.ns, listOpts)
+
+	_, err := c.Fake.Invokes(action, &v1.DatabaseGrantList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched databaseGrant.
+func (c *FakeDatabaseGrants) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (result *v1.DatabaseGrant, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(databasegrantsResource, c.ns, name, pt, data, subresources...), &v1.DatabaseGrant{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.DatabaseGrant), err
+}
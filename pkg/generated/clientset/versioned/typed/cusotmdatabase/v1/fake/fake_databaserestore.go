@@ -0,0 +1,128 @@
+/*
+
+ */
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+)
+
+// FakeDatabaseRestores implements DatabaseRestoreInterface
+type FakeDatabaseRestores struct {
+	Fake *FakeIgorV1
+	ns   string
+}
+
+var databaserestoresResource = v1.SchemeGroupVersion.WithResource("databaserestores")
+
+var databaserestoresKind = v1.SchemeGroupVersion.WithKind("DatabaseRestore")
+
+// Get takes name of the databaseRestore, and returns the corresponding databaseRestore object, and an error if there is any.
+func (c *FakeDatabaseRestores) Get(ctx context.Context, name string, options metav1.GetOptions) (result *v1.DatabaseRestore, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(databaserestoresResource, c.ns, name), &v1.DatabaseRestore{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.DatabaseRestore), err
+}
+
+// List takes label and field selectors, and returns the list of DatabaseRestores that match those selectors.
+func (c *FakeDatabaseRestores) List(ctx context.Context, opts metav1.ListOptions) (result *v1.DatabaseRestoreList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(databaserestoresResource, databaserestoresKind, c.ns, opts), &v1.DatabaseRestoreList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1.DatabaseRestoreList{ListMeta: obj.(*v1.DatabaseRestoreList).ListMeta}
+	for _, item := range obj.(*v1.DatabaseRestoreList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested databaseRestores.
+func (c *FakeDatabaseRestores) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(databaserestoresResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a databaseRestore and creates it.  Returns the server's representation of the databaseRestore, and an error, if there is any.
+func (c *FakeDatabaseRestores) Create(ctx context.Context, databaseRestore *v1.DatabaseRestore, opts metav1.CreateOptions) (result *v1.DatabaseRestore, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(databaserestoresResource, c.ns, databaseRestore), &v1.DatabaseRestore{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.DatabaseRestore), err
+}
+
+// Update takes the representation of a databaseRestore and updates it. Returns the server's representation of the databaseRestore, and an error, if there is any.
+func (c *FakeDatabaseRestores) Update(ctx context.Context, databaseRestore *v1.DatabaseRestore, opts metav1.UpdateOptions) (result *v1.DatabaseRestore, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(databaserestoresResource, c.ns, databaseRestore), &v1.DatabaseRestore{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.DatabaseRestore), err
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeDatabaseRestores) UpdateStatus(ctx context.Context, databaseRestore *v1.DatabaseRestore, opts metav1.UpdateOptions) (*v1.DatabaseRestore, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(databaserestoresResource, "status", c.ns, databaseRestore), &v1.DatabaseRestore{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.DatabaseRestore), err
+}
+
+// Delete takes name of the databaseRestore and deletes it. Returns an error if one occurs.
+func (c *FakeDatabaseRestores) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteActionWithOptions(databaserestoresResource, c.ns, name, opts), &v1.DatabaseRestore{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeDatabaseRestores) DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(databaserestoresResource, c.ns, listOpts)
+
+	_, err := c.Fake.Invokes(action, &v1.DatabaseRestoreList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched databaseRestore.
+func (c *FakeDatabaseRestores) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (result *v1.DatabaseRestore, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(databaserestoresResource, c.ns, name, pt, data, subresources...), &v1.DatabaseRestore{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.DatabaseRestore), err
+}
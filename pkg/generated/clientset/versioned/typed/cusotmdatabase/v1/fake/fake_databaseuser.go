@@ -0,0 +1,128 @@
+/*
+
+ */
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+	v1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+)
+
+// FakeDatabaseUsers implements DatabaseUserInterface
+type FakeDatabaseUsers struct {
+	Fake *FakeIgorV1
+	ns   string
+}
+
+var databaseusersResource = v1.SchemeGroupVersion.WithResource("databaseusers")
+
+var databaseusersKind = v1.SchemeGroupVersion.WithKind("DatabaseUser")
+
+// Get takes name of the databaseUser, and returns the corresponding databaseUser object, and an error if there is any.
+func (c *FakeDatabaseUsers) Get(ctx context.Context, name string, options metav1.GetOptions) (result *v1.DatabaseUser, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(databaseusersResource, c.ns, name), &v1.DatabaseUser{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.DatabaseUser), err
+}
+
+// List takes label and field selectors, and returns the list of DatabaseUsers that match those selectors.
+func (c *FakeDatabaseUsers) List(ctx context.Context, opts metav1.ListOptions) (result *v1.DatabaseUserList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(databaseusersResource, databaseusersKind, c.ns, opts), &v1.DatabaseUserList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1.DatabaseUserList{ListMeta: obj.(*v1.DatabaseUserList).ListMeta}
+	for _, item := range obj.(*v1.DatabaseUserList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested databaseUsers.
+func (c *FakeDatabaseUsers) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(databaseusersResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a databaseUser and creates it.  Returns the server's representation of the databaseUser, and an error, if there is any.
+func (c *FakeDatabaseUsers) Create(ctx context.Context, databaseUser *v1.DatabaseUser, opts metav1.CreateOptions) (result *v1.DatabaseUser, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(databaseusersResource, c.ns, databaseUser), &v1.DatabaseUser{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.DatabaseUser), err
+}
+
+// Update takes the representation of a databaseUser and updates it. Returns the server's representation of the databaseUser, and an error, if there is any.
+func (c *FakeDatabaseUsers) Update(ctx context.Context, databaseUser *v1.DatabaseUser, opts metav1.UpdateOptions) (result *v1.DatabaseUser, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(databaseusersResource, c.ns, databaseUser), &v1.DatabaseUser{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.DatabaseUser), err
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeDatabaseUsers) UpdateStatus(ctx context.Context, databaseUser *v1.DatabaseUser, opts metav1.UpdateOptions) (*v1.DatabaseUser, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(databaseusersResource, "status", c.ns, databaseUser), &v1.DatabaseUser{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.DatabaseUser), err
+}
+
+// Delete takes name of the databaseUser and deletes it. Returns an error if one occurs.
+func (c *FakeDatabaseUsers) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteActionWithOptions(databaseusersResource, c.ns, name, opts), &v1.DatabaseUser{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeDatabaseUsers) DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(databaseusersResource, c.ns, listOpts)
+
+	_, err := c.Fake.Invokes(action, &v1.DatabaseUserList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched databaseUser.
+func (c *FakeDatabaseUsers) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (result *v1.DatabaseUser, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(databaseusersResource, c.ns, name, pt, data, subresources...), &v1.DatabaseUser{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.DatabaseUser), err
+}
@@ -6,3 +6,11 @@
 package v1
 
 type CustomDatabaseExpansion interface{}
+
+type DatabaseBackupExpansion interface{}
+
+type DatabaseGrantExpansion interface{}
+
+type DatabaseRestoreExpansion interface{}
+
+type DatabaseUserExpansion interface{}
@@ -0,0 +1,77 @@
+/*
+
+ */
+// Code generated by informer-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	"context"
+	time "time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	cache "k8s.io/client-go/tools/cache"
+	cusotmdatabasev1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+	versioned "k8s.io/custom-database/pkg/generated/clientset/versioned"
+	internalinterfaces "k8s.io/custom-database/pkg/generated/informers/externalversions/internalinterfaces"
+	v1 "k8s.io/custom-database/pkg/generated/listers/cusotmdatabase/v1"
+)
+
+// DatabaseGrantInformer provides access to a shared informer and lister for
+// DatabaseGrants.
+type DatabaseGrantInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() v1.DatabaseGrantLister
+}
+
+type databaseGrantInformer struct {
+	factory          internalinterfaces.SharedInformerFactory
+	tweakListOptions internalinterfaces.TweakListOptionsFunc
+	namespace        string
+}
+
+// NewDatabaseGrantInformer constructs a new informer for DatabaseGrant type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewDatabaseGrantInformer(client versioned.Interface, namespace string, resyncPeriod time.Duration, indexers cache.Indexers) cache.SharedIndexInformer {
+	return NewFilteredDatabaseGrantInformer(client, namespace, resyncPeriod, indexers, nil)
+}
+
+// NewFilteredDatabaseGrantInformer constructs a new informer for DatabaseGrant type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewFilteredDatabaseGrantInformer(client versioned.Interface, namespace string, resyncPeriod time.Duration, indexers cache.Indexers, tweakListOptions internalinterfaces.TweakListOptionsFunc) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.IgorV1().DatabaseGrants(namespace).List(context.TODO(), options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.IgorV1().DatabaseGrants(namespace).Watch(context.TODO(), options)
+			},
+		},
+		&cusotmdatabasev1.DatabaseGrant{},
+		resyncPeriod,
+		indexers,
+	)
+}
+
+func (f *databaseGrantInformer) defaultInformer(client versioned.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return NewFilteredDatabaseGrantInformer(client, f.namespace, resyncPeriod, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, f.tweakListOptions)
+}
+
+func (f *databaseGrantInformer) Informer() cache.SharedIndexInformer {
+	return f.factory.InformerFor(&cusotmdatabasev1.DatabaseGrant{}, f.defaultInformer)
+}
+
+func (f *databaseGrantInformer) Lister() v1.DatabaseGrantLister {
+	return v1.NewDatabaseGrantLister(f.Informer().GetIndexer())
+}
@@ -0,0 +1,77 @@
+/*
+
+ */
+// Code generated by informer-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	"context"
+	time "time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	cache "k8s.io/client-go/tools/cache"
+	cusotmdatabasev1 "k8s.io/custom-database/pkg/apis/cusotmdatabase/v1"
+	versioned "k8s.io/custom-database/pkg/generated/clientset/versioned"
+	internalinterfaces "k8s.io/custom-database/pkg/generated/informers/externalversions/internalinterfaces"
+	v1 "k8s.io/custom-database/pkg/generated/listers/cusotmdatabase/v1"
+)
+
+// DatabaseUserInformer provides access to a shared informer and lister for
+// DatabaseUsers.
+type DatabaseUserInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() v1.DatabaseUserLister
+}
+
+type databaseUserInformer struct {
+	factory          internalinterfaces.SharedInformerFactory
+	tweakListOptions internalinterfaces.TweakListOptionsFunc
+	namespace        string
+}
+
+// NewDatabaseUserInformer constructs a new informer for DatabaseUser type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewDatabaseUserInformer(client versioned.Interface, namespace string, resyncPeriod time.Duration, indexers cache.Indexers) cache.SharedIndexInformer {
+	return NewFilteredDatabaseUserInformer(client, namespace, resyncPeriod, indexers, nil)
+}
+
+// NewFilteredDatabaseUserInformer constructs a new informer for DatabaseUser type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewFilteredDatabaseUserInformer(client versioned.Interface, namespace string, resyncPeriod time.Duration, indexers cache.Indexers, tweakListOptions internalinterfaces.TweakListOptionsFunc) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.IgorV1().DatabaseUsers(namespace).List(context.TODO(), options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.IgorV1().DatabaseUsers(namespace).Watch(context.TODO(), options)
+			},
+		},
+		&cusotmdatabasev1.DatabaseUser{},
+		resyncPeriod,
+		indexers,
+	)
+}
+
+func (f *databaseUserInformer) defaultInformer(client versioned.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return NewFilteredDatabaseUserInformer(client, f.namespace, resyncPeriod, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, f.tweakListOptions)
+}
+
+func (f *databaseUserInformer) Informer() cache.SharedIndexInformer {
+	return f.factory.InformerFor(&cusotmdatabasev1.DatabaseUser{}, f.defaultInformer)
+}
+
+func (f *databaseUserInformer) Lister() v1.DatabaseUserLister {
+	return v1.NewDatabaseUserLister(f.Informer().GetIndexer())
+}
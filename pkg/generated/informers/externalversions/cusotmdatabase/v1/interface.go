@@ -13,6 +13,14 @@ import (
 type Interface interface {
 	// CustomDatabases returns a CustomDatabaseInformer.
 	CustomDatabases() CustomDatabaseInformer
+	// DatabaseBackups returns a DatabaseBackupInformer.
+	DatabaseBackups() DatabaseBackupInformer
+	// DatabaseGrants returns a DatabaseGrantInformer.
+	DatabaseGrants() DatabaseGrantInformer
+	// DatabaseRestores returns a DatabaseRestoreInformer.
+	DatabaseRestores() DatabaseRestoreInformer
+	// DatabaseUsers returns a DatabaseUserInformer.
+	DatabaseUsers() DatabaseUserInformer
 }
 
 type version struct {
@@ -30,3 +38,23 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 func (v *version) CustomDatabases() CustomDatabaseInformer {
 	return &customDatabaseInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
+
+// DatabaseBackups returns a DatabaseBackupInformer.
+func (v *version) DatabaseBackups() DatabaseBackupInformer {
+	return &databaseBackupInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// DatabaseGrants returns a DatabaseGrantInformer.
+func (v *version) DatabaseGrants() DatabaseGrantInformer {
+	return &databaseGrantInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// DatabaseRestores returns a DatabaseRestoreInformer.
+func (v *version) DatabaseRestores() DatabaseRestoreInformer {
+	return &databaseRestoreInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// DatabaseUsers returns a DatabaseUserInformer.
+func (v *version) DatabaseUsers() DatabaseUserInformer {
+	return &databaseUserInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
@@ -42,6 +42,14 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 	// Group=igor.yatsevich.ru, Version=v1
 	case v1.SchemeGroupVersion.WithResource("customdatabases"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Igor().V1().CustomDatabases().Informer()}, nil
+	case v1.SchemeGroupVersion.WithResource("databasebackups"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Igor().V1().DatabaseBackups().Informer()}, nil
+	case v1.SchemeGroupVersion.WithResource("databasegrants"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Igor().V1().DatabaseGrants().Informer()}, nil
+	case v1.SchemeGroupVersion.WithResource("databaserestores"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Igor().V1().DatabaseRestores().Informer()}, nil
+	case v1.SchemeGroupVersion.WithResource("databaseusers"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Igor().V1().DatabaseUsers().Informer()}, nil
 
 	}
 
@@ -12,3 +12,35 @@ type CustomDatabaseListerExpansion interface{}
 // CustomDatabaseNamespaceListerExpansion allows custom methods to be added to
 // CustomDatabaseNamespaceLister.
 type CustomDatabaseNamespaceListerExpansion interface{}
+
+// DatabaseBackupListerExpansion allows custom methods to be added to
+// DatabaseBackupLister.
+type DatabaseBackupListerExpansion interface{}
+
+// DatabaseBackupNamespaceListerExpansion allows custom methods to be added to
+// DatabaseBackupNamespaceLister.
+type DatabaseBackupNamespaceListerExpansion interface{}
+
+// DatabaseGrantListerExpansion allows custom methods to be added to
+// DatabaseGrantLister.
+type DatabaseGrantListerExpansion interface{}
+
+// DatabaseGrantNamespaceListerExpansion allows custom methods to be added to
+// DatabaseGrantNamespaceLister.
+type DatabaseGrantNamespaceListerExpansion interface{}
+
+// DatabaseRestoreListerExpansion allows custom methods to be added to
+// DatabaseRestoreLister.
+type DatabaseRestoreListerExpansion interface{}
+
+// DatabaseRestoreNamespaceListerExpansion allows custom methods to be added to
+// DatabaseRestoreNamespaceLister.
+type DatabaseRestoreNamespaceListerExpansion interface{}
+
+// DatabaseUserListerExpansion allows custom methods to be added to
+// DatabaseUserLister.
+type DatabaseUserListerExpansion interface{}
+
+// DatabaseUserNamespaceListerExpansion allows custom methods to be added to
+// DatabaseUserNamespaceLister.
+type DatabaseUserNamespaceListerExpansion interface{}
@@ -2,9 +2,12 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"net/url"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -41,6 +44,7 @@ type QueryExecutor interface {
 	sqlx.Execer
 	sqlx.ExecerContext
 	sqlx.QueryerContext
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
 }
 
 type Logger interface {
@@ -69,11 +73,37 @@ func NewDB(ctx context.Context, dsn string, l Logger) (*Database, error) {
 
 	err = res.connect(ctx, pingCheckMethod)
 	if err != nil {
-		return nil, fmt.Errorf("cannot init connection to DB, dsn='%s': %w", dsn, err)
+		return nil, fmt.Errorf("cannot init connection to DB, target='%s': %w", dsnTarget(dsn), err)
 	}
 	return res, nil
 }
 
+// dsnTarget возвращает адрес и имя БД из DSN без пользователя и пароля, чтобы их не было в ошибках и логах.
+// Поддерживаются URL ("postgres://...") и формат "key=value".
+func dsnTarget(dsn string) string {
+	if parsedDSN, err := url.Parse(dsn); err == nil && parsedDSN.Scheme != "" {
+		return parsedDSN.Host + parsedDSN.Path
+	}
+
+	var host, port, dbName string
+	for _, field := range strings.Fields(dsn) {
+		key, value, _ := strings.Cut(field, "=")
+		value = strings.Trim(value, "'")
+		switch key {
+		case "host":
+			host = value
+		case "port":
+			port = value
+		case "dbname":
+			dbName = value
+		}
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	return host + "/" + dbName
+}
+
 type PoolSettings struct {
 	ConnMaxIdleTime time.Duration
 	ConnMaxLifetime time.Duration
@@ -211,3 +241,102 @@ func (d *Database) Close() error {
 func (d *Database) DB() DB {
 	return d.db
 }
+
+// ConnectionConfig parameters of connection to Postgresql server
+type ConnectionConfig struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+	Database string
+	TLS      TLSConfig
+}
+
+// DSN returns connection string in URL format
+func (c ConnectionConfig) DSN() string {
+	query := url.Values{}
+	query.Set("sslmode", c.TLS.mode())
+	if c.TLS.RootCertFile != "" {
+		query.Set("sslrootcert", c.TLS.RootCertFile)
+	}
+	if c.TLS.CertFile != "" {
+		query.Set("sslcert", c.TLS.CertFile)
+	}
+	if c.TLS.KeyFile != "" {
+		query.Set("sslkey", c.TLS.KeyFile)
+	}
+
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.User, c.Password),
+		Host:     fmt.Sprintf("%s:%d", c.Host, c.Port),
+		Path:     "/" + c.Database,
+		RawQuery: query.Encode(),
+	}
+
+	return dsn.String()
+}
+
+// Connector opens short-living connections to different databases of the same server. Some statements (e.g. about
+// schemas or objects inside database) can be executed only in connection to the concrete database.
+type Connector struct {
+	mu     sync.RWMutex
+	config ConnectionConfig
+	logger Logger
+}
+
+func NewConnector(config ConnectionConfig, l Logger) *Connector {
+	return &Connector{config: config, logger: l}
+}
+
+// Connect opens connection to database with credentials of connector. Caller should close it.
+// Unlike NewDB, it doesn't retry failed attempts - caller decides, when to try again.
+func (c *Connector) Connect(ctx context.Context, database string) (*Database, error) {
+	config := c.Config()
+	config.Database = database
+
+	return c.connect(ctx, config)
+}
+
+// ConnectAs opens connection to database with credentials of another role, e.g. to execute statements on behalf of
+// database owner
+func (c *Connector) ConnectAs(ctx context.Context, database, user, password string) (*Database, error) {
+	config := c.Config()
+	config.Database = database
+	config.User = user
+	config.Password = password
+
+	return c.connect(ctx, config)
+}
+
+// Config returns parameters of connections, which are opened by connector
+func (c *Connector) Config() ConnectionConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.config
+}
+
+// SetCredentials changes credentials of connections, which are opened after it. Opened connections aren't changed.
+func (c *Connector) SetCredentials(user, password string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.config.User = user
+	c.config.Password = password
+}
+
+func (c *Connector) connect(ctx context.Context, config ConnectionConfig) (*Database, error) {
+	db, err := sqlx.Open(dbDriverName, config.DSN())
+	if err != nil {
+		return nil, fmt.Errorf("cannot init connection to DB: %w", err)
+	}
+
+	db.SetMaxOpenConns(1)
+	if err = db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("cannot connect to database %s: %w", config.Database, err)
+	}
+
+	return &Database{db: db, logger: c.logger}, nil
+}
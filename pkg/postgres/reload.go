@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// DefaultDrainDelay how long the old pool stays open after it's replaced
+const DefaultDrainDelay = time.Minute
+
+// ReloadableDB pool of connections, which may be replaced by pool with new credentials. Queries, which are started
+// on the old pool, are finished before it's closed, so callers don't notice replacement.
+type ReloadableDB struct {
+	current atomic.Pointer[Database]
+	// drainDelay how long the old pool stays open, caller may take it just before replacement and start query later
+	drainDelay time.Duration
+}
+
+func NewReloadableDB(db *Database) *ReloadableDB {
+	r := &ReloadableDB{drainDelay: DefaultDrainDelay}
+	r.current.Store(db)
+
+	return r
+}
+
+// Swap replaces pool and closes the old one after drain delay
+func (r *ReloadableDB) Swap(db *Database) {
+	old := r.current.Swap(db)
+	if old == nil {
+		return
+	}
+
+	// Close waits for queries, that are already started, and prevents new ones. Queries aren't started on the old
+	// pool after delay, because every call takes the current pool.
+	time.AfterFunc(r.drainDelay, func() {
+		if err := old.Close(); err != nil && old.logger != nil {
+			old.logger.Error(err, "не удалось закрыть старый пул соединений")
+		}
+	})
+}
+
+// Close closes the current pool
+func (r *ReloadableDB) Close() error {
+	return r.current.Load().Close()
+}
+
+func (r *ReloadableDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return r.current.Load().db.Exec(query, args...)
+}
+
+func (r *ReloadableDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return r.current.Load().db.ExecContext(ctx, query, args...)
+}
+
+func (r *ReloadableDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return r.current.Load().db.QueryContext(ctx, query, args...)
+}
+
+func (r *ReloadableDB) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	return r.current.Load().db.QueryxContext(ctx, query, args...)
+}
+
+func (r *ReloadableDB) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row {
+	return r.current.Load().db.QueryRowxContext(ctx, query, args...)
+}
+
+func (r *ReloadableDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return r.current.Load().db.QueryRowContext(ctx, query, args...)
+}
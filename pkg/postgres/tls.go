@@ -0,0 +1,95 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// SSLModes values of sslmode, which are supported by driver
+var SSLModes = []string{"disable", "require", "verify-ca", "verify-full"} // nolint: gochecknoglobals
+
+// TLSConfig TLS parameters of connection in terms of libpq. Empty Mode means, that TLS is disabled.
+type TLSConfig struct {
+	Mode string
+	// RootCertFile CA bundle, which certificate of server is verified by in modes verify-ca and verify-full
+	RootCertFile string
+	// CertFile and KeyFile client certificate, when role authenticates by certificate
+	CertFile string
+	KeyFile  string
+}
+
+// Validate checks, that mode is supported and files of client certificate are set together
+func (t TLSConfig) Validate() error {
+	isSupported := false
+	for _, mode := range SSLModes {
+		isSupported = isSupported || t.mode() == mode
+	}
+	if !isSupported {
+		return fmt.Errorf("unsupported sslmode %q, it should be one of %v", t.Mode, SSLModes)
+	}
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return fmt.Errorf("client certificate and its key should be set together")
+	}
+	if (t.Mode == "verify-ca" || t.Mode == "verify-full") && t.RootCertFile == "" {
+		return fmt.Errorf("sslmode %s requires root certificate", t.Mode)
+	}
+
+	return nil
+}
+
+// WithoutClientCertificate returns config for connections of another role, which can't use client certificate of
+// this one
+func (t TLSConfig) WithoutClientCertificate() TLSConfig {
+	t.CertFile = ""
+	t.KeyFile = ""
+
+	return t
+}
+
+// Env returns environment variables of libpq, that are used by pg_dump and pg_restore
+func (t TLSConfig) Env() []string {
+	env := []string{"PGSSLMODE=" + t.mode()}
+	if t.RootCertFile != "" {
+		env = append(env, "PGSSLROOTCERT="+t.RootCertFile)
+	}
+	if t.CertFile != "" {
+		env = append(env, "PGSSLCERT="+t.CertFile, "PGSSLKEY="+t.KeyFile)
+	}
+
+	return env
+}
+
+// WithPEM writes not empty PEM contents to files in dir and returns config, which refers to them. Driver requires key
+// file to be readable only by owner.
+func (t TLSConfig) WithPEM(dir string, rootCert, cert, key []byte) (TLSConfig, error) {
+	files := []struct {
+		content []byte
+		name    string
+		path    *string
+	}{
+		{rootCert, "ca.crt", &t.RootCertFile},
+		{cert, "tls.crt", &t.CertFile},
+		{key, "tls.key", &t.KeyFile},
+	}
+	for _, file := range files {
+		if len(file.content) == 0 {
+			continue
+		}
+		path := filepath.Join(dir, file.name)
+		if err := os.WriteFile(path, file.content, 0o600); err != nil {
+			return t, fmt.Errorf("cannot write %s: %w", file.name, err)
+		}
+		*file.path = path
+	}
+
+	return t, nil
+}
+
+func (t TLSConfig) mode() string {
+	if t.Mode == "" {
+		return "disable"
+	}
+
+	return t.Mode
+}